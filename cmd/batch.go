@@ -18,6 +18,7 @@ import (
 	"github.com/valpere/tile_to_json/internal/config"
 	"github.com/valpere/tile_to_json/internal/output"
 	"github.com/valpere/tile_to_json/internal/tile"
+	"github.com/valpere/tile_to_json/pkg/dem"
 )
 
 // batchCmd represents the batch command
@@ -220,6 +221,10 @@ func runBatch(cmd *cobra.Command, args []string) error {
 		if outputFile == "" {
 			return fmt.Errorf("output file must be specified when using --single-file")
 		}
+		// A single FeatureCollection is built from tile features, which grids have none of
+		if cfg.Raster.Encoding != "" && cfg.Raster.Output == dem.OutputGrid && cfg.Output.Format == "geojson" {
+			return fmt.Errorf("raster grid output cannot be combined into one geojson file; use the json format")
+		}
 		jobConfig.OutputPath = outputFile
 		jobConfig.MultiFile = false
 	} else {
@@ -233,19 +238,17 @@ func runBatch(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create fetcher: %w", err)
 	}
 
-	processor := tile.NewMVTProcessor()
+	processor, err := newProcessor(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create processor: %w", err)
+	}
 
 	// Create writer
-	writerConfig := &output.WriterConfig{
-		Format:      output.Format(cfg.Output.Format),
-		Pretty:      cfg.Output.Pretty,
-		Compression: cfg.Output.Compression,
-		Metadata:    true,
-	}
+	writerConfig := newWriterConfig(cfg, cfg.Output.Compression, true)
 
 	var writer output.Writer
 	if singleFile {
-		writer, err = output.NewFileWriter(writerConfig, outputFile)
+		writer, err = output.NewSingleFileWriter(writerConfig, outputFile)
 	} else {
 		writer, err = output.NewMultiFileWriter(writerConfig, outputDir)
 	}
@@ -285,6 +288,7 @@ func runBatch(cmd *cobra.Command, args []string) error {
 		fmt.Fprintf(os.Stderr, "\nBatch processing completed successfully!\n")
 		fmt.Fprintf(os.Stderr, "Processed: %d tiles\n", job.Progress.ProcessedTiles)
 		fmt.Fprintf(os.Stderr, "Success: %d, Failed: %d\n", job.Progress.SuccessTiles, job.Progress.FailedTiles)
+		fmt.Fprintf(os.Stderr, "Warnings: %d\n", job.Progress.WarningCount)
 		fmt.Fprintf(os.Stderr, "Duration: %v\n", elapsed)
 		fmt.Fprintf(os.Stderr, "Throughput: %.2f tiles/second\n", job.Progress.Throughput)
 		fmt.Fprintf(os.Stderr, "Source: %s\n", sourceType)
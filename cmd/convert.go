@@ -165,7 +165,10 @@ func runConvert(cmd *cobra.Command, args []string) error {
 	}
 
 	// Create processor
-	processor := tile.NewMVTProcessor()
+	processor, err := newProcessor(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create processor: %w", err)
+	}
 
 	// Report what we're doing
 	if viper.GetBool("logging.verbose") {
@@ -196,19 +199,23 @@ func runConvert(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to process tile: %w", err)
 	}
+	for _, warning := range processedTile.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
 
 	// Create writer configuration
-	writerConfig := &output.WriterConfig{
-		Format:      output.Format(cfg.Output.Format),
-		Pretty:      cfg.Output.Pretty,
-		Compression: viper.GetBool("output.compression"),
-		Metadata:    metadata,
-	}
+	writerConfig := newWriterConfig(cfg, viper.GetBool("output.compression"), metadata)
 
 	// Create writer
 	var writer output.Writer
-	if outputPath == "" || outputPath == "-" {
-		writer, err = output.NewStdoutWriter(writerConfig.Format, writerConfig.Pretty)
+	if writerConfig.Format.IsTabular() {
+		// The CSV writer also handles stdout and per-layer files
+		writer, err = output.NewCSVWriter(writerConfig, outputPath)
+	} else if writerConfig.Format == output.FormatGeoParquet {
+		// The GeoParquet writer also handles stdout and partitioned datasets
+		writer, err = output.NewGeoParquetWriter(writerConfig, outputPath)
+	} else if outputPath == "" || outputPath == "-" {
+		writer, err = output.NewStdoutWriterWithConfig(writerConfig)
 	} else {
 		// Ensure output directory exists
 		if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
@@ -247,3 +254,39 @@ func runConvert(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// newProcessor creates the raster DEM processor when a DEM encoding is configured,
+// and the vector tile processor otherwise
+func newProcessor(cfg *config.Config) (tile.Processor, error) {
+	if cfg.Raster.Encoding != "" {
+		return tile.NewRasterProcessor(cfg.ToDEMOptions())
+	}
+
+	conversionOptions, err := cfg.Conversion.ToConversionOptions()
+	if err != nil {
+		return nil, fmt.Errorf("invalid conversion options: %w", err)
+	}
+	return tile.NewMVTProcessorWithOptions(conversionOptions)
+}
+
+// newWriterConfig builds the writer configuration for the configured output format
+func newWriterConfig(cfg *config.Config, compression, metadata bool) *output.WriterConfig {
+	return &output.WriterConfig{
+		Format:               output.Format(cfg.Output.Format),
+		Pretty:               cfg.Output.Pretty,
+		Compression:          compression,
+		Metadata:             metadata,
+		RFC7946:              cfg.Conversion.RFC7946,
+		Timings:              cfg.Output.Timings,
+		Template:             cfg.Output.Template,
+		CoordinateSystem:     cfg.OutputCoordinateSystem(),
+		CSV:                  output.NewCSVOptions(cfg.Output.CSV.Columns, cfg.Output.CSV.Geometry, cfg.Output.CSV.Metadata),
+		FlatGeobufIndex:      cfg.Output.FlatGeobuf.Index,
+		GeoParquet:           output.NewGeoParquetOptions(cfg.Output.Parquet.RowGroupSize, cfg.Output.Parquet.Compression, cfg.Output.Parquet.Partition),
+		GeoPackageIndex:      cfg.Output.GeoPackage.Index,
+		LayerProperty:        cfg.Conversion.LayerProperty,
+		TopoJSONQuantization: cfg.Output.TopoJSON.Quantization,
+		KMLStyles:            cfg.Output.KML.ToKMLStyles(),
+		WKT:                  &output.WKTOptions{SRID: cfg.Output.WKT.SRID, Copy: cfg.Output.WKT.Copy, Binary: cfg.Output.WKT.WKBBinary},
+	}
+}
@@ -0,0 +1,182 @@
+// cmd/encode.go - GeoJSON to Mapbox Vector Tile encoding command
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/valpere/tile_to_json/pkg/mvt"
+)
+
+// encodeCmd represents the encode command
+var encodeCmd = &cobra.Command{
+	Use:   "encode",
+	Short: "Encode GeoJSON features into Mapbox Vector Tiles",
+	Long: `Encode GeoJSON features into Mapbox Vector Tile Protocol Buffer format, the reverse
+of the convert command.
+
+The input may be a FeatureCollection, a single Feature or geometry, or newline-delimited
+GeoJSON. Features are projected to tile space, clipped to the tile plus a buffer and
+snapped to the tile grid. Each feature goes to the layer named by its --layer-property
+value, or to the --layer layer when it has none, so the output of convert encodes back
+into tiles with the same layers.
+
+Input coordinates are read in the --coordinate-system, matching the convert output.
+
+Encode a single tile with --z/--x/--y, or every tile the features touch over a zoom range
+with --min-zoom/--max-zoom. Zoom ranges are written to the output directory as
+z/x/y.mvt.
+
+Examples:
+  # Re-encode an edited tile
+  tile-to-json encode --input tile.geojson --z 14 --x 8362 --y 5956 --output 5956.mvt
+
+  # Encode WGS84 GeoJSON into a tile pyramid
+  tile-to-json encode --input roads.geojson --coordinate-system wgs84 --layer roads \
+    --min-zoom 10 --max-zoom 14 --output ./tiles
+
+  # Encode newline-delimited GeoJSON from stdin with a 512 extent and gzip
+  cat features.ndjson | tile-to-json encode --z 12 --x 2048 --y 1361 --extent 512 --gzip --output tile.mvt`,
+	RunE: runEncode,
+}
+
+func init() {
+	rootCmd.AddCommand(encodeCmd)
+
+	// Input flags
+	encodeCmd.Flags().StringP("input", "i", "-", "input GeoJSON or newline-delimited GeoJSON file (default: stdin)")
+
+	// Tile selection flags
+	encodeCmd.Flags().Int("z", 0, "tile zoom level")
+	encodeCmd.Flags().Int("x", 0, "tile x coordinate")
+	encodeCmd.Flags().Int("y", 0, "tile y coordinate")
+	encodeCmd.Flags().Int("min-zoom", -1, "minimum zoom level of a tile range")
+	encodeCmd.Flags().Int("max-zoom", -1, "maximum zoom level of a tile range")
+
+	// Encoding flags
+	encodeCmd.Flags().Uint32("extent", mvt.DefaultEncodeExtent, "tile extent in integer units")
+	encodeCmd.Flags().Int("buffer", mvt.DefaultEncodeBuffer, "clip buffer around each tile in extent units")
+	encodeCmd.Flags().String("layer", mvt.DefaultEncodeLayer, "layer for features without a layer property")
+	encodeCmd.Flags().Bool("keep-layer-property", false, "keep the layer property as a tile attribute")
+	encodeCmd.Flags().Bool("gzip", false, "gzip the encoded tiles")
+
+	// Output flags
+	encodeCmd.Flags().StringP("output", "o", "", "output tile file (default: stdout), or directory for a zoom range")
+	encodeCmd.Flags().String("extension", ".mvt", "tile file extension for a zoom range")
+
+	// Mark required flags and mutual exclusions
+	encodeCmd.MarkFlagsRequiredTogether("z", "x", "y")
+	encodeCmd.MarkFlagsRequiredTogether("min-zoom", "max-zoom")
+	encodeCmd.MarkFlagsMutuallyExclusive("z", "min-zoom")
+	encodeCmd.MarkFlagsOneRequired("z", "min-zoom")
+}
+
+func runEncode(cmd *cobra.Command, args []string) error {
+	// Get command flags
+	inputPath, _ := cmd.Flags().GetString("input")
+	z, _ := cmd.Flags().GetInt("z")
+	x, _ := cmd.Flags().GetInt("x")
+	y, _ := cmd.Flags().GetInt("y")
+	minZoom, _ := cmd.Flags().GetInt("min-zoom")
+	maxZoom, _ := cmd.Flags().GetInt("max-zoom")
+	extent, _ := cmd.Flags().GetUint32("extent")
+	buffer, _ := cmd.Flags().GetInt("buffer")
+	layer, _ := cmd.Flags().GetString("layer")
+	keepLayerProperty, _ := cmd.Flags().GetBool("keep-layer-property")
+	gzip, _ := cmd.Flags().GetBool("gzip")
+	outputPath, _ := cmd.Flags().GetString("output")
+	extension, _ := cmd.Flags().GetString("extension")
+
+	isRange := cmd.Flags().Changed("min-zoom")
+	if isRange && (outputPath == "" || outputPath == "-") {
+		return fmt.Errorf("an output directory is required when encoding a zoom range")
+	}
+
+	// Create encoder
+	encoder, err := mvt.NewEncoderWithOptions(&mvt.EncoderOptions{
+		Extent:            extent,
+		Buffer:            buffer,
+		LayerProperty:     viper.GetString("conversion.layer_property"),
+		DefaultLayer:      layer,
+		KeepLayerProperty: keepLayerProperty,
+		CoordinateSystem:  viper.GetString("conversion.coordinate_system"),
+		Gzip:              gzip,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create encoder: %w", err)
+	}
+
+	// Read the features
+	var input io.Reader = os.Stdin
+	if inputPath != "" && inputPath != "-" {
+		file, err := os.Open(inputPath)
+		if err != nil {
+			return fmt.Errorf("failed to open input: %w", err)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	features, err := mvt.ReadFeatures(input)
+	if err != nil {
+		return fmt.Errorf("failed to read features: %w", err)
+	}
+
+	if viper.GetBool("logging.verbose") {
+		fmt.Fprintf(os.Stderr, "Read %d features\n", len(features))
+	}
+
+	// Encode a zoom range into a z/x/y directory tree
+	if isRange {
+		count := 0
+		err := encoder.EncodeRange(features, minZoom, maxZoom, func(tile mvt.TileID, data []byte) error {
+			tilePath := filepath.Join(outputPath, fmt.Sprint(tile.Z), fmt.Sprint(tile.X), fmt.Sprintf("%d%s", tile.Y, extension))
+			if err := os.MkdirAll(filepath.Dir(tilePath), 0755); err != nil {
+				return fmt.Errorf("failed to create output directory: %w", err)
+			}
+			if err := os.WriteFile(tilePath, data, 0644); err != nil {
+				return fmt.Errorf("failed to write tile: %w", err)
+			}
+			count++
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to encode tiles: %w", err)
+		}
+
+		if viper.GetBool("logging.verbose") {
+			fmt.Fprintf(os.Stderr, "Encoded %d tiles to: %s\n", count, outputPath)
+		}
+		return nil
+	}
+
+	// Encode a single tile
+	data, err := encoder.Encode(features, z, x, y)
+	if err != nil {
+		return fmt.Errorf("failed to encode tile: %w", err)
+	}
+
+	if outputPath == "" || outputPath == "-" {
+		if _, err := os.Stdout.Write(data); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+	} else {
+		if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+		if err := os.WriteFile(outputPath, data, 0644); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+	}
+
+	if viper.GetBool("logging.verbose") {
+		fmt.Fprintf(os.Stderr, "Tile %d/%d/%d encoded successfully (%d bytes)\n", z, x, y, len(data))
+	}
+
+	return nil
+}
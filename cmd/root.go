@@ -77,9 +77,52 @@ func init() {
 	rootCmd.PersistentFlags().String("api-key", "", "API key for authentication (HTTP source)")
 	
 	// Output flags
-	rootCmd.PersistentFlags().StringP("format", "f", "geojson", "output format (geojson, json)")
+	rootCmd.PersistentFlags().StringP("format", "f", "geojson", "output format (geojson, json, ndjson, geojsonseq, csv, tsv, flatgeobuf, geoparquet, geopackage, topojson, kml, kmz, wkt, wkb, custom)")
 	rootCmd.PersistentFlags().Bool("pretty", true, "pretty print JSON output")
 	rootCmd.PersistentFlags().Bool("compression", false, "compress output files")
+	rootCmd.PersistentFlags().Bool("timings", false, "include timestamps and processing times in output metadata (repeated runs then differ)")
+	rootCmd.PersistentFlags().String("template", "", "text/template file for the custom format, with \"tile\" and \"feature\" hooks")
+	rootCmd.PersistentFlags().String("csv-columns", "union", "CSV property columns: union, layer (one file per layer) or a comma-separated list")
+	rootCmd.PersistentFlags().String("csv-geometry", "wkt", "CSV geometry columns: wkt, lonlat (points), centroid or none")
+	rootCmd.PersistentFlags().StringSlice("csv-metadata", nil, "CSV metadata columns to add: layer, z, x, y, id")
+	rootCmd.PersistentFlags().Bool("fgb-index", true, "write a packed Hilbert R-tree spatial index into FlatGeobuf files")
+	rootCmd.PersistentFlags().Int("parquet-row-group-size", 65536, "rows per GeoParquet row group")
+	rootCmd.PersistentFlags().String("parquet-compression", "snappy", "GeoParquet page compression (none, snappy, gzip, zstd)")
+	rootCmd.PersistentFlags().String("parquet-partition", "none", "partition GeoParquet output into a directory by layer or zoom (none, layer, zoom)")
+	rootCmd.PersistentFlags().Bool("gpkg-index", true, "add an R-tree spatial index to each GeoPackage feature table")
+	rootCmd.PersistentFlags().Int("topojson-quantization", 100000, "TopoJSON grid positions per axis coordinates are snapped to (0 keeps full precision)")
+	rootCmd.PersistentFlags().Bool("wkt-srid", false, "prefix WKT and WKB geometries with the output SRID (EWKT, EWKB)")
+	rootCmd.PersistentFlags().Bool("wkt-copy", true, "escape WKT and WKB rows for PostgreSQL COPY text format")
+	rootCmd.PersistentFlags().Bool("wkb-binary", false, "write WKB as raw binary records instead of hex text rows")
+
+	// Conversion flags
+	rootCmd.PersistentFlags().String("coordinate-system", "web-mercator", "output coordinate system (web-mercator, wgs84)")
+	rootCmd.PersistentFlags().Bool("simplify", false, "simplify output geometries")
+	rootCmd.PersistentFlags().String("simplify-algorithm", "douglas-peucker", "simplification algorithm (douglas-peucker, visvalingam, radial)")
+	rootCmd.PersistentFlags().Float64("simplify-tolerance", 1.0, "simplification tolerance in --simplify-units")
+	rootCmd.PersistentFlags().String("simplify-units", "pixels", "simplification tolerance units (pixels at the tile's zoom, map)")
+	rootCmd.PersistentFlags().Bool("preserve-topology", false, "keep shared polygon edges coincident when simplifying")
+	rootCmd.PersistentFlags().String("precision", "", "round coordinates to N decimal places, or 'auto' to derive from the tile's zoom")
+	rootCmd.PersistentFlags().Bool("rfc7946", false, "strict RFC 7946 GeoJSON: WGS84, right-hand winding, repaired rings, antimeridian splits, bbox")
+	rootCmd.PersistentFlags().Bool("large-int-as-string", false, "emit integer IDs and attributes beyond ±2^53 as strings to avoid JSON precision loss")
+	rootCmd.PersistentFlags().StringArray("filter", nil, "Mapbox GL filter expression as layer=JSON (repeatable; omit layer= to apply to all layers)")
+	rootCmd.PersistentFlags().String("within", "", "keep only features intersecting the polygons in this GeoJSON file")
+	rootCmd.PersistentFlags().String("clip-geometry", "", "keep only features intersecting the polygons in this GeoJSON file and clip them to it")
+	rootCmd.PersistentFlags().String("layer-property", "_layer", "property holding each feature's layer name (empty to omit)")
+	rootCmd.PersistentFlags().Bool("strict", false, "fail tiles with skipped or unconvertible features instead of reporting warnings")
+	rootCmd.PersistentFlags().StringArray("property", nil, "property pipeline step: rename:FROM=TO, drop:PATTERN, cast:KEY=TYPE, set:KEY=VALUE or compute:KEY=FUNCTION (repeatable)")
+	rootCmd.PersistentFlags().StringArray("promote-id", nil, "property promoted to the feature ID as layer=property (repeatable; omit layer= to apply to all layers)")
+	rootCmd.PersistentFlags().Bool("synthetic-ids", false, "derive stable IDs from layer, tile and geometry for features without one")
+	rootCmd.PersistentFlags().String("label-points", "", "add a label point per polygon: property (as _label) or layer (as a <layer>_label point layer)")
+	rootCmd.PersistentFlags().String("label-algorithm", "polylabel", "label point algorithm (polylabel, centroid, representative)")
+	rootCmd.PersistentFlags().Float64("label-precision", 1.0, "polylabel precision in pixels at the tile's zoom")
+
+	// Raster DEM flags
+	rootCmd.PersistentFlags().String("dem-encoding", "", "treat tiles as raster DEM tiles with this elevation encoding (mapbox, terrarium)")
+	rootCmd.PersistentFlags().String("dem-output", "grid", "raster DEM output (grid, points, contours, polygons)")
+	rootCmd.PersistentFlags().Float64("contour-interval", 10, "elevation interval between contours in meters")
+	rootCmd.PersistentFlags().Float64("contour-base", 0, "elevation contour levels are offset from in meters")
+	rootCmd.PersistentFlags().Int("dem-sample-step", 1, "keep every Nth pixel for grid and point DEM output")
 	
 	// Processing flags
 	rootCmd.PersistentFlags().Bool("verbose", false, "verbose output")
@@ -95,6 +138,45 @@ func init() {
 	viper.BindPFlag("output.format", rootCmd.PersistentFlags().Lookup("format"))
 	viper.BindPFlag("output.pretty", rootCmd.PersistentFlags().Lookup("pretty"))
 	viper.BindPFlag("output.compression", rootCmd.PersistentFlags().Lookup("compression"))
+	viper.BindPFlag("output.timings", rootCmd.PersistentFlags().Lookup("timings"))
+	viper.BindPFlag("output.template", rootCmd.PersistentFlags().Lookup("template"))
+	viper.BindPFlag("output.csv.columns", rootCmd.PersistentFlags().Lookup("csv-columns"))
+	viper.BindPFlag("output.csv.geometry", rootCmd.PersistentFlags().Lookup("csv-geometry"))
+	viper.BindPFlag("output.csv.metadata", rootCmd.PersistentFlags().Lookup("csv-metadata"))
+	viper.BindPFlag("output.flatgeobuf.index", rootCmd.PersistentFlags().Lookup("fgb-index"))
+	viper.BindPFlag("output.parquet.row_group_size", rootCmd.PersistentFlags().Lookup("parquet-row-group-size"))
+	viper.BindPFlag("output.parquet.compression", rootCmd.PersistentFlags().Lookup("parquet-compression"))
+	viper.BindPFlag("output.parquet.partition", rootCmd.PersistentFlags().Lookup("parquet-partition"))
+	viper.BindPFlag("output.geopackage.index", rootCmd.PersistentFlags().Lookup("gpkg-index"))
+	viper.BindPFlag("output.topojson.quantization", rootCmd.PersistentFlags().Lookup("topojson-quantization"))
+	viper.BindPFlag("output.wkt.srid", rootCmd.PersistentFlags().Lookup("wkt-srid"))
+	viper.BindPFlag("output.wkt.copy", rootCmd.PersistentFlags().Lookup("wkt-copy"))
+	viper.BindPFlag("output.wkt.wkb_binary", rootCmd.PersistentFlags().Lookup("wkb-binary"))
+	viper.BindPFlag("conversion.coordinate_system", rootCmd.PersistentFlags().Lookup("coordinate-system"))
+	viper.BindPFlag("conversion.simplify", rootCmd.PersistentFlags().Lookup("simplify"))
+	viper.BindPFlag("conversion.simplify_algorithm", rootCmd.PersistentFlags().Lookup("simplify-algorithm"))
+	viper.BindPFlag("conversion.simplify_tolerance", rootCmd.PersistentFlags().Lookup("simplify-tolerance"))
+	viper.BindPFlag("conversion.simplify_units", rootCmd.PersistentFlags().Lookup("simplify-units"))
+	viper.BindPFlag("conversion.preserve_topology", rootCmd.PersistentFlags().Lookup("preserve-topology"))
+	viper.BindPFlag("conversion.precision", rootCmd.PersistentFlags().Lookup("precision"))
+	viper.BindPFlag("conversion.rfc7946", rootCmd.PersistentFlags().Lookup("rfc7946"))
+	viper.BindPFlag("conversion.large_int_as_string", rootCmd.PersistentFlags().Lookup("large-int-as-string"))
+	viper.BindPFlag("conversion.filter", rootCmd.PersistentFlags().Lookup("filter"))
+	viper.BindPFlag("conversion.within", rootCmd.PersistentFlags().Lookup("within"))
+	viper.BindPFlag("conversion.clip_geometry", rootCmd.PersistentFlags().Lookup("clip-geometry"))
+	viper.BindPFlag("conversion.layer_property", rootCmd.PersistentFlags().Lookup("layer-property"))
+	viper.BindPFlag("conversion.property", rootCmd.PersistentFlags().Lookup("property"))
+	viper.BindPFlag("conversion.strict", rootCmd.PersistentFlags().Lookup("strict"))
+	viper.BindPFlag("conversion.promote_id", rootCmd.PersistentFlags().Lookup("promote-id"))
+	viper.BindPFlag("conversion.synthetic_ids", rootCmd.PersistentFlags().Lookup("synthetic-ids"))
+	viper.BindPFlag("conversion.label_points", rootCmd.PersistentFlags().Lookup("label-points"))
+	viper.BindPFlag("conversion.label_algorithm", rootCmd.PersistentFlags().Lookup("label-algorithm"))
+	viper.BindPFlag("conversion.label_precision", rootCmd.PersistentFlags().Lookup("label-precision"))
+	viper.BindPFlag("raster.encoding", rootCmd.PersistentFlags().Lookup("dem-encoding"))
+	viper.BindPFlag("raster.output", rootCmd.PersistentFlags().Lookup("dem-output"))
+	viper.BindPFlag("raster.interval", rootCmd.PersistentFlags().Lookup("contour-interval"))
+	viper.BindPFlag("raster.base", rootCmd.PersistentFlags().Lookup("contour-base"))
+	viper.BindPFlag("raster.sample_step", rootCmd.PersistentFlags().Lookup("dem-sample-step"))
 	viper.BindPFlag("logging.verbose", rootCmd.PersistentFlags().Lookup("verbose"))
 	viper.BindPFlag("batch.concurrency", rootCmd.PersistentFlags().Lookup("concurrency"))
 	viper.BindPFlag("server.timeout", rootCmd.PersistentFlags().Lookup("timeout"))
@@ -0,0 +1,430 @@
+// cmd/subset.go - Tileset filtering and re-encoding command
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/valpere/tile_to_json/internal"
+	"github.com/valpere/tile_to_json/internal/config"
+	"github.com/valpere/tile_to_json/internal/tile"
+	"github.com/valpere/tile_to_json/pkg/mvt"
+	"github.com/valpere/tile_to_json/pkg/pmtiles"
+)
+
+// subsetCmd represents the subset command
+var subsetCmd = &cobra.Command{
+	Use:   "subset",
+	Short: "Filter a vector tileset and re-encode it as MVT",
+	Long: `Filter the layers, attributes and features of a vector tileset and write the result
+as Mapbox Vector Tiles, without converting to JSON.
+
+Tiles are read from the configured source over the same tile ranges as the batch command.
+Layers are selected with --layers and --exclude-layers, attributes with --keep-properties
+and features with the global --filter expressions. Geometry is copied unchanged, and layer
+extents, feature IDs and attribute types are preserved. Tiles left without features are
+not written, and tiles missing from the source are skipped.
+
+The output is a z/x/y directory pyramid, or a PMTiles v3 archive when the output path
+ends in .pmtiles.
+
+Examples:
+  # Drop POIs and keep two road attributes in a local pyramid
+  tile-to-json subset --base-path "/path/to/tiles" --min-zoom 0 --max-zoom 14 \
+    --exclude-layers poi --keep-properties class,name --output ./mobile-tiles/
+
+  # Write only major roads of a remote tileset to a PMTiles archive
+  tile-to-json subset --base-url "https://example.com/tiles" --zoom 12 --bbox "-74.0,40.7,-73.9,40.8" \
+    --layers roads --filter 'roads=["in", "class", "motorway", "trunk"]' --output roads.pmtiles`,
+	RunE: runSubset,
+}
+
+func init() {
+	rootCmd.AddCommand(subsetCmd)
+
+	// Tile range flags
+	subsetCmd.Flags().Int("zoom", 0, "single zoom level to process")
+	subsetCmd.Flags().Int("min-zoom", 0, "minimum zoom level")
+	subsetCmd.Flags().Int("max-zoom", 0, "maximum zoom level")
+	subsetCmd.Flags().String("bbox", "", "bounding box: 'min_lon,min_lat,max_lon,max_lat'")
+	subsetCmd.Flags().String("tiles", "", "specific tiles list: 'z/x/y,z/x/y,...'")
+
+	// Source override flags
+	subsetCmd.Flags().String("source-type", "", "override source type (http, local)")
+
+	// Filter flags
+	subsetCmd.Flags().StringSlice("layers", nil, "keep only these layers")
+	subsetCmd.Flags().StringSlice("exclude-layers", nil, "drop these layers")
+	subsetCmd.Flags().StringSlice("keep-properties", nil, "keep only these attributes")
+
+	// Output flags
+	subsetCmd.Flags().StringP("output", "o", "", "output directory, or .pmtiles archive")
+	subsetCmd.Flags().String("extension", ".mvt", "tile file extension for directory output")
+	subsetCmd.Flags().Bool("gzip", false, "gzip the output tiles")
+	subsetCmd.Flags().String("name", "", "tileset name for archive metadata")
+
+	// Processing flags
+	subsetCmd.Flags().Bool("fail-on-error", false, "stop processing on first error")
+
+	// Mark required flags and mutual exclusions
+	subsetCmd.MarkFlagRequired("output")
+	subsetCmd.MarkFlagsMutuallyExclusive("zoom", "min-zoom")
+	subsetCmd.MarkFlagsMutuallyExclusive("zoom", "max-zoom")
+}
+
+// tileSink receives re-encoded tiles
+type tileSink interface {
+	WriteTile(z, x, y int, data []byte) error
+	Close() error
+}
+
+// directorySink writes tiles to a z/x/y directory pyramid
+type directorySink struct {
+	dir       string
+	extension string
+}
+
+// WriteTile writes a tile to dir/z/x/y+extension
+func (s *directorySink) WriteTile(z, x, y int, data []byte) error {
+	tilePath := filepath.Join(s.dir, fmt.Sprint(z), fmt.Sprint(x), fmt.Sprintf("%d%s", y, s.extension))
+	if err := os.MkdirAll(filepath.Dir(tilePath), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	return os.WriteFile(tilePath, data, 0644)
+}
+
+// Close does nothing; tiles are written as they arrive
+func (s *directorySink) Close() error {
+	return nil
+}
+
+func runSubset(cmd *cobra.Command, args []string) error {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Get command flags
+	zoom, _ := cmd.Flags().GetInt("zoom")
+	minZoom, _ := cmd.Flags().GetInt("min-zoom")
+	maxZoom, _ := cmd.Flags().GetInt("max-zoom")
+	bboxStr, _ := cmd.Flags().GetString("bbox")
+	tilesStr, _ := cmd.Flags().GetString("tiles")
+	sourceTypeOverride, _ := cmd.Flags().GetString("source-type")
+	layers, _ := cmd.Flags().GetStringSlice("layers")
+	excludeLayers, _ := cmd.Flags().GetStringSlice("exclude-layers")
+	keepProperties, _ := cmd.Flags().GetStringSlice("keep-properties")
+	outputPath, _ := cmd.Flags().GetString("output")
+	extension, _ := cmd.Flags().GetString("extension")
+	gzipTiles, _ := cmd.Flags().GetBool("gzip")
+	name, _ := cmd.Flags().GetString("name")
+	failOnError, _ := cmd.Flags().GetBool("fail-on-error")
+
+	// Override source type if specified
+	if sourceTypeOverride != "" {
+		switch sourceTypeOverride {
+		case "http":
+			cfg.Source.Type = "http"
+		case "local":
+			cfg.Source.Type = "local"
+		default:
+			return fmt.Errorf("invalid source type: %s (must be 'http' or 'local')", sourceTypeOverride)
+		}
+	}
+
+	// Determine and validate source type
+	sourceType := cfg.DetermineSourceType()
+	factory := tile.NewFetcherFactory(cfg)
+
+	if err := factory.ValidateConfiguration(sourceType); err != nil {
+		return fmt.Errorf("source configuration validation failed: %w", err)
+	}
+
+	// Parse tile ranges
+	var tileRanges []*tile.TileRange
+	if tilesStr != "" {
+		tileRanges, err = parseTilesList(tilesStr)
+		if err != nil {
+			return fmt.Errorf("failed to parse tiles list: %w", err)
+		}
+	} else {
+		if zoom > 0 {
+			minZoom = zoom
+			maxZoom = zoom
+		}
+		if maxZoom < minZoom {
+			maxZoom = minZoom
+		}
+
+		var bbox *BoundingBox
+		if bboxStr != "" {
+			bbox, err = parseBoundingBox(bboxStr)
+			if err != nil {
+				return fmt.Errorf("failed to parse bounding box: %w", err)
+			}
+		}
+
+		tileRanges, err = generateTileRanges(minZoom, maxZoom, bbox)
+		if err != nil {
+			return fmt.Errorf("failed to generate tile ranges: %w", err)
+		}
+	}
+
+	var totalTiles int64
+	for _, tr := range tileRanges {
+		totalTiles += tr.Count()
+	}
+
+	// Create converter with the subset filters
+	conversionOptions, err := cfg.Conversion.ToConversionOptions()
+	if err != nil {
+		return fmt.Errorf("invalid conversion options: %w", err)
+	}
+	conversionOptions.LayerFilter = layers
+	conversionOptions.ExcludeLayers = excludeLayers
+	conversionOptions.PropertyFilter = keepProperties
+
+	converter, err := mvt.NewConverterWithOptions(conversionOptions)
+	if err != nil {
+		return fmt.Errorf("failed to create converter: %w", err)
+	}
+
+	fetcher, err := factory.CreateFetcherForType(sourceType)
+	if err != nil {
+		return fmt.Errorf("failed to create fetcher: %w", err)
+	}
+
+	// Create the output
+	var sink tileSink
+	var archive *pmtiles.Writer
+	if strings.HasSuffix(strings.ToLower(outputPath), ".pmtiles") {
+		tileCompression := pmtiles.CompressionNone
+		if gzipTiles {
+			tileCompression = pmtiles.CompressionGzip
+		}
+		archive, err = pmtiles.NewWriter(outputPath, pmtiles.TileTypeMVT, tileCompression)
+		if err != nil {
+			return fmt.Errorf("failed to create archive: %w", err)
+		}
+		sink = archive
+	} else {
+		sink = &directorySink{dir: outputPath, extension: extension}
+	}
+
+	if viper.GetBool("logging.verbose") {
+		fmt.Fprintf(os.Stderr, "Subsetting up to %d tiles from %s source to: %s\n", totalTiles, sourceType, outputPath)
+	}
+
+	// Fetch, filter and write tiles concurrently
+	var written, empty, missing, failed, featuresKept, featuresDropped int64
+	schema := make(mvt.Schema)
+	var schemaMutex sync.Mutex
+	var firstErr error
+	var errOnce sync.Once
+	stop := make(chan struct{})
+
+	requests := make(chan *tile.TileRequest)
+	go func() {
+		defer close(requests)
+		for _, tr := range tileRanges {
+			for z := tr.MinZ; z <= tr.MaxZ; z++ {
+				for x := tr.MinX; x <= tr.MaxX; x++ {
+					for y := tr.MinY; y <= tr.MaxY; y++ {
+						select {
+						case requests <- subsetTileRequest(cfg, sourceType, z, x, y):
+						case <-stop:
+							return
+						}
+					}
+				}
+			}
+		}
+	}()
+
+	fail := func(err error) {
+		atomic.AddInt64(&failed, 1)
+		if viper.GetBool("logging.verbose") {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		if failOnError {
+			errOnce.Do(func() {
+				firstErr = err
+				close(stop)
+			})
+		}
+	}
+
+	concurrency := cfg.Batch.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for request := range requests {
+				if err := tile.ValidateCoordinates(request.Z, request.X, request.Y); err != nil {
+					fail(fmt.Errorf("tile %d/%d/%d: %w", request.Z, request.X, request.Y, err))
+					continue
+				}
+
+				response, err := fetcher.FetchWithRetry(request)
+				if err != nil {
+					if isMissingTile(response, err) {
+						atomic.AddInt64(&missing, 1)
+					} else {
+						fail(fmt.Errorf("tile %d/%d/%d: fetch failed: %w", request.Z, request.X, request.Y, err))
+					}
+					continue
+				}
+
+				result, err := converter.Subset(response.Data, request.Z)
+				if err != nil {
+					fail(fmt.Errorf("tile %d/%d/%d: %w", request.Z, request.X, request.Y, err))
+					continue
+				}
+				atomic.AddInt64(&featuresKept, int64(result.FeatureCount))
+				atomic.AddInt64(&featuresDropped, int64(result.FeaturesDropped))
+				if result.FeatureCount == 0 {
+					atomic.AddInt64(&empty, 1)
+					continue
+				}
+
+				data := result.Data
+				if gzipTiles {
+					if data, err = gzipBytes(data); err != nil {
+						fail(fmt.Errorf("tile %d/%d/%d: %w", request.Z, request.X, request.Y, err))
+						continue
+					}
+				}
+
+				if err := sink.WriteTile(request.Z, request.X, request.Y, data); err != nil {
+					fail(fmt.Errorf("tile %d/%d/%d: write failed: %w", request.Z, request.X, request.Y, err))
+					continue
+				}
+				atomic.AddInt64(&written, 1)
+
+				schemaMutex.Lock()
+				schema.Merge(result.Schema)
+				schemaMutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		sink.Close()
+		return fmt.Errorf("subset failed: %w", firstErr)
+	}
+
+	if archive != nil {
+		if written == 0 {
+			archive.Close()
+			return fmt.Errorf("no tiles to write")
+		}
+		if name == "" {
+			name = strings.TrimSuffix(filepath.Base(outputPath), filepath.Ext(outputPath))
+		}
+		archive.SetMetadata("name", name)
+		archive.SetMetadata("format", "pbf")
+		archive.SetMetadata("generator", "tile-to-json")
+		archive.SetMetadata("vector_layers", vectorLayers(schema))
+	}
+
+	if err := sink.Close(); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Subset completed: %d tiles written, %d empty, %d missing, %d failed\n",
+		written, empty, missing, failed)
+	fmt.Fprintf(os.Stderr, "Features: %d kept, %d dropped\n", featuresKept, featuresDropped)
+
+	return nil
+}
+
+// subsetTileRequest builds the request for a tile of the configured source
+func subsetTileRequest(cfg *config.Config, sourceType internal.SourceType, z, x, y int) *tile.TileRequest {
+	if sourceType == internal.SourceTypeHTTP {
+		return tile.NewTileRequest(z, x, y, cfg.Server.BaseURL)
+	}
+	return &tile.TileRequest{Z: z, X: x, Y: y}
+}
+
+// isMissingTile reports whether a fetch failed because the source has no such tile
+func isMissingTile(response *tile.TileResponse, err error) bool {
+	var appErr *internal.Error
+	if errors.As(err, &appErr) && appErr.Code == internal.ErrorCodeNotFound {
+		return true
+	}
+	return response != nil && (response.StatusCode == http.StatusNotFound || response.StatusCode == http.StatusNoContent)
+}
+
+// gzipBytes compresses data with gzip
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress tile: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress tile: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// vectorLayers describes the layers of a schema in TileJSON vector_layers form
+func vectorLayers(schema mvt.Schema) []map[string]interface{} {
+	names := make([]string, 0, len(schema))
+	for name := range schema {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	layers := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		fields := make(map[string]string, len(schema[name].Attributes))
+		for key, attribute := range schema[name].Attributes {
+			fields[key] = tileJSONFieldType(attribute.Types)
+		}
+		layers = append(layers, map[string]interface{}{"id": name, "fields": fields})
+	}
+	return layers
+}
+
+// tileJSONFieldType maps MVT value types to a TileJSON field description
+func tileJSONFieldType(types []string) string {
+	kinds := make(map[string]bool)
+	for _, valueType := range types {
+		switch valueType {
+		case mvt.ValueTypeString:
+			kinds["String"] = true
+		case mvt.ValueTypeBool:
+			kinds["Boolean"] = true
+		default:
+			kinds["Number"] = true
+		}
+	}
+
+	if len(kinds) != 1 {
+		return "Mixed"
+	}
+	for kind := range kinds {
+		return kind
+	}
+	return "Mixed"
+}
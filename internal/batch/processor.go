@@ -3,12 +3,15 @@ package batch
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/valpere/tile_to_json/internal/output"
 	"github.com/valpere/tile_to_json/internal/tile"
+	"github.com/valpere/tile_to_json/pkg/mvt"
 )
 
 // BatchProcessor implements the Processor interface for batch processing operations
@@ -149,9 +152,13 @@ func (bp *BatchProcessor) ProcessChunk(ctx context.Context, workItems []*WorkIte
 	var processedTiles []*tile.ProcessedTile
 	successCount := 0
 	failureCount := 0
+	warningCount := 0
 
 	for result := range resultChan {
 		results = append(results, result)
+		if result.Tile != nil {
+			warningCount += len(result.Tile.Warnings)
+		}
 
 		if result.Error != nil {
 			failureCount++
@@ -163,7 +170,9 @@ func (bp *BatchProcessor) ProcessChunk(ctx context.Context, workItems []*WorkIte
 		}
 	}
 
-	// Write processed tiles
+	// Write processed tiles in coordinate order, independent of worker scheduling
+	sort.Slice(results, func(i, j int) bool { return results[i].Item.ItemID < results[j].Item.ItemID })
+	tile.SortProcessedTiles(processedTiles)
 	if len(processedTiles) > 0 {
 		if err := bp.writer.WriteBatch(processedTiles); err != nil {
 			return &ChunkResult{
@@ -172,6 +181,7 @@ func (bp *BatchProcessor) ProcessChunk(ctx context.Context, workItems []*WorkIte
 				Duration:     time.Since(start),
 				SuccessCount: successCount,
 				FailureCount: failureCount,
+				WarningCount: warningCount,
 			}, fmt.Errorf("failed to write batch: %w", err)
 		}
 	}
@@ -182,6 +192,7 @@ func (bp *BatchProcessor) ProcessChunk(ctx context.Context, workItems []*WorkIte
 		Duration:     time.Since(start),
 		SuccessCount: successCount,
 		FailureCount: failureCount,
+		WarningCount: warningCount,
 	}, nil
 }
 
@@ -226,6 +237,18 @@ func (bp *BatchProcessor) processWorkItem(ctx context.Context, workItem *WorkIte
 		processedTile, err := bp.processor.Process(response)
 		if err != nil {
 			lastErr = fmt.Errorf("process failed: %w", err)
+
+			// Strict mode failures are deterministic, so retrying cannot help
+			var strictErr *mvt.StrictError
+			if errors.As(err, &strictErr) {
+				return &WorkResult{
+					Item:     workItem,
+					Tile:     processedTile,
+					Error:    lastErr,
+					Duration: time.Since(start),
+					Attempts: attempt + 1,
+				}
+			}
 			continue
 		}
 
@@ -294,6 +317,7 @@ func (bp *BatchProcessor) updateJobProgress(job *Job, chunkResult *ChunkResult)
 	job.Progress.ProcessedTiles += int64(len(chunkResult.Results))
 	job.Progress.SuccessTiles += int64(chunkResult.SuccessCount)
 	job.Progress.FailedTiles += int64(chunkResult.FailureCount)
+	job.Progress.WarningCount += int64(chunkResult.WarningCount)
 	job.Progress.UpdateThroughput()
 
 	estimatedEnd := job.Progress.EstimateCompletion()
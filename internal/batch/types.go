@@ -54,6 +54,7 @@ type JobProgress struct {
 	ProcessedTiles int64      `json:"processed_tiles"`
 	FailedTiles    int64      `json:"failed_tiles"`
 	SuccessTiles   int64      `json:"success_tiles"`
+	WarningCount   int64      `json:"warning_count"`
 	CurrentChunk   int        `json:"current_chunk"`
 	TotalChunks    int        `json:"total_chunks"`
 	StartTime      time.Time  `json:"start_time"`
@@ -87,6 +88,7 @@ type ChunkResult struct {
 	Duration     time.Duration `json:"duration"`
 	SuccessCount int           `json:"success_count"`
 	FailureCount int           `json:"failure_count"`
+	WarningCount int           `json:"warning_count"`
 }
 
 // Coordinator defines the interface for managing batch jobs
@@ -3,21 +3,28 @@ package config
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
 	"github.com/valpere/tile_to_json/internal"
+	"github.com/valpere/tile_to_json/pkg/dem"
+	"github.com/valpere/tile_to_json/pkg/kml"
+	"github.com/valpere/tile_to_json/pkg/mvt"
 )
 
 // Config represents the complete application configuration
 type Config struct {
-	Server  ServerConfig  `mapstructure:"server"`
-	Local   LocalConfig   `mapstructure:"local"`
-	Source  SourceConfig  `mapstructure:"source"`
-	Output  OutputConfig  `mapstructure:"output"`
-	Batch   BatchConfig   `mapstructure:"batch"`
-	Network NetworkConfig `mapstructure:"network"`
-	Logging LoggingConfig `mapstructure:"logging"`
+	Server     ServerConfig     `mapstructure:"server"`
+	Local      LocalConfig      `mapstructure:"local"`
+	Source     SourceConfig     `mapstructure:"source"`
+	Output     OutputConfig     `mapstructure:"output"`
+	Conversion ConversionConfig `mapstructure:"conversion"`
+	Raster     RasterConfig     `mapstructure:"raster"`
+	Batch      BatchConfig      `mapstructure:"batch"`
+	Network    NetworkConfig    `mapstructure:"network"`
+	Logging    LoggingConfig    `mapstructure:"logging"`
 }
 
 // ServerConfig contains tile server configuration for HTTP sources
@@ -53,6 +60,169 @@ type OutputConfig struct {
 	Compression bool   `mapstructure:"compression"`
 	Pretty      bool   `mapstructure:"pretty"`
 	Stdout      bool   `mapstructure:"stdout"`
+
+	// Timings adds run-dependent metadata such as timestamps and processing times.
+	// It is off by default so repeated exports are byte-for-byte identical.
+	Timings bool `mapstructure:"timings"`
+
+	// Template is the text/template file used by the custom format
+	Template string `mapstructure:"template"`
+
+	CSV CSVConfig `mapstructure:"csv"`
+
+	FlatGeobuf FlatGeobufConfig `mapstructure:"flatgeobuf"`
+
+	Parquet ParquetConfig `mapstructure:"parquet"`
+
+	GeoPackage GeoPackageConfig `mapstructure:"geopackage"`
+
+	TopoJSON TopoJSONConfig `mapstructure:"topojson"`
+
+	KML KMLConfig `mapstructure:"kml"`
+
+	WKT WKTConfig `mapstructure:"wkt"`
+}
+
+// CSVConfig contains CSV and TSV output configuration
+type CSVConfig struct {
+	// Columns is "union", "layer" or a comma-separated list of property columns
+	Columns  string   `mapstructure:"columns"`
+	Geometry string   `mapstructure:"geometry"`
+	Metadata []string `mapstructure:"metadata"`
+}
+
+// FlatGeobufConfig contains FlatGeobuf output configuration
+type FlatGeobufConfig struct {
+	// Index adds a packed Hilbert R-tree so readers can fetch features by bounding box
+	Index bool `mapstructure:"index"`
+}
+
+// GeoPackageConfig contains GeoPackage output configuration
+type GeoPackageConfig struct {
+	// Index adds an R-tree spatial index to each feature table
+	Index bool `mapstructure:"index"`
+}
+
+// TopoJSONConfig contains TopoJSON output configuration
+type TopoJSONConfig struct {
+	// Quantization is the number of grid positions per axis coordinates are snapped to
+	// before arcs are built; 0 keeps full precision
+	Quantization int `mapstructure:"quantization"`
+}
+
+// KMLConfig contains KML and KMZ output configuration
+type KMLConfig struct {
+	// Styles maps layer names ("*" for all other layers) to the style of their
+	// placemarks. Layer names match ignoring case, as config keys are lower-cased.
+	Styles map[string]KMLStyleConfig `mapstructure:"styles"`
+}
+
+// KMLStyleConfig is the style of a layer's KML placemarks. Colors are #RRGGBB or
+// #RRGGBBAA.
+type KMLStyleConfig struct {
+	LineColor string  `mapstructure:"line_color"`
+	LineWidth float64 `mapstructure:"line_width"`
+	FillColor string  `mapstructure:"fill_color"`
+	Icon      string  `mapstructure:"icon"`
+	IconColor string  `mapstructure:"icon_color"`
+	IconScale float64 `mapstructure:"icon_scale"`
+}
+
+// ToKMLStyles converts the layer styles to kml styles
+func (c *KMLConfig) ToKMLStyles() map[string]kml.Style {
+	if len(c.Styles) == 0 {
+		return nil
+	}
+	styles := make(map[string]kml.Style, len(c.Styles))
+	for layer, style := range c.Styles {
+		styles[layer] = kml.Style{
+			LineColor: style.LineColor,
+			LineWidth: style.LineWidth,
+			FillColor: style.FillColor,
+			Icon:      style.Icon,
+			IconColor: style.IconColor,
+			IconScale: style.IconScale,
+		}
+	}
+	return styles
+}
+
+// WKTConfig contains WKT and WKB output configuration
+type WKTConfig struct {
+	// SRID prefixes geometries with the SRID of the output coordinate system, as EWKT
+	// or EWKB
+	SRID bool `mapstructure:"srid"`
+
+	// Copy escapes rows for PostgreSQL's COPY text format
+	Copy bool `mapstructure:"copy"`
+
+	// WKBBinary writes WKB as raw binary records instead of hex text rows
+	WKBBinary bool `mapstructure:"wkb_binary"`
+}
+
+// ParquetConfig contains GeoParquet output configuration
+type ParquetConfig struct {
+	RowGroupSize int    `mapstructure:"row_group_size"`
+	Compression  string `mapstructure:"compression"`
+
+	// Partition is "none", or "layer" or "zoom" for a Hive-style partitioned dataset
+	Partition string `mapstructure:"partition"`
+}
+
+// ConversionConfig contains MVT to GeoJSON conversion configuration
+type ConversionConfig struct {
+	CoordinateSystem  string  `mapstructure:"coordinate_system"`
+	Simplify          bool    `mapstructure:"simplify"`
+	SimplifyAlgorithm string  `mapstructure:"simplify_algorithm"`
+	SimplifyTolerance float64 `mapstructure:"simplify_tolerance"`
+	SimplifyUnits     string  `mapstructure:"simplify_units"`
+	PreserveTopology  bool    `mapstructure:"preserve_topology"`
+	Precision         string  `mapstructure:"precision"`
+	RFC7946           bool    `mapstructure:"rfc7946"`
+	LargeIntAsString  bool    `mapstructure:"large_int_as_string"`
+	Strict            bool    `mapstructure:"strict"`
+
+	// Filters maps layer names ("*" for all layers) to Mapbox GL filter expressions;
+	// Filter holds "layer=expression" entries from the command line
+	Filters map[string]interface{} `mapstructure:"filters"`
+	Filter  []string               `mapstructure:"filter"`
+
+	// Within keeps only features intersecting the polygons of a GeoJSON file;
+	// ClipGeometry additionally clips them to it
+	Within       string `mapstructure:"within"`
+	ClipGeometry string `mapstructure:"clip_geometry"`
+
+	// LayerProperty names the layer name property; empty omits it. Properties is the
+	// property pipeline and Property holds compact "op:args" steps from the command line,
+	// which run after it.
+	LayerProperty string           `mapstructure:"layer_property"`
+	Properties    []mvt.PropertyOp `mapstructure:"properties"`
+	Property      []string         `mapstructure:"property"`
+
+	// PromoteIDs maps layer names ("*" for all layers) to the property promoted to the
+	// feature ID; PromoteID holds "layer=property" entries from the command line.
+	// SyntheticIDs derives stable IDs for features that still have none.
+	PromoteIDs   map[string]string `mapstructure:"promote_ids"`
+	PromoteID    []string          `mapstructure:"promote_id"`
+	SyntheticIDs bool              `mapstructure:"synthetic_ids"`
+
+	// LabelPoints adds a label point per polygon as a property or as a separate
+	// "<layer><suffix>" point layer, located by LabelAlgorithm
+	LabelPoints      string  `mapstructure:"label_points"`
+	LabelAlgorithm   string  `mapstructure:"label_algorithm"`
+	LabelPrecision   float64 `mapstructure:"label_precision"`
+	LabelProperty    string  `mapstructure:"label_property"`
+	LabelLayerSuffix string  `mapstructure:"label_layer_suffix"`
+}
+
+// RasterConfig contains raster DEM tile configuration. An empty encoding processes
+// tiles as vector tiles.
+type RasterConfig struct {
+	Encoding   string  `mapstructure:"encoding"`
+	Output     string  `mapstructure:"output"`
+	Interval   float64 `mapstructure:"interval"`
+	Base       float64 `mapstructure:"base"`
+	SampleStep int     `mapstructure:"sample_step"`
 }
 
 // BatchConfig contains batch processing configuration
@@ -122,6 +292,46 @@ func setDefaults() {
 	viper.SetDefault("output.pretty", true)
 	viper.SetDefault("output.compression", false)
 	viper.SetDefault("output.stdout", false)
+	viper.SetDefault("output.timings", false)
+	viper.SetDefault("output.template", "")
+	viper.SetDefault("output.csv.columns", "union")
+	viper.SetDefault("output.csv.geometry", "wkt")
+	viper.SetDefault("output.csv.metadata", []string{})
+	viper.SetDefault("output.flatgeobuf.index", true)
+	viper.SetDefault("output.parquet.row_group_size", 65536)
+	viper.SetDefault("output.parquet.compression", "snappy")
+	viper.SetDefault("output.parquet.partition", "none")
+	viper.SetDefault("output.geopackage.index", true)
+	viper.SetDefault("output.topojson.quantization", 100000)
+	viper.SetDefault("output.wkt.srid", false)
+	viper.SetDefault("output.wkt.copy", true)
+	viper.SetDefault("output.wkt.wkb_binary", false)
+
+	// Conversion defaults
+	viper.SetDefault("conversion.coordinate_system", mvt.CoordSystemWebMercator)
+	viper.SetDefault("conversion.simplify", false)
+	viper.SetDefault("conversion.simplify_algorithm", mvt.SimplifyDouglasPeucker)
+	viper.SetDefault("conversion.simplify_tolerance", mvt.DefaultSimplifyTolerance)
+	viper.SetDefault("conversion.simplify_units", mvt.ToleranceUnitPixels)
+	viper.SetDefault("conversion.preserve_topology", false)
+	viper.SetDefault("conversion.precision", "")
+	viper.SetDefault("conversion.rfc7946", false)
+	viper.SetDefault("conversion.large_int_as_string", false)
+	viper.SetDefault("conversion.strict", false)
+	viper.SetDefault("conversion.layer_property", mvt.DefaultLayerProperty)
+	viper.SetDefault("conversion.synthetic_ids", false)
+	viper.SetDefault("conversion.label_points", mvt.LabelModeNone)
+	viper.SetDefault("conversion.label_algorithm", mvt.LabelPolylabel)
+	viper.SetDefault("conversion.label_precision", mvt.DefaultLabelPrecision)
+	viper.SetDefault("conversion.label_property", mvt.DefaultLabelProperty)
+	viper.SetDefault("conversion.label_layer_suffix", mvt.DefaultLabelLayerSuffix)
+
+	// Raster DEM defaults
+	viper.SetDefault("raster.encoding", "")
+	viper.SetDefault("raster.output", dem.OutputGrid)
+	viper.SetDefault("raster.interval", 10.0)
+	viper.SetDefault("raster.base", 0.0)
+	viper.SetDefault("raster.sample_step", 1)
 
 	// Batch defaults
 	viper.SetDefault("batch.concurrency", 10)
@@ -162,6 +372,129 @@ func (c *Config) ToApplicationConfig() *internal.ApplicationConfig {
 	}
 }
 
+// ToConversionOptions converts ConversionConfig to mvt.ConversionOptions, loading
+// the area of interest file when one is configured
+func (c *ConversionConfig) ToConversionOptions() (*mvt.ConversionOptions, error) {
+	options := &mvt.ConversionOptions{
+		SimplifyGeometry:  c.Simplify,
+		SimplifyAlgorithm: c.SimplifyAlgorithm,
+		SimplifyTolerance: c.SimplifyTolerance,
+		SimplifyUnits:     c.SimplifyUnits,
+		PreserveTopology:  c.PreserveTopology,
+		CoordinateSystem:  c.CoordinateSystem,
+		RFC7946:           c.RFC7946,
+		LargeIntAsString:  c.LargeIntAsString,
+		Strict:            c.Strict,
+		LayerProperty:     c.LayerProperty,
+		OmitLayerProperty: c.LayerProperty == "",
+		LabelPoints:       c.LabelPoints,
+		LabelAlgorithm:    c.LabelAlgorithm,
+		LabelPrecision:    c.LabelPrecision,
+		LabelProperty:     c.LabelProperty,
+		LabelLayerSuffix:  c.LabelLayerSuffix,
+		SyntheticIDs:      c.SyntheticIDs,
+	}
+
+	if len(c.PromoteIDs) > 0 || len(c.PromoteID) > 0 {
+		options.PromoteID = make(map[string]string, len(c.PromoteIDs)+len(c.PromoteID))
+		for layer, property := range c.PromoteIDs {
+			options.PromoteID[layer] = property
+		}
+		for _, spec := range c.PromoteID {
+			layer, property := mvt.ParsePromoteIDSpec(spec)
+			options.PromoteID[layer] = property
+		}
+	}
+
+	options.Properties = append(options.Properties, c.Properties...)
+	for _, spec := range c.Property {
+		op, err := mvt.ParsePropertyOp(spec)
+		if err != nil {
+			return nil, err
+		}
+		options.Properties = append(options.Properties, op)
+	}
+
+	if len(c.Filters) > 0 || len(c.Filter) > 0 {
+		options.Filters = make(map[string]interface{}, len(c.Filters)+len(c.Filter))
+		for layer, expr := range c.Filters {
+			options.Filters[layer] = expr
+		}
+		for _, spec := range c.Filter {
+			layer, expr := parseFilterSpec(spec)
+			options.Filters[layer] = expr
+		}
+	}
+
+	// Precision is either "auto" or a number of decimal places
+	switch c.Precision {
+	case "":
+	case mvt.PrecisionModeAuto:
+		options.PrecisionMode = mvt.PrecisionModeAuto
+	default:
+		if decimals, err := strconv.Atoi(c.Precision); err == nil {
+			options.PrecisionMode = mvt.PrecisionModeFixed
+			options.CoordinatePrecision = decimals
+		}
+	}
+
+	// Clipping implies the spatial filter; both take a GeoJSON polygon file
+	areaPath := c.Within
+	if c.ClipGeometry != "" {
+		if c.Within != "" && c.Within != c.ClipGeometry {
+			return nil, fmt.Errorf("within and clip_geometry must not name different files")
+		}
+		areaPath = c.ClipGeometry
+		options.ClipToArea = true
+	}
+	if areaPath != "" {
+		area, err := mvt.LoadAreaOfInterest(areaPath)
+		if err != nil {
+			return nil, err
+		}
+		options.AreaOfInterest = area
+	}
+
+	return options, nil
+}
+
+// OutputCoordinateSystem returns the coordinate system of converted geometries,
+// which is always WGS84 for RFC 7946 output
+func (c *Config) OutputCoordinateSystem() string {
+	if c.Conversion.RFC7946 {
+		return mvt.CoordSystemWGS84
+	}
+	return c.Conversion.CoordinateSystem
+}
+
+// ToDEMOptions converts RasterConfig to dem.Options, using the conversion
+// coordinate system
+func (c *Config) ToDEMOptions() *dem.Options {
+	coordinateSystem := c.OutputCoordinateSystem()
+
+	return &dem.Options{
+		Encoding:         c.Raster.Encoding,
+		Output:           c.Raster.Output,
+		Interval:         c.Raster.Interval,
+		Base:             c.Raster.Base,
+		SampleStep:       c.Raster.SampleStep,
+		CoordinateSystem: coordinateSystem,
+	}
+}
+
+// parseFilterSpec splits a "layer=expression" filter flag. An expression without
+// a layer prefix applies to all layers.
+func parseFilterSpec(spec string) (string, string) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "[") {
+		return mvt.FilterAllLayers, spec
+	}
+	if layer, expr, found := strings.Cut(spec, "="); found {
+		return strings.TrimSpace(layer), strings.TrimSpace(expr)
+	}
+	return mvt.FilterAllLayers, spec
+}
+
 // GetTileURL builds a tile URL using the configured template for HTTP sources
 func (c *Config) GetTileURL(z, x, y int) string {
 	if c.Server.BaseURL != "" {
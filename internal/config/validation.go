@@ -6,9 +6,12 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/valpere/tile_to_json/internal"
+	"github.com/valpere/tile_to_json/pkg/dem"
+	"github.com/valpere/tile_to_json/pkg/mvt"
 )
 
 // Validate validates the configuration structure and values
@@ -29,6 +32,14 @@ func Validate(config *Config) error {
 		return fmt.Errorf("output configuration invalid: %w", err)
 	}
 
+	if err := validateConversion(&config.Conversion); err != nil {
+		return fmt.Errorf("conversion configuration invalid: %w", err)
+	}
+
+	if err := validateRaster(config); err != nil {
+		return fmt.Errorf("raster configuration invalid: %w", err)
+	}
+
 	if err := validateBatch(&config.Batch); err != nil {
 		return fmt.Errorf("batch configuration invalid: %w", err)
 	}
@@ -46,6 +57,10 @@ func Validate(config *Config) error {
 		return fmt.Errorf("source configuration combination invalid: %w", err)
 	}
 
+	if err := validateOutputCoordinateSystem(config); err != nil {
+		return fmt.Errorf("output configuration invalid: %w", err)
+	}
+
 	return nil
 }
 
@@ -132,11 +147,51 @@ func validateLocal(config *LocalConfig) error {
 
 // validateOutput validates output configuration parameters
 func validateOutput(config *OutputConfig) error {
-	validFormats := []string{"geojson", "json", "custom"}
+	validFormats := []string{"geojson", "json", "custom", "ndjson", "geojsonseq", "csv", "tsv", "flatgeobuf", "geoparquet", "geopackage", "topojson", "kml", "kmz", "wkt", "wkb"}
 	if !contains(validFormats, config.Format) {
 		return fmt.Errorf("invalid format: %s, must be one of %v", config.Format, validFormats)
 	}
 
+	if config.Format == "custom" && config.Template == "" {
+		return fmt.Errorf("template is required for the custom format")
+	}
+
+	validGeometries := []string{"wkt", "lonlat", "centroid", "none"}
+	if !contains(validGeometries, config.CSV.Geometry) {
+		return fmt.Errorf("invalid csv geometry: %s, must be one of %v", config.CSV.Geometry, validGeometries)
+	}
+
+	validMetadata := []string{"layer", "z", "x", "y", "id"}
+	for _, column := range config.CSV.Metadata {
+		if !contains(validMetadata, column) {
+			return fmt.Errorf("invalid csv metadata column: %s, must be one of %v", column, validMetadata)
+		}
+	}
+
+	if config.Parquet.RowGroupSize <= 0 {
+		return fmt.Errorf("parquet row group size must be positive")
+	}
+
+	validCompressions := []string{"none", "snappy", "gzip", "zstd"}
+	if !contains(validCompressions, config.Parquet.Compression) {
+		return fmt.Errorf("invalid parquet compression: %s, must be one of %v", config.Parquet.Compression, validCompressions)
+	}
+
+	validPartitions := []string{"none", "layer", "zoom"}
+	if !contains(validPartitions, config.Parquet.Partition) {
+		return fmt.Errorf("invalid parquet partition: %s, must be one of %v", config.Parquet.Partition, validPartitions)
+	}
+
+	if config.TopoJSON.Quantization < 0 || config.TopoJSON.Quantization == 1 {
+		return fmt.Errorf("invalid topojson quantization: %d, must be 0 or at least 2", config.TopoJSON.Quantization)
+	}
+
+	for layer, style := range config.KML.ToKMLStyles() {
+		if err := style.Validate(); err != nil {
+			return fmt.Errorf("invalid kml style for layer %s: %w", layer, err)
+		}
+	}
+
 	if !config.Stdout && config.Directory == "" {
 		return fmt.Errorf("directory is required when not using stdout")
 	}
@@ -144,6 +199,57 @@ func validateOutput(config *OutputConfig) error {
 	return nil
 }
 
+// validateConversion validates conversion configuration parameters
+func validateConversion(config *ConversionConfig) error {
+	if config.Precision != "" && config.Precision != mvt.PrecisionModeAuto {
+		if _, err := strconv.Atoi(config.Precision); err != nil {
+			return fmt.Errorf("invalid precision: %s, must be 'auto' or a number of decimal places", config.Precision)
+		}
+	}
+
+	options, err := config.ToConversionOptions()
+	if err != nil {
+		return err
+	}
+
+	return mvt.ValidateConversionOptions(options)
+}
+
+// validateRaster validates raster DEM configuration parameters when a DEM encoding
+// is configured
+func validateRaster(config *Config) error {
+	if config.Raster.Encoding == "" {
+		return nil
+	}
+
+	if err := dem.ValidateOptions(config.ToDEMOptions()); err != nil {
+		return err
+	}
+
+	// A grid is an elevation object rather than features, so only formats that write
+	// the converted tile as it is can hold it
+	if config.Raster.Output == dem.OutputGrid {
+		switch config.Output.Format {
+		case "json", "geojson", "custom":
+		default:
+			return fmt.Errorf("grid output requires the json, geojson or custom format, got %s; use points, contours or polygons for feature formats", config.Output.Format)
+		}
+	}
+	return nil
+}
+
+// validateOutputCoordinateSystem checks that formats tied to a coordinate system get
+// it: KML is defined in WGS84 longitude/latitude only
+func validateOutputCoordinateSystem(config *Config) error {
+	if config.Output.Format != "kml" && config.Output.Format != "kmz" {
+		return nil
+	}
+	if config.OutputCoordinateSystem() != mvt.CoordSystemWGS84 {
+		return fmt.Errorf("%s output requires the %s coordinate system, got %s", config.Output.Format, mvt.CoordSystemWGS84, config.OutputCoordinateSystem())
+	}
+	return nil
+}
+
 // validateBatch validates batch processing configuration parameters
 func validateBatch(config *BatchConfig) error {
 	if config.Concurrency <= 0 {
@@ -0,0 +1,658 @@
+// internal/output/csv.go - CSV and TSV output
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/orb/planar"
+	"github.com/valpere/tile_to_json/internal/tile"
+)
+
+// CSV column modes
+const (
+	CSVColumnsUnion  = "union"  // One column per property key found in any feature
+	CSVColumnsSchema = "schema" // A fixed list of property columns
+	CSVColumnsLayer  = "layer"  // One file per layer, each with its own layer's property keys
+)
+
+// CSV geometry columns
+const (
+	CSVGeometryWKT      = "wkt"      // A wkt column in the output coordinate system
+	CSVGeometryLonLat   = "lonlat"   // lon and lat columns for points, empty for other geometries
+	CSVGeometryCentroid = "centroid" // lon and lat columns with each geometry's centroid
+	CSVGeometryNone     = "none"     // No geometry columns
+)
+
+// CSV metadata columns
+const (
+	CSVMetadataLayer = "layer"
+	CSVMetadataZ     = "z"
+	CSVMetadataX     = "x"
+	CSVMetadataY     = "y"
+	CSVMetadataID    = "id"
+)
+
+// CSVOptions configures CSV and TSV output
+type CSVOptions struct {
+	Columns  string   // CSVColumnsUnion, CSVColumnsSchema or CSVColumnsLayer
+	Schema   []string // Property columns for CSVColumnsSchema
+	Geometry string   // Geometry columns
+	Metadata []string // Metadata columns, written first in the given order
+}
+
+// DefaultCSVOptions returns CSV options with a column per property and a WKT column
+func DefaultCSVOptions() *CSVOptions {
+	return &CSVOptions{
+		Columns:  CSVColumnsUnion,
+		Geometry: CSVGeometryWKT,
+	}
+}
+
+// ParseCSVColumns parses a columns setting: "union", "layer", or a comma-separated
+// list of property names for a fixed schema
+func ParseCSVColumns(spec string) (string, []string) {
+	spec = strings.TrimSpace(spec)
+	switch spec {
+	case "", CSVColumnsUnion:
+		return CSVColumnsUnion, nil
+	case CSVColumnsLayer:
+		return CSVColumnsLayer, nil
+	}
+
+	var schema []string
+	for _, column := range strings.Split(spec, ",") {
+		if column = strings.TrimSpace(column); column != "" {
+			schema = append(schema, column)
+		}
+	}
+	return CSVColumnsSchema, schema
+}
+
+// NewCSVOptions creates CSV options from a columns setting, a geometry mode and a
+// list of metadata columns
+func NewCSVOptions(columns, geometry string, metadata []string) *CSVOptions {
+	options := DefaultCSVOptions()
+	options.Columns, options.Schema = ParseCSVColumns(columns)
+	if geometry != "" {
+		options.Geometry = geometry
+	}
+	options.Metadata = metadata
+	return options
+}
+
+// ValidateCSVOptions validates CSV options
+func ValidateCSVOptions(options *CSVOptions) error {
+	switch options.Columns {
+	case CSVColumnsUnion, CSVColumnsLayer:
+	case CSVColumnsSchema:
+		if len(options.Schema) == 0 {
+			return fmt.Errorf("csv schema requires at least one column")
+		}
+	default:
+		return fmt.Errorf("invalid csv columns: %s", options.Columns)
+	}
+
+	switch options.Geometry {
+	case CSVGeometryWKT, CSVGeometryLonLat, CSVGeometryCentroid, CSVGeometryNone:
+	default:
+		return fmt.Errorf("invalid csv geometry: %s", options.Geometry)
+	}
+
+	for _, column := range options.Metadata {
+		switch column {
+		case CSVMetadataLayer, CSVMetadataZ, CSVMetadataX, CSVMetadataY, CSVMetadataID:
+		default:
+			return fmt.Errorf("invalid csv metadata column: %s", column)
+		}
+	}
+
+	return nil
+}
+
+// csvLayout maps features to CSV records: metadata columns, then geometry columns,
+// then property columns
+type csvLayout struct {
+	options          *CSVOptions
+	coordinateSystem string
+	layerProperty    string   // Property holding each feature's layer name
+	fixed            []string // Metadata and geometry column names
+	layerColumn      bool
+}
+
+// newCSVLayout creates a record layout, using the defaults when options is nil.
+// Layer names are read from layerProperty.
+func newCSVLayout(options *CSVOptions, coordinateSystem, layerProperty string) (*csvLayout, error) {
+	if options == nil {
+		options = DefaultCSVOptions()
+	}
+	if err := ValidateCSVOptions(options); err != nil {
+		return nil, err
+	}
+
+	layout := &csvLayout{
+		options:          options,
+		coordinateSystem: coordinateSystem,
+		layerProperty:    layerProperty,
+	}
+	for _, column := range options.Metadata {
+		layout.fixed = append(layout.fixed, column)
+		layout.layerColumn = layout.layerColumn || column == CSVMetadataLayer
+	}
+	switch options.Geometry {
+	case CSVGeometryWKT:
+		layout.fixed = append(layout.fixed, "wkt")
+	case CSVGeometryLonLat, CSVGeometryCentroid:
+		layout.fixed = append(layout.fixed, "lon", "lat")
+	}
+
+	return layout, nil
+}
+
+// header returns the column names for the given property columns
+func (l *csvLayout) header(properties []string) []string {
+	return append(append([]string{}, l.fixed...), properties...)
+}
+
+// fixedValues returns the metadata and geometry values of a feature
+func (l *csvLayout) fixedValues(feature *geojson.Feature, coordinate *tile.TileCoordinate) []string {
+	values := make([]string, 0, len(l.fixed))
+	for _, column := range l.options.Metadata {
+		switch column {
+		case CSVMetadataLayer:
+			values = append(values, featureLayer(feature, l.layerProperty))
+		case CSVMetadataZ:
+			values = append(values, strconv.Itoa(coordinate.Z))
+		case CSVMetadataX:
+			values = append(values, strconv.Itoa(coordinate.X))
+		case CSVMetadataY:
+			values = append(values, strconv.Itoa(coordinate.Y))
+		case CSVMetadataID:
+			values = append(values, formatCSVValue(feature.ID))
+		}
+	}
+
+	switch l.options.Geometry {
+	case CSVGeometryWKT:
+		values = append(values, templateWKT(feature.Geometry))
+	case CSVGeometryLonLat:
+		if point, ok := feature.Geometry.(orb.Point); ok {
+			point = lonLatGeometry(point, l.coordinateSystem).(orb.Point)
+			values = append(values, formatCSVValue(point[0]), formatCSVValue(point[1]))
+		} else {
+			values = append(values, "", "")
+		}
+	case CSVGeometryCentroid:
+		if feature.Geometry != nil {
+			centroid, _ := planar.CentroidArea(feature.Geometry)
+			centroid = lonLatGeometry(centroid, l.coordinateSystem).(orb.Point)
+			values = append(values, formatCSVValue(centroid[0]), formatCSVValue(centroid[1]))
+		} else {
+			values = append(values, "", "")
+		}
+	}
+
+	return values
+}
+
+// propertyColumns returns the property keys of a feature that become columns, sorted.
+// The layer property is left out when the layer metadata column is written.
+func (l *csvLayout) propertyColumns(feature *geojson.Feature) []string {
+	keys := make([]string, 0, len(feature.Properties))
+	for key := range feature.Properties {
+		if l.layerColumn && key == l.layerProperty {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// record returns the CSV record of a feature for the given property columns
+func (l *csvLayout) record(feature *geojson.Feature, coordinate *tile.TileCoordinate, properties []string) []string {
+	record := l.fixedValues(feature, coordinate)
+	for _, column := range properties {
+		record = append(record, formatCSVValue(feature.Properties[column]))
+	}
+	return record
+}
+
+// featureLayer returns the layer name a feature carries in a layer property, or ""
+// when the property is empty or missing
+func featureLayer(feature *geojson.Feature, layerProperty string) string {
+	if layerProperty == "" {
+		return ""
+	}
+	layer, _ := feature.Properties[layerProperty].(string)
+	return layer
+}
+
+// formatCSVValue formats a property value as a CSV field. Missing values are empty,
+// and arrays and objects are written as JSON.
+func formatCSVValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	}
+}
+
+// csvDelimiter returns the field delimiter of a tabular format
+func csvDelimiter(format Format) rune {
+	if format == FormatTSV {
+		return '\t'
+	}
+	return ','
+}
+
+// CSVFormatter formats tiles as CSV or TSV with a header row. Union columns are
+// taken from the formatted tiles, so each call produces a complete table.
+type CSVFormatter struct {
+	layout    *csvLayout
+	delimiter rune
+}
+
+// NewCSVFormatter creates a CSV or TSV formatter, reading layer names from
+// layerProperty. Per-layer columns need one file per layer and are only supported by
+// the CSV writer.
+func NewCSVFormatter(options *CSVOptions, format Format, coordinateSystem, layerProperty string) (*CSVFormatter, error) {
+	layout, err := newCSVLayout(options, coordinateSystem, layerProperty)
+	if err != nil {
+		return nil, err
+	}
+	if layout.options.Columns == CSVColumnsLayer {
+		return nil, fmt.Errorf("per-layer csv columns require single-file output")
+	}
+
+	return &CSVFormatter{
+		layout:    layout,
+		delimiter: csvDelimiter(format),
+	}, nil
+}
+
+// Format formats the features of a single tile as a table
+func (f *CSVFormatter) Format(t *tile.ProcessedTile) ([]byte, error) {
+	if t.Error != nil {
+		return nil, fmt.Errorf("cannot format tile with error: %w", t.Error)
+	}
+	return f.FormatBatch([]*tile.ProcessedTile{t})
+}
+
+// FormatBatch formats the features of multiple tiles as one table, skipping failed tiles
+func (f *CSVFormatter) FormatBatch(tiles []*tile.ProcessedTile) ([]byte, error) {
+	properties := f.layout.options.Schema
+	if f.layout.options.Columns == CSVColumnsUnion {
+		seen := make(map[string]bool)
+		properties = nil
+		for _, t := range tiles {
+			if t.Error != nil {
+				continue
+			}
+			for _, feature := range newTemplateTile(t).Features {
+				for _, key := range f.layout.propertyColumns(feature) {
+					if !seen[key] {
+						seen[key] = true
+						properties = append(properties, key)
+					}
+				}
+			}
+		}
+		sort.Strings(properties)
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Comma = f.delimiter
+	if err := writer.Write(f.layout.header(properties)); err != nil {
+		return nil, fmt.Errorf("failed to write csv header: %w", err)
+	}
+
+	for _, t := range tiles {
+		if t.Error != nil {
+			continue
+		}
+		for _, feature := range newTemplateTile(t).Features {
+			if err := writer.Write(f.layout.record(feature, t.Coordinate, properties)); err != nil {
+				return nil, fmt.Errorf("failed to write csv record: %w", err)
+			}
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, fmt.Errorf("failed to write csv: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ContentType returns the MIME type for CSV or TSV
+func (f *CSVFormatter) ContentType() string {
+	if f.delimiter == '\t' {
+		return "text/tab-separated-values"
+	}
+	return "text/csv"
+}
+
+// CSVWriter streams the features of all tiles into one CSV or TSV file, or one file
+// per layer. With a fixed schema rows are written as they arrive. Otherwise property
+// columns are discovered as features arrive: rows are spooled to a temporary file and
+// the table is written, with its header once, on Close.
+type CSVWriter struct {
+	config      *WriterConfig
+	layout      *csvLayout
+	destination string
+	tables      map[string]*csvTable
+	closed      bool
+}
+
+// NewCSVWriter creates a CSV or TSV writer that writes the header once, with the
+// columns of all tiles. A destination of "" or "-" writes to stdout, except with
+// per-layer columns.
+func NewCSVWriter(config *WriterConfig, destination string) (*CSVWriter, error) {
+	layout, err := newCSVLayout(config.CSV, config.CoordinateSystem, config.LayerProperty)
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv options: %w", err)
+	}
+	if destination == "" {
+		destination = "-"
+	}
+	if destination == "-" && layout.options.Columns == CSVColumnsLayer {
+		return nil, fmt.Errorf("per-layer csv columns require an output file")
+	}
+
+	w := &CSVWriter{
+		config:      config,
+		layout:      layout,
+		destination: destination,
+		tables:      make(map[string]*csvTable),
+	}
+
+	// A single table is created up front, so a job without features still gets a header
+	if layout.options.Columns != CSVColumnsLayer {
+		if _, err := w.table(""); err != nil {
+			return nil, err
+		}
+	}
+
+	return w, nil
+}
+
+// Write appends the features of a single processed tile
+func (w *CSVWriter) Write(t *tile.ProcessedTile) error {
+	return w.WriteBatch([]*tile.ProcessedTile{t})
+}
+
+// WriteBatch appends the features of multiple processed tiles, skipping failed tiles
+func (w *CSVWriter) WriteBatch(tiles []*tile.ProcessedTile) error {
+	if w.closed {
+		return fmt.Errorf("write to closed csv writer")
+	}
+
+	for _, t := range tiles {
+		if t.Error != nil {
+			continue
+		}
+		for _, feature := range newTemplateTile(t).Features {
+			layer := ""
+			if w.layout.options.Columns == CSVColumnsLayer {
+				layer = featureLayer(feature, w.layout.layerProperty)
+			}
+			table, err := w.table(layer)
+			if err != nil {
+				return err
+			}
+			if err := table.add(feature, t.Coordinate); err != nil {
+				return fmt.Errorf("failed to write feature of tile %s: %w", t.Coordinate.String(), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Close writes any spooled tables and closes all files
+func (w *CSVWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	layers := make([]string, 0, len(w.tables))
+	for layer := range w.tables {
+		layers = append(layers, layer)
+	}
+	sort.Strings(layers)
+
+	var firstErr error
+	for _, layer := range layers {
+		if err := w.tables[layer].close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to write csv table %s: %w", w.tables[layer].path, err)
+		}
+	}
+	return firstErr
+}
+
+// table returns the table of a layer, creating it on first use
+func (w *CSVWriter) table(layer string) (*csvTable, error) {
+	if table, ok := w.tables[layer]; ok {
+		return table, nil
+	}
+
+	path := w.destination
+	if w.layout.options.Columns == CSVColumnsLayer {
+		path = layerPath(w.destination, layer)
+	}
+
+	table, err := newCSVTable(w.layout, path, csvDelimiter(w.config.Format), w.config.Compression)
+	if err != nil {
+		return nil, err
+	}
+	w.tables[layer] = table
+	return table, nil
+}
+
+// layerPath inserts a layer name before a file's extension: out.csv becomes
+// out.roads.csv
+func layerPath(path, layer string) string {
+	suffix := ""
+	if strings.HasSuffix(path, ".gz") {
+		path, suffix = strings.TrimSuffix(path, ".gz"), ".gz"
+	}
+	if layer == "" {
+		layer = "_"
+	}
+	layer = strings.NewReplacer("/", "_", "\\", "_").Replace(layer)
+
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + layer + ext + suffix
+}
+
+// csvTable is one CSV output file
+type csvTable struct {
+	layout      *csvLayout
+	path        string
+	delimiter   rune
+	compression bool
+
+	// Fixed schema: rows go straight to the output
+	output    io.WriteCloser
+	outputCSV *csv.Writer
+
+	// Discovered columns: rows are spooled with properties in discovery order
+	properties []string
+	index      map[string]int
+	spool      *os.File
+	spoolCSV   *csv.Writer
+}
+
+// newCSVTable creates a table, opening the output for a fixed schema and a spool file
+// otherwise
+func newCSVTable(layout *csvLayout, path string, delimiter rune, compression bool) (*csvTable, error) {
+	table := &csvTable{
+		layout:      layout,
+		path:        path,
+		delimiter:   delimiter,
+		compression: compression,
+	}
+
+	if layout.options.Columns == CSVColumnsSchema {
+		if err := table.open(); err != nil {
+			return nil, err
+		}
+		if err := table.outputCSV.Write(layout.header(layout.options.Schema)); err != nil {
+			return nil, fmt.Errorf("failed to write csv header: %w", err)
+		}
+		return table, nil
+	}
+
+	spool, err := os.CreateTemp("", "tile-to-json-*.csv")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create csv spool file: %w", err)
+	}
+	table.spool = spool
+	table.spoolCSV = csv.NewWriter(spool)
+	table.index = make(map[string]int)
+	return table, nil
+}
+
+// open opens the table's output file, or stdout
+func (t *csvTable) open() error {
+	if t.path == "-" {
+		t.output = nopWriteCloser{os.Stdout}
+	} else {
+		dest, err := newFileDestination(t.path, t.compression)
+		if err != nil {
+			return fmt.Errorf("failed to create file destination: %w", err)
+		}
+		t.output = dest
+	}
+	t.outputCSV = csv.NewWriter(t.output)
+	t.outputCSV.Comma = t.delimiter
+	return nil
+}
+
+// add writes or spools the record of a feature
+func (t *csvTable) add(feature *geojson.Feature, coordinate *tile.TileCoordinate) error {
+	if t.spool == nil {
+		return t.outputCSV.Write(t.layout.record(feature, coordinate, t.layout.options.Schema))
+	}
+
+	for _, key := range t.layout.propertyColumns(feature) {
+		if _, ok := t.index[key]; !ok {
+			t.index[key] = len(t.properties)
+			t.properties = append(t.properties, key)
+		}
+	}
+	return t.spoolCSV.Write(t.layout.record(feature, coordinate, t.properties))
+}
+
+// close writes the header and spooled rows, with properties in sorted column order,
+// and closes the output
+func (t *csvTable) close() error {
+	if t.spool != nil {
+		defer os.Remove(t.spool.Name())
+		defer t.spool.Close()
+
+		if err := t.writeSpool(); err != nil {
+			if t.output != nil {
+				t.output.Close()
+			}
+			return err
+		}
+	}
+
+	t.outputCSV.Flush()
+	err := t.outputCSV.Error()
+	if closeErr := t.output.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// writeSpool copies the spooled rows to the output. Rows spooled before a column was
+// discovered are shorter and leave it empty.
+func (t *csvTable) writeSpool() error {
+	t.spoolCSV.Flush()
+	if err := t.spoolCSV.Error(); err != nil {
+		return fmt.Errorf("failed to write csv spool file: %w", err)
+	}
+	if _, err := t.spool.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read csv spool file: %w", err)
+	}
+
+	sorted := append([]string{}, t.properties...)
+	sort.Strings(sorted)
+	position := make([]int, len(t.properties))
+	for i, key := range sorted {
+		position[t.index[key]] = i
+	}
+
+	if err := t.open(); err != nil {
+		return err
+	}
+	if err := t.outputCSV.Write(t.layout.header(sorted)); err != nil {
+		return fmt.Errorf("failed to write csv header: %w", err)
+	}
+
+	fixed := len(t.layout.fixed)
+	reader := csv.NewReader(t.spool)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	record := make([]string, fixed+len(sorted))
+	for {
+		spooled, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read csv spool file: %w", err)
+		}
+
+		copy(record, spooled[:fixed])
+		for i := fixed; i < len(record); i++ {
+			record[i] = ""
+		}
+		for i, value := range spooled[fixed:] {
+			record[fixed+position[i]] = value
+		}
+		if err := t.outputCSV.Write(record); err != nil {
+			return err
+		}
+	}
+}
+
+// nopWriteCloser adds a no-op Close to stdout
+type nopWriteCloser struct {
+	io.Writer
+}
+
+// Close implements io.Closer
+func (nopWriteCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,220 @@
+// internal/output/csv_test.go - Unit tests for CSV and TSV output
+package output
+
+import (
+	"encoding/csv"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/project"
+	"github.com/valpere/tile_to_json/internal/tile"
+	"github.com/valpere/tile_to_json/pkg/mvt"
+)
+
+// writeCSV writes tiles through a CSV writer, one batch per tile, and closes it
+func writeCSV(t *testing.T, config *WriterConfig, destination string, tiles ...*tile.ProcessedTile) *CSVWriter {
+	t.Helper()
+	writer, err := NewCSVWriter(config, destination)
+	if err != nil {
+		t.Fatalf("Failed to create csv writer: %v", err)
+	}
+	for _, tt := range tiles {
+		if err := writer.WriteBatch([]*tile.ProcessedTile{tt}); err != nil {
+			t.Fatalf("Failed to write batch: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close csv writer: %v", err)
+	}
+	return writer
+}
+
+func TestCSVWriterUnionColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.csv")
+	config := &WriterConfig{Format: FormatCSV, LayerProperty: mvt.DefaultLayerProperty}
+
+	// Columns first seen in later batches are padded in the rows spooled before them
+	writeCSV(t, config, path,
+		testTile(1, 0, 0, testFeature(orb.Point{1, 2}, map[string]interface{}{"b": "x"})),
+		testTile(1, 1, 0, testFeature(orb.Point{3, 4}, map[string]interface{}{"a": 1.0, "b": "y"})),
+		testTile(1, 1, 1, testFeature(orb.Point{5, 6}, map[string]interface{}{"c": true})),
+	)
+
+	expected := "wkt,a,b,c\nPOINT(1 2),,x,\nPOINT(3 4),1,y,\nPOINT(5 6),,,true\n"
+	if got := readTestFile(t, path); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestCSVWriterSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.csv")
+	config := &WriterConfig{
+		Format: FormatCSV,
+		CSV:    NewCSVOptions("b,a", CSVGeometryNone, []string{CSVMetadataZ, CSVMetadataX, CSVMetadataY}),
+	}
+
+	writer := writeCSV(t, config, path,
+		testTile(3, 1, 2, testFeature(orb.Point{1, 2}, map[string]interface{}{"a": 1.0, "b": "x", "dropped": 2.0})),
+		testTile(3, 2, 2, testFeature(orb.Point{3, 4}, map[string]interface{}{"b": "y"})),
+	)
+
+	if writer.tables[""].spool != nil {
+		t.Error("Expected a fixed schema to stream rows without a spool file")
+	}
+	expected := "z,x,y,b,a\n3,1,2,x,1\n3,2,2,y,\n"
+	if got := readTestFile(t, path); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestCSVWriterPerLayer(t *testing.T) {
+	dir := t.TempDir()
+	config := &WriterConfig{
+		Format:        FormatCSV,
+		CSV:           NewCSVOptions(CSVColumnsLayer, CSVGeometryNone, []string{CSVMetadataLayer}),
+		LayerProperty: "lyr",
+	}
+
+	writeCSV(t, config, filepath.Join(dir, "out.csv"),
+		testTile(1, 0, 0,
+			testFeature(orb.Point{1, 2}, map[string]interface{}{"lyr": "roads", "name": "A"}),
+			testFeature(orb.Point{3, 4}, map[string]interface{}{"lyr": "pois", "kind": "cafe"}),
+			testFeature(orb.Point{5, 6}, map[string]interface{}{"v": 1.0}),
+		),
+		testTile(1, 1, 0, testFeature(orb.Point{7, 8}, map[string]interface{}{"lyr": "roads", "lanes": 2.0})),
+	)
+
+	expected := map[string]string{
+		"out.roads.csv": "layer,lanes,name\nroads,,A\nroads,2,\n",
+		"out.pois.csv":  "layer,kind\npois,cafe\n",
+		"out._.csv":     "layer,v\n,1\n",
+	}
+	for name, content := range expected {
+		if got := readTestFile(t, filepath.Join(dir, name)); got != content {
+			t.Errorf("Expected %s to be %q, got %q", name, content, got)
+		}
+	}
+}
+
+func TestLayerPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		layer    string
+		expected string
+	}{
+		{"out.csv", "roads", "out.roads.csv"},
+		{"dir/out.tsv", "pois", "dir/out.pois.tsv"},
+		{"out.csv.gz", "roads", "out.roads.csv.gz"},
+		{"out.csv", "", "out._.csv"},
+		{"out.csv", "a/b\\c", "out.a_b_c.csv"},
+		{"out", "roads", "out.roads"},
+	}
+
+	for _, test := range tests {
+		if got := layerPath(test.path, test.layer); got != test.expected {
+			t.Errorf("layerPath(%q, %q): expected %q, got %q", test.path, test.layer, test.expected, got)
+		}
+	}
+}
+
+func TestCSVFormatterTSV(t *testing.T) {
+	formatter, err := NewCSVFormatter(nil, FormatTSV, mvt.CoordSystemWebMercator, mvt.DefaultLayerProperty)
+	if err != nil {
+		t.Fatalf("Failed to create formatter: %v", err)
+	}
+
+	data, err := formatter.Format(testTile(0, 0, 0, testFeature(orb.Point{1, 2}, map[string]interface{}{"name": "a, b"})))
+	if err != nil {
+		t.Fatalf("Failed to format tile: %v", err)
+	}
+
+	expected := "wkt\tname\nPOINT(1 2)\ta, b\n"
+	if string(data) != expected {
+		t.Errorf("Expected %q, got %q", expected, string(data))
+	}
+	if formatter.ContentType() != "text/tab-separated-values" {
+		t.Errorf("Expected content type text/tab-separated-values, got %s", formatter.ContentType())
+	}
+}
+
+func TestCSVFormatterLonLat(t *testing.T) {
+	toMercator := func(lon, lat float64) orb.Point {
+		return project.Point(orb.Point{lon, lat}, project.WGS84.ToMercator)
+	}
+	square := orb.Polygon{{toMercator(1, 1), toMercator(3, 1), toMercator(3, 3), toMercator(1, 3), toMercator(1, 1)}}
+
+	tests := []struct {
+		geometry string
+		feature  orb.Geometry
+		expected []float64
+	}{
+		{CSVGeometryLonLat, toMercator(10, 20), []float64{10, 20}},
+		{CSVGeometryLonLat, orb.LineString{toMercator(0, 0), toMercator(1, 1)}, nil},
+		{CSVGeometryCentroid, toMercator(10, 20), []float64{10, 20}},
+		// The centroid is taken in Web Mercator, so its latitude is not the midpoint
+		{CSVGeometryCentroid, square, []float64{2, 2.0003048}},
+	}
+
+	for _, test := range tests {
+		formatter, err := NewCSVFormatter(NewCSVOptions("", test.geometry, nil), FormatCSV, mvt.CoordSystemWebMercator, "")
+		if err != nil {
+			t.Fatalf("Failed to create formatter: %v", err)
+		}
+		data, err := formatter.Format(testTile(0, 0, 0, testFeature(test.feature, nil)))
+		if err != nil {
+			t.Fatalf("Failed to format tile: %v", err)
+		}
+
+		records, err := csv.NewReader(strings.NewReader(string(data))).ReadAll()
+		if err != nil {
+			t.Fatalf("Failed to parse csv: %v", err)
+		}
+		if !reflect.DeepEqual(records[0], []string{"lon", "lat"}) {
+			t.Fatalf("%s %s: expected lon,lat header, got %v", test.geometry, test.feature.GeoJSONType(), records[0])
+		}
+
+		row := records[1]
+		if test.expected == nil {
+			if row[0] != "" || row[1] != "" {
+				t.Errorf("%s %s: expected empty lon/lat, got %v", test.geometry, test.feature.GeoJSONType(), row)
+			}
+			continue
+		}
+		for i, want := range test.expected {
+			got, err := strconv.ParseFloat(row[i], 64)
+			if err != nil || math.Abs(got-want) > 1e-6 {
+				t.Errorf("%s %s: expected %v, got %v", test.geometry, test.feature.GeoJSONType(), test.expected, row)
+				break
+			}
+		}
+	}
+}
+
+func TestFormatCSVValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{"nil", nil, ""},
+		{"string", "a,b", "a,b"},
+		{"bool", false, "false"},
+		{"float", 1.5, "1.5"},
+		{"large float", 1e21, "1000000000000000000000"},
+		{"int64", int64(9007199254740993), "9007199254740993"},
+		{"uint64", uint64(math.MaxUint64), "18446744073709551615"},
+		{"array", []interface{}{1.0, "a", nil}, `[1,"a",null]`},
+		{"map", map[string]interface{}{"k": "v", "n": 2.0}, `{"k":"v","n":2}`},
+	}
+
+	for _, test := range tests {
+		if got := formatCSVValue(test.value); got != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, got)
+		}
+	}
+}
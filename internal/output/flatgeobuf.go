@@ -0,0 +1,190 @@
+// internal/output/flatgeobuf.go - FlatGeobuf output formatting and writing
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/paulmach/orb/geojson"
+	"github.com/valpere/tile_to_json/internal/tile"
+	"github.com/valpere/tile_to_json/pkg/flatgeobuf"
+	"github.com/valpere/tile_to_json/pkg/mvt"
+)
+
+// flatGeobufOptions returns the FlatGeobuf options for a dataset name, index setting
+// and coordinate system
+func flatGeobufOptions(name string, index bool, coordinateSystem string) flatgeobuf.Options {
+	options := flatgeobuf.Options{Name: name}
+	if index {
+		options.IndexNodeSize = flatgeobuf.DefaultIndexNodeSize
+	}
+	switch coordinateSystem {
+	case mvt.CoordSystemWGS84:
+		options.EPSG = 4326
+	case mvt.CoordSystemWebMercator:
+		options.EPSG = 3857
+	}
+	return options
+}
+
+// writeFlatGeobufFeatures adds the features of a tile to a FlatGeobuf writer, tagging
+// each with its tile coordinate when tag is set
+func writeFlatGeobufFeatures(writer *flatgeobuf.Writer, t *tile.ProcessedTile, tag bool) error {
+	for _, feature := range collectionFeatures(t, tag) {
+		feat, ok := feature.(*geojson.Feature)
+		if !ok {
+			continue
+		}
+		if err := writer.Write(feat); err != nil {
+			return fmt.Errorf("failed to write feature of tile %s: %w", t.Coordinate.String(), err)
+		}
+	}
+	return nil
+}
+
+// FlatGeobufFormatter formats tiles as complete FlatGeobuf files
+type FlatGeobufFormatter struct {
+	index            bool
+	includeStats     bool
+	coordinateSystem string
+}
+
+// NewFlatGeobufFormatter creates a FlatGeobuf formatter. With index set each file gets
+// a packed Hilbert R-tree.
+func NewFlatGeobufFormatter(index, includeStats bool, coordinateSystem string) *FlatGeobufFormatter {
+	return &FlatGeobufFormatter{
+		index:            index,
+		includeStats:     includeStats,
+		coordinateSystem: coordinateSystem,
+	}
+}
+
+// Format formats a single tile as a FlatGeobuf file
+func (f *FlatGeobufFormatter) Format(t *tile.ProcessedTile) ([]byte, error) {
+	if t.Error != nil {
+		return nil, fmt.Errorf("cannot format tile with error: %w", t.Error)
+	}
+	return f.format([]*tile.ProcessedTile{t}, false)
+}
+
+// FormatBatch formats multiple tiles as one FlatGeobuf file, skipping failed tiles.
+// Features are tagged with their tile when statistics are included.
+func (f *FlatGeobufFormatter) FormatBatch(tiles []*tile.ProcessedTile) ([]byte, error) {
+	return f.format(tiles, f.includeStats)
+}
+
+func (f *FlatGeobufFormatter) format(tiles []*tile.ProcessedTile, tag bool) ([]byte, error) {
+	writer, err := flatgeobuf.NewWriter(flatGeobufOptions("", f.index, f.coordinateSystem))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create flatgeobuf writer: %w", err)
+	}
+	defer writer.Close()
+
+	for _, t := range tiles {
+		if t.Error != nil {
+			continue
+		}
+		if err := writeFlatGeobufFeatures(writer, t, tag); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := writer.WriteTo(&buf); err != nil {
+		return nil, fmt.Errorf("failed to write flatgeobuf: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ContentType returns the MIME type for FlatGeobuf
+func (f *FlatGeobufFormatter) ContentType() string {
+	return "application/flatgeobuf"
+}
+
+// FlatGeobufWriter writes the features of all tiles of a job into one FlatGeobuf file.
+// The schema and spatial index cover every feature, so features are spooled as they
+// arrive and the file is written on Close.
+type FlatGeobufWriter struct {
+	config      *WriterConfig
+	writer      *flatgeobuf.Writer
+	destination string
+	closed      bool
+}
+
+// NewFlatGeobufWriter creates a FlatGeobuf writer whose schema and index span all
+// tiles. A destination of "" or "-" writes to stdout.
+func NewFlatGeobufWriter(config *WriterConfig, destination string) (*FlatGeobufWriter, error) {
+	if destination == "" {
+		destination = "-"
+	}
+
+	name := ""
+	if destination != "-" {
+		name = strings.TrimSuffix(filepath.Base(destination), filepath.Ext(destination))
+	}
+
+	writer, err := flatgeobuf.NewWriter(flatGeobufOptions(name, config.FlatGeobufIndex, config.CoordinateSystem))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create flatgeobuf writer: %w", err)
+	}
+
+	return &FlatGeobufWriter{
+		config:      config,
+		writer:      writer,
+		destination: destination,
+	}, nil
+}
+
+// Write adds the features of a single processed tile
+func (w *FlatGeobufWriter) Write(t *tile.ProcessedTile) error {
+	return w.WriteBatch([]*tile.ProcessedTile{t})
+}
+
+// WriteBatch adds the features of multiple processed tiles, skipping failed tiles
+func (w *FlatGeobufWriter) WriteBatch(tiles []*tile.ProcessedTile) error {
+	if w.closed {
+		return fmt.Errorf("write to closed flatgeobuf writer")
+	}
+
+	for _, t := range tiles {
+		if t.Error != nil {
+			continue
+		}
+		if err := writeFlatGeobufFeatures(w.writer, t, w.config.Metadata); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close writes the file and removes the spooled features. A writer that received no
+// features still writes a valid, empty file.
+func (w *FlatGeobufWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	defer w.writer.Close()
+
+	var output io.WriteCloser = nopWriteCloser{os.Stdout}
+	if w.destination != "-" {
+		dest, err := newFileDestination(w.destination, w.config.Compression)
+		if err != nil {
+			return fmt.Errorf("failed to create file destination: %w", err)
+		}
+		output = dest
+	}
+
+	_, err := w.writer.WriteTo(output)
+	if closeErr := output.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write flatgeobuf: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+// internal/output/flatgeobuf_test.go - Unit tests for FlatGeobuf output
+package output
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/valpere/tile_to_json/internal/tile"
+	"github.com/valpere/tile_to_json/pkg/mvt"
+)
+
+// flatGeobufMagic starts every FlatGeobuf file
+var flatGeobufMagic = []byte{'f', 'g', 'b', 3, 'f', 'g', 'b', 0}
+
+// flatGeobufTiles returns two tiles of point features for FlatGeobuf tests
+func flatGeobufTiles() []*tile.ProcessedTile {
+	return []*tile.ProcessedTile{
+		testTile(1, 0, 0, testFeature(orb.Point{1, 2}, map[string]interface{}{"name": "a"})),
+		testTile(1, 1, 0, testFeature(orb.Point{3, 4}, map[string]interface{}{"name": "b"})),
+	}
+}
+
+// hasEPSG reports whether FlatGeobuf data holds an EPSG CRS with the given code
+func hasEPSG(data []byte, code int) bool {
+	return bytes.Contains(data, []byte("EPSG")) && bytes.Contains(data, binary.LittleEndian.AppendUint32(nil, uint32(code)))
+}
+
+func TestFlatGeobufFormatter(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   *FormatterConfig
+		wantEPSG int
+		wantTile bool
+	}{
+		{"web mercator", &FormatterConfig{Format: FormatFlatGeobuf, CoordinateSystem: mvt.CoordSystemWebMercator}, 3857, false},
+		{"wgs84", &FormatterConfig{Format: FormatFlatGeobuf, CoordinateSystem: mvt.CoordSystemWGS84}, 4326, false},
+		{"unknown coordinate system", &FormatterConfig{Format: FormatFlatGeobuf}, 0, false},
+		{"stats tag tiles", &FormatterConfig{Format: FormatFlatGeobuf, IncludeStats: true, CoordinateSystem: mvt.CoordSystemWGS84}, 4326, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatter, err := NewFormatter(tt.config)
+			if err != nil {
+				t.Fatalf("Failed to create formatter: %v", err)
+			}
+			data, err := formatter.FormatBatch(flatGeobufTiles())
+			if err != nil {
+				t.Fatalf("Failed to format batch: %v", err)
+			}
+
+			if !bytes.HasPrefix(data, flatGeobufMagic) {
+				t.Fatalf("Expected FlatGeobuf magic bytes, got %v", data[:8])
+			}
+			if tt.wantEPSG != 0 && !hasEPSG(data, tt.wantEPSG) {
+				t.Errorf("Expected EPSG:%d CRS", tt.wantEPSG)
+			}
+			if tt.wantEPSG == 0 && bytes.Contains(data, []byte("EPSG")) {
+				t.Error("Expected no CRS")
+			}
+			if got := bytes.Contains(data, []byte("_tile")); got != tt.wantTile {
+				t.Errorf("Expected _tile column %v, got %v", tt.wantTile, got)
+			}
+		})
+	}
+}
+
+func TestFlatGeobufFormatterIndex(t *testing.T) {
+	sizes := make(map[bool]int)
+	for _, index := range []bool{false, true} {
+		formatter, err := NewFormatter(&FormatterConfig{Format: FormatFlatGeobuf, FlatGeobufIndex: index})
+		if err != nil {
+			t.Fatalf("Failed to create formatter: %v", err)
+		}
+		data, err := formatter.FormatBatch(flatGeobufTiles())
+		if err != nil {
+			t.Fatalf("Failed to format batch: %v", err)
+		}
+		sizes[index] = len(data)
+	}
+
+	// Two features make a packed R-tree of two leaves and a root, 40 bytes per node
+	if diff := sizes[true] - sizes[false]; diff != 3*40 {
+		t.Errorf("Expected the index to add 120 bytes, got %d", diff)
+	}
+}
+
+func TestFlatGeobufFormatterErrors(t *testing.T) {
+	formatter, err := NewFormatter(&FormatterConfig{Format: FormatFlatGeobuf})
+	if err != nil {
+		t.Fatalf("Failed to create formatter: %v", err)
+	}
+
+	failed := testTile(1, 1, 1, testFeature(orb.Point{5, 6}, nil))
+	failed.Error = errTestTile
+	if _, err := formatter.Format(failed); !errors.Is(err, errTestTile) {
+		t.Errorf("Expected tile error, got %v", err)
+	}
+
+	// Failed tiles are skipped in batches
+	expected, err := formatter.FormatBatch(flatGeobufTiles())
+	if err != nil {
+		t.Fatalf("Failed to format batch: %v", err)
+	}
+	data, err := formatter.FormatBatch(append(flatGeobufTiles(), failed))
+	if err != nil {
+		t.Fatalf("Failed to format batch: %v", err)
+	}
+	if !bytes.Equal(data, expected) {
+		t.Error("Expected the failed tile to be skipped")
+	}
+}
+
+func TestFlatGeobufWriter(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      *WriterConfig
+		destination string
+		gzip        bool
+	}{
+		{"plain", &WriterConfig{Format: FormatFlatGeobuf, FlatGeobufIndex: true, CoordinateSystem: mvt.CoordSystemWGS84}, "roads.fgb", false},
+		{"compressed", &WriterConfig{Format: FormatFlatGeobuf, Compression: true, CoordinateSystem: mvt.CoordSystemWGS84}, "roads.fgb.gz", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.destination)
+			writer, err := NewSingleFileWriter(tt.config, path)
+			if err != nil {
+				t.Fatalf("Failed to create writer: %v", err)
+			}
+			if _, ok := writer.(*FlatGeobufWriter); !ok {
+				t.Fatalf("Expected a FlatGeobufWriter, got %T", writer)
+			}
+			if err := writer.WriteBatch(flatGeobufTiles()); err != nil {
+				t.Fatalf("Failed to write batch: %v", err)
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatalf("Failed to close writer: %v", err)
+			}
+			if err := writer.Close(); err != nil {
+				t.Errorf("Expected closing twice to succeed, got %v", err)
+			}
+			if err := writer.Write(flatGeobufTiles()[0]); err == nil {
+				t.Error("Expected error writing to a closed writer")
+			}
+
+			data := []byte(readTestFile(t, path))
+			if tt.gzip {
+				if !bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+					t.Errorf("Expected gzip output, got %v", data[:2])
+				}
+				return
+			}
+			if !bytes.HasPrefix(data, flatGeobufMagic) {
+				t.Fatalf("Expected FlatGeobuf magic bytes, got %v", data[:8])
+			}
+			if !bytes.Contains(data, []byte("roads")) {
+				t.Error("Expected the file name as the dataset name")
+			}
+			if !hasEPSG(data, 4326) {
+				t.Error("Expected EPSG:4326 CRS")
+			}
+		})
+	}
+}
@@ -2,17 +2,23 @@
 package output
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"time"
 
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
 	"github.com/valpere/tile_to_json/internal/tile"
+	"github.com/valpere/tile_to_json/pkg/mvt"
 )
 
 // GeoJSONFormatter formats tiles as GeoJSON FeatureCollection
 type GeoJSONFormatter struct {
 	pretty       bool
 	includeStats bool
+	rfc7946      bool
+	timings      bool
 }
 
 // NewGeoJSONFormatter creates a new GeoJSON formatter
@@ -35,14 +41,38 @@ func (f *GeoJSONFormatter) Format(tile *tile.ProcessedTile) ([]byte, error) {
 	// Add metadata if requested
 	if f.includeStats && tile.Metadata != nil {
 		if geoJSON, ok := output.(map[string]interface{}); ok {
-			geoJSON["_metadata"] = map[string]interface{}{
+			metadata := map[string]interface{}{
 				"tile_coordinate": tile.Coordinate,
 				"layers":          tile.Metadata.Layers,
 				"feature_count":   tile.Metadata.FeatureCount,
 				"size_bytes":      tile.Metadata.Size,
-				"process_time":    tile.Metadata.ProcessTime,
 				"version":         tile.Metadata.Version,
 				"extent":          tile.Metadata.Extent,
+				"format":          tile.Metadata.Format,
+				"schema":          tile.Metadata.Schema,
+			}
+			if f.timings {
+				metadata["process_time"] = tile.Metadata.ProcessTime
+			}
+			if tile.Metadata.Repairs != nil {
+				metadata["repairs"] = tile.Metadata.Repairs
+			}
+			if len(tile.Metadata.Warnings) > 0 {
+				metadata["warnings"] = tile.Metadata.Warnings
+			}
+			geoJSON["_metadata"] = metadata
+		}
+	}
+
+	// RFC 7946 output always carries a bbox member
+	if f.rfc7946 {
+		if geoJSON, ok := output.(map[string]interface{}); ok {
+			if _, exists := geoJSON["bbox"]; !exists {
+				if features, ok := geoJSON["features"].([]*geojson.Feature); ok {
+					if bbox := mvt.CollectionBBox(features); bbox != nil {
+						geoJSON["bbox"] = bbox
+					}
+				}
 			}
 		}
 	}
@@ -55,53 +85,32 @@ func (f *GeoJSONFormatter) Format(tile *tile.ProcessedTile) ([]byte, error) {
 
 // FormatBatch formats multiple tiles as a single GeoJSON FeatureCollection
 func (f *GeoJSONFormatter) FormatBatch(tiles []*tile.ProcessedTile) ([]byte, error) {
-	collection := map[string]interface{}{
-		"type":     "FeatureCollection",
-		"features": make([]interface{}, 0),
-	}
-
-	var totalFeatures int
-	var processedTiles int
-	var failedTiles int
+	features := make([]interface{}, 0)
+	summary := newBatchSummary()
 
 	for _, t := range tiles {
+		summary.add(t)
 		if t.Error != nil {
-			failedTiles++
 			continue
 		}
+		// Add tile coordinate to each feature if metadata is enabled
+		tileFeatures := collectionFeatures(t, f.includeStats)
+		features = append(features, tileFeatures...)
+		summary.totalFeatures += len(tileFeatures)
+	}
 
-		processedTiles++
-
-		// Extract features from the tile's GeoJSON data
-		if data, ok := t.Data.(map[string]interface{}); ok {
-			if features, exists := data["features"]; exists {
-				if featureList, ok := features.([]interface{}); ok {
-					// Add tile coordinate to each feature if metadata is enabled
-					if f.includeStats {
-						for _, feature := range featureList {
-							if feat, ok := feature.(map[string]interface{}); ok {
-								if props, ok := feat["properties"].(map[string]interface{}); ok {
-									props["_tile"] = fmt.Sprintf("%d/%d/%d", t.Coordinate.Z, t.Coordinate.X, t.Coordinate.Y)
-								}
-							}
-						}
-					}
-					collection["features"] = append(collection["features"].([]interface{}), featureList...)
-					totalFeatures += len(featureList)
-				}
-			}
-		}
+	collection := map[string]interface{}{
+		"type":     "FeatureCollection",
+		"features": features,
+	}
+
+	if f.rfc7946 && summary.hasBound {
+		collection["bbox"] = geojson.NewBBox(summary.bound)
 	}
 
 	// Add collection-level metadata
 	if f.includeStats {
-		collection["_metadata"] = map[string]interface{}{
-			"total_tiles":     len(tiles),
-			"processed_tiles": processedTiles,
-			"failed_tiles":    failedTiles,
-			"total_features":  totalFeatures,
-			"generated_at":    time.Now().UTC(),
-		}
+		collection["_metadata"] = summary.metadata(f.rfc7946, f.timings)
 	}
 
 	if f.pretty {
@@ -110,6 +119,132 @@ func (f *GeoJSONFormatter) FormatBatch(tiles []*tile.ProcessedTile) ([]byte, err
 	return json.Marshal(collection)
 }
 
+// collectionFeatures returns the features of a tile's FeatureCollection, which the
+// converters produce as []*geojson.Feature and decoded JSON holds as []interface{}.
+// When tag is set each feature gets a _tile property with the tile coordinate.
+func collectionFeatures(t *tile.ProcessedTile, tag bool) []interface{} {
+	data, ok := t.Data.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	coordinate := fmt.Sprintf("%d/%d/%d", t.Coordinate.Z, t.Coordinate.X, t.Coordinate.Y)
+
+	var features []interface{}
+	switch list := data["features"].(type) {
+	case []*geojson.Feature:
+		features = make([]interface{}, 0, len(list))
+		for _, feature := range list {
+			if tag {
+				if feature.Properties == nil {
+					feature.Properties = make(geojson.Properties)
+				}
+				feature.Properties["_tile"] = coordinate
+			}
+			features = append(features, feature)
+		}
+	case []interface{}:
+		if tag {
+			for _, feature := range list {
+				if feat, ok := feature.(map[string]interface{}); ok {
+					if props, ok := feat["properties"].(map[string]interface{}); ok {
+						props["_tile"] = coordinate
+					}
+				}
+			}
+		}
+		features = list
+	}
+	return features
+}
+
+// maxWarningSamples is the number of warnings kept in a batch summary; the rest are
+// only counted
+const maxWarningSamples = 100
+
+// batchSummary accumulates the collection-level statistics of a batch of tiles
+type batchSummary struct {
+	totalTiles     int
+	processedTiles int
+	failedTiles    int
+	totalFeatures  int
+	bound          orb.Bound
+	hasBound       bool
+	repairs        *mvt.RepairStats
+	schema         mvt.Schema
+	warningCount   int
+	warningCodes   map[string]int
+	warnings       []mvt.Warning // The first maxWarningSamples warnings
+}
+
+// newBatchSummary creates an empty batch summary
+func newBatchSummary() *batchSummary {
+	return &batchSummary{
+		repairs:      &mvt.RepairStats{},
+		schema:       make(mvt.Schema),
+		warningCodes: make(map[string]int),
+	}
+}
+
+// add records a tile's status, warnings, metadata and bounding box. Features are
+// counted by the caller as they are written.
+func (s *batchSummary) add(t *tile.ProcessedTile) {
+	s.totalTiles++
+	for _, warning := range t.Warnings {
+		s.warningCount++
+		s.warningCodes[warning.Code]++
+		if len(s.warnings) < maxWarningSamples {
+			s.warnings = append(s.warnings, warning)
+		}
+	}
+	if t.Error != nil {
+		s.failedTiles++
+		return
+	}
+
+	s.processedTiles++
+
+	if t.Metadata != nil {
+		s.repairs.Add(t.Metadata.Repairs)
+		s.schema.Merge(t.Metadata.Schema)
+	}
+
+	if data, ok := t.Data.(map[string]interface{}); ok {
+		if bbox, ok := data["bbox"].(geojson.BBox); ok && bbox.Valid() {
+			if s.hasBound {
+				s.bound = s.bound.Union(bbox.Bound())
+			} else {
+				s.bound = bbox.Bound()
+				s.hasBound = true
+			}
+		}
+	}
+}
+
+// metadata returns the collection's _metadata member, with the generation time when
+// timings is set
+func (s *batchSummary) metadata(rfc7946, timings bool) map[string]interface{} {
+	metadata := map[string]interface{}{
+		"total_tiles":     s.totalTiles,
+		"processed_tiles": s.processedTiles,
+		"failed_tiles":    s.failedTiles,
+		"total_features":  s.totalFeatures,
+		"schema":          s.schema,
+	}
+	if timings {
+		metadata["generated_at"] = time.Now().UTC()
+	}
+	if rfc7946 {
+		metadata["repairs"] = s.repairs
+	}
+	if s.warningCount > 0 {
+		metadata["warning_count"] = s.warningCount
+		metadata["warning_codes"] = s.warningCodes
+		metadata["warnings"] = s.warnings
+	}
+	return metadata
+}
+
 // ContentType returns the MIME type for GeoJSON
 func (f *GeoJSONFormatter) ContentType() string {
 	return "application/geo+json"
@@ -119,6 +254,7 @@ func (f *GeoJSONFormatter) ContentType() string {
 type JSONFormatter struct {
 	pretty       bool
 	includeStats bool
+	timings      bool
 }
 
 // NewJSONFormatter creates a new JSON formatter
@@ -142,7 +278,7 @@ func (f *JSONFormatter) Format(tile *tile.ProcessedTile) ([]byte, error) {
 	}
 
 	if f.includeStats && tile.Metadata != nil {
-		output["metadata"] = tile.Metadata
+		output["metadata"] = f.tileMetadata(tile.Metadata)
 	}
 
 	if f.pretty {
@@ -164,10 +300,13 @@ func (f *JSONFormatter) FormatBatch(tiles []*tile.ProcessedTile) ([]byte, error)
 		if t.Error != nil {
 			tileOutput["error"] = t.Error.Error()
 			tileOutput["data"] = nil
+			if len(t.Warnings) > 0 {
+				tileOutput["warnings"] = t.Warnings
+			}
 		}
 
 		if f.includeStats && t.Metadata != nil {
-			tileOutput["metadata"] = t.Metadata
+			tileOutput["metadata"] = f.tileMetadata(t.Metadata)
 		}
 
 		output = append(output, tileOutput)
@@ -178,21 +317,31 @@ func (f *JSONFormatter) FormatBatch(tiles []*tile.ProcessedTile) ([]byte, error)
 	}
 
 	if f.includeStats {
-		var successCount, errorCount int
+		var successCount, errorCount, warningCount int
+		schema := make(mvt.Schema)
 		for _, t := range tiles {
+			warningCount += len(t.Warnings)
 			if t.Error != nil {
 				errorCount++
 			} else {
 				successCount++
 			}
+			if t.Metadata != nil {
+				schema.Merge(t.Metadata.Schema)
+			}
 		}
 
-		result["summary"] = map[string]interface{}{
+		summary := map[string]interface{}{
 			"total_tiles":   len(tiles),
 			"success_tiles": successCount,
 			"failed_tiles":  errorCount,
-			"generated_at":  time.Now().UTC(),
+			"warning_count": warningCount,
+			"schema":        schema,
 		}
+		if f.timings {
+			summary["generated_at"] = time.Now().UTC()
+		}
+		result["summary"] = summary
 	}
 
 	if f.pretty {
@@ -201,18 +350,123 @@ func (f *JSONFormatter) FormatBatch(tiles []*tile.ProcessedTile) ([]byte, error)
 	return json.Marshal(result)
 }
 
+// tileMetadata returns the metadata to emit for a tile, without the processing time
+// unless timings are included
+func (f *JSONFormatter) tileMetadata(metadata *tile.TileMetadata) *tile.TileMetadata {
+	if f.timings {
+		return metadata
+	}
+	stable := *metadata
+	stable.ProcessTime = 0
+	return &stable
+}
+
 // ContentType returns the MIME type for JSON
 func (f *JSONFormatter) ContentType() string {
 	return "application/json"
 }
 
+// FeatureSeqFormatter formats tiles as one GeoJSON feature per line, either as
+// newline-delimited JSON or as an RFC 8142 GeoJSON text sequence, where each record
+// is also prefixed with an ASCII record separator. Each feature carries its source
+// tile in a _tile property next to the converter's layer property.
+type FeatureSeqFormatter struct {
+	recordSeparator bool
+}
+
+// asciiRecordSeparator prefixes each RFC 8142 GeoJSON text sequence record
+const asciiRecordSeparator = 0x1E
+
+// NewFeatureSeqFormatter creates a line-delimited feature formatter
+func NewFeatureSeqFormatter(recordSeparator bool) *FeatureSeqFormatter {
+	return &FeatureSeqFormatter{
+		recordSeparator: recordSeparator,
+	}
+}
+
+// Format formats the features of a single tile, one per line
+func (f *FeatureSeqFormatter) Format(tile *tile.ProcessedTile) ([]byte, error) {
+	if tile.Error != nil {
+		return nil, fmt.Errorf("cannot format tile with error: %w", tile.Error)
+	}
+
+	data, ok := tile.Data.(map[string]interface{})
+	if !ok || data["features"] == nil {
+		return nil, fmt.Errorf("tile %s has no features to write as line-delimited GeoJSON", tile.Coordinate.String())
+	}
+
+	var buf bytes.Buffer
+	for _, feature := range collectionFeatures(tile, true) {
+		line, err := json.Marshal(feature)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode feature: %w", err)
+		}
+		if f.recordSeparator {
+			buf.WriteByte(asciiRecordSeparator)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// FormatBatch formats the features of multiple tiles, one per line. Failed tiles
+// have no features and are skipped.
+func (f *FeatureSeqFormatter) FormatBatch(tiles []*tile.ProcessedTile) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, t := range tiles {
+		if t.Error != nil {
+			continue
+		}
+		data, err := f.Format(t)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+	}
+	return buf.Bytes(), nil
+}
+
+// ContentType returns the MIME type for the line-delimited format
+func (f *FeatureSeqFormatter) ContentType() string {
+	if f.recordSeparator {
+		return "application/geo+json-seq"
+	}
+	return "application/x-ndjson"
+}
+
 // NewFormatter creates a formatter based on the specified configuration
 func NewFormatter(config *FormatterConfig) (Formatter, error) {
 	switch config.Format {
 	case FormatGeoJSON:
-		return NewGeoJSONFormatter(config.Pretty, config.IncludeStats), nil
+		formatter := NewGeoJSONFormatter(config.Pretty, config.IncludeStats)
+		formatter.rfc7946 = config.RFC7946
+		formatter.timings = config.Timings
+		return formatter, nil
 	case FormatJSON:
-		return NewJSONFormatter(config.Pretty, config.IncludeStats), nil
+		formatter := NewJSONFormatter(config.Pretty, config.IncludeStats)
+		formatter.timings = config.Timings
+		return formatter, nil
+	case FormatCustom:
+		return NewTemplateFormatter(config.Template, config.CoordinateSystem, config.LayerProperty)
+	case FormatCSV, FormatTSV:
+		return NewCSVFormatter(config.CSV, config.Format, config.CoordinateSystem, config.LayerProperty)
+	case FormatNDJSON:
+		return NewFeatureSeqFormatter(false), nil
+	case FormatGeoJSONSeq:
+		return NewFeatureSeqFormatter(true), nil
+	case FormatFlatGeobuf:
+		return NewFlatGeobufFormatter(config.FlatGeobufIndex, config.IncludeStats, config.CoordinateSystem), nil
+	case FormatGeoParquet:
+		return NewGeoParquetFormatter(config.GeoParquet, config.IncludeStats, config.CoordinateSystem)
+	case FormatGeoPackage:
+		return NewGeoPackageFormatter(config.GeoPackageIndex, config.LayerProperty, config.IncludeStats, config.Timings, config.CoordinateSystem), nil
+	case FormatTopoJSON:
+		return NewTopoJSONFormatter(config.TopoJSONQuantization, config.LayerProperty, config.Pretty, config.IncludeStats)
+	case FormatKML, FormatKMZ:
+		return NewKMLFormatter(config.Format == FormatKMZ, config.KMLStyles, config.LayerProperty, config.IncludeStats)
+	case FormatWKT, FormatWKB:
+		return NewWKTFormatter(config.Format, config.WKT, config.IncludeStats, config.CoordinateSystem)
 	default:
 		return nil, fmt.Errorf("unsupported format: %s", config.Format)
 	}
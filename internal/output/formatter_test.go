@@ -0,0 +1,224 @@
+// internal/output/formatter_test.go - Unit tests for output formatters
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paulmach/orb"
+	"github.com/valpere/tile_to_json/internal/tile"
+	"github.com/valpere/tile_to_json/pkg/mvt"
+)
+
+// errTestTile is the error of tiles that failed to process in tests
+var errTestTile = errors.New("tile failed")
+
+func TestGeoJSONFormatterFormatBatch(t *testing.T) {
+	failed := testTile(1, 1, 1, testFeature(orb.Point{7, 8}, map[string]interface{}{"name": "failed"}))
+	failed.Error = errTestTile
+
+	// The converter holds features as []*geojson.Feature, which must not be dropped
+	formatter := NewGeoJSONFormatter(false, true)
+	data, err := formatter.FormatBatch([]*tile.ProcessedTile{
+		testTile(1, 0, 0, testFeature(orb.Point{1, 2}, map[string]interface{}{"name": "a"})),
+		failed,
+		testTile(1, 1, 0,
+			testFeature(orb.Point{3, 4}, map[string]interface{}{"name": "b"}),
+			testFeature(orb.Point{5, 6}, map[string]interface{}{"name": "c"}),
+		),
+	})
+	if err != nil {
+		t.Fatalf("Failed to format batch: %v", err)
+	}
+
+	var collection map[string]interface{}
+	if err := json.Unmarshal(data, &collection); err != nil {
+		t.Fatalf("Failed to parse feature collection: %v", err)
+	}
+	expected := []string{"a", "b", "c"}
+	if names := collectionNames(collection); !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected features %v, got %v", expected, names)
+	}
+
+	features := collection["features"].([]interface{})
+	properties := features[2].(map[string]interface{})["properties"].(map[string]interface{})
+	if properties["_tile"] != "1/1/0" {
+		t.Errorf("Expected _tile 1/1/0, got %v", properties["_tile"])
+	}
+	metadata := collection["_metadata"].(map[string]interface{})
+	if metadata["total_features"] != 3.0 || metadata["failed_tiles"] != 1.0 {
+		t.Errorf("Expected 3 features and 1 failed tile, got %v", metadata)
+	}
+}
+
+func TestGeoJSONFormatterWarnings(t *testing.T) {
+	var tiles []*tile.ProcessedTile
+	for i := 0; i < 3; i++ {
+		processed := testTile(2, i, 0, testFeature(orb.Point{1, 2}, nil))
+		for j := 0; j < 50; j++ {
+			code := mvt.WarningNilGeometry
+			if j%5 == 0 {
+				code = mvt.WarningConversionError
+			}
+			processed.Warnings = append(processed.Warnings, mvt.Warning{Tile: processed.Coordinate.String(), Layer: "pois", Feature: j, Code: code})
+		}
+		tiles = append(tiles, processed)
+	}
+
+	data, err := NewGeoJSONFormatter(false, true).FormatBatch(tiles)
+	if err != nil {
+		t.Fatalf("Failed to format batch: %v", err)
+	}
+	var collection struct {
+		Metadata struct {
+			WarningCount int            `json:"warning_count"`
+			WarningCodes map[string]int `json:"warning_codes"`
+			Warnings     []mvt.Warning  `json:"warnings"`
+		} `json:"_metadata"`
+	}
+	if err := json.Unmarshal(data, &collection); err != nil {
+		t.Fatalf("Failed to parse feature collection: %v", err)
+	}
+
+	metadata := collection.Metadata
+	if metadata.WarningCount != 150 {
+		t.Errorf("Expected 150 warnings counted, got %d", metadata.WarningCount)
+	}
+	expected := map[string]int{mvt.WarningNilGeometry: 120, mvt.WarningConversionError: 30}
+	if !reflect.DeepEqual(metadata.WarningCodes, expected) {
+		t.Errorf("Expected warning codes %v, got %v", expected, metadata.WarningCodes)
+	}
+	if len(metadata.Warnings) != maxWarningSamples || metadata.Warnings[0].Tile != "2/0/0" {
+		t.Errorf("Expected the first %d warnings as samples, got %d", maxWarningSamples, len(metadata.Warnings))
+	}
+}
+
+func TestFeatureSeqFormatter(t *testing.T) {
+	tests := []struct {
+		format      Format
+		prefix      string
+		contentType string
+	}{
+		{FormatNDJSON, "", "application/x-ndjson"},
+		{FormatGeoJSONSeq, "\x1e", "application/geo+json-seq"},
+	}
+
+	for _, test := range tests {
+		formatter, err := NewFormatter(&FormatterConfig{Format: test.format})
+		if err != nil {
+			t.Fatalf("Failed to create %s formatter: %v", test.format, err)
+		}
+
+		data, err := formatter.Format(testTile(2, 1, 3,
+			testFeature(orb.Point{1, 2}, map[string]interface{}{"_layer": "pois", "name": "a"}),
+			testFeature(orb.Point{3, 4}, map[string]interface{}{"_layer": "roads", "name": "b"}),
+		))
+		if err != nil {
+			t.Fatalf("Failed to format tile as %s: %v", test.format, err)
+		}
+
+		lines := strings.SplitAfter(string(data), "\n")
+		if lines[len(lines)-1] != "" {
+			t.Fatalf("%s: expected output to end with a newline, got %q", test.format, string(data))
+		}
+		lines = lines[:len(lines)-1]
+		if len(lines) != 2 {
+			t.Fatalf("%s: expected 2 lines, got %d", test.format, len(lines))
+		}
+
+		expectedLayers := []string{"pois", "roads"}
+		for i, line := range lines {
+			if !strings.HasPrefix(line, test.prefix+"{") {
+				t.Errorf("%s: expected line %d to start with %q, got %q", test.format, i, test.prefix+"{", line)
+				continue
+			}
+			var feature map[string]interface{}
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, test.prefix)), &feature); err != nil {
+				t.Fatalf("%s: failed to parse line %d: %v", test.format, i, err)
+			}
+			properties := feature["properties"].(map[string]interface{})
+			if feature["type"] != "Feature" || properties["_tile"] != "2/1/3" || properties["_layer"] != expectedLayers[i] {
+				t.Errorf("%s: expected %s feature of tile 2/1/3, got %s", test.format, expectedLayers[i], line)
+			}
+		}
+
+		if formatter.ContentType() != test.contentType {
+			t.Errorf("%s: expected content type %s, got %s", test.format, test.contentType, formatter.ContentType())
+		}
+	}
+}
+
+func TestFeatureSeqFormatterFormatBatch(t *testing.T) {
+	failed := testTile(1, 1, 1, testFeature(orb.Point{7, 8}, map[string]interface{}{"name": "failed"}))
+	failed.Error = errTestTile
+
+	formatter := NewFeatureSeqFormatter(false)
+	data, err := formatter.FormatBatch([]*tile.ProcessedTile{
+		testTile(1, 0, 0, testFeature(orb.Point{1, 2}, map[string]interface{}{"name": "a"})),
+		failed,
+		testTile(1, 1, 0, testFeature(orb.Point{3, 4}, map[string]interface{}{"name": "b"})),
+	})
+	if err != nil {
+		t.Fatalf("Failed to format batch: %v", err)
+	}
+
+	var tiles []string
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		var feature map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &feature); err != nil {
+			t.Fatalf("Failed to parse line %q: %v", line, err)
+		}
+		tiles = append(tiles, feature["properties"].(map[string]interface{})["_tile"].(string))
+	}
+
+	expected := []string{"1/0/0", "1/1/0"}
+	if !reflect.DeepEqual(tiles, expected) {
+		t.Errorf("Expected features of tiles %v, got %v", expected, tiles)
+	}
+}
+
+func TestFormatterDefaultsDeterministic(t *testing.T) {
+	// Each run processes its tiles in a different time
+	run := func(config *FormatterConfig, processTime time.Duration) ([]byte, []byte) {
+		t.Helper()
+		formatter, err := NewFormatter(config)
+		if err != nil {
+			t.Fatalf("Failed to create formatter: %v", err)
+		}
+		tiles := []*tile.ProcessedTile{
+			testTile(1, 0, 0, testFeature(orb.Point{1, 2}, map[string]interface{}{"name": "a"})),
+			testTile(1, 1, 0, testFeature(orb.Point{3, 4}, map[string]interface{}{"name": "b"})),
+		}
+		for _, processed := range tiles {
+			processed.Metadata.ProcessTime = processTime
+		}
+		single, err := formatter.Format(tiles[0])
+		if err != nil {
+			t.Fatalf("Failed to format tile: %v", err)
+		}
+		batch, err := formatter.FormatBatch(tiles)
+		if err != nil {
+			t.Fatalf("Failed to format batch: %v", err)
+		}
+		return single, batch
+	}
+
+	for _, format := range []Format{FormatGeoJSON, FormatJSON, FormatGeoPackage} {
+		config := &FormatterConfig{Format: format, IncludeStats: true}
+		firstSingle, firstBatch := run(config, time.Millisecond)
+		time.Sleep(10 * time.Millisecond)
+		secondSingle, secondBatch := run(config, time.Second)
+		if string(firstSingle) != string(secondSingle) || string(firstBatch) != string(secondBatch) {
+			t.Errorf("%s: expected identical output from two default runs", format)
+		}
+
+		config.Timings = true
+		if _, batch := run(config, time.Second); format != FormatGeoPackage && !strings.Contains(string(batch), "generated_at") {
+			t.Errorf("%s: expected generated_at with timings", format)
+		}
+	}
+}
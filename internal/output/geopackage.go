@@ -0,0 +1,186 @@
+// internal/output/geopackage.go - GeoPackage output formatting and writing
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/paulmach/orb/geojson"
+	"github.com/valpere/tile_to_json/internal/tile"
+	"github.com/valpere/tile_to_json/pkg/geopackage"
+	"github.com/valpere/tile_to_json/pkg/mvt"
+)
+
+// geoPackageOptions returns the GeoPackage options for a writer configuration's index,
+// layer property, timings and coordinate system settings. Without timings the last
+// change time is the Unix epoch, so repeated exports are identical.
+func geoPackageOptions(index bool, layerProperty string, timings bool, coordinateSystem string) geopackage.Options {
+	options := geopackage.Options{
+		Index:         index,
+		LayerProperty: layerProperty,
+	}
+	if !timings {
+		options.LastChange = time.Unix(0, 0)
+	}
+	switch coordinateSystem {
+	case mvt.CoordSystemWGS84:
+		options.EPSG = 4326
+	case mvt.CoordSystemWebMercator:
+		options.EPSG = 3857
+	}
+	return options
+}
+
+// writeGeoPackageFeatures adds the features of a tile to a GeoPackage writer, tagging
+// each with its tile coordinate when tag is set
+func writeGeoPackageFeatures(writer *geopackage.Writer, t *tile.ProcessedTile, tag bool) error {
+	for _, feature := range collectionFeatures(t, tag) {
+		feat, ok := feature.(*geojson.Feature)
+		if !ok {
+			continue
+		}
+		if err := writer.Write(feat); err != nil {
+			return fmt.Errorf("failed to write feature of tile %s: %w", t.Coordinate.String(), err)
+		}
+	}
+	return nil
+}
+
+// GeoPackageFormatter formats tiles as complete GeoPackage databases
+type GeoPackageFormatter struct {
+	options      geopackage.Options
+	includeStats bool
+}
+
+// NewGeoPackageFormatter creates a GeoPackage formatter. Features are written to one
+// table per layer, named by their layer property.
+func NewGeoPackageFormatter(index bool, layerProperty string, includeStats, timings bool, coordinateSystem string) *GeoPackageFormatter {
+	return &GeoPackageFormatter{
+		options:      geoPackageOptions(index, layerProperty, timings, coordinateSystem),
+		includeStats: includeStats,
+	}
+}
+
+// Format formats a single tile as a GeoPackage
+func (f *GeoPackageFormatter) Format(t *tile.ProcessedTile) ([]byte, error) {
+	if t.Error != nil {
+		return nil, fmt.Errorf("cannot format tile with error: %w", t.Error)
+	}
+	return f.format([]*tile.ProcessedTile{t}, false)
+}
+
+// FormatBatch formats multiple tiles as one GeoPackage, skipping failed tiles.
+// Features are tagged with their tile when statistics are included.
+func (f *GeoPackageFormatter) FormatBatch(tiles []*tile.ProcessedTile) ([]byte, error) {
+	return f.format(tiles, f.includeStats)
+}
+
+func (f *GeoPackageFormatter) format(tiles []*tile.ProcessedTile, tag bool) ([]byte, error) {
+	writer, err := geopackage.NewWriter(f.options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create geopackage writer: %w", err)
+	}
+	defer writer.Close()
+
+	for _, t := range tiles {
+		if t.Error != nil {
+			continue
+		}
+		if err := writeGeoPackageFeatures(writer, t, tag); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := writer.WriteTo(&buf); err != nil {
+		return nil, fmt.Errorf("failed to write geopackage: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ContentType returns the MIME type for GeoPackage
+func (f *GeoPackageFormatter) ContentType() string {
+	return "application/geopackage+sqlite3"
+}
+
+// GeoPackageWriter writes the features of all tiles of a job into one GeoPackage, with
+// a feature table per layer. Column types are inferred over all tiles, so features are
+// spooled as they arrive and the database is written on Close.
+type GeoPackageWriter struct {
+	config      *WriterConfig
+	writer      *geopackage.Writer
+	destination string
+	closed      bool
+}
+
+// NewGeoPackageWriter creates a GeoPackage writer with a table per layer, whose column
+// types span all tiles. A destination of "" or "-" writes to stdout.
+func NewGeoPackageWriter(config *WriterConfig, destination string) (*GeoPackageWriter, error) {
+	if destination == "" {
+		destination = "-"
+	}
+
+	writer, err := geopackage.NewWriter(geoPackageOptions(config.GeoPackageIndex, config.LayerProperty, config.Timings, config.CoordinateSystem))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create geopackage writer: %w", err)
+	}
+
+	return &GeoPackageWriter{
+		config:      config,
+		writer:      writer,
+		destination: destination,
+	}, nil
+}
+
+// Write adds the features of a single processed tile
+func (w *GeoPackageWriter) Write(t *tile.ProcessedTile) error {
+	return w.WriteBatch([]*tile.ProcessedTile{t})
+}
+
+// WriteBatch adds the features of multiple processed tiles, skipping failed tiles
+func (w *GeoPackageWriter) WriteBatch(tiles []*tile.ProcessedTile) error {
+	if w.closed {
+		return fmt.Errorf("write to closed geopackage writer")
+	}
+
+	for _, t := range tiles {
+		if t.Error != nil {
+			continue
+		}
+		if err := writeGeoPackageFeatures(w.writer, t, w.config.Metadata); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close writes the database and removes the spooled features. Output compression is
+// not applied, so the file can be opened directly.
+func (w *GeoPackageWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	defer w.writer.Close()
+
+	var output io.WriteCloser = nopWriteCloser{os.Stdout}
+	if w.destination != "-" {
+		dest, err := newFileDestination(w.destination, false)
+		if err != nil {
+			return fmt.Errorf("failed to create file destination: %w", err)
+		}
+		output = dest
+	}
+
+	_, err := w.writer.WriteTo(output)
+	if closeErr := output.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write geopackage: %w", err)
+	}
+	return nil
+}
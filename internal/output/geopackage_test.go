@@ -0,0 +1,159 @@
+// internal/output/geopackage_test.go - Unit tests for GeoPackage output
+package output
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/valpere/tile_to_json/internal/tile"
+	"github.com/valpere/tile_to_json/pkg/mvt"
+)
+
+// geoPackageTiles returns two tiles with features in the layers named by property
+func geoPackageTiles(property string) []*tile.ProcessedTile {
+	return []*tile.ProcessedTile{
+		testTile(1, 0, 0, testFeature(orb.Point{1, 2}, map[string]interface{}{property: "pois", "name": "a"})),
+		testTile(1, 1, 0, testFeature(orb.LineString{{1, 2}, {3, 4}}, map[string]interface{}{property: "roads", "lanes": int64(2)})),
+	}
+}
+
+// queryGeoPackage opens a GeoPackage file and returns the rows of a single column query
+func queryGeoPackage(t *testing.T, path, query string) []string {
+	t.Helper()
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("Failed to open geopackage: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(query)
+	if err != nil {
+		t.Fatalf("Failed to query %q: %v", query, err)
+	}
+	defer rows.Close()
+
+	var result []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			t.Fatalf("Failed to scan %q: %v", query, err)
+		}
+		result = append(result, value)
+	}
+	return result
+}
+
+func TestGeoPackageWriter(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   *WriterConfig
+		property string
+		tables   []string
+		index    bool
+		timings  bool
+		tagged   bool
+	}{
+		{
+			name:     "default layer property",
+			config:   &WriterConfig{Format: FormatGeoPackage, GeoPackageIndex: true, LayerProperty: mvt.DefaultLayerProperty, Timings: true, CoordinateSystem: mvt.CoordSystemWebMercator},
+			property: mvt.DefaultLayerProperty,
+			tables:   []string{"pois 3857", "roads 3857"},
+			index:    true,
+			timings:  true,
+		},
+		{
+			name:     "custom layer property",
+			config:   &WriterConfig{Format: FormatGeoPackage, LayerProperty: "lyr", CoordinateSystem: mvt.CoordSystemWGS84},
+			property: "lyr",
+			tables:   []string{"pois 4326", "roads 4326"},
+		},
+		{
+			name:     "metadata tags tiles",
+			config:   &WriterConfig{Format: FormatGeoPackage, LayerProperty: mvt.DefaultLayerProperty, Metadata: true},
+			property: mvt.DefaultLayerProperty,
+			tables:   []string{"pois -1", "roads -1"},
+			tagged:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "out.gpkg")
+			writer, err := NewSingleFileWriter(tt.config, path)
+			if err != nil {
+				t.Fatalf("Failed to create writer: %v", err)
+			}
+			if _, ok := writer.(*GeoPackageWriter); !ok {
+				t.Fatalf("Expected a GeoPackageWriter, got %T", writer)
+			}
+			if err := writer.WriteBatch(geoPackageTiles(tt.property)); err != nil {
+				t.Fatalf("Failed to write batch: %v", err)
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatalf("Failed to close writer: %v", err)
+			}
+			if err := writer.Write(geoPackageTiles(tt.property)[0]); err == nil {
+				t.Error("Expected error writing to a closed writer")
+			}
+
+			tables := queryGeoPackage(t, path, "SELECT table_name || ' ' || srs_id FROM gpkg_geometry_columns ORDER BY table_name")
+			if !reflect.DeepEqual(tables, tt.tables) {
+				t.Errorf("Expected tables %v, got %v", tt.tables, tables)
+			}
+			if index := queryGeoPackage(t, path, "SELECT name FROM sqlite_master WHERE name LIKE 'rtree%'"); (len(index) > 0) != tt.index {
+				t.Errorf("Expected spatial index %v, got %v", tt.index, index)
+			}
+			changes := queryGeoPackage(t, path, "SELECT last_change FROM gpkg_contents")
+			if got := !strings.HasPrefix(changes[0], "1970-01-01"); got != tt.timings {
+				t.Errorf("Expected current last change %v, got %v", tt.timings, changes)
+			}
+			columns := queryGeoPackage(t, path, "SELECT name FROM pragma_table_info('pois')")
+			for _, column := range columns {
+				if column == tt.property {
+					t.Errorf("Expected layer property %q not to be stored", tt.property)
+				}
+			}
+			if got := len(queryGeoPackage(t, path, "SELECT name FROM pragma_table_info('pois') WHERE name = '_tile'")) > 0; got != tt.tagged {
+				t.Errorf("Expected _tile column %v, got columns %v", tt.tagged, columns)
+			}
+		})
+	}
+}
+
+func TestGeoPackageFormatter(t *testing.T) {
+	formatter, err := NewFormatter(&FormatterConfig{Format: FormatGeoPackage, LayerProperty: "lyr", CoordinateSystem: mvt.CoordSystemWGS84})
+	if err != nil {
+		t.Fatalf("Failed to create formatter: %v", err)
+	}
+
+	failed := testTile(1, 1, 1, testFeature(orb.Point{5, 6}, map[string]interface{}{"lyr": "failed"}))
+	failed.Error = errTestTile
+	if _, err := formatter.Format(failed); !errors.Is(err, errTestTile) {
+		t.Errorf("Expected tile error, got %v", err)
+	}
+
+	data, err := formatter.FormatBatch(append(geoPackageTiles("lyr"), failed))
+	if err != nil {
+		t.Fatalf("Failed to format batch: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "out.gpkg")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to save geopackage: %v", err)
+	}
+
+	expected := []string{"pois 4326", "roads 4326"}
+	tables := queryGeoPackage(t, path, "SELECT table_name || ' ' || srs_id FROM gpkg_geometry_columns ORDER BY table_name")
+	if !reflect.DeepEqual(tables, expected) {
+		t.Errorf("Expected tables %v without the failed tile, got %v", expected, tables)
+	}
+	lanes := queryGeoPackage(t, path, "SELECT typeof(lanes) || ' ' || lanes FROM roads")
+	if !reflect.DeepEqual(lanes, []string{"integer 2"}) {
+		t.Errorf("Expected an integer lanes column, got %v", lanes)
+	}
+}
@@ -0,0 +1,312 @@
+// internal/output/geoparquet.go - GeoParquet output formatting and writing
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/paulmach/orb/geojson"
+	"github.com/valpere/tile_to_json/internal/tile"
+	"github.com/valpere/tile_to_json/pkg/geoparquet"
+	"github.com/valpere/tile_to_json/pkg/mvt"
+)
+
+// GeoParquet partitioning modes
+const (
+	ParquetPartitionNone  = "none"  // One file
+	ParquetPartitionLayer = "layer" // One file per layer, in layer=<name> directories
+	ParquetPartitionZoom  = "zoom"  // One file per zoom level, in zoom=<z> directories
+)
+
+// GeoParquetOptions configures GeoParquet output
+type GeoParquetOptions struct {
+	RowGroupSize int    // Rows per row group; 0 uses the default
+	Compression  string // Page compression codec: none, snappy, gzip or zstd
+	Partition    string // ParquetPartitionNone, ParquetPartitionLayer or ParquetPartitionZoom
+}
+
+// DefaultGeoParquetOptions returns GeoParquet options for a single snappy-compressed file
+func DefaultGeoParquetOptions() *GeoParquetOptions {
+	return &GeoParquetOptions{
+		RowGroupSize: geoparquet.DefaultRowGroupSize,
+		Compression:  string(geoparquet.DefaultCompression),
+		Partition:    ParquetPartitionNone,
+	}
+}
+
+// NewGeoParquetOptions builds GeoParquet options from configuration values, using the
+// defaults for empty ones
+func NewGeoParquetOptions(rowGroupSize int, compression, partition string) *GeoParquetOptions {
+	options := DefaultGeoParquetOptions()
+	if rowGroupSize != 0 {
+		options.RowGroupSize = rowGroupSize
+	}
+	if compression != "" {
+		options.Compression = compression
+	}
+	if partition != "" {
+		options.Partition = partition
+	}
+	return options
+}
+
+// geoParquetOptions returns the GeoParquet file options for output options and a
+// coordinate system
+func geoParquetOptions(options *GeoParquetOptions, coordinateSystem string) geoparquet.Options {
+	if options == nil {
+		options = DefaultGeoParquetOptions()
+	}
+	result := geoparquet.Options{
+		RowGroupSize: options.RowGroupSize,
+		Compression:  geoparquet.Codec(options.Compression),
+	}
+	switch coordinateSystem {
+	case mvt.CoordSystemWGS84:
+		result.EPSG = 4326
+	case mvt.CoordSystemWebMercator:
+		result.EPSG = 3857
+	}
+	return result
+}
+
+// geoParquetPartition returns the partitioning mode of output options
+func geoParquetPartition(options *GeoParquetOptions) (string, error) {
+	if options == nil || options.Partition == "" {
+		return ParquetPartitionNone, nil
+	}
+	switch options.Partition {
+	case ParquetPartitionNone, ParquetPartitionLayer, ParquetPartitionZoom:
+		return options.Partition, nil
+	}
+	return "", fmt.Errorf("invalid parquet partition: %s", options.Partition)
+}
+
+// GeoParquetFormatter formats tiles as complete GeoParquet files
+type GeoParquetFormatter struct {
+	options          *GeoParquetOptions
+	includeStats     bool
+	coordinateSystem string
+}
+
+// NewGeoParquetFormatter creates a GeoParquet formatter. Partitioning needs one file
+// per partition and is only supported by the GeoParquet writer.
+func NewGeoParquetFormatter(options *GeoParquetOptions, includeStats bool, coordinateSystem string) (*GeoParquetFormatter, error) {
+	partition, err := geoParquetPartition(options)
+	if err != nil {
+		return nil, err
+	}
+	if partition != ParquetPartitionNone {
+		return nil, fmt.Errorf("parquet partitioning requires single-file output")
+	}
+
+	return &GeoParquetFormatter{
+		options:          options,
+		includeStats:     includeStats,
+		coordinateSystem: coordinateSystem,
+	}, nil
+}
+
+// Format formats a single tile as a GeoParquet file
+func (f *GeoParquetFormatter) Format(t *tile.ProcessedTile) ([]byte, error) {
+	if t.Error != nil {
+		return nil, fmt.Errorf("cannot format tile with error: %w", t.Error)
+	}
+	return f.format([]*tile.ProcessedTile{t}, false)
+}
+
+// FormatBatch formats multiple tiles as one GeoParquet file, skipping failed tiles.
+// Features are tagged with their tile when statistics are included.
+func (f *GeoParquetFormatter) FormatBatch(tiles []*tile.ProcessedTile) ([]byte, error) {
+	return f.format(tiles, f.includeStats)
+}
+
+func (f *GeoParquetFormatter) format(tiles []*tile.ProcessedTile, tag bool) ([]byte, error) {
+	writer, err := geoparquet.NewWriter(geoParquetOptions(f.options, f.coordinateSystem))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create geoparquet writer: %w", err)
+	}
+	defer writer.Close()
+
+	for _, t := range tiles {
+		if t.Error != nil {
+			continue
+		}
+		for _, feature := range collectionFeatures(t, tag) {
+			feat, ok := feature.(*geojson.Feature)
+			if !ok {
+				continue
+			}
+			if err := writer.Write(feat); err != nil {
+				return nil, fmt.Errorf("failed to write feature of tile %s: %w", t.Coordinate.String(), err)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := writer.WriteTo(&buf); err != nil {
+		return nil, fmt.Errorf("failed to write geoparquet: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ContentType returns the MIME type for Parquet
+func (f *GeoParquetFormatter) ContentType() string {
+	return "application/vnd.apache.parquet"
+}
+
+// GeoParquetWriter writes the features of all tiles of a job into one GeoParquet file,
+// or into a Hive-style partitioned dataset with one file per layer or zoom level. The
+// schema is inferred from every feature of a file, so features are spooled as they
+// arrive and the files are written on Close.
+type GeoParquetWriter struct {
+	config      *WriterConfig
+	options     geoparquet.Options
+	partition   string
+	destination string
+	writers     map[string]*geoparquet.Writer
+	closed      bool
+}
+
+// NewGeoParquetWriter creates a GeoParquet writer whose schema spans all tiles. A
+// destination of "" or "-" writes to stdout, except when partitioning, where the
+// destination is the dataset directory.
+func NewGeoParquetWriter(config *WriterConfig, destination string) (*GeoParquetWriter, error) {
+	partition, err := geoParquetPartition(config.GeoParquet)
+	if err != nil {
+		return nil, err
+	}
+	if destination == "" {
+		destination = "-"
+	}
+	if destination == "-" && partition != ParquetPartitionNone {
+		return nil, fmt.Errorf("parquet partitioning requires an output directory")
+	}
+
+	w := &GeoParquetWriter{
+		config:      config,
+		options:     geoParquetOptions(config.GeoParquet, config.CoordinateSystem),
+		partition:   partition,
+		destination: destination,
+		writers:     make(map[string]*geoparquet.Writer),
+	}
+
+	// A single file is created up front, so its options are checked early and a job
+	// without features still gets a valid, empty file
+	if partition == ParquetPartitionNone {
+		if _, err := w.writer(""); err != nil {
+			return nil, err
+		}
+	}
+
+	return w, nil
+}
+
+// Write adds the features of a single processed tile
+func (w *GeoParquetWriter) Write(t *tile.ProcessedTile) error {
+	return w.WriteBatch([]*tile.ProcessedTile{t})
+}
+
+// WriteBatch adds the features of multiple processed tiles, skipping failed tiles
+func (w *GeoParquetWriter) WriteBatch(tiles []*tile.ProcessedTile) error {
+	if w.closed {
+		return fmt.Errorf("write to closed geoparquet writer")
+	}
+
+	for _, t := range tiles {
+		if t.Error != nil {
+			continue
+		}
+		for _, feature := range collectionFeatures(t, w.config.Metadata) {
+			feat, ok := feature.(*geojson.Feature)
+			if !ok {
+				continue
+			}
+
+			key := ""
+			switch w.partition {
+			case ParquetPartitionLayer:
+				key = ParquetPartitionLayer + "=" + url.PathEscape(featureLayer(feat, w.config.LayerProperty))
+			case ParquetPartitionZoom:
+				key = ParquetPartitionZoom + "=" + strconv.Itoa(t.Coordinate.Z)
+			}
+			writer, err := w.writer(key)
+			if err != nil {
+				return err
+			}
+			if err := writer.Write(feat); err != nil {
+				return fmt.Errorf("failed to write feature of tile %s: %w", t.Coordinate.String(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// writer returns the file writer of a partition, creating it on first use
+func (w *GeoParquetWriter) writer(partition string) (*geoparquet.Writer, error) {
+	if writer, ok := w.writers[partition]; ok {
+		return writer, nil
+	}
+	writer, err := geoparquet.NewWriter(w.options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create geoparquet writer: %w", err)
+	}
+	w.writers[partition] = writer
+	return writer, nil
+}
+
+// Close writes the files and removes the spooled features. Output compression is not
+// applied: Parquet pages are compressed with the configured codec instead.
+func (w *GeoParquetWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	partitions := make([]string, 0, len(w.writers))
+	for partition := range w.writers {
+		partitions = append(partitions, partition)
+	}
+	sort.Strings(partitions)
+
+	var firstErr error
+	for _, partition := range partitions {
+		if err := w.writeFile(partition); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// writeFile writes the file of a partition
+func (w *GeoParquetWriter) writeFile(partition string) error {
+	writer := w.writers[partition]
+	defer writer.Close()
+
+	var output io.WriteCloser = nopWriteCloser{os.Stdout}
+	path := w.destination
+	if partition != "" {
+		path = filepath.Join(w.destination, partition, "data.parquet")
+	}
+	if path != "-" {
+		dest, err := newFileDestination(path, false)
+		if err != nil {
+			return fmt.Errorf("failed to create file destination: %w", err)
+		}
+		output = dest
+	}
+
+	_, err := writer.WriteTo(output)
+	if closeErr := output.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write geoparquet %s: %w", path, err)
+	}
+	return nil
+}
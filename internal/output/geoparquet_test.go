@@ -0,0 +1,53 @@
+// internal/output/geoparquet_test.go - Unit tests for GeoParquet output
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/valpere/tile_to_json/internal/tile"
+)
+
+func TestGeoParquetWriterLayerPartition(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "dataset")
+	config := &WriterConfig{
+		Format:        FormatGeoParquet,
+		GeoParquet:    NewGeoParquetOptions(0, "", ParquetPartitionLayer),
+		LayerProperty: "lyr",
+	}
+
+	writer, err := NewGeoParquetWriter(config, dir)
+	if err != nil {
+		t.Fatalf("Failed to create geoparquet writer: %v", err)
+	}
+	err = writer.WriteBatch([]*tile.ProcessedTile{testTile(1, 0, 0,
+		testFeature(orb.Point{1, 2}, map[string]interface{}{"lyr": "roads"}),
+		testFeature(orb.Point{3, 4}, map[string]interface{}{"lyr": "pois", "_layer": "roads"}),
+	)})
+	if err != nil {
+		t.Fatalf("Failed to write batch: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close geoparquet writer: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read dataset directory: %v", err)
+	}
+	var partitions []string
+	for _, entry := range entries {
+		partitions = append(partitions, entry.Name())
+		if _, err := os.Stat(filepath.Join(dir, entry.Name(), "data.parquet")); err != nil {
+			t.Errorf("Expected %s/data.parquet: %v", entry.Name(), err)
+		}
+	}
+
+	expected := []string{"layer=pois", "layer=roads"}
+	if !reflect.DeepEqual(partitions, expected) {
+		t.Errorf("Expected partitions %v, got %v", expected, partitions)
+	}
+}
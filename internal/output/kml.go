@@ -0,0 +1,308 @@
+// internal/output/kml.go - KML and KMZ output formatting and writing
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/paulmach/orb/geojson"
+	"github.com/valpere/tile_to_json/internal/tile"
+	"github.com/valpere/tile_to_json/pkg/kml"
+)
+
+// kmlDocumentName is the name of documents not named after a tile or file
+const kmlDocumentName = "tile-to-json"
+
+// writeKMLFeatures adds the features of a tile to a KML writer, tagging each with its
+// tile coordinate when tag is set, and returns the number of features written
+func writeKMLFeatures(writer *kml.Writer, t *tile.ProcessedTile, tag bool) (int, error) {
+	count := 0
+	for _, feature := range collectionFeatures(t, tag) {
+		feat, ok := feature.(*geojson.Feature)
+		if !ok {
+			continue
+		}
+		if err := writer.Write(feat); err != nil {
+			return count, fmt.Errorf("failed to write feature of tile %s: %w", t.Coordinate.String(), err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// kmlTileDescription describes a tile's metadata for a KML document description
+func kmlTileDescription(t *tile.ProcessedTile) string {
+	lines := []string{fmt.Sprintf("Tile %s", t.Coordinate.String())}
+	if t.Metadata != nil {
+		lines = append(lines,
+			fmt.Sprintf("Layers: %s", strings.Join(t.Metadata.Layers, ", ")),
+			fmt.Sprintf("Features: %d", t.Metadata.FeatureCount),
+			fmt.Sprintf("Format: %s, version %d, extent %d", t.Metadata.Format, t.Metadata.Version, t.Metadata.Extent),
+		)
+		if len(t.Metadata.Warnings) > 0 {
+			lines = append(lines, fmt.Sprintf("Warnings: %d", len(t.Metadata.Warnings)))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// kmlBatchDescription accumulates the metadata of the tiles in a KML document for its
+// description
+type kmlBatchDescription struct {
+	summary *batchSummary
+	layers  []string
+	seen    map[string]bool
+	minZoom int
+	maxZoom int
+	hasZoom bool
+}
+
+func newKMLBatchDescription() *kmlBatchDescription {
+	return &kmlBatchDescription{
+		summary: newBatchSummary(),
+		seen:    make(map[string]bool),
+	}
+}
+
+// add records a tile's status, zoom level and layers, and the number of its features
+// that were written
+func (d *kmlBatchDescription) add(t *tile.ProcessedTile, features int) {
+	d.summary.add(t)
+	d.summary.totalFeatures += features
+	if t.Error != nil {
+		return
+	}
+
+	if !d.hasZoom || t.Coordinate.Z < d.minZoom {
+		d.minZoom = t.Coordinate.Z
+	}
+	if !d.hasZoom || t.Coordinate.Z > d.maxZoom {
+		d.maxZoom = t.Coordinate.Z
+	}
+	d.hasZoom = true
+
+	if t.Metadata != nil {
+		for _, layer := range t.Metadata.Layers {
+			if !d.seen[layer] {
+				d.seen[layer] = true
+				d.layers = append(d.layers, layer)
+			}
+		}
+	}
+}
+
+// String returns the description text
+func (d *kmlBatchDescription) String() string {
+	s := d.summary
+	lines := []string{fmt.Sprintf("Tiles: %d (%d processed, %d failed)", s.totalTiles, s.processedTiles, s.failedTiles)}
+	if d.hasZoom {
+		zoom := fmt.Sprintf("%d", d.minZoom)
+		if d.maxZoom != d.minZoom {
+			zoom = fmt.Sprintf("%d-%d", d.minZoom, d.maxZoom)
+		}
+		lines = append(lines, fmt.Sprintf("Zoom: %s", zoom))
+	}
+	lines = append(lines,
+		fmt.Sprintf("Layers: %s", strings.Join(d.layers, ", ")),
+		fmt.Sprintf("Features: %d", s.totalFeatures),
+	)
+	if s.warningCount > 0 {
+		lines = append(lines, fmt.Sprintf("Warnings: %d", s.warningCount))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// KMLFormatter formats tiles as KML documents, or zipped as KMZ
+type KMLFormatter struct {
+	options      kml.Options
+	includeStats bool
+}
+
+// NewKMLFormatter creates a KML formatter. Features are written to one Folder per
+// layer, named by their layer property, with each layer's placemarks styled by its
+// entry in styles. With kmz set documents are zipped.
+func NewKMLFormatter(kmz bool, styles map[string]kml.Style, layerProperty string, includeStats bool) (*KMLFormatter, error) {
+	for layer, style := range styles {
+		if err := style.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid kml style for layer %s: %w", layer, err)
+		}
+	}
+	return &KMLFormatter{
+		options: kml.Options{
+			LayerProperty: layerProperty,
+			Styles:        styles,
+			KMZ:           kmz,
+		},
+		includeStats: includeStats,
+	}, nil
+}
+
+// Format formats a single tile as a document named after the tile. The tile's metadata
+// is described when statistics are included.
+func (f *KMLFormatter) Format(t *tile.ProcessedTile) ([]byte, error) {
+	if t.Error != nil {
+		return nil, fmt.Errorf("cannot format tile with error: %w", t.Error)
+	}
+
+	description := ""
+	if f.includeStats {
+		description = kmlTileDescription(t)
+	}
+	return f.format([]*tile.ProcessedTile{t}, t.Coordinate.String(), false, description, nil)
+}
+
+// FormatBatch formats multiple tiles as one document, skipping failed tiles. When
+// statistics are included, features are tagged with their tile and the tiles are
+// summarized in the description.
+func (f *KMLFormatter) FormatBatch(tiles []*tile.ProcessedTile) ([]byte, error) {
+	var batch *kmlBatchDescription
+	if f.includeStats {
+		batch = newKMLBatchDescription()
+	}
+	return f.format(tiles, kmlDocumentName, f.includeStats, "", batch)
+}
+
+// format writes tiles into one document with the given description, or with the tiles
+// summarized when batch is set
+func (f *KMLFormatter) format(tiles []*tile.ProcessedTile, name string, tag bool, description string, batch *kmlBatchDescription) ([]byte, error) {
+	options := f.options
+	options.Name = name
+	writer, err := kml.NewWriter(options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kml writer: %w", err)
+	}
+	defer writer.Close()
+
+	for _, t := range tiles {
+		count := 0
+		if t.Error == nil {
+			if count, err = writeKMLFeatures(writer, t, tag); err != nil {
+				return nil, err
+			}
+		}
+		if batch != nil {
+			batch.add(t, count)
+		}
+	}
+	if batch != nil {
+		description = batch.String()
+	}
+	writer.SetDescription(description)
+
+	var buf bytes.Buffer
+	if _, err := writer.WriteTo(&buf); err != nil {
+		return nil, fmt.Errorf("failed to write kml: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ContentType returns the MIME type for KML or KMZ
+func (f *KMLFormatter) ContentType() string {
+	if f.options.KMZ {
+		return "application/vnd.google-earth.kmz"
+	}
+	return "application/vnd.google-earth.kml+xml"
+}
+
+// KMLWriter writes the features of all tiles of a job into one KML or KMZ document,
+// with a Folder per layer. Placemarks are spooled as they arrive and the document is
+// written on Close, with the job's tiles summarized in its description.
+type KMLWriter struct {
+	config      *WriterConfig
+	writer      *kml.Writer
+	description *kmlBatchDescription
+	destination string
+	closed      bool
+}
+
+// NewKMLWriter creates a KML writer of one document with a folder per layer; the
+// format selects KML or KMZ. A destination of "" or "-" writes to stdout.
+func NewKMLWriter(config *WriterConfig, destination string) (*KMLWriter, error) {
+	if destination == "" {
+		destination = "-"
+	}
+
+	name := kmlDocumentName
+	if destination != "-" {
+		base := filepath.Base(destination)
+		name = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+
+	writer, err := kml.NewWriter(kml.Options{
+		Name:          name,
+		LayerProperty: config.LayerProperty,
+		Styles:        config.KMLStyles,
+		KMZ:           config.Format == FormatKMZ,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kml writer: %w", err)
+	}
+
+	return &KMLWriter{
+		config:      config,
+		writer:      writer,
+		description: newKMLBatchDescription(),
+		destination: destination,
+	}, nil
+}
+
+// Write adds the features of a single processed tile
+func (w *KMLWriter) Write(t *tile.ProcessedTile) error {
+	return w.WriteBatch([]*tile.ProcessedTile{t})
+}
+
+// WriteBatch adds the features of multiple processed tiles, skipping failed tiles
+func (w *KMLWriter) WriteBatch(tiles []*tile.ProcessedTile) error {
+	if w.closed {
+		return fmt.Errorf("write to closed kml writer")
+	}
+
+	for _, t := range tiles {
+		if t.Error != nil {
+			w.description.add(t, 0)
+			continue
+		}
+		count, err := writeKMLFeatures(w.writer, t, w.config.Metadata)
+		w.description.add(t, count)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close writes the document and removes the spooled placemarks. KML is compressed
+// when configured; KMZ is already zipped.
+func (w *KMLWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	defer w.writer.Close()
+
+	if w.config.Metadata {
+		w.writer.SetDescription(w.description.String())
+	}
+
+	var output io.WriteCloser = nopWriteCloser{os.Stdout}
+	if w.destination != "-" {
+		dest, err := newFileDestination(w.destination, w.config.Compression && w.config.Format.IsCompressible())
+		if err != nil {
+			return fmt.Errorf("failed to create file destination: %w", err)
+		}
+		output = dest
+	}
+
+	_, err := w.writer.WriteTo(output)
+	if closeErr := output.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write kml: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+// internal/output/kml_test.go - Unit tests for KML and KMZ output
+package output
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/valpere/tile_to_json/internal/tile"
+	"github.com/valpere/tile_to_json/pkg/kml"
+	"github.com/valpere/tile_to_json/pkg/mvt"
+)
+
+// testKMLDocument is the part of a KML document checked by tests
+type testKMLDocument struct {
+	Name        string `xml:"Document>name"`
+	Description string `xml:"Document>description"`
+	Styles      []struct {
+		ID    string `xml:"id,attr"`
+		Color string `xml:"LineStyle>color"`
+	} `xml:"Document>Style"`
+	Folders []struct {
+		Name       string `xml:"name"`
+		Placemarks []struct {
+			StyleURL string `xml:"styleUrl"`
+			Data     []struct {
+				Name string `xml:"name,attr"`
+			} `xml:"ExtendedData>Data"`
+		} `xml:"Placemark"`
+	} `xml:"Document>Folder"`
+}
+
+// readKML parses a KML document, unzipping it first when kmz is set
+func readKML(t *testing.T, data []byte, kmz bool) *testKMLDocument {
+	t.Helper()
+	if kmz {
+		archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("Failed to open kmz: %v", err)
+		}
+		entry, err := archive.Open(kml.KMZEntry)
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", kml.KMZEntry, err)
+		}
+		defer entry.Close()
+		if data, err = io.ReadAll(entry); err != nil {
+			t.Fatalf("Failed to read %s: %v", kml.KMZEntry, err)
+		}
+	}
+
+	var document testKMLDocument
+	if err := xml.Unmarshal(data, &document); err != nil {
+		t.Fatalf("Failed to parse kml: %v", err)
+	}
+	return &document
+}
+
+// folderNames returns the folder names of a KML document
+func (d *testKMLDocument) folderNames() []string {
+	names := make([]string, len(d.Folders))
+	for i, folder := range d.Folders {
+		names[i] = folder.Name
+	}
+	return names
+}
+
+// kmlTiles returns two tiles with features in the layers named by property
+func kmlTiles(property string) []*tile.ProcessedTile {
+	return []*tile.ProcessedTile{
+		testTile(1, 0, 0, testFeature(orb.Point{1, 2}, map[string]interface{}{property: "pois", "name": "a"})),
+		testTile(1, 1, 0, testFeature(orb.LineString{{1, 2}, {3, 4}}, map[string]interface{}{property: "roads", "name": "b"})),
+	}
+}
+
+func TestKMLFormatter(t *testing.T) {
+	styles := map[string]kml.Style{"Roads": {LineColor: "#ff0000", LineWidth: 2}}
+
+	tests := []struct {
+		name     string
+		config   *FormatterConfig
+		property string
+		folders  string
+		styled   string
+		tagged   bool
+	}{
+		{"kml", &FormatterConfig{Format: FormatKML, LayerProperty: mvt.DefaultLayerProperty}, mvt.DefaultLayerProperty, "pois,roads", "", false},
+		{"kmz", &FormatterConfig{Format: FormatKMZ, LayerProperty: "lyr"}, "lyr", "pois,roads", "", false},
+		{"styles", &FormatterConfig{Format: FormatKML, LayerProperty: mvt.DefaultLayerProperty, KMLStyles: styles}, mvt.DefaultLayerProperty, "pois,roads", "roads", false},
+		{"no layer property", &FormatterConfig{Format: FormatKML, IncludeStats: true}, mvt.DefaultLayerProperty, kml.DefaultFolder, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatter, err := NewFormatter(tt.config)
+			if err != nil {
+				t.Fatalf("Failed to create formatter: %v", err)
+			}
+			data, err := formatter.FormatBatch(kmlTiles(tt.property))
+			if err != nil {
+				t.Fatalf("Failed to format batch: %v", err)
+			}
+
+			document := readKML(t, data, tt.config.Format == FormatKMZ)
+			if document.Name != kmlDocumentName {
+				t.Errorf("Expected document name %q, got %q", kmlDocumentName, document.Name)
+			}
+			if folders := strings.Join(document.folderNames(), ","); folders != tt.folders {
+				t.Errorf("Expected folders %q, got %q", tt.folders, folders)
+			}
+
+			for _, folder := range document.Folders {
+				for _, placemark := range folder.Placemarks {
+					if styled := placemark.StyleURL != ""; styled != (folder.Name == tt.styled) {
+						t.Errorf("Unexpected style %q for folder %s", placemark.StyleURL, folder.Name)
+					}
+					tagged := false
+					for _, data := range placemark.Data {
+						tagged = tagged || data.Name == "_tile"
+						if tt.config.LayerProperty != "" && data.Name == tt.property {
+							t.Errorf("Expected layer property %q not to be stored", tt.property)
+						}
+					}
+					if tagged != tt.tagged {
+						t.Errorf("Expected _tile data %v, got %v", tt.tagged, tagged)
+					}
+				}
+			}
+			if tt.styled != "" && (len(document.Styles) != 1 || document.Styles[0].Color != "ff0000ff") {
+				t.Errorf("Expected one red line style, got %+v", document.Styles)
+			}
+			if got := strings.Contains(document.Description, "Tiles: 2"); got != tt.config.IncludeStats {
+				t.Errorf("Expected batch description %v, got %q", tt.config.IncludeStats, document.Description)
+			}
+		})
+	}
+}
+
+func TestKMLFormatterFormat(t *testing.T) {
+	formatter, err := NewFormatter(&FormatterConfig{Format: FormatKML, LayerProperty: mvt.DefaultLayerProperty, IncludeStats: true})
+	if err != nil {
+		t.Fatalf("Failed to create formatter: %v", err)
+	}
+
+	data, err := formatter.Format(kmlTiles(mvt.DefaultLayerProperty)[1])
+	if err != nil {
+		t.Fatalf("Failed to format tile: %v", err)
+	}
+	document := readKML(t, data, false)
+	if document.Name != "1/1/0" {
+		t.Errorf("Expected document named after the tile, got %q", document.Name)
+	}
+	if !strings.HasPrefix(document.Description, "Tile 1/1/0\nLayers: pois") {
+		t.Errorf("Expected tile description, got %q", document.Description)
+	}
+
+	failed := testTile(1, 1, 1, testFeature(orb.Point{5, 6}, nil))
+	failed.Error = errTestTile
+	if _, err := formatter.Format(failed); !errors.Is(err, errTestTile) {
+		t.Errorf("Expected tile error, got %v", err)
+	}
+}
+
+func TestKMLFormatterErrors(t *testing.T) {
+	invalid := []map[string]kml.Style{
+		{"roads": {LineColor: "red"}},
+		{"roads": {LineWidth: -1}},
+		{"*": {IconScale: -1}},
+	}
+	for _, styles := range invalid {
+		if _, err := NewFormatter(&FormatterConfig{Format: FormatKML, KMLStyles: styles}); err == nil {
+			t.Errorf("Expected error for styles %+v", styles)
+		}
+		if _, err := NewSingleFileWriter(&WriterConfig{Format: FormatKML, KMLStyles: styles}, "-"); err == nil {
+			t.Errorf("Expected writer error for styles %+v", styles)
+		}
+	}
+}
+
+func TestKMLWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *WriterConfig
+		file   string
+	}{
+		{"kml", &WriterConfig{Format: FormatKML, LayerProperty: mvt.DefaultLayerProperty, Metadata: true}, "roads.kml"},
+		{"kmz", &WriterConfig{Format: FormatKMZ, LayerProperty: mvt.DefaultLayerProperty, Metadata: true}, "roads.kmz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			writer, err := NewSingleFileWriter(tt.config, path)
+			if err != nil {
+				t.Fatalf("Failed to create writer: %v", err)
+			}
+			if _, ok := writer.(*KMLWriter); !ok {
+				t.Fatalf("Expected a KMLWriter, got %T", writer)
+			}
+
+			failed := testTile(2, 0, 0, testFeature(orb.Point{5, 6}, nil))
+			failed.Error = errTestTile
+			if err := writer.WriteBatch(append(kmlTiles(mvt.DefaultLayerProperty), failed)); err != nil {
+				t.Fatalf("Failed to write batch: %v", err)
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatalf("Failed to close writer: %v", err)
+			}
+			if err := writer.Write(kmlTiles(mvt.DefaultLayerProperty)[0]); err == nil {
+				t.Error("Expected error writing to a closed writer")
+			}
+
+			document := readKML(t, []byte(readTestFile(t, path)), tt.config.Format == FormatKMZ)
+			if document.Name != "roads" {
+				t.Errorf("Expected document named after the file, got %q", document.Name)
+			}
+			if folders := strings.Join(document.folderNames(), ","); folders != "pois,roads" {
+				t.Errorf("Expected folders pois,roads, got %q", folders)
+			}
+			if !strings.Contains(document.Description, "Tiles: 3 (2 processed, 1 failed)") {
+				t.Errorf("Expected job summary with the failed tile, got %q", document.Description)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+// internal/output/output_test.go - Shared helpers for output tests
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/valpere/tile_to_json/internal/tile"
+)
+
+// testFeature creates a feature with the given properties
+func testFeature(geometry orb.Geometry, properties map[string]interface{}) *geojson.Feature {
+	feature := geojson.NewFeature(geometry)
+	for key, value := range properties {
+		feature.Properties[key] = value
+	}
+	return feature
+}
+
+// testTile creates a processed tile holding features the way the converter does
+func testTile(z, x, y int, features ...*geojson.Feature) *tile.ProcessedTile {
+	return &tile.ProcessedTile{
+		Coordinate: &tile.TileCoordinate{Z: z, X: x, Y: y},
+		Data: map[string]interface{}{
+			"type":     "FeatureCollection",
+			"features": features,
+		},
+		Metadata: &tile.TileMetadata{
+			Layers:       []string{"pois"},
+			FeatureCount: len(features),
+			Version:      2,
+			Extent:       4096,
+		},
+	}
+}
+
+// readTestFile reads a file written by a test
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", filepath.Base(path), err)
+	}
+	return string(data)
+}
@@ -0,0 +1,189 @@
+// internal/output/stream.go - Streaming single-file GeoJSON output
+package output
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+
+	"github.com/paulmach/orb/geojson"
+	"github.com/valpere/tile_to_json/internal/tile"
+)
+
+// GeoJSONStreamWriter writes all tiles of a job into one GeoJSON FeatureCollection.
+// The collection is opened on the first write, features are written as each batch
+// arrives and the collection is closed, with its bbox and metadata, on Close. Only
+// the collection summary is kept in memory.
+type GeoJSONStreamWriter struct {
+	destination Destination
+	buffer      *bufio.Writer
+	config      *WriterConfig
+	summary     *batchSummary
+	opened      bool
+	closed      bool
+}
+
+// NewGeoJSONStreamWriter creates a streaming FeatureCollection writer for a file
+func NewGeoJSONStreamWriter(config *WriterConfig, destination string) (*GeoJSONStreamWriter, error) {
+	dest, err := newFileDestination(destination, config.Compression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file destination: %w", err)
+	}
+
+	return &GeoJSONStreamWriter{
+		destination: dest,
+		buffer:      bufio.NewWriter(dest),
+		config:      config,
+		summary:     newBatchSummary(),
+	}, nil
+}
+
+// Write appends the features of a single processed tile to the collection
+func (w *GeoJSONStreamWriter) Write(t *tile.ProcessedTile) error {
+	return w.WriteBatch([]*tile.ProcessedTile{t})
+}
+
+// WriteBatch appends the features of multiple processed tiles to the collection
+func (w *GeoJSONStreamWriter) WriteBatch(tiles []*tile.ProcessedTile) error {
+	if w.closed {
+		return fmt.Errorf("write to closed stream writer")
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	for _, t := range tiles {
+		w.summary.add(t)
+		if t.Error != nil {
+			continue
+		}
+
+		for _, feature := range collectionFeatures(t, w.config.Metadata) {
+			if err := w.writeFeature(feature); err != nil {
+				return fmt.Errorf("failed to write feature of tile %s: %w", t.Coordinate.String(), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Close closes the collection and the underlying destination. A writer that never
+// received a tile still produces an empty, valid FeatureCollection.
+func (w *GeoJSONStreamWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+
+	err := w.open()
+	if err == nil {
+		err = w.writeFooter()
+	}
+	if err == nil {
+		err = w.buffer.Flush()
+	}
+	w.closed = true
+
+	if closeErr := w.destination.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to close feature collection: %w", err)
+	}
+	return nil
+}
+
+// open writes the collection header once
+func (w *GeoJSONStreamWriter) open() error {
+	if w.opened {
+		return nil
+	}
+	w.opened = true
+
+	header := `{"type":"FeatureCollection","features":[`
+	if w.config.Pretty {
+		header = "{\n  \"type\": \"FeatureCollection\",\n  \"features\": ["
+	}
+	if _, err := w.buffer.WriteString(header); err != nil {
+		return fmt.Errorf("failed to write collection header: %w", err)
+	}
+	return nil
+}
+
+// writeFeature writes one feature, separated from the previous one
+func (w *GeoJSONStreamWriter) writeFeature(feature interface{}) error {
+	var data []byte
+	var err error
+	if w.config.Pretty {
+		data, err = json.MarshalIndent(feature, "    ", "  ")
+	} else {
+		data, err = json.Marshal(feature)
+	}
+	if err != nil {
+		return err
+	}
+
+	separator := ","
+	if w.summary.totalFeatures == 0 {
+		separator = ""
+	}
+	if w.config.Pretty {
+		separator += "\n    "
+	}
+
+	if _, err := w.buffer.WriteString(separator); err != nil {
+		return err
+	}
+	if _, err := w.buffer.Write(data); err != nil {
+		return err
+	}
+	w.summary.totalFeatures++
+	return nil
+}
+
+// writeFooter closes the features array and writes the bbox and metadata members
+func (w *GeoJSONStreamWriter) writeFooter() error {
+	footer := "]"
+	if w.config.Pretty && w.summary.totalFeatures > 0 {
+		footer = "\n  ]"
+	}
+
+	members := make([]string, 0, 2)
+	values := make([]interface{}, 0, 2)
+	if w.config.RFC7946 && w.summary.hasBound {
+		members = append(members, "bbox")
+		values = append(values, geojson.NewBBox(w.summary.bound))
+	}
+	if w.config.Metadata {
+		members = append(members, "_metadata")
+		values = append(values, w.summary.metadata(w.config.RFC7946, w.config.Timings))
+	}
+
+	for i, member := range members {
+		if w.config.Pretty {
+			data, err := json.MarshalIndent(values[i], "  ", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to encode collection %s: %w", member, err)
+			}
+			footer += fmt.Sprintf(",\n  %q: %s", member, data)
+			continue
+		}
+
+		data, err := json.Marshal(values[i])
+		if err != nil {
+			return fmt.Errorf("failed to encode collection %s: %w", member, err)
+		}
+		footer += fmt.Sprintf(",%q:%s", member, data)
+	}
+
+	if w.config.Pretty {
+		footer += "\n}"
+	} else {
+		footer += "}"
+	}
+
+	if _, err := w.buffer.WriteString(footer); err != nil {
+		return fmt.Errorf("failed to write collection footer: %w", err)
+	}
+	return nil
+}
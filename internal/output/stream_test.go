@@ -0,0 +1,156 @@
+// internal/output/stream_test.go - Unit tests for streaming GeoJSON output
+package output
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/valpere/tile_to_json/internal/tile"
+)
+
+// readCollection parses a FeatureCollection written by a test
+func readCollection(t *testing.T, path string) map[string]interface{} {
+	t.Helper()
+	var collection map[string]interface{}
+	if err := json.Unmarshal([]byte(readTestFile(t, path)), &collection); err != nil {
+		t.Fatalf("Failed to parse feature collection: %v", err)
+	}
+	if collection["type"] != "FeatureCollection" {
+		t.Fatalf("Expected a FeatureCollection, got %v", collection["type"])
+	}
+	return collection
+}
+
+// collectionNames returns the name property of each feature of a parsed collection
+func collectionNames(collection map[string]interface{}) []string {
+	names := make([]string, 0)
+	features, _ := collection["features"].([]interface{})
+	for _, feature := range features {
+		properties := feature.(map[string]interface{})["properties"].(map[string]interface{})
+		name, _ := properties["name"].(string)
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestGeoJSONStreamWriterBatches(t *testing.T) {
+	for _, pretty := range []bool{false, true} {
+		path := filepath.Join(t.TempDir(), "out.geojson")
+		writer, err := NewGeoJSONStreamWriter(&WriterConfig{Format: FormatGeoJSON, Pretty: pretty}, path)
+		if err != nil {
+			t.Fatalf("Failed to create stream writer: %v", err)
+		}
+
+		failed := testTile(1, 1, 1, testFeature(orb.Point{7, 8}, map[string]interface{}{"name": "failed"}))
+		failed.Error = errTestTile
+		batches := [][]*tile.ProcessedTile{
+			{testTile(1, 0, 0, testFeature(orb.Point{1, 2}, map[string]interface{}{"name": "a"}))},
+			{testTile(1, 1, 0), failed},
+			{testTile(1, 0, 1,
+				testFeature(orb.Point{3, 4}, map[string]interface{}{"name": "b"}),
+				testFeature(orb.Point{5, 6}, map[string]interface{}{"name": "c"}),
+			)},
+		}
+		for _, batch := range batches {
+			if err := writer.WriteBatch(batch); err != nil {
+				t.Fatalf("Failed to write batch: %v", err)
+			}
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("Failed to close stream writer: %v", err)
+		}
+
+		if pretty && !strings.Contains(readTestFile(t, path), "\n    {") {
+			t.Error("Expected pretty output to be indented")
+		}
+		expected := []string{"a", "b", "c"}
+		if names := collectionNames(readCollection(t, path)); !reflect.DeepEqual(names, expected) {
+			t.Errorf("Pretty %v: expected features %v, got %v", pretty, expected, names)
+		}
+	}
+}
+
+func TestGeoJSONStreamWriterEmpty(t *testing.T) {
+	for _, pretty := range []bool{false, true} {
+		path := filepath.Join(t.TempDir(), "out.geojson")
+		writer, err := NewGeoJSONStreamWriter(&WriterConfig{Format: FormatGeoJSON, Pretty: pretty}, path)
+		if err != nil {
+			t.Fatalf("Failed to create stream writer: %v", err)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("Failed to close stream writer: %v", err)
+		}
+
+		if names := collectionNames(readCollection(t, path)); len(names) != 0 {
+			t.Errorf("Pretty %v: expected no features, got %v", pretty, names)
+		}
+	}
+}
+
+func TestGeoJSONStreamWriterFooter(t *testing.T) {
+	for _, pretty := range []bool{false, true} {
+		path := filepath.Join(t.TempDir(), "out.geojson")
+		config := &WriterConfig{Format: FormatGeoJSON, Pretty: pretty, Metadata: true, RFC7946: true}
+		writer, err := NewGeoJSONStreamWriter(config, path)
+		if err != nil {
+			t.Fatalf("Failed to create stream writer: %v", err)
+		}
+
+		first := testTile(1, 0, 0, testFeature(orb.Point{1, 2}, map[string]interface{}{"name": "a"}))
+		first.Data.(map[string]interface{})["bbox"] = geojson.NewBBox(orb.Bound{Min: orb.Point{1, 2}, Max: orb.Point{1, 2}})
+		second := testTile(1, 1, 0, testFeature(orb.Point{3, 4}, map[string]interface{}{"name": "b"}))
+		second.Data.(map[string]interface{})["bbox"] = geojson.NewBBox(orb.Bound{Min: orb.Point{3, 4}, Max: orb.Point{3, 4}})
+		if err := writer.WriteBatch([]*tile.ProcessedTile{first}); err != nil {
+			t.Fatalf("Failed to write batch: %v", err)
+		}
+		if err := writer.WriteBatch([]*tile.ProcessedTile{second}); err != nil {
+			t.Fatalf("Failed to write batch: %v", err)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("Failed to close stream writer: %v", err)
+		}
+
+		collection := readCollection(t, path)
+		if bbox := collection["bbox"]; !reflect.DeepEqual(bbox, []interface{}{1.0, 2.0, 3.0, 4.0}) {
+			t.Errorf("Pretty %v: expected bbox [1 2 3 4], got %v", pretty, bbox)
+		}
+		metadata, ok := collection["_metadata"].(map[string]interface{})
+		if !ok {
+			t.Fatalf("Pretty %v: expected _metadata member", pretty)
+		}
+		if metadata["total_tiles"] != 2.0 || metadata["total_features"] != 2.0 {
+			t.Errorf("Pretty %v: expected 2 tiles and 2 features, got %v", pretty, metadata)
+		}
+		if _, ok := metadata["generated_at"]; ok {
+			t.Errorf("Pretty %v: expected no generated_at without timings", pretty)
+		}
+
+		features := collection["features"].([]interface{})
+		properties := features[1].(map[string]interface{})["properties"].(map[string]interface{})
+		if properties["_tile"] != "1/1/0" {
+			t.Errorf("Pretty %v: expected _tile 1/1/0, got %v", pretty, properties["_tile"])
+		}
+	}
+}
+
+func TestGeoJSONStreamWriterClosed(t *testing.T) {
+	writer, err := NewGeoJSONStreamWriter(&WriterConfig{Format: FormatGeoJSON}, filepath.Join(t.TempDir(), "out.geojson"))
+	if err != nil {
+		t.Fatalf("Failed to create stream writer: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close stream writer: %v", err)
+	}
+
+	if err := writer.Write(testTile(0, 0, 0)); err == nil {
+		t.Error("Expected error writing to a closed writer")
+	}
+	if err := writer.Close(); err != nil {
+		t.Errorf("Expected a second Close to succeed, got %v", err)
+	}
+}
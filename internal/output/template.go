@@ -0,0 +1,246 @@
+// internal/output/template.go - User-supplied template output formatting
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"text/template"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/wkt"
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/orb/maptile"
+	"github.com/paulmach/orb/project"
+	"github.com/valpere/tile_to_json/internal/tile"
+	"github.com/valpere/tile_to_json/pkg/mvt"
+)
+
+// Template hook names. A template file defines either or both; a file that defines
+// neither is used as the feature template.
+const (
+	TemplateHookTile    = "tile"    // Executed once per tile, before its features
+	TemplateHookFeature = "feature" // Executed once per feature
+)
+
+// TemplateTile is the data passed to the tile template
+type TemplateTile struct {
+	Z        int
+	X        int
+	Y        int
+	Layers   []string
+	Features []*geojson.Feature
+	Data     interface{} // The converted tile, e.g. a FeatureCollection or elevation grid
+	Metadata *tile.TileMetadata
+}
+
+// TemplateFeature is the data passed to the feature template
+type TemplateFeature struct {
+	Tile       *TemplateTile
+	Index      int // Position of the feature within its tile
+	ID         interface{}
+	Layer      string // The feature's layer property
+	Geometry   orb.Geometry
+	Properties geojson.Properties
+	Feature    *geojson.Feature
+}
+
+// TemplateFormatter formats tiles with a user-supplied text/template file
+type TemplateFormatter struct {
+	tileTemplate     *template.Template
+	featureTemplate  *template.Template
+	coordinateSystem string
+	layerProperty    string
+}
+
+// NewTemplateFormatter loads and parses a template file. Coordinates are taken to be
+// in coordinateSystem when the lonlat helper projects them, and feature layers are
+// read from layerProperty.
+func NewTemplateFormatter(path, coordinateSystem, layerProperty string) (*TemplateFormatter, error) {
+	if path == "" {
+		return nil, fmt.Errorf("custom format requires a template file")
+	}
+
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+
+	f := &TemplateFormatter{coordinateSystem: coordinateSystem, layerProperty: layerProperty}
+	root, err := template.New(filepath.Base(path)).Funcs(f.funcs()).Parse(string(source))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	f.tileTemplate = root.Lookup(TemplateHookTile)
+	f.featureTemplate = root.Lookup(TemplateHookFeature)
+	if f.tileTemplate == nil && f.featureTemplate == nil {
+		f.featureTemplate = root
+	}
+
+	return f, nil
+}
+
+// Format executes the tile template and then the feature template for each feature
+func (f *TemplateFormatter) Format(tile *tile.ProcessedTile) ([]byte, error) {
+	if tile.Error != nil {
+		return nil, fmt.Errorf("cannot format tile with error: %w", tile.Error)
+	}
+
+	data := newTemplateTile(tile)
+
+	var buf bytes.Buffer
+	if f.tileTemplate != nil {
+		if err := f.tileTemplate.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("tile template failed for %s: %w", tile.Coordinate.String(), err)
+		}
+	}
+
+	if f.featureTemplate != nil {
+		for i, feature := range data.Features {
+			featureData := &TemplateFeature{
+				Tile:       data,
+				Index:      i,
+				ID:         feature.ID,
+				Layer:      featureLayer(feature, f.layerProperty),
+				Geometry:   feature.Geometry,
+				Properties: feature.Properties,
+				Feature:    feature,
+			}
+			if err := f.featureTemplate.Execute(&buf, featureData); err != nil {
+				return nil, fmt.Errorf("feature template failed for %s: %w", tile.Coordinate.String(), err)
+			}
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// FormatBatch formats multiple tiles in order, skipping failed tiles
+func (f *TemplateFormatter) FormatBatch(tiles []*tile.ProcessedTile) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, t := range tiles {
+		if t.Error != nil {
+			continue
+		}
+		data, err := f.Format(t)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+	}
+	return buf.Bytes(), nil
+}
+
+// ContentType returns the MIME type for template output, which is unknown
+func (f *TemplateFormatter) ContentType() string {
+	return "text/plain"
+}
+
+// newTemplateTile builds the tile template data from a processed tile
+func newTemplateTile(t *tile.ProcessedTile) *TemplateTile {
+	data := &TemplateTile{
+		Z:        t.Coordinate.Z,
+		X:        t.Coordinate.X,
+		Y:        t.Coordinate.Y,
+		Data:     t.Data,
+		Metadata: t.Metadata,
+	}
+	if t.Metadata != nil {
+		data.Layers = t.Metadata.Layers
+	}
+
+	if collection, ok := t.Data.(map[string]interface{}); ok {
+		switch features := collection["features"].(type) {
+		case []*geojson.Feature:
+			data.Features = features
+		case []interface{}:
+			for _, feature := range features {
+				if feat, ok := feature.(*geojson.Feature); ok {
+					data.Features = append(data.Features, feat)
+				}
+			}
+		}
+	}
+
+	return data
+}
+
+// funcs returns the helper functions available to templates
+func (f *TemplateFormatter) funcs() template.FuncMap {
+	return template.FuncMap{
+		"json":       templateJSON,
+		"wkt":        templateWKT,
+		"round":      templateRound,
+		"lonlat":     f.lonlat,
+		"tileBounds": templateTileBounds,
+	}
+}
+
+// templateJSON encodes a value as compact JSON
+func templateJSON(value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// templateWKT encodes a geometry as Well-Known Text
+func templateWKT(geometry orb.Geometry) string {
+	if geometry == nil {
+		return ""
+	}
+	return wkt.MarshalString(geometry)
+}
+
+// templateRound rounds a number to the given number of decimal places
+func templateRound(value interface{}, places int) (float64, error) {
+	var number float64
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		number = v.Float()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		number = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		number = float64(v.Uint())
+	case reflect.String:
+		parsed, err := strconv.ParseFloat(v.String(), 64)
+		if err != nil {
+			return 0, fmt.Errorf("round: %w", err)
+		}
+		number = parsed
+	default:
+		return 0, fmt.Errorf("round: unsupported value %v of type %T", value, value)
+	}
+
+	factor := math.Pow10(places)
+	return math.Round(number*factor) / factor, nil
+}
+
+// lonlat returns a geometry in WGS84 longitude/latitude, projecting Web Mercator
+// output. A point is returned as [lon, lat], so its parts can be read with index.
+func (f *TemplateFormatter) lonlat(geometry orb.Geometry) orb.Geometry {
+	return lonLatGeometry(geometry, f.coordinateSystem)
+}
+
+// lonLatGeometry returns a copy of a geometry in WGS84 longitude/latitude, given the
+// coordinate system it is in
+func lonLatGeometry(geometry orb.Geometry, coordinateSystem string) orb.Geometry {
+	if geometry == nil || coordinateSystem == mvt.CoordSystemWGS84 {
+		return geometry
+	}
+	return project.Geometry(orb.Clone(geometry), project.Mercator.ToWGS84)
+}
+
+// templateTileBounds returns the [west, south, east, north] bounds of tile z/x/y in
+// WGS84 longitude/latitude
+func templateTileBounds(z, x, y int) []float64 {
+	bound := maptile.New(uint32(x), uint32(y), maptile.Zoom(z)).Bound()
+	return []float64{bound.Min[0], bound.Min[1], bound.Max[0], bound.Max[1]}
+}
@@ -0,0 +1,142 @@
+// internal/output/template_test.go - Unit tests for custom template output
+package output
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/orb/project"
+	"github.com/valpere/tile_to_json/internal/tile"
+	"github.com/valpere/tile_to_json/pkg/mvt"
+)
+
+// newTestTemplate writes a template file and creates a formatter for it
+func newTestTemplate(t *testing.T, source, layerProperty string) *TemplateFormatter {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.tmpl")
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+	formatter, err := NewTemplateFormatter(path, mvt.CoordSystemWebMercator, layerProperty)
+	if err != nil {
+		t.Fatalf("Failed to create formatter: %v", err)
+	}
+	return formatter
+}
+
+func TestTemplateFormatterLayer(t *testing.T) {
+	formatter := newTestTemplate(t, `{{.Layer}};`, "lyr")
+
+	data, err := formatter.Format(testTile(0, 0, 0,
+		testFeature(orb.Point{1, 2}, map[string]interface{}{"lyr": "roads", mvt.DefaultLayerProperty: "pois"}),
+		testFeature(orb.Point{3, 4}, nil),
+	))
+	if err != nil {
+		t.Fatalf("Failed to format tile: %v", err)
+	}
+
+	expected := "roads;;"
+	if string(data) != expected {
+		t.Errorf("Expected %q, got %q", expected, string(data))
+	}
+}
+
+func TestTemplateFormatterHooks(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"both hooks", `{{define "tile"}}[{{.Z}}/{{.X}}/{{.Y}} {{len .Features}}]{{end}}{{define "feature"}}{{.Index}}:{{.Properties.name}};{{end}}`, "[2/1/3 2]0:a;1:b;"},
+		{"tile hook only", `{{define "tile"}}{{range .Layers}}{{.}}{{end}}{{end}}`, "pois"},
+		{"feature hook only", `{{define "feature"}}{{.Tile.Z}}-{{.Properties.name}};{{end}}`, "2-a;2-b;"},
+		{"root template", `{{.Properties.name}};`, "a;b;"},
+	}
+
+	for _, test := range tests {
+		formatter := newTestTemplate(t, test.source, mvt.DefaultLayerProperty)
+		data, err := formatter.Format(testTile(2, 1, 3,
+			testFeature(orb.Point{1, 2}, map[string]interface{}{"name": "a"}),
+			testFeature(orb.Point{3, 4}, map[string]interface{}{"name": "b"}),
+		))
+		if err != nil {
+			t.Fatalf("%s: failed to format tile: %v", test.name, err)
+		}
+		if string(data) != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, string(data))
+		}
+	}
+}
+
+func TestTemplateFormatterFormatBatch(t *testing.T) {
+	formatter := newTestTemplate(t, `{{.Tile.X}};`, mvt.DefaultLayerProperty)
+
+	failed := testTile(1, 1, 0, testFeature(orb.Point{3, 4}, nil))
+	failed.Error = fmt.Errorf("fetch failed")
+	data, err := formatter.FormatBatch([]*tile.ProcessedTile{
+		testTile(1, 0, 0, testFeature(orb.Point{1, 2}, nil)),
+		failed,
+		testTile(1, 1, 1, testFeature(orb.Point{5, 6}, nil)),
+	})
+	if err != nil {
+		t.Fatalf("Failed to format batch: %v", err)
+	}
+
+	expected := "0;1;"
+	if string(data) != expected {
+		t.Errorf("Expected %q, got %q", expected, string(data))
+	}
+}
+
+func TestTemplateHelpers(t *testing.T) {
+	point := project.Point(orb.Point{10, 20}, project.WGS84.ToMercator)
+
+	tests := []struct {
+		name     string
+		source   string
+		feature  *geojson.Feature
+		expected string
+	}{
+		{"json", `{{json .Properties}}`, testFeature(orb.Point{1, 2}, map[string]interface{}{"a": []interface{}{1.0, "x"}}), `{"a":[1,"x"]}`},
+		{"wkt", `{{wkt .Geometry}}`, testFeature(orb.LineString{{1, 2}, {3, 4}}, nil), "LINESTRING(1 2,3 4)"},
+		{"round", `{{round .Properties.v 2}}`, testFeature(orb.Point{1, 2}, map[string]interface{}{"v": 1.23456}), "1.23"},
+		{"round integer", `{{round .Properties.v 1}}`, testFeature(orb.Point{1, 2}, map[string]interface{}{"v": int64(7)}), "7"},
+		{"round string", `{{round .Properties.v 1}}`, testFeature(orb.Point{1, 2}, map[string]interface{}{"v": "2.46"}), "2.5"},
+		{"lonlat", `{{with lonlat .Geometry}}{{round (index . 0) 6}},{{round (index . 1) 6}}{{end}}`, testFeature(point, nil), "10,20"},
+		{"tileBounds", `{{json (tileBounds 1 1 0)}}`, testFeature(orb.Point{1, 2}, nil), "[0,0,180,85.05112877980659]"},
+	}
+
+	for _, test := range tests {
+		formatter := newTestTemplate(t, test.source, mvt.DefaultLayerProperty)
+		data, err := formatter.Format(testTile(0, 0, 0, test.feature))
+		if err != nil {
+			t.Fatalf("%s: failed to format tile: %v", test.name, err)
+		}
+		if string(data) != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, string(data))
+		}
+	}
+}
+
+func TestTemplateFormatterErrors(t *testing.T) {
+	if _, err := NewTemplateFormatter(filepath.Join(t.TempDir(), "missing.tmpl"), mvt.CoordSystemWebMercator, ""); err == nil {
+		t.Error("Expected error for a missing template file")
+	}
+	if _, err := NewTemplateFormatter("", mvt.CoordSystemWebMercator, ""); err == nil {
+		t.Error("Expected error without a template file")
+	}
+
+	formatter := newTestTemplate(t, `{{round .Properties.name 1}}`, "")
+	_, err := formatter.Format(testTile(3, 2, 1, testFeature(orb.Point{1, 2}, map[string]interface{}{"name": "a"})))
+	if err == nil {
+		t.Fatal("Expected error for a failing template")
+	}
+	if !strings.Contains(err.Error(), "3/2/1") {
+		t.Errorf("Expected error to name the tile, got %v", err)
+	}
+}
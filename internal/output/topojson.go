@@ -0,0 +1,179 @@
+// internal/output/topojson.go - TopoJSON output formatting and writing
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/paulmach/orb/geojson"
+	"github.com/valpere/tile_to_json/internal/tile"
+	"github.com/valpere/tile_to_json/pkg/topojson"
+)
+
+// DefaultTopoJSONQuantization is the default number of grid positions per axis
+const DefaultTopoJSONQuantization = 100000
+
+// writeTopoJSONFeatures adds the features of a tile to a TopoJSON writer, tagging each
+// with its tile coordinate when tag is set
+func writeTopoJSONFeatures(writer *topojson.Writer, t *tile.ProcessedTile, tag bool) error {
+	for _, feature := range collectionFeatures(t, tag) {
+		feat, ok := feature.(*geojson.Feature)
+		if !ok {
+			continue
+		}
+		if err := writer.Write(feat); err != nil {
+			return fmt.Errorf("failed to write feature of tile %s: %w", t.Coordinate.String(), err)
+		}
+	}
+	return nil
+}
+
+// TopoJSONFormatter formats tiles as TopoJSON topologies
+type TopoJSONFormatter struct {
+	options      topojson.Options
+	includeStats bool
+}
+
+// NewTopoJSONFormatter creates a TopoJSON formatter. Features are added to one object
+// per layer, named by their layer property, and coordinates are snapped to a grid of
+// quantization positions per axis unless it is 0.
+func NewTopoJSONFormatter(quantization int, layerProperty string, pretty, includeStats bool) (*TopoJSONFormatter, error) {
+	options := topojson.Options{
+		Quantization:  quantization,
+		LayerProperty: layerProperty,
+		Pretty:        pretty,
+	}
+	if _, err := topojson.NewWriter(options); err != nil {
+		return nil, fmt.Errorf("invalid topojson options: %w", err)
+	}
+	return &TopoJSONFormatter{
+		options:      options,
+		includeStats: includeStats,
+	}, nil
+}
+
+// Format formats a single tile as a topology
+func (f *TopoJSONFormatter) Format(t *tile.ProcessedTile) ([]byte, error) {
+	if t.Error != nil {
+		return nil, fmt.Errorf("cannot format tile with error: %w", t.Error)
+	}
+	return f.format([]*tile.ProcessedTile{t}, false)
+}
+
+// FormatBatch formats multiple tiles as one topology with arcs shared across tiles,
+// skipping failed tiles. Features are tagged with their tile when statistics are
+// included.
+func (f *TopoJSONFormatter) FormatBatch(tiles []*tile.ProcessedTile) ([]byte, error) {
+	return f.format(tiles, f.includeStats)
+}
+
+func (f *TopoJSONFormatter) format(tiles []*tile.ProcessedTile, tag bool) ([]byte, error) {
+	writer, err := topojson.NewWriter(f.options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create topojson writer: %w", err)
+	}
+
+	for _, t := range tiles {
+		if t.Error != nil {
+			continue
+		}
+		if err := writeTopoJSONFeatures(writer, t, tag); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := writer.WriteTo(&buf); err != nil {
+		return nil, fmt.Errorf("failed to write topology: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ContentType returns the MIME type for TopoJSON
+func (f *TopoJSONFormatter) ContentType() string {
+	return "application/json"
+}
+
+// TopoJSONWriter writes the features of all tiles of a job into one topology, so
+// borders are shared across tiles as well as within them. Arcs can only be built once
+// every feature is known, so features are kept in memory and the topology is written
+// on Close.
+type TopoJSONWriter struct {
+	config      *WriterConfig
+	writer      *topojson.Writer
+	destination string
+	closed      bool
+}
+
+// NewTopoJSONWriter creates a TopoJSON writer with arcs shared across all tiles. A
+// destination of "" or "-" writes to stdout.
+func NewTopoJSONWriter(config *WriterConfig, destination string) (*TopoJSONWriter, error) {
+	if destination == "" {
+		destination = "-"
+	}
+
+	writer, err := topojson.NewWriter(topojson.Options{
+		Quantization:  config.TopoJSONQuantization,
+		LayerProperty: config.LayerProperty,
+		Pretty:        config.Pretty,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create topojson writer: %w", err)
+	}
+
+	return &TopoJSONWriter{
+		config:      config,
+		writer:      writer,
+		destination: destination,
+	}, nil
+}
+
+// Write adds the features of a single processed tile
+func (w *TopoJSONWriter) Write(t *tile.ProcessedTile) error {
+	return w.WriteBatch([]*tile.ProcessedTile{t})
+}
+
+// WriteBatch adds the features of multiple processed tiles, skipping failed tiles
+func (w *TopoJSONWriter) WriteBatch(tiles []*tile.ProcessedTile) error {
+	if w.closed {
+		return fmt.Errorf("write to closed topojson writer")
+	}
+
+	for _, t := range tiles {
+		if t.Error != nil {
+			continue
+		}
+		if err := writeTopoJSONFeatures(w.writer, t, w.config.Metadata); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close builds the topology and writes it, compressed when configured
+func (w *TopoJSONWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	var output io.WriteCloser = nopWriteCloser{os.Stdout}
+	if w.destination != "-" {
+		dest, err := newFileDestination(w.destination, w.config.Compression)
+		if err != nil {
+			return fmt.Errorf("failed to create file destination: %w", err)
+		}
+		output = dest
+	}
+
+	_, err := w.writer.WriteTo(output)
+	if closeErr := output.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write topology: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+// internal/output/topojson_test.go - Unit tests for TopoJSON output
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/valpere/tile_to_json/internal/tile"
+	"github.com/valpere/tile_to_json/pkg/mvt"
+	"github.com/valpere/tile_to_json/pkg/topojson"
+)
+
+// testTopology is the part of a TopoJSON topology checked by tests
+type testTopology struct {
+	Type      string          `json:"type"`
+	Transform json.RawMessage `json:"transform"`
+	Objects   map[string]struct {
+		Geometries []struct {
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"geometries"`
+	} `json:"objects"`
+}
+
+// readTopology parses a topology and returns it with its sorted object names
+func readTopology(t *testing.T, data []byte) (*testTopology, []string) {
+	t.Helper()
+	var topology testTopology
+	if err := json.Unmarshal(data, &topology); err != nil {
+		t.Fatalf("Failed to parse topology: %v", err)
+	}
+	if topology.Type != "Topology" {
+		t.Errorf("Expected type Topology, got %q", topology.Type)
+	}
+	names := make([]string, 0, len(topology.Objects))
+	for name := range topology.Objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return &topology, names
+}
+
+// topoJSONTiles returns two tiles with polygons in the layers named by property
+func topoJSONTiles(property string) []*tile.ProcessedTile {
+	square := orb.Polygon{{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}}
+	next := orb.Polygon{{{1, 0}, {2, 0}, {2, 1}, {1, 1}, {1, 0}}}
+	return []*tile.ProcessedTile{
+		testTile(1, 0, 0, testFeature(square, map[string]interface{}{property: "parks", "name": "a"})),
+		testTile(1, 1, 0, testFeature(next, map[string]interface{}{property: "water", "name": "b"})),
+	}
+}
+
+func TestTopoJSONFormatter(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    *FormatterConfig
+		property  string
+		objects   []string
+		transform bool
+		pretty    bool
+		tagged    bool
+	}{
+		{
+			name:      "quantized",
+			config:    &FormatterConfig{Format: FormatTopoJSON, TopoJSONQuantization: 1e5, LayerProperty: mvt.DefaultLayerProperty},
+			property:  mvt.DefaultLayerProperty,
+			objects:   []string{"parks", "water"},
+			transform: true,
+		},
+		{
+			name:     "full precision",
+			config:   &FormatterConfig{Format: FormatTopoJSON, LayerProperty: "lyr", Pretty: true},
+			property: "lyr",
+			objects:  []string{"parks", "water"},
+			pretty:   true,
+		},
+		{
+			name:     "no layer property",
+			config:   &FormatterConfig{Format: FormatTopoJSON, IncludeStats: true},
+			property: mvt.DefaultLayerProperty,
+			objects:  []string{topojson.DefaultObject},
+			tagged:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatter, err := NewFormatter(tt.config)
+			if err != nil {
+				t.Fatalf("Failed to create formatter: %v", err)
+			}
+			data, err := formatter.FormatBatch(topoJSONTiles(tt.property))
+			if err != nil {
+				t.Fatalf("Failed to format batch: %v", err)
+			}
+
+			topology, names := readTopology(t, data)
+			if !reflect.DeepEqual(names, tt.objects) {
+				t.Errorf("Expected objects %v, got %v", tt.objects, names)
+			}
+			if got := len(topology.Transform) > 0; got != tt.transform {
+				t.Errorf("Expected transform %v, got %s", tt.transform, topology.Transform)
+			}
+			if got := strings.Contains(string(data), "\n  "); got != tt.pretty {
+				t.Errorf("Expected pretty output %v, got %v", tt.pretty, got)
+			}
+
+			for _, name := range names {
+				for _, geometry := range topology.Objects[name].Geometries {
+					if _, exists := geometry.Properties["_tile"]; exists != tt.tagged {
+						t.Errorf("Expected _tile property %v, got %v", tt.tagged, geometry.Properties)
+					}
+					if tt.config.LayerProperty != "" {
+						if _, exists := geometry.Properties[tt.property]; exists {
+							t.Errorf("Expected layer property %q not to be stored", tt.property)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestTopoJSONFormatterErrors(t *testing.T) {
+	for _, quantization := range []int{-1, 1} {
+		if _, err := NewFormatter(&FormatterConfig{Format: FormatTopoJSON, TopoJSONQuantization: quantization}); err == nil {
+			t.Errorf("Expected error for quantization %d", quantization)
+		}
+		if _, err := NewSingleFileWriter(&WriterConfig{Format: FormatTopoJSON, TopoJSONQuantization: quantization}, "-"); err == nil {
+			t.Errorf("Expected writer error for quantization %d", quantization)
+		}
+	}
+
+	formatter, err := NewFormatter(&FormatterConfig{Format: FormatTopoJSON, LayerProperty: mvt.DefaultLayerProperty})
+	if err != nil {
+		t.Fatalf("Failed to create formatter: %v", err)
+	}
+	failed := testTile(1, 1, 1, testFeature(orb.Point{5, 6}, map[string]interface{}{mvt.DefaultLayerProperty: "failed"}))
+	failed.Error = errTestTile
+	if _, err := formatter.Format(failed); !errors.Is(err, errTestTile) {
+		t.Errorf("Expected tile error, got %v", err)
+	}
+
+	data, err := formatter.FormatBatch(append(topoJSONTiles(mvt.DefaultLayerProperty), failed))
+	if err != nil {
+		t.Fatalf("Failed to format batch: %v", err)
+	}
+	if _, names := readTopology(t, data); !reflect.DeepEqual(names, []string{"parks", "water"}) {
+		t.Errorf("Expected the failed tile to be skipped, got objects %v", names)
+	}
+}
+
+func TestTopoJSONWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.topojson")
+	config := &WriterConfig{Format: FormatTopoJSON, TopoJSONQuantization: 1e4, LayerProperty: "lyr", Metadata: true}
+
+	writer, err := NewSingleFileWriter(config, path)
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	if _, ok := writer.(*TopoJSONWriter); !ok {
+		t.Fatalf("Expected a TopoJSONWriter, got %T", writer)
+	}
+	// Tiles written one at a time end up in one topology
+	for _, processed := range topoJSONTiles("lyr") {
+		if err := writer.Write(processed); err != nil {
+			t.Fatalf("Failed to write tile: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+	if err := writer.Write(topoJSONTiles("lyr")[0]); err == nil {
+		t.Error("Expected error writing to a closed writer")
+	}
+
+	topology, names := readTopology(t, []byte(readTestFile(t, path)))
+	if !reflect.DeepEqual(names, []string{"parks", "water"}) {
+		t.Errorf("Expected objects [parks water], got %v", names)
+	}
+	if len(topology.Transform) == 0 {
+		t.Error("Expected a quantization transform")
+	}
+	if coordinate := topology.Objects["water"].Geometries[0].Properties["_tile"]; coordinate != "1/1/0" {
+		t.Errorf("Expected water tagged with tile 1/1/0, got %v", coordinate)
+	}
+}
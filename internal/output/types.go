@@ -4,6 +4,7 @@ package output
 import (
 	"fmt"
 	"github.com/valpere/tile_to_json/internal/tile"
+	"github.com/valpere/tile_to_json/pkg/kml"
 	"io"
 	"time"
 )
@@ -12,9 +13,21 @@ import (
 type Format string
 
 const (
-	FormatGeoJSON Format = "geojson"
-	FormatJSON    Format = "json"
-	FormatCustom  Format = "custom"
+	FormatGeoJSON    Format = "geojson"
+	FormatJSON       Format = "json"
+	FormatCustom     Format = "custom"
+	FormatNDJSON     Format = "ndjson"     // Newline-delimited GeoJSON features
+	FormatGeoJSONSeq Format = "geojsonseq" // RFC 8142 GeoJSON text sequence
+	FormatCSV        Format = "csv"
+	FormatTSV        Format = "tsv"
+	FormatFlatGeobuf Format = "flatgeobuf"
+	FormatGeoParquet Format = "geoparquet"
+	FormatGeoPackage Format = "geopackage"
+	FormatTopoJSON   Format = "topojson"
+	FormatKML        Format = "kml"
+	FormatKMZ        Format = "kmz" // Zipped KML
+	FormatWKT        Format = "wkt" // WKT rows for database loading
+	FormatWKB        Format = "wkb" // Hex WKB rows or raw WKB records for database loading
 )
 
 // OutputConfig represents configuration for output handling
@@ -74,14 +87,58 @@ type WriterConfig struct {
 	BaseDir     string
 	Template    string
 	Metadata    bool
+	RFC7946     bool
+	Timings     bool
+
+	// CoordinateSystem is the coordinate system of the converted geometries, used to
+	// produce longitude/latitude in template and CSV output
+	CoordinateSystem string
+
+	// CSV configures the CSV and TSV formats; nil uses the defaults
+	CSV *CSVOptions
+
+	// FlatGeobufIndex adds a packed Hilbert R-tree spatial index to FlatGeobuf files
+	FlatGeobufIndex bool
+
+	// GeoParquet configures the GeoParquet format; nil uses the defaults
+	GeoParquet *GeoParquetOptions
+
+	// GeoPackageIndex adds an R-tree spatial index to each GeoPackage feature table
+	GeoPackageIndex bool
+
+	// LayerProperty is the property holding each feature's layer name, used by formats
+	// that keep layers apart; empty when the converter omits it
+	LayerProperty string
+
+	// TopoJSONQuantization is the number of grid positions per axis TopoJSON coordinates
+	// are snapped to; 0 keeps full precision
+	TopoJSONQuantization int
+
+	// KMLStyles maps layer names to the style of their KML placemarks; "*" styles all
+	// other layers
+	KMLStyles map[string]kml.Style
+
+	// WKT configures the WKT and WKB formats; nil uses the defaults
+	WKT *WKTOptions
 }
 
 // FormatterConfig contains configuration for creating formatters
 type FormatterConfig struct {
-	Format       Format
-	Pretty       bool
-	IncludeStats bool
-	Template     string
+	Format               Format
+	Pretty               bool
+	IncludeStats         bool
+	Template             string
+	RFC7946              bool
+	Timings              bool
+	CoordinateSystem     string
+	CSV                  *CSVOptions
+	FlatGeobufIndex      bool
+	GeoParquet           *GeoParquetOptions
+	GeoPackageIndex      bool
+	LayerProperty        string
+	TopoJSONQuantization int
+	KMLStyles            map[string]kml.Style
+	WKT                  *WKTOptions
 }
 
 // NewOutputConfig creates a new output configuration with default values
@@ -97,7 +154,7 @@ func NewOutputConfig() *OutputConfig {
 
 // Validate validates the output configuration
 func (c *OutputConfig) Validate() error {
-	validFormats := []Format{FormatGeoJSON, FormatJSON, FormatCustom}
+	validFormats := []Format{FormatGeoJSON, FormatJSON, FormatCustom, FormatNDJSON, FormatGeoJSONSeq, FormatCSV, FormatTSV, FormatFlatGeobuf, FormatGeoParquet, FormatGeoPackage, FormatTopoJSON, FormatKML, FormatKMZ, FormatWKT, FormatWKB}
 	for _, format := range validFormats {
 		if c.Format == format {
 			return nil
@@ -114,9 +171,32 @@ func (f Format) String() string {
 // IsValid checks if the format is supported
 func (f Format) IsValid() bool {
 	switch f {
-	case FormatGeoJSON, FormatJSON, FormatCustom:
+	case FormatGeoJSON, FormatJSON, FormatCustom, FormatNDJSON, FormatGeoJSONSeq, FormatCSV, FormatTSV, FormatFlatGeobuf, FormatGeoParquet, FormatGeoPackage, FormatTopoJSON, FormatKML, FormatKMZ, FormatWKT, FormatWKB:
 		return true
 	default:
 		return false
 	}
 }
+
+// IsLineDelimited reports whether the format writes one feature per line, or per
+// record for binary WKB, so output can be appended tile by tile
+func (f Format) IsLineDelimited() bool {
+	return f == FormatNDJSON || f == FormatGeoJSONSeq || f == FormatWKT || f == FormatWKB
+}
+
+// IsTabular reports whether the format writes features as table rows
+func (f Format) IsTabular() bool {
+	return f == FormatCSV || f == FormatTSV
+}
+
+// IsBinary reports whether the format writes binary files rather than text
+func (f Format) IsBinary() bool {
+	return f == FormatFlatGeobuf || f == FormatGeoParquet || f == FormatGeoPackage || f == FormatKMZ
+}
+
+// IsCompressible reports whether output files can be gzip-compressed. GeoParquet
+// compresses its own pages, GeoPackage databases must be opened in place and KMZ is
+// already zipped.
+func (f Format) IsCompressible() bool {
+	return f != FormatGeoParquet && f != FormatGeoPackage && f != FormatKMZ
+}
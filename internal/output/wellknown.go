@@ -0,0 +1,124 @@
+// internal/output/wellknown.go - WKT and WKB output formatting
+package output
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/paulmach/orb/geojson"
+	"github.com/valpere/tile_to_json/internal/tile"
+	"github.com/valpere/tile_to_json/pkg/mvt"
+	"github.com/valpere/tile_to_json/pkg/wellknown"
+)
+
+// WKTOptions configures WKT and WKB output
+type WKTOptions struct {
+	SRID   bool // Prefix geometries with the output coordinate system's SRID (EWKT/EWKB)
+	Copy   bool // Escape rows for PostgreSQL's COPY text format
+	Binary bool // Write WKB as raw binary records instead of hex text rows
+}
+
+// DefaultWKTOptions returns WKT options for plain geometries in COPY-ready rows
+func DefaultWKTOptions() *WKTOptions {
+	return &WKTOptions{
+		Copy: true,
+	}
+}
+
+// wellKnownOptions returns the row options for a format, output options and a
+// coordinate system
+func wellKnownOptions(format Format, options *WKTOptions, coordinateSystem string) wellknown.Options {
+	if options == nil {
+		options = DefaultWKTOptions()
+	}
+
+	result := wellknown.Options{
+		Encoding: wellknown.EncodingWKT,
+		Copy:     options.Copy,
+	}
+	if format == FormatWKB {
+		result.Encoding = wellknown.EncodingHex
+		if options.Binary {
+			result.Encoding = wellknown.EncodingBinary
+		}
+	}
+	if options.SRID {
+		switch coordinateSystem {
+		case mvt.CoordSystemWGS84:
+			result.SRID = 4326
+		case mvt.CoordSystemWebMercator:
+			result.SRID = 3857
+		}
+	}
+	return result
+}
+
+// WKTFormatter formats tiles as WKT or WKB rows for database loading, one per feature.
+// Rows carry no header, so the rows of each tile can be appended to the last.
+type WKTFormatter struct {
+	options      wellknown.Options
+	includeStats bool
+}
+
+// NewWKTFormatter creates a WKT or WKB formatter; the format selects the geometry
+// encoding
+func NewWKTFormatter(format Format, options *WKTOptions, includeStats bool, coordinateSystem string) (*WKTFormatter, error) {
+	rowOptions := wellKnownOptions(format, options, coordinateSystem)
+	if _, err := wellknown.NewWriter(&bytes.Buffer{}, rowOptions); err != nil {
+		return nil, fmt.Errorf("invalid %s options: %w", format, err)
+	}
+	return &WKTFormatter{
+		options:      rowOptions,
+		includeStats: includeStats,
+	}, nil
+}
+
+// Format formats the features of a single tile
+func (f *WKTFormatter) Format(t *tile.ProcessedTile) ([]byte, error) {
+	if t.Error != nil {
+		return nil, fmt.Errorf("cannot format tile with error: %w", t.Error)
+	}
+	return f.format([]*tile.ProcessedTile{t}, false)
+}
+
+// FormatBatch formats the features of multiple tiles, skipping failed tiles. Features
+// are tagged with their tile when statistics are included.
+func (f *WKTFormatter) FormatBatch(tiles []*tile.ProcessedTile) ([]byte, error) {
+	return f.format(tiles, f.includeStats)
+}
+
+func (f *WKTFormatter) format(tiles []*tile.ProcessedTile, tag bool) ([]byte, error) {
+	var buf bytes.Buffer
+	writer, err := wellknown.NewWriter(&buf, f.options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create row writer: %w", err)
+	}
+
+	for _, t := range tiles {
+		if t.Error != nil {
+			continue
+		}
+		for _, feature := range collectionFeatures(t, tag) {
+			feat, ok := feature.(*geojson.Feature)
+			if !ok {
+				continue
+			}
+			if err := writer.Write(feat); err != nil {
+				return nil, fmt.Errorf("failed to write feature of tile %s: %w", t.Coordinate.String(), err)
+			}
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ContentType returns the MIME type for text rows or binary WKB records
+func (f *WKTFormatter) ContentType() string {
+	if f.options.Encoding == wellknown.EncodingBinary {
+		return "application/octet-stream"
+	}
+	return "text/tab-separated-values"
+}
@@ -0,0 +1,157 @@
+// internal/output/wellknown_test.go - Unit tests for WKT and WKB output
+package output
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/valpere/tile_to_json/internal/tile"
+	"github.com/valpere/tile_to_json/pkg/mvt"
+	"github.com/valpere/tile_to_json/pkg/wellknown"
+)
+
+// wellKnownTiles returns two tiles of point features, one with a backslash in a
+// property for COPY escaping
+func wellKnownTiles() []*tile.ProcessedTile {
+	return []*tile.ProcessedTile{
+		testTile(1, 0, 0, testFeature(orb.Point{1, 2}, map[string]interface{}{"path": `a\b`})),
+		testTile(1, 1, 0, testFeature(orb.Point{3, 4}, nil)),
+	}
+}
+
+// wkb returns the WKB record of a geometry
+func wkb(t *testing.T, geometry orb.Geometry, srid int) []byte {
+	t.Helper()
+	data, err := wellknown.MarshalBinary(geometry, srid)
+	if err != nil {
+		t.Fatalf("Failed to encode geometry: %v", err)
+	}
+	return data
+}
+
+func TestWKTFormatter(t *testing.T) {
+	hexRow := func(geometry orb.Geometry, srid int) string {
+		return strings.ToUpper(hex.EncodeToString(wkb(t, geometry, srid)))
+	}
+
+	tests := []struct {
+		name     string
+		config   *FormatterConfig
+		expected string
+	}{
+		{
+			"default options",
+			&FormatterConfig{Format: FormatWKT, CoordinateSystem: mvt.CoordSystemWGS84},
+			"POINT(1 2)\t{\"path\":\"a\\\\\\\\b\"}\nPOINT(3 4)\t{}\n",
+		},
+		{
+			"srid",
+			&FormatterConfig{Format: FormatWKT, WKT: &WKTOptions{SRID: true}, CoordinateSystem: mvt.CoordSystemWGS84},
+			"SRID=4326;POINT(1 2)\t{\"path\":\"a\\\\b\"}\nSRID=4326;POINT(3 4)\t{}\n",
+		},
+		{
+			"srid without coordinate system",
+			&FormatterConfig{Format: FormatWKT, WKT: &WKTOptions{SRID: true, Copy: true}},
+			"POINT(1 2)\t{\"path\":\"a\\\\\\\\b\"}\nPOINT(3 4)\t{}\n",
+		},
+		{
+			"hex wkb",
+			&FormatterConfig{Format: FormatWKB, WKT: &WKTOptions{SRID: true}, CoordinateSystem: mvt.CoordSystemWebMercator},
+			hexRow(orb.Point{1, 2}, 3857) + "\t{\"path\":\"a\\\\b\"}\n" + hexRow(orb.Point{3, 4}, 3857) + "\t{}\n",
+		},
+		{
+			"binary wkb",
+			&FormatterConfig{Format: FormatWKB, WKT: &WKTOptions{Binary: true}},
+			string(wkb(t, orb.Point{1, 2}, 0)) + string(wkb(t, orb.Point{3, 4}, 0)),
+		},
+		{
+			"stats tag tiles",
+			&FormatterConfig{Format: FormatWKT, WKT: &WKTOptions{}, IncludeStats: true},
+			"POINT(1 2)\t{\"_tile\":\"1/0/0\",\"path\":\"a\\\\b\"}\nPOINT(3 4)\t{\"_tile\":\"1/1/0\"}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatter, err := NewFormatter(tt.config)
+			if err != nil {
+				t.Fatalf("Failed to create formatter: %v", err)
+			}
+			data, err := formatter.FormatBatch(wellKnownTiles())
+			if err != nil {
+				t.Fatalf("Failed to format batch: %v", err)
+			}
+			if string(data) != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, string(data))
+			}
+		})
+	}
+}
+
+func TestWKTFormatterErrors(t *testing.T) {
+	formatter, err := NewFormatter(&FormatterConfig{Format: FormatWKT})
+	if err != nil {
+		t.Fatalf("Failed to create formatter: %v", err)
+	}
+
+	failed := testTile(1, 1, 1, testFeature(orb.Point{5, 6}, nil))
+	failed.Error = errTestTile
+	if _, err := formatter.Format(failed); !errors.Is(err, errTestTile) {
+		t.Errorf("Expected tile error, got %v", err)
+	}
+
+	data, err := formatter.FormatBatch([]*tile.ProcessedTile{failed, testTile(1, 0, 0, testFeature(orb.Point{1, 2}, nil))})
+	if err != nil {
+		t.Fatalf("Failed to format batch: %v", err)
+	}
+	if expected := "POINT(1 2)\t{}\n"; string(data) != expected {
+		t.Errorf("Expected the failed tile to be skipped, got %q", string(data))
+	}
+}
+
+func TestWKTWriter(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   *WriterConfig
+		expected []byte
+	}{
+		{
+			"wkt rows",
+			&WriterConfig{Format: FormatWKT, WKT: &WKTOptions{SRID: true}, CoordinateSystem: mvt.CoordSystemWebMercator},
+			[]byte("SRID=3857;POINT(1 2)\t{\"path\":\"a\\\\b\"}\nSRID=3857;POINT(3 4)\t{}\n"),
+		},
+		{
+			"binary wkb records",
+			&WriterConfig{Format: FormatWKB, WKT: &WKTOptions{Binary: true}},
+			append(wkb(t, orb.Point{1, 2}, 0), wkb(t, orb.Point{3, 4}, 0)...),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "out")
+			writer, err := NewSingleFileWriter(tt.config, path)
+			if err != nil {
+				t.Fatalf("Failed to create writer: %v", err)
+			}
+			// Rows are appended tile by tile, without separators between batches
+			for _, processed := range wellKnownTiles() {
+				if err := writer.Write(processed); err != nil {
+					t.Fatalf("Failed to write tile: %v", err)
+				}
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatalf("Failed to close writer: %v", err)
+			}
+
+			if got := []byte(readTestFile(t, path)); !bytes.Equal(got, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -21,16 +21,12 @@ type FileWriter struct {
 
 // NewFileWriter creates a new file-based writer
 func NewFileWriter(config *WriterConfig, destination string) (*FileWriter, error) {
-	formatter, err := NewFormatter(&FormatterConfig{
-		Format:       config.Format,
-		Pretty:       config.Pretty,
-		IncludeStats: config.Metadata,
-	})
+	formatter, err := NewFormatter(formatterConfigFrom(config))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create formatter: %w", err)
 	}
 
-	dest, err := newFileDestination(destination, config.Compression)
+	dest, err := newFileDestination(destination, config.Compression && config.Format.IsCompressible())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create file destination: %w", err)
 	}
@@ -80,20 +76,31 @@ func (w *FileWriter) Close() error {
 // StdoutWriter writes output to standard output
 type StdoutWriter struct {
 	formatter Formatter
+	newline   bool
 }
 
 // NewStdoutWriter creates a new stdout-based writer
 func NewStdoutWriter(format Format, pretty bool) (*StdoutWriter, error) {
-	formatter, err := NewFormatter(&FormatterConfig{
-		Format:       format,
-		Pretty:       pretty,
-		IncludeStats: false,
+	return NewStdoutWriterWithConfig(&WriterConfig{
+		Format: format,
+		Pretty: pretty,
 	})
+}
+
+// NewStdoutWriterWithConfig creates a stdout-based writer from a writer configuration.
+// Metadata is not written to stdout.
+func NewStdoutWriterWithConfig(config *WriterConfig) (*StdoutWriter, error) {
+	formatterConfig := formatterConfigFrom(config)
+	formatterConfig.IncludeStats = false
+	formatter, err := NewFormatter(formatterConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create formatter: %w", err)
 	}
 
-	return &StdoutWriter{formatter: formatter}, nil
+	// Line-delimited, template and binary output are written exactly as formatted
+	newline := !config.Format.IsLineDelimited() && config.Format != FormatCustom && !config.Format.IsBinary()
+
+	return &StdoutWriter{formatter: formatter, newline: newline}, nil
 }
 
 // Write writes a single tile to stdout
@@ -108,7 +115,10 @@ func (w *StdoutWriter) Write(tile *tile.ProcessedTile) error {
 		return fmt.Errorf("write to stdout failed: %w", err)
 	}
 
-	// Add newline for readability
+	// Add newline for readability; line-delimited output already ends each line
+	if !w.newline {
+		return nil
+	}
 	_, err = os.Stdout.Write([]byte("\n"))
 	return err
 }
@@ -125,7 +135,10 @@ func (w *StdoutWriter) WriteBatch(tiles []*tile.ProcessedTile) error {
 		return fmt.Errorf("batch write to stdout failed: %w", err)
 	}
 
-	// Add newline for readability
+	// Add newline for readability; line-delimited output already ends each line
+	if !w.newline {
+		return nil
+	}
 	_, err = os.Stdout.Write([]byte("\n"))
 	return err
 }
@@ -144,11 +157,7 @@ type MultiFileWriter struct {
 
 // NewMultiFileWriter creates a writer that outputs each tile to a separate file
 func NewMultiFileWriter(config *WriterConfig, baseDir string) (*MultiFileWriter, error) {
-	formatter, err := NewFormatter(&FormatterConfig{
-		Format:       config.Format,
-		Pretty:       config.Pretty,
-		IncludeStats: config.Metadata,
-	})
+	formatter, err := NewFormatter(formatterConfigFrom(config))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create formatter: %w", err)
 	}
@@ -175,7 +184,7 @@ func (w *MultiFileWriter) Write(tile *tile.ProcessedTile) error {
 		return fmt.Errorf("failed to create subdirectory: %w", err)
 	}
 
-	dest, err := newFileDestination(filePath, w.config.Compression)
+	dest, err := newFileDestination(filePath, w.config.Compression && w.config.Format.IsCompressible())
 	if err != nil {
 		return fmt.Errorf("failed to create file destination: %w", err)
 	}
@@ -212,7 +221,7 @@ func (w *MultiFileWriter) Close() error {
 // generateFilename creates a filename for a tile based on its coordinates
 func (w *MultiFileWriter) generateFilename(coord *tile.TileCoordinate) string {
 	ext := w.getFileExtension()
-	if w.config.Compression {
+	if w.config.Compression && w.config.Format.IsCompressible() {
 		ext += ".gz"
 	}
 	return fmt.Sprintf("%d/%d/%d%s", coord.Z, coord.X, coord.Y, ext)
@@ -225,6 +234,30 @@ func (w *MultiFileWriter) getFileExtension() string {
 		return ".geojson"
 	case FormatJSON:
 		return ".json"
+	case FormatNDJSON:
+		return ".ndjson"
+	case FormatGeoJSONSeq:
+		return ".geojsons"
+	case FormatCSV:
+		return ".csv"
+	case FormatTSV:
+		return ".tsv"
+	case FormatFlatGeobuf:
+		return ".fgb"
+	case FormatGeoParquet:
+		return ".parquet"
+	case FormatGeoPackage:
+		return ".gpkg"
+	case FormatTopoJSON:
+		return ".topojson"
+	case FormatKML:
+		return ".kml"
+	case FormatKMZ:
+		return ".kmz"
+	case FormatWKT:
+		return ".wkt"
+	case FormatWKB:
+		return ".wkb"
 	default:
 		return ".json"
 	}
@@ -245,6 +278,11 @@ func newFileDestination(path string, compression bool) (*fileDestination, error)
 		return nil, fmt.Errorf("failed to create directory: %w", err)
 	}
 
+	// Add .gz extension if not already present
+	if compression && !strings.HasSuffix(path, ".gz") {
+		path += ".gz"
+	}
+
 	file, err := os.Create(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create file: %w", err)
@@ -252,16 +290,6 @@ func newFileDestination(path string, compression bool) (*fileDestination, error)
 
 	var writer io.WriteCloser = file
 	if compression {
-		// Add .gz extension if not already present
-		if !strings.HasSuffix(path, ".gz") {
-			file.Close()
-			newPath := path + ".gz"
-			file, err = os.Create(newPath)
-			if err != nil {
-				return nil, fmt.Errorf("failed to create compressed file: %w", err)
-			}
-			path = newPath
-		}
 		writer = gzip.NewWriter(file)
 	}
 
@@ -304,12 +332,65 @@ func (d *fileDestination) Size() int64 {
 // NewWriter creates the appropriate writer based on configuration
 func NewWriter(config *WriterConfig, destination string, multiFile bool) (Writer, error) {
 	if destination == "" || destination == "-" {
-		return NewStdoutWriter(config.Format, config.Pretty)
+		return NewStdoutWriterWithConfig(config)
 	}
 
 	if multiFile {
 		return NewMultiFileWriter(config, destination)
 	}
 
+	return NewSingleFileWriter(config, destination)
+}
+
+// NewSingleFileWriter creates a writer that combines all tiles into one file
+func NewSingleFileWriter(config *WriterConfig, destination string) (Writer, error) {
+	if config.Format.IsTabular() {
+		return NewCSVWriter(config, destination)
+	}
+	if config.Format == FormatFlatGeobuf {
+		return NewFlatGeobufWriter(config, destination)
+	}
+	if config.Format == FormatGeoParquet {
+		return NewGeoParquetWriter(config, destination)
+	}
+	if config.Format == FormatGeoPackage {
+		return NewGeoPackageWriter(config, destination)
+	}
+	if config.Format == FormatTopoJSON {
+		return NewTopoJSONWriter(config, destination)
+	}
+	if config.Format == FormatKML || config.Format == FormatKMZ {
+		return NewKMLWriter(config, destination)
+	}
+	// Line-delimited rows, including WKT and WKB, and template output are appended as
+	// tiles arrive, so they can also be streamed to stdout
+	appendOnly := config.Format.IsLineDelimited() || config.Format == FormatCustom
+	if appendOnly && (destination == "" || destination == "-") {
+		return NewStdoutWriterWithConfig(config)
+	}
+	if config.Format == FormatGeoJSON {
+		return NewGeoJSONStreamWriter(config, destination)
+	}
 	return NewFileWriter(config, destination)
 }
+
+// formatterConfigFrom builds the formatter configuration for a writer configuration
+func formatterConfigFrom(config *WriterConfig) *FormatterConfig {
+	return &FormatterConfig{
+		Format:               config.Format,
+		Pretty:               config.Pretty,
+		IncludeStats:         config.Metadata,
+		Template:             config.Template,
+		RFC7946:              config.RFC7946,
+		Timings:              config.Timings,
+		CoordinateSystem:     config.CoordinateSystem,
+		CSV:                  config.CSV,
+		FlatGeobufIndex:      config.FlatGeobufIndex,
+		GeoParquet:           config.GeoParquet,
+		GeoPackageIndex:      config.GeoPackageIndex,
+		LayerProperty:        config.LayerProperty,
+		TopoJSONQuantization: config.TopoJSONQuantization,
+		KMLStyles:            config.KMLStyles,
+		WKT:                  config.WKT,
+	}
+}
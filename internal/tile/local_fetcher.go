@@ -120,6 +120,9 @@ func (f *LocalFetcher) Fetch(request *TileRequest) (*TileResponse, error) {
 	// Add pseudo-headers for consistency with HTTP fetcher
 	response.Headers = make(map[string][]string)
 	response.Headers["Content-Type"] = []string{"application/x-protobuf"}
+	if strings.HasSuffix(strings.TrimSuffix(filePath, ".gz"), ".mlt") {
+		response.Headers["Content-Type"] = []string{"application/vnd.maplibre-vector-tile"}
+	}
 	response.Headers["Content-Length"] = []string{fmt.Sprintf("%d", len(data))}
 	if isCompressed {
 		response.Headers["Content-Encoding"] = []string{"gzip"}
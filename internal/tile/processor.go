@@ -2,7 +2,9 @@
 package tile
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/valpere/tile_to_json/pkg/mvt"
@@ -20,6 +22,18 @@ func NewMVTProcessor() *MVTProcessor {
 	}
 }
 
+// NewMVTProcessorWithOptions creates a new processor with custom conversion options
+func NewMVTProcessorWithOptions(options *mvt.ConversionOptions) (*MVTProcessor, error) {
+	converter, err := mvt.NewConverterWithOptions(options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create converter: %w", err)
+	}
+
+	return &MVTProcessor{
+		converter: converter,
+	}, nil
+}
+
 // Process converts a single tile response to processed JSON data
 func (p *MVTProcessor) Process(response *TileResponse) (*ProcessedTile, error) {
 	start := time.Now()
@@ -46,18 +60,24 @@ func (p *MVTProcessor) Process(response *TileResponse) (*ProcessedTile, error) {
 		}, fmt.Errorf("empty tile data for tile %s", coordinate.String())
 	}
 
-	// Convert the MVT data to GeoJSON format
-	geojson, metadata, err := p.converter.Convert(
+	// Convert the MVT or MLT data to GeoJSON format
+	geojson, metadata, err := p.converter.ConvertFormat(
 		response.Data,
+		mvt.DetectTileFormat(response.Data, http.Header(response.Headers).Get("Content-Type")),
 		response.Request.Z,
 		response.Request.X,
 		response.Request.Y,
 	)
 	if err != nil {
-		return &ProcessedTile{
+		processed := &ProcessedTile{
 			Coordinate: coordinate,
 			Error:      fmt.Errorf("MVT conversion failed: %w", err),
-		}, err
+		}
+		var strictErr *mvt.StrictError
+		if errors.As(err, &strictErr) {
+			processed.Warnings = strictErr.Warnings
+		}
+		return processed, err
 	}
 
 	processTime := time.Since(start)
@@ -70,13 +90,18 @@ func (p *MVTProcessor) Process(response *TileResponse) (*ProcessedTile, error) {
 		ProcessTime:  processTime,
 		Version:      metadata.Version,
 		Extent:       metadata.Extent,
+		Format:       metadata.Format,
 		Compressed:   isCompressed(response.Headers),
+		Repairs:      metadata.Repairs,
+		Schema:       metadata.Schema,
+		Warnings:     metadata.Warnings,
 	}
 
 	return &ProcessedTile{
 		Coordinate: coordinate,
 		Data:       geojson,
 		Metadata:   tileMetadata,
+		Warnings:   metadata.Warnings,
 	}, nil
 }
 
@@ -4,7 +4,10 @@ package tile
 import (
 	"fmt"
 	"net/http"
+	"sort"
 	"time"
+
+	"github.com/valpere/tile_to_json/pkg/mvt"
 )
 
 // TileRequest represents a request for a specific tile
@@ -50,17 +53,22 @@ type ProcessedTile struct {
 	Data       interface{}     `json:"data"`
 	Metadata   *TileMetadata   `json:"metadata"`
 	Error      error           `json:"error,omitempty"`
+	Warnings   []mvt.Warning   `json:"warnings,omitempty"` // Also kept when strict mode fails the tile
 }
 
 // TileMetadata contains metadata about the processed tile
 type TileMetadata struct {
-	Layers       []string      `json:"layers"`
-	FeatureCount int           `json:"feature_count"`
-	Size         int           `json:"size"`
-	ProcessTime  time.Duration `json:"process_time"`
-	Version      int           `json:"version"`
-	Extent       int           `json:"extent"`
-	Compressed   bool          `json:"compressed"`
+	Layers       []string         `json:"layers"`
+	FeatureCount int              `json:"feature_count"`
+	Size         int              `json:"size"`
+	ProcessTime  time.Duration    `json:"process_time"`
+	Version      int              `json:"version"`
+	Extent       int              `json:"extent"`
+	Format       mvt.TileFormat   `json:"format"`
+	Compressed   bool             `json:"compressed"`
+	Repairs      *mvt.RepairStats `json:"repairs,omitempty"`
+	Schema       mvt.Schema       `json:"schema,omitempty"`
+	Warnings     []mvt.Warning    `json:"warnings,omitempty"`
 }
 
 // Fetcher defines the interface for fetching tiles from remote servers
@@ -113,6 +121,25 @@ func (tc *TileCoordinate) String() string {
 	return fmt.Sprintf("%d/%d/%d", tc.Z, tc.X, tc.Y)
 }
 
+// Less reports whether the coordinate sorts before another, by zoom, then x, then y
+func (tc *TileCoordinate) Less(other *TileCoordinate) bool {
+	if tc.Z != other.Z {
+		return tc.Z < other.Z
+	}
+	if tc.X != other.X {
+		return tc.X < other.X
+	}
+	return tc.Y < other.Y
+}
+
+// SortProcessedTiles orders tiles by coordinate so merged output does not depend on
+// the order in which concurrent workers finished
+func SortProcessedTiles(tiles []*ProcessedTile) {
+	sort.SliceStable(tiles, func(i, j int) bool {
+		return tiles[i].Coordinate.Less(tiles[j].Coordinate)
+	})
+}
+
 // Count returns the total number of tiles in the range
 func (tr *TileRange) Count() int64 {
 	var total int64
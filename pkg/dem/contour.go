@@ -0,0 +1,400 @@
+// pkg/dem/contour.go - Contour lines and bands by marching squares
+package dem
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/planar"
+)
+
+// maxLevels bounds the contour levels generated for one tile, guarding against an
+// interval that is tiny relative to the tile's relief
+const maxLevels = 10000
+
+// Contour is the set of contour lines at one elevation, in pixel coordinates with y
+// pointing down
+type Contour struct {
+	Elevation float64
+	Lines     []orb.LineString
+}
+
+// Band is the area whose elevation lies in [Min, Max), in pixel coordinates with y
+// pointing down. The highest band has no upper ring and covers everything above Min.
+type Band struct {
+	Min     float64
+	Max     float64
+	Polygon orb.MultiPolygon
+}
+
+// Levels returns the contour elevations base + k*interval within [low, high]
+func Levels(low, high, interval, base float64) ([]float64, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("contour interval must be positive")
+	}
+
+	first := math.Ceil((low - base) / interval)
+	last := math.Floor((high - base) / interval)
+	if last-first+1 > maxLevels {
+		return nil, fmt.Errorf("contour interval %g yields more than %d levels", interval, maxLevels)
+	}
+
+	var levels []float64
+	for k := first; k <= last; k++ {
+		levels = append(levels, base+k*interval)
+	}
+	return levels, nil
+}
+
+// Contours traces the contour lines of the grid at each level. Lines run to the tile
+// edge, so contours of adjacent tiles meet. They are oriented with higher ground on
+// the left when viewed north up.
+func (g *Grid) Contours(interval, base float64) ([]Contour, error) {
+	low, high := g.Range()
+	levels, err := Levels(low, high, interval, base)
+	if err != nil {
+		return nil, err
+	}
+
+	f := newField(g, false)
+	var contours []Contour
+	for _, level := range levels {
+		var lines []orb.LineString
+		for _, chain := range f.trace(level) {
+			if line := orb.LineString(cleanChain(chain, false)); len(line) >= 2 {
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) > 0 {
+			contours = append(contours, Contour{Elevation: level, Lines: lines})
+		}
+	}
+	return contours, nil
+}
+
+// Bands builds the polygons between consecutive contour levels, covering the whole
+// tile. Exterior rings are counter-clockwise when viewed north up.
+func (g *Grid) Bands(interval, base float64) ([]Band, error) {
+	low, high := g.Range()
+	levels, err := Levels(low, high, interval, base)
+	if err != nil {
+		return nil, err
+	}
+	// The lowest band starts at or below the grid's minimum
+	if len(levels) == 0 || levels[0] > low {
+		start := base + math.Floor((low-base)/interval)*interval
+		levels = append([]float64{start}, levels...)
+	}
+
+	f := newField(g, true)
+	rings := make([][]orb.Ring, len(levels)+1)
+	for i, level := range levels {
+		rings[i] = f.rings(level)
+	}
+
+	var bands []Band
+	for i, level := range levels {
+		// The band is the area above its level less the area above the next level, so
+		// the next level's rings join it reversed
+		bandRings := append([]orb.Ring(nil), rings[i]...)
+		for _, ring := range rings[i+1] {
+			bandRings = append(bandRings, reverseRing(ring))
+		}
+		if polygon := assemblePolygons(bandRings); len(polygon) > 0 {
+			bands = append(bands, Band{Min: level, Max: level + interval, Polygon: polygon})
+		}
+	}
+	return bands, nil
+}
+
+// field is a sample lattice with explicit sample positions. The grid's pixel centres
+// are padded with a copy of the border samples placed on the tile edge, so contours
+// reach it, and optionally with a further ring of -Inf samples on the same edge, so
+// every contour closes along the tile boundary.
+type field struct {
+	width, height int
+	xs, ys        []float64
+	values        []float64
+}
+
+// newField pads a grid for contouring
+func newField(g *Grid, closed bool) *field {
+	pad := 1
+	if closed {
+		pad = 2
+	}
+
+	f := &field{
+		width:  g.Width + 2*pad,
+		height: g.Height + 2*pad,
+		xs:     samplePositions(g.Width, pad),
+		ys:     samplePositions(g.Height, pad),
+	}
+	f.values = make([]float64, f.width*f.height)
+	for r := 0; r < f.height; r++ {
+		for c := 0; c < f.width; c++ {
+			gc, gr := c-pad, r-pad
+			if closed && (c == 0 || r == 0 || c == f.width-1 || r == f.height-1) {
+				f.values[r*f.width+c] = math.Inf(-1)
+				continue
+			}
+			gc = min(max(gc, 0), g.Width-1)
+			gr = min(max(gr, 0), g.Height-1)
+			f.values[r*f.width+c] = g.At(gc, gr)
+		}
+	}
+	return f
+}
+
+// samplePositions returns pixel centre positions padded by samples on the edges
+func samplePositions(n, pad int) []float64 {
+	positions := make([]float64, 0, n+2*pad)
+	for i := 0; i < pad; i++ {
+		positions = append(positions, 0)
+	}
+	for i := 0; i < n; i++ {
+		positions = append(positions, float64(i)+0.5)
+	}
+	for i := 0; i < pad; i++ {
+		positions = append(positions, float64(n))
+	}
+	return positions
+}
+
+// value returns the sample at column c and row r
+func (f *field) value(c, r int) float64 {
+	return f.values[r*f.width+c]
+}
+
+// Edge identifiers: the horizontal edge from sample (c, r) to (c+1, r) and the
+// vertical edge from (c, r) to (c, r+1)
+func (f *field) horizontalEdge(c, r int) int { return (r*f.width + c) * 2 }
+func (f *field) verticalEdge(c, r int) int   { return (r*f.width+c)*2 + 1 }
+
+// crossing is a contour crossing of a cell edge. Walking the cell boundary clockwise
+// on screen, an up crossing goes from low to high ground and a down crossing from high
+// to low.
+type crossing struct {
+	edge int
+	up   bool
+}
+
+// segment is a contour piece within a cell, directed from an up crossing to a down
+// crossing so higher ground is always on its left on screen
+type segment struct {
+	from, to int
+}
+
+// trace returns the contour chains at a level. Chains that close are returned with
+// their first point repeated at the end.
+func (f *field) trace(level float64) [][]orb.Point {
+	var segments []segment
+	for r := 0; r < f.height-1; r++ {
+		for c := 0; c < f.width-1; c++ {
+			segments = append(segments, f.cellSegments(c, r, level)...)
+		}
+	}
+
+	// Each crossing starts at most one segment and ends at most one
+	next := make(map[int]int, len(segments))
+	ends := make(map[int]bool, len(segments))
+	for _, s := range segments {
+		next[s.from] = s.to
+		ends[s.to] = true
+	}
+
+	visited := make(map[int]bool, len(segments))
+	walk := func(start int) []orb.Point {
+		chain := []orb.Point{f.crossingPoint(start, level)}
+		for edge := start; !visited[edge]; {
+			visited[edge] = true
+			to, ok := next[edge]
+			if !ok {
+				break
+			}
+			chain = append(chain, f.crossingPoint(to, level))
+			edge = to
+		}
+		return chain
+	}
+
+	// Open chains start where no segment ends; the remaining segments form rings
+	var chains [][]orb.Point
+	for _, s := range segments {
+		if !ends[s.from] && !visited[s.from] {
+			chains = append(chains, walk(s.from))
+		}
+	}
+	for _, s := range segments {
+		if !visited[s.from] {
+			chains = append(chains, walk(s.from))
+		}
+	}
+	return chains
+}
+
+// cellSegments returns the contour segments of the cell whose top-left sample is
+// (c, r). Saddle cells are resolved by the average of the four corners.
+func (f *field) cellSegments(c, r int, level float64) []segment {
+	corners := [4]float64{f.value(c, r), f.value(c+1, r), f.value(c+1, r+1), f.value(c, r+1)}
+	edges := [4]int{f.horizontalEdge(c, r), f.verticalEdge(c+1, r), f.horizontalEdge(c, r+1), f.verticalEdge(c, r)}
+
+	var crossings []crossing
+	for i := 0; i < 4; i++ {
+		from, to := corners[i] >= level, corners[(i+1)%4] >= level
+		if from != to {
+			crossings = append(crossings, crossing{edge: edges[i], up: to})
+		}
+	}
+
+	switch len(crossings) {
+	case 2:
+		if crossings[0].up {
+			return []segment{{from: crossings[0].edge, to: crossings[1].edge}}
+		}
+		return []segment{{from: crossings[1].edge, to: crossings[0].edge}}
+	case 4:
+		// When the centre is high the high corners connect, so each down crossing
+		// pairs with the up crossing after it; otherwise with the one before it
+		centre := (corners[0] + corners[1] + corners[2] + corners[3]) / 4
+		step := 3
+		if centre >= level {
+			step = 1
+		}
+		var segments []segment
+		for i, cr := range crossings {
+			if !cr.up {
+				segments = append(segments, segment{from: crossings[(i+step)%4].edge, to: cr.edge})
+			}
+		}
+		return segments
+	default:
+		return nil
+	}
+}
+
+// crossingPoint interpolates where a contour level crosses an edge
+func (f *field) crossingPoint(edge int, level float64) orb.Point {
+	sample := edge / 2
+	c, r := sample%f.width, sample/f.width
+	c2, r2 := c+1, r
+	if edge%2 == 1 {
+		c2, r2 = c, r+1
+	}
+
+	a, b := f.value(c, r), f.value(c2, r2)
+	t := 0.5
+	if !math.IsInf(a, 0) && !math.IsInf(b, 0) && a != b {
+		t = (level - a) / (b - a)
+	}
+	return orb.Point{
+		f.xs[c] + t*(f.xs[c2]-f.xs[c]),
+		f.ys[r] + t*(f.ys[r2]-f.ys[r]),
+	}
+}
+
+// rings returns the closed contour rings at a level
+func (f *field) rings(level float64) []orb.Ring {
+	var rings []orb.Ring
+	for _, chain := range f.trace(level) {
+		ring := orb.Ring(cleanChain(chain, true))
+		if len(ring) >= 4 && ring.Closed() && signedArea(ring) != 0 {
+			rings = append(rings, ring)
+		}
+	}
+	return rings
+}
+
+// cleanChain removes repeated and collinear points, which the zero-width padding
+// cells produce along the tile edge
+func cleanChain(chain []orb.Point, closed bool) []orb.Point {
+	var result []orb.Point
+	for _, p := range chain {
+		if n := len(result); n > 0 && result[n-1] == p {
+			continue
+		}
+		for n := len(result); n >= 2 && collinear(result[n-2], result[n-1], p); n = len(result) {
+			result = result[:n-1]
+		}
+		result = append(result, p)
+	}
+
+	// Closing point: drop a collinear start vertex as well
+	if closed && len(result) >= 4 && result[0] == result[len(result)-1] {
+		if collinear(result[len(result)-2], result[0], result[1]) {
+			result = append(result[1:len(result)-1], result[1])
+		}
+	}
+	return result
+}
+
+// collinear reports whether b lies on the straight line from a to c
+func collinear(a, b, c orb.Point) bool {
+	return (b[0]-a[0])*(c[1]-a[1])-(b[1]-a[1])*(c[0]-a[0]) == 0
+}
+
+// signedArea returns a ring's shoelace area, negative for rings around higher ground
+// in y-down pixel coordinates
+func signedArea(ring orb.Ring) float64 {
+	var sum float64
+	for i := 0; i < len(ring)-1; i++ {
+		sum += ring[i][0]*ring[i+1][1] - ring[i+1][0]*ring[i][1]
+	}
+	return sum / 2
+}
+
+// reverseRing returns a ring with its orientation reversed
+func reverseRing(ring orb.Ring) orb.Ring {
+	reversed := make(orb.Ring, len(ring))
+	for i, p := range ring {
+		reversed[len(ring)-1-i] = p
+	}
+	return reversed
+}
+
+// assemblePolygons groups oriented, non-crossing rings into polygons: rings around
+// the area are exteriors, and each remaining ring is a hole of the smallest exterior
+// containing it
+func assemblePolygons(rings []orb.Ring) orb.MultiPolygon {
+	var polygons orb.MultiPolygon
+	var areas []float64
+	var holes []orb.Ring
+	for _, ring := range rings {
+		if area := signedArea(ring); area < 0 {
+			polygons = append(polygons, orb.Polygon{ring})
+			areas = append(areas, -area)
+		} else {
+			holes = append(holes, ring)
+		}
+	}
+
+	for _, hole := range holes {
+		probe := ringProbe(hole)
+		best := -1
+		for i, polygon := range polygons {
+			if (best < 0 || areas[i] < areas[best]) && planar.RingContains(polygon[0], probe) {
+				best = i
+			}
+		}
+		if best >= 0 {
+			polygons[best] = append(polygons[best], hole)
+		}
+	}
+	return polygons
+}
+
+// ringProbe returns a point inside a hole, just off the midpoint of its first edge,
+// so containment tests are not confused by rings touching at vertices
+func ringProbe(ring orb.Ring) orb.Point {
+	a, b := ring[0], ring[1]
+	mid := orb.Point{(a[0] + b[0]) / 2, (a[1] + b[1]) / 2}
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return mid
+	}
+	// Holes run with their interior on the right on screen
+	const offset = 1e-6
+	return orb.Point{mid[0] - dy/length*offset, mid[1] + dx/length*offset}
+}
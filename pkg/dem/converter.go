@@ -0,0 +1,265 @@
+// pkg/dem/converter.go - Raster DEM tile to JSON conversion
+package dem
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+// Output modes
+const (
+	OutputGrid     = "grid"     // Elevation grid as a JSON object
+	OutputPoints   = "points"   // Point features at pixel centres
+	OutputContours = "contours" // Contour line features
+	OutputPolygons = "polygons" // Elevation band polygon features
+)
+
+// Coordinate systems of the output, matching the vector tile converter
+const (
+	CoordSystemWebMercator = "web-mercator"
+	CoordSystemWGS84       = "wgs84"
+)
+
+// webMercatorMax is the half circumference of the Web Mercator plane in meters
+const webMercatorMax = 20037508.342789244
+
+// Options configures raster DEM conversion
+type Options struct {
+	Encoding         string  `json:"encoding"`          // "mapbox" or "terrarium"
+	Output           string  `json:"output"`            // "grid", "points", "contours" or "polygons"
+	Interval         float64 `json:"interval"`          // Contour interval in meters
+	Base             float64 `json:"base"`              // Elevation contour levels are offset from
+	SampleStep       int     `json:"sample_step"`       // Keep every Nth pixel for grid and point output
+	CoordinateSystem string  `json:"coordinate_system"` // "web-mercator" or "wgs84"
+}
+
+// Metadata describes a converted DEM tile
+type Metadata struct {
+	Encoding     string  `json:"encoding"`
+	Output       string  `json:"output"`
+	Width        int     `json:"width"`
+	Height       int     `json:"height"`
+	MinElevation float64 `json:"min_elevation"`
+	MaxElevation float64 `json:"max_elevation"`
+	FeatureCount int     `json:"feature_count"`
+}
+
+// Converter converts raster DEM tiles to elevation grids and GeoJSON
+type Converter struct {
+	options *Options
+}
+
+// DefaultOptions returns the default conversion options
+func DefaultOptions() *Options {
+	return &Options{
+		Encoding:         EncodingMapbox,
+		Output:           OutputGrid,
+		Interval:         10,
+		SampleStep:       1,
+		CoordinateSystem: CoordSystemWebMercator,
+	}
+}
+
+// ValidateOptions checks conversion options for invalid values
+func ValidateOptions(options *Options) error {
+	if _, err := elevationFunc(options.Encoding); err != nil {
+		return err
+	}
+
+	switch options.Output {
+	case OutputGrid, OutputPoints, OutputContours, OutputPolygons:
+	default:
+		return fmt.Errorf("unknown DEM output: %s, must be one of %s, %s, %s or %s",
+			options.Output, OutputGrid, OutputPoints, OutputContours, OutputPolygons)
+	}
+
+	if options.Interval <= 0 {
+		return fmt.Errorf("contour interval must be positive")
+	}
+	if options.SampleStep < 1 {
+		return fmt.Errorf("sample step must be at least 1")
+	}
+
+	switch options.CoordinateSystem {
+	case CoordSystemWebMercator, CoordSystemWGS84:
+	default:
+		return fmt.Errorf("unsupported coordinate system: %s", options.CoordinateSystem)
+	}
+
+	return nil
+}
+
+// NewConverter creates a converter with the given options
+func NewConverter(options *Options) (*Converter, error) {
+	if err := ValidateOptions(options); err != nil {
+		return nil, fmt.Errorf("invalid DEM options: %w", err)
+	}
+	return &Converter{options: options}, nil
+}
+
+// Convert decodes a DEM tile and converts it to the configured output. Grid output
+// is an "ElevationGrid" object; the other outputs are GeoJSON FeatureCollections.
+func (c *Converter) Convert(data []byte, z, x, y int) (map[string]interface{}, *Metadata, error) {
+	grid, err := Decode(data, c.options.Encoding)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.ConvertGrid(grid, z, x, y)
+}
+
+// ConvertGrid converts a decoded elevation grid of tile z/x/y
+func (c *Converter) ConvertGrid(grid *Grid, z, x, y int) (map[string]interface{}, *Metadata, error) {
+	low, high := grid.Range()
+	metadata := &Metadata{
+		Encoding:     c.options.Encoding,
+		Output:       c.options.Output,
+		Width:        grid.Width,
+		Height:       grid.Height,
+		MinElevation: low,
+		MaxElevation: high,
+	}
+	projection := &tileProjection{
+		z: z, x: x, y: y,
+		width:  grid.Width,
+		height: grid.Height,
+		wgs84:  c.options.CoordinateSystem == CoordSystemWGS84,
+	}
+
+	if c.options.Output == OutputGrid {
+		return c.gridOutput(grid, projection, metadata), metadata, nil
+	}
+
+	var features []*geojson.Feature
+	switch c.options.Output {
+	case OutputPoints:
+		step := c.options.SampleStep
+		for r := 0; r < grid.Height; r += step {
+			for col := 0; col < grid.Width; col += step {
+				feature := geojson.NewFeature(projection.point(orb.Point{float64(col) + 0.5, float64(r) + 0.5}))
+				feature.Properties["elevation"] = grid.At(col, r)
+				features = append(features, feature)
+			}
+		}
+	case OutputContours:
+		contours, err := grid.Contours(c.options.Interval, c.options.Base)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, contour := range contours {
+			for _, line := range contour.Lines {
+				feature := geojson.NewFeature(projection.geometry(line))
+				feature.Properties["elevation"] = contour.Elevation
+				features = append(features, feature)
+			}
+		}
+	case OutputPolygons:
+		bands, err := grid.Bands(c.options.Interval, c.options.Base)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, band := range bands {
+			var geometry orb.Geometry = band.Polygon
+			if len(band.Polygon) == 1 {
+				geometry = band.Polygon[0]
+			}
+			feature := geojson.NewFeature(projection.geometry(geometry))
+			feature.Properties["elevation_min"] = band.Min
+			feature.Properties["elevation_max"] = band.Max
+			features = append(features, feature)
+		}
+	}
+
+	if features == nil {
+		features = make([]*geojson.Feature, 0)
+	}
+	metadata.FeatureCount = len(features)
+
+	return map[string]interface{}{
+		"type":     "FeatureCollection",
+		"features": features,
+	}, metadata, nil
+}
+
+// gridOutput builds the elevation grid object, sampled every SampleStep pixels. Rows
+// run from north to south; bbox is [west, south, east, north] in output coordinates.
+func (c *Converter) gridOutput(grid *Grid, projection *tileProjection, metadata *Metadata) map[string]interface{} {
+	step := c.options.SampleStep
+	var rows [][]float64
+	for r := 0; r < grid.Height; r += step {
+		row := make([]float64, 0, (grid.Width+step-1)/step)
+		for col := 0; col < grid.Width; col += step {
+			row = append(row, grid.At(col, r))
+		}
+		rows = append(rows, row)
+	}
+
+	northWest := projection.point(orb.Point{0, 0})
+	southEast := projection.point(orb.Point{float64(grid.Width), float64(grid.Height)})
+	metadata.FeatureCount = 0
+
+	return map[string]interface{}{
+		"type":          "ElevationGrid",
+		"encoding":      c.options.Encoding,
+		"width":         len(rows[0]),
+		"height":        len(rows),
+		"sample_step":   step,
+		"bbox":          []float64{northWest[0], southEast[1], southEast[0], northWest[1]},
+		"min_elevation": metadata.MinElevation,
+		"max_elevation": metadata.MaxElevation,
+		"elevations":    rows,
+	}
+}
+
+// tileProjection maps pixel coordinates of a tile to output coordinates
+type tileProjection struct {
+	z, x, y       int
+	width, height int
+	wgs84         bool
+}
+
+// point projects a pixel position, with y pointing down, to Web Mercator meters or
+// longitude/latitude
+func (p *tileProjection) point(pixel orb.Point) orb.Point {
+	scale := math.Exp2(float64(p.z))
+	// Fractions of the world, from the north-west corner
+	fx := (float64(p.x) + pixel[0]/float64(p.width)) / scale
+	fy := (float64(p.y) + pixel[1]/float64(p.height)) / scale
+
+	if p.wgs84 {
+		lon := fx*360 - 180
+		lat := math.Atan(math.Sinh(math.Pi*(1-2*fy))) * 180 / math.Pi
+		return orb.Point{lon, lat}
+	}
+	return orb.Point{(2*fx - 1) * webMercatorMax, (1 - 2*fy) * webMercatorMax}
+}
+
+// geometry projects every vertex of a line or polygon geometry
+func (p *tileProjection) geometry(geometry orb.Geometry) orb.Geometry {
+	switch g := geometry.(type) {
+	case orb.LineString:
+		result := make(orb.LineString, len(g))
+		for i, pt := range g {
+			result[i] = p.point(pt)
+		}
+		return result
+	case orb.Ring:
+		return orb.Ring(p.geometry(orb.LineString(g)).(orb.LineString))
+	case orb.Polygon:
+		result := make(orb.Polygon, len(g))
+		for i, ring := range g {
+			result[i] = p.geometry(ring).(orb.Ring)
+		}
+		return result
+	case orb.MultiPolygon:
+		result := make(orb.MultiPolygon, len(g))
+		for i, polygon := range g {
+			result[i] = p.geometry(polygon).(orb.Polygon)
+		}
+		return result
+	default:
+		return geometry
+	}
+}
@@ -0,0 +1,93 @@
+// pkg/dem/dem.go - Raster DEM tile decoding
+package dem
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/png" // PNG tile decoding
+	"math"
+
+	_ "golang.org/x/image/webp" // WebP tile decoding
+)
+
+// Elevation encodings of raster DEM tiles
+const (
+	// EncodingMapbox is Mapbox Terrain-RGB: -10000 + (R*65536 + G*256 + B) * 0.1 meters
+	EncodingMapbox = "mapbox"
+	// EncodingTerrarium is Mapzen Terrarium: R*256 + G + B/256 - 32768 meters
+	EncodingTerrarium = "terrarium"
+)
+
+// Grid is a decoded elevation raster. Elevations are in meters, row-major from the
+// tile's north-west corner, one value per pixel centre.
+type Grid struct {
+	Width      int
+	Height     int
+	Elevations []float64
+}
+
+// At returns the elevation of the pixel at column c and row r
+func (g *Grid) At(c, r int) float64 {
+	return g.Elevations[r*g.Width+c]
+}
+
+// Range returns the lowest and highest elevation in the grid
+func (g *Grid) Range() (float64, float64) {
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, value := range g.Elevations {
+		low = math.Min(low, value)
+		high = math.Max(high, value)
+	}
+	return low, high
+}
+
+// Decode decodes a PNG or WebP DEM tile using the given elevation encoding
+func Decode(data []byte, encoding string) (*Grid, error) {
+	elevation, err := elevationFunc(encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode raster tile: %w", err)
+	}
+
+	bounds := img.Bounds()
+	grid := &Grid{
+		Width:      bounds.Dx(),
+		Height:     bounds.Dy(),
+		Elevations: make([]float64, bounds.Dx()*bounds.Dy()),
+	}
+	if grid.Width == 0 || grid.Height == 0 {
+		return nil, fmt.Errorf("raster tile is empty")
+	}
+
+	for r := 0; r < grid.Height; r++ {
+		for c := 0; c < grid.Width; c++ {
+			// RGBA returns 16-bit channels; DEM encodings use the 8-bit values
+			red, green, blue, _ := img.At(bounds.Min.X+c, bounds.Min.Y+r).RGBA()
+			grid.Elevations[r*grid.Width+c] = elevation(red>>8, green>>8, blue>>8)
+		}
+	}
+
+	return grid, nil
+}
+
+// elevationFunc returns the decoding function of an elevation encoding
+func elevationFunc(encoding string) (func(r, g, b uint32) float64, error) {
+	switch encoding {
+	case EncodingMapbox:
+		// Integer arithmetic first keeps the 0.1 m steps exact decimals
+		return func(r, g, b uint32) float64 {
+			return float64(int64(r)*65536+int64(g)*256+int64(b)-100000) / 10
+		}, nil
+	case EncodingTerrarium:
+		return func(r, g, b uint32) float64 {
+			return float64(r)*256 + float64(g) + float64(b)/256 - 32768
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown DEM encoding: %s, must be %s or %s", encoding, EncodingMapbox, EncodingTerrarium)
+	}
+}
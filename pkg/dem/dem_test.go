@@ -0,0 +1,291 @@
+// pkg/dem/dem_test.go - Unit tests for raster DEM decoding and contouring
+package dem
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+// encodeTile encodes a PNG tile from a per-pixel color function
+func encodeTile(t *testing.T, width, height int, pixel func(c, r int) color.RGBA) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for r := 0; r < height; r++ {
+		for c := 0; c < width; c++ {
+			img.SetRGBA(c, r, pixel(c, r))
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// peakGrid returns a grid rising linearly towards its centre
+func peakGrid(size int) *Grid {
+	grid := &Grid{Width: size, Height: size, Elevations: make([]float64, size*size)}
+	centre := float64(size-1) / 2
+	for r := 0; r < size; r++ {
+		for c := 0; c < size; c++ {
+			distance := math.Max(math.Abs(float64(c)-centre), math.Abs(float64(r)-centre))
+			grid.Elevations[r*size+c] = 100 - 10*distance
+		}
+	}
+	return grid
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding string
+		pixel    color.RGBA
+		want     float64
+	}{
+		{"mapbox sea level", EncodingMapbox, color.RGBA{1, 134, 160, 255}, 0},
+		{"mapbox summit", EncodingMapbox, color.RGBA{1, 217, 115, 255}, 2120.3},
+		{"terrarium sea level", EncodingTerrarium, color.RGBA{128, 0, 0, 255}, 0},
+		{"terrarium fraction", EncodingTerrarium, color.RGBA{128, 100, 128, 255}, 100.5},
+		{"terrarium below sea", EncodingTerrarium, color.RGBA{127, 246, 0, 255}, -10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := encodeTile(t, 2, 2, func(c, r int) color.RGBA { return tt.pixel })
+			grid, err := Decode(data, tt.encoding)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if grid.Width != 2 || grid.Height != 2 {
+				t.Fatalf("Expected a 2x2 grid, got %dx%d", grid.Width, grid.Height)
+			}
+			if got := grid.At(1, 1); got != tt.want {
+				t.Errorf("Expected elevation %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := Decode(encodeTile(t, 1, 1, func(c, r int) color.RGBA { return color.RGBA{} }), "srtm"); err == nil {
+		t.Error("Expected an error for an unknown encoding")
+	}
+	if _, err := Decode([]byte("not an image"), EncodingMapbox); err == nil {
+		t.Error("Expected an error for undecodable data")
+	}
+}
+
+func TestContours(t *testing.T) {
+	grid := peakGrid(7)
+
+	contours, err := grid.Contours(25, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Elevations run from 70 at the edge to 100 at the centre; the 100 m level only
+	// touches the summit pixel and yields no line
+	if len(contours) != 1 || contours[0].Elevation != 75 {
+		t.Fatalf("Unexpected contour levels: %+v", contours)
+	}
+
+	lines := contours[0].Lines
+	if len(lines) != 1 {
+		t.Fatalf("Expected one line at 75, got %d", len(lines))
+	}
+	ring := orb.Ring(lines[0])
+	if !ring.Closed() {
+		t.Errorf("Expected the contour around the peak to close")
+	}
+	// Higher ground on the left in y-down pixel coordinates
+	if area := signedArea(ring); area >= 0 {
+		t.Errorf("Expected the ring to run with the peak on its left, area %v", area)
+	}
+	for _, p := range ring {
+		if p[0] <= 0.5 || p[0] >= 6.5 || p[1] <= 0.5 || p[1] >= 6.5 {
+			t.Errorf("Contour point %v outside the pixel centres", p)
+		}
+	}
+}
+
+func TestContoursReachTileEdge(t *testing.T) {
+	// A slope rising to the east: the 15 m contour runs south across the whole tile,
+	// with higher ground on its left
+	grid := &Grid{Width: 4, Height: 3, Elevations: []float64{
+		0, 10, 20, 30,
+		0, 10, 20, 30,
+		0, 10, 20, 30,
+	}}
+
+	contours, err := grid.Contours(15, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(contours) != 2 || len(contours[0].Lines) != 1 {
+		t.Fatalf("Unexpected contours: %+v", contours)
+	}
+
+	want := orb.LineString{{2, 0}, {2, 3}}
+	if got := contours[0].Lines[0]; !got.Equal(want) {
+		t.Errorf("Expected line %v, got %v", want, got)
+	}
+}
+
+func TestBands(t *testing.T) {
+	grid := peakGrid(9)
+
+	bands, err := grid.Bands(15, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Elevations run from 60 to 100: bands from 60, 75 and 90
+	if len(bands) != 3 || bands[0].Min != 60 || bands[2].Min != 90 {
+		t.Fatalf("Unexpected bands: %+v", bands)
+	}
+
+	// The bands tile the whole 9x9 pixel tile without overlap
+	var total float64
+	for _, band := range bands {
+		for _, polygon := range band.Polygon {
+			for i, ring := range polygon {
+				area := signedArea(ring)
+				if (i == 0) != (area < 0) {
+					t.Errorf("Band %v: ring %d has the wrong orientation", band.Min, i)
+				}
+				total -= area
+			}
+		}
+	}
+	if math.Abs(total-81) > 1e-9 {
+		t.Errorf("Expected bands to cover 81 square pixels, got %v", total)
+	}
+
+	// The lowest band surrounds the middle one, leaving a hole
+	if len(bands[0].Polygon) != 1 || len(bands[0].Polygon[0]) != 2 {
+		t.Errorf("Expected the lowest band to be one polygon with a hole, got %v", bands[0].Polygon)
+	}
+}
+
+func TestConverterOutputs(t *testing.T) {
+	// Terrarium 0 m in the west half, 100 m in the east half: contours at 50 and 100,
+	// bands from 0, 50 and 100
+	data := encodeTile(t, 4, 4, func(c, r int) color.RGBA {
+		if c < 2 {
+			return color.RGBA{128, 0, 0, 255}
+		}
+		return color.RGBA{128, 100, 0, 255}
+	})
+
+	tests := []struct {
+		name     string
+		output   string
+		features int
+	}{
+		{"points", OutputPoints, 4},
+		{"contours", OutputContours, 2},
+		{"polygons", OutputPolygons, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := DefaultOptions()
+			options.Encoding = EncodingTerrarium
+			options.Output = tt.output
+			options.Interval = 50
+			options.SampleStep = 2
+			options.CoordinateSystem = CoordSystemWGS84
+
+			converter, err := NewConverter(options)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			result, metadata, err := converter.Convert(data, 0, 0, 0)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			features := result["features"].([]*geojson.Feature)
+			if len(features) != tt.features || metadata.FeatureCount != tt.features {
+				t.Fatalf("Expected %d features, got %d", tt.features, len(features))
+			}
+			if metadata.MinElevation != 0 || metadata.MaxElevation != 100 {
+				t.Errorf("Unexpected elevation range %v to %v", metadata.MinElevation, metadata.MaxElevation)
+			}
+
+			// The whole z0 tile spans longitudes -180 to 180
+			bound := features[0].Geometry.Bound()
+			if bound.Min[0] < -180 || bound.Max[0] > 180 {
+				t.Errorf("Geometry %v outside the world", bound)
+			}
+			if tt.output == OutputContours && bound.Min[0] != 0 {
+				t.Errorf("Expected the contour at the prime meridian, got %v", bound)
+			}
+		})
+	}
+}
+
+func TestConverterGrid(t *testing.T) {
+	data := encodeTile(t, 4, 4, func(c, r int) color.RGBA {
+		return color.RGBA{128, uint8(r*4 + c), 0, 255}
+	})
+
+	options := DefaultOptions()
+	options.Encoding = EncodingTerrarium
+	options.SampleStep = 2
+	converter, err := NewConverter(options)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	result, _, err := converter.Convert(data, 1, 1, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	elevations := result["elevations"].([][]float64)
+	want := [][]float64{{0, 2}, {8, 10}}
+	for r := range want {
+		for c := range want[r] {
+			if elevations[r][c] != want[r][c] {
+				t.Errorf("Elevation [%d][%d] = %v, want %v", r, c, elevations[r][c], want[r][c])
+			}
+		}
+	}
+
+	// Tile 1/1/0 is the north-east quarter of the Web Mercator plane
+	bbox := result["bbox"].([]float64)
+	if bbox[0] != 0 || bbox[1] != 0 || math.Abs(bbox[2]-webMercatorMax) > 1e-6 || math.Abs(bbox[3]-webMercatorMax) > 1e-6 {
+		t.Errorf("Unexpected bbox %v", bbox)
+	}
+}
+
+func TestValidateOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Options)
+		wantErr bool
+	}{
+		{"defaults", func(o *Options) {}, false},
+		{"unknown encoding", func(o *Options) { o.Encoding = "srtm" }, true},
+		{"unknown output", func(o *Options) { o.Output = "hillshade" }, true},
+		{"zero interval", func(o *Options) { o.Interval = 0 }, true},
+		{"zero sample step", func(o *Options) { o.SampleStep = 0 }, true},
+		{"unknown coordinate system", func(o *Options) { o.CoordinateSystem = "utm" }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := DefaultOptions()
+			tt.modify(options)
+			if err := ValidateOptions(options); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+// pkg/flatgeobuf/flatbuffers.go - Minimal FlatBuffers builder
+package flatgeobuf
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// builder builds a FlatBuffer back to front, as the FlatBuffers reference builders do:
+// children are written before the tables that refer to them, so references are always
+// forward. Offsets are measured from the end of the buffer.
+type builder struct {
+	buf      []byte // Data occupies buf[head:]
+	head     int
+	minAlign int
+
+	fields      []int // End offset of each field of the current table, 0 when absent
+	tableObject int   // Offset where the current table's fields start
+}
+
+// newBuilder creates a builder with an initial capacity
+func newBuilder(capacity int) *builder {
+	if capacity < 64 {
+		capacity = 64
+	}
+	return &builder{
+		buf:      make([]byte, capacity),
+		head:     capacity,
+		minAlign: 1,
+	}
+}
+
+// offset returns the number of bytes written so far
+func (b *builder) offset() int {
+	return len(b.buf) - b.head
+}
+
+// grow makes room for at least n more bytes in front of the data
+func (b *builder) grow(n int) {
+	if b.head >= n {
+		return
+	}
+	size := len(b.buf) * 2
+	for size-b.offset() < n {
+		size *= 2
+	}
+	buf := make([]byte, size)
+	copy(buf[size-b.offset():], b.buf[b.head:])
+	b.head = size - b.offset()
+	b.buf = buf
+}
+
+// prep pads so that a value of size bytes written after additional more bytes is
+// aligned to size
+func (b *builder) prep(size, additional int) {
+	if size > b.minAlign {
+		b.minAlign = size
+	}
+	pad := (^(b.offset() + additional) + 1) & (size - 1)
+	b.grow(pad + size + additional)
+	for i := 0; i < pad; i++ {
+		b.head--
+		b.buf[b.head] = 0
+	}
+}
+
+func (b *builder) placeUint8(v uint8) {
+	b.head--
+	b.buf[b.head] = v
+}
+
+func (b *builder) placeUint16(v uint16) {
+	b.head -= 2
+	binary.LittleEndian.PutUint16(b.buf[b.head:], v)
+}
+
+func (b *builder) placeUint32(v uint32) {
+	b.head -= 4
+	binary.LittleEndian.PutUint32(b.buf[b.head:], v)
+}
+
+func (b *builder) placeUint64(v uint64) {
+	b.head -= 8
+	binary.LittleEndian.PutUint64(b.buf[b.head:], v)
+}
+
+// placeReference writes a reference to an object at offset target, relative to the
+// reference's own position
+func (b *builder) placeReference(target int) {
+	b.placeUint32(uint32(b.offset() + 4 - target))
+}
+
+// createString writes a null-terminated string and returns its offset
+func (b *builder) createString(s string) int {
+	b.prep(4, len(s)+1)
+	b.placeUint8(0)
+	b.head -= len(s)
+	copy(b.buf[b.head:], s)
+	b.placeUint32(uint32(len(s)))
+	return b.offset()
+}
+
+// createBytes writes a byte vector and returns its offset
+func (b *builder) createBytes(data []byte) int {
+	b.prep(4, len(data))
+	b.head -= len(data)
+	copy(b.buf[b.head:], data)
+	b.placeUint32(uint32(len(data)))
+	return b.offset()
+}
+
+// createFloat64s writes a double vector and returns its offset
+func (b *builder) createFloat64s(values []float64) int {
+	b.prep(4, 8*len(values))
+	b.prep(8, 8*len(values))
+	for i := len(values) - 1; i >= 0; i-- {
+		b.placeUint64(math.Float64bits(values[i]))
+	}
+	b.placeUint32(uint32(len(values)))
+	return b.offset()
+}
+
+// createUint32s writes a uint vector and returns its offset
+func (b *builder) createUint32s(values []uint32) int {
+	b.prep(4, 4*len(values))
+	for i := len(values) - 1; i >= 0; i-- {
+		b.placeUint32(values[i])
+	}
+	b.placeUint32(uint32(len(values)))
+	return b.offset()
+}
+
+// createReferences writes a vector of table references and returns its offset
+func (b *builder) createReferences(targets []int) int {
+	b.prep(4, 4*len(targets))
+	for i := len(targets) - 1; i >= 0; i-- {
+		b.placeReference(targets[i])
+	}
+	b.placeUint32(uint32(len(targets)))
+	return b.offset()
+}
+
+// startTable begins a table with numFields field slots
+func (b *builder) startTable(numFields int) {
+	b.fields = make([]int, numFields)
+	b.tableObject = b.offset()
+}
+
+func (b *builder) addUint8(slot int, v uint8) {
+	b.prep(1, 0)
+	b.placeUint8(v)
+	b.fields[slot] = b.offset()
+}
+
+func (b *builder) addUint16(slot int, v uint16) {
+	b.prep(2, 0)
+	b.placeUint16(v)
+	b.fields[slot] = b.offset()
+}
+
+func (b *builder) addInt32(slot int, v int32) {
+	b.prep(4, 0)
+	b.placeUint32(uint32(v))
+	b.fields[slot] = b.offset()
+}
+
+func (b *builder) addUint64(slot int, v uint64) {
+	b.prep(8, 0)
+	b.placeUint64(v)
+	b.fields[slot] = b.offset()
+}
+
+// addReference adds a field referring to a string, vector or table at offset target
+func (b *builder) addReference(slot, target int) {
+	b.prep(4, 0)
+	b.placeReference(target)
+	b.fields[slot] = b.offset()
+}
+
+// endTable writes the table's vtable in front of it and returns the table's offset
+func (b *builder) endTable() int {
+	b.prep(4, 0)
+	b.placeUint32(0) // Placeholder for the vtable offset
+	table := b.offset()
+
+	used := len(b.fields)
+	for used > 0 && b.fields[used-1] == 0 {
+		used--
+	}
+
+	b.grow((used + 2) * 2)
+	for i := used - 1; i >= 0; i-- {
+		position := 0
+		if b.fields[i] != 0 {
+			position = table - b.fields[i]
+		}
+		b.placeUint16(uint16(position))
+	}
+	b.placeUint16(uint16(table - b.tableObject))
+	b.placeUint16(uint16((used + 2) * 2))
+
+	// The vtable precedes the table: vtable position = table position - soffset
+	vtable := b.offset()
+	binary.LittleEndian.PutUint32(b.buf[len(b.buf)-table:], uint32(int32(vtable-table)))
+
+	b.fields = nil
+	return table
+}
+
+// finish writes the root table reference and returns the finished buffer
+func (b *builder) finish(root int) []byte {
+	b.prep(b.minAlign, 4)
+	b.placeReference(root)
+	return b.buf[b.head:]
+}
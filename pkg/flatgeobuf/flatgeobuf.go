@@ -0,0 +1,335 @@
+// pkg/flatgeobuf/flatgeobuf.go - FlatGeobuf v3 header and feature encoding
+package flatgeobuf
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/paulmach/orb"
+	"github.com/valpere/tile_to_json/pkg/mvt"
+)
+
+// Magic identifies a FlatGeobuf v3 file
+var Magic = []byte{0x66, 0x67, 0x62, 0x03, 0x66, 0x67, 0x62, 0x00}
+
+// GeometryType is a FlatGeobuf geometry type
+type GeometryType uint8
+
+// Geometry types
+const (
+	GeometryUnknown            GeometryType = 0
+	GeometryPoint              GeometryType = 1
+	GeometryLineString         GeometryType = 2
+	GeometryPolygon            GeometryType = 3
+	GeometryMultiPoint         GeometryType = 4
+	GeometryMultiLineString    GeometryType = 5
+	GeometryMultiPolygon       GeometryType = 6
+	GeometryGeometryCollection GeometryType = 7
+)
+
+// ColumnType is a FlatGeobuf attribute column type
+type ColumnType uint8
+
+// Column types written by this package; the others of the schema are never inferred
+const (
+	ColumnBool   ColumnType = 2
+	ColumnLong   ColumnType = 7
+	ColumnULong  ColumnType = 8
+	ColumnDouble ColumnType = 10
+	ColumnString ColumnType = 11
+	ColumnJSON   ColumnType = 12
+)
+
+// Column describes an attribute column
+type Column struct {
+	Name string
+	Type ColumnType
+}
+
+// Header field slots of the FlatGeobuf schema
+const (
+	headerName          = 0
+	headerEnvelope      = 1
+	headerGeometryType  = 2
+	headerColumns       = 7
+	headerFeaturesCount = 8
+	headerIndexNodeSize = 9
+	headerCRS           = 10
+	headerFields        = 14
+
+	columnNameField = 0
+	columnTypeField = 1
+	columnFields    = 11
+
+	crsOrg    = 0
+	crsCode   = 1
+	crsFields = 6
+
+	geometryEnds   = 0
+	geometryXY     = 1
+	geometryType   = 6
+	geometryParts  = 7
+	geometryFields = 8
+
+	featureGeometry   = 0
+	featureProperties = 1
+	featureFields     = 3
+)
+
+// header holds the values written to the file header
+type header struct {
+	name          string
+	envelope      orb.Bound
+	hasEnvelope   bool
+	geometryType  GeometryType
+	columns       []Column
+	featuresCount uint64
+	indexNodeSize uint16
+	epsg          int
+}
+
+// encodeHeader encodes the header as a FlatBuffer
+func encodeHeader(h *header) []byte {
+	b := newBuilder(1024)
+
+	name := 0
+	if h.name != "" {
+		name = b.createString(h.name)
+	}
+
+	envelope := 0
+	if h.hasEnvelope {
+		envelope = b.createFloat64s([]float64{h.envelope.Min[0], h.envelope.Min[1], h.envelope.Max[0], h.envelope.Max[1]})
+	}
+
+	columns := 0
+	if len(h.columns) > 0 {
+		tables := make([]int, len(h.columns))
+		for i, column := range h.columns {
+			name := b.createString(column.Name)
+			b.startTable(columnFields)
+			b.addReference(columnNameField, name)
+			b.addUint8(columnTypeField, uint8(column.Type))
+			tables[i] = b.endTable()
+		}
+		columns = b.createReferences(tables)
+	}
+
+	crs := 0
+	if h.epsg != 0 {
+		org := b.createString("EPSG")
+		b.startTable(crsFields)
+		b.addReference(crsOrg, org)
+		b.addInt32(crsCode, int32(h.epsg))
+		crs = b.endTable()
+	}
+
+	b.startTable(headerFields)
+	b.addUint64(headerFeaturesCount, h.featuresCount)
+	if name != 0 {
+		b.addReference(headerName, name)
+	}
+	if envelope != 0 {
+		b.addReference(headerEnvelope, envelope)
+	}
+	if columns != 0 {
+		b.addReference(headerColumns, columns)
+	}
+	if crs != 0 {
+		b.addReference(headerCRS, crs)
+	}
+	b.addUint16(headerIndexNodeSize, h.indexNodeSize)
+	b.addUint8(headerGeometryType, uint8(h.geometryType))
+	return b.finish(b.endTable())
+}
+
+// encodeFeature encodes a feature as a FlatBuffer. The geometry type is written when
+// the header's type is unknown, so readers can tell the features apart.
+func encodeFeature(geometry orb.Geometry, properties []byte, withType bool) ([]byte, error) {
+	b := newBuilder(256 + len(properties))
+
+	geom := 0
+	if geometry != nil {
+		var err error
+		if geom, err = encodeGeometry(b, geometry, withType); err != nil {
+			return nil, err
+		}
+	}
+
+	props := 0
+	if len(properties) > 0 {
+		props = b.createBytes(properties)
+	}
+
+	b.startTable(featureFields)
+	if geom != 0 {
+		b.addReference(featureGeometry, geom)
+	}
+	if props != 0 {
+		b.addReference(featureProperties, props)
+	}
+	return b.finish(b.endTable()), nil
+}
+
+// encodeGeometry writes a geometry table and returns its offset. Multi-polygons and
+// collections are written as parts; other geometries as flat coordinates with the
+// end index of each line or ring when there are several.
+func encodeGeometry(b *builder, geometry orb.Geometry, withType bool) (int, error) {
+	var xy []float64
+	var ends []uint32
+	var parts []int
+
+	appendPoints := func(points []orb.Point) {
+		for _, p := range points {
+			xy = append(xy, p[0], p[1])
+		}
+	}
+
+	switch g := geometry.(type) {
+	case orb.Point:
+		xy = []float64{g[0], g[1]}
+	case orb.MultiPoint:
+		appendPoints(g)
+	case orb.LineString:
+		appendPoints(g)
+	case orb.MultiLineString:
+		for _, line := range g {
+			appendPoints(line)
+			ends = append(ends, uint32(len(xy)/2))
+		}
+	case orb.Polygon:
+		for _, ring := range g {
+			appendPoints(ring)
+			ends = append(ends, uint32(len(xy)/2))
+		}
+	case orb.MultiPolygon:
+		for _, polygon := range g {
+			part, err := encodeGeometry(b, polygon, true)
+			if err != nil {
+				return 0, err
+			}
+			parts = append(parts, part)
+		}
+	case orb.Collection:
+		for _, child := range g {
+			part, err := encodeGeometry(b, child, true)
+			if err != nil {
+				return 0, err
+			}
+			parts = append(parts, part)
+		}
+	default:
+		return 0, fmt.Errorf("unsupported geometry type %T", geometry)
+	}
+
+	// A single line or ring needs no ends
+	if len(ends) == 1 {
+		ends = nil
+	}
+
+	endsVector, xyVector, partsVector := 0, 0, 0
+	if len(ends) > 0 {
+		endsVector = b.createUint32s(ends)
+	}
+	if len(xy) > 0 {
+		xyVector = b.createFloat64s(xy)
+	}
+	if len(parts) > 0 {
+		partsVector = b.createReferences(parts)
+	}
+
+	b.startTable(geometryFields)
+	if endsVector != 0 {
+		b.addReference(geometryEnds, endsVector)
+	}
+	if xyVector != 0 {
+		b.addReference(geometryXY, xyVector)
+	}
+	if partsVector != 0 {
+		b.addReference(geometryParts, partsVector)
+	}
+	if withType {
+		b.addUint8(geometryType, uint8(typeOf(geometry)))
+	}
+	return b.endTable(), nil
+}
+
+// typeOf returns the FlatGeobuf type of a geometry
+func typeOf(geometry orb.Geometry) GeometryType {
+	switch geometry.(type) {
+	case orb.Point:
+		return GeometryPoint
+	case orb.MultiPoint:
+		return GeometryMultiPoint
+	case orb.LineString:
+		return GeometryLineString
+	case orb.MultiLineString:
+		return GeometryMultiLineString
+	case orb.Polygon:
+		return GeometryPolygon
+	case orb.MultiPolygon:
+		return GeometryMultiPolygon
+	case orb.Collection:
+		return GeometryGeometryCollection
+	default:
+		return GeometryUnknown
+	}
+}
+
+// columnType infers a column type from the kinds of value found in it (see
+// mvt.ValueKind). Integers within the int64 range are longs and larger unsigned ones
+// unsigned longs; other mixed numbers widen to doubles and any other mixture is
+// written as strings.
+func columnType(kinds uint8) ColumnType {
+	switch kinds {
+	case mvt.KindBool:
+		return ColumnBool
+	case mvt.KindInt, mvt.KindUint, mvt.KindInt | mvt.KindUint:
+		return ColumnLong
+	case mvt.KindLargeUint:
+		return ColumnULong
+	case mvt.KindString:
+		return ColumnString
+	case mvt.KindJSON:
+		return ColumnJSON
+	}
+	if kinds&^(mvt.KindInt|mvt.KindUint|mvt.KindLargeUint|mvt.KindFloat|mvt.KindDouble) == 0 {
+		return ColumnDouble
+	}
+	return ColumnString
+}
+
+// appendProperty appends a column index and a value converted to the column's type
+func appendProperty(buf []byte, index uint16, columnType ColumnType, value interface{}) ([]byte, error) {
+	buf = binary.LittleEndian.AppendUint16(buf, index)
+
+	switch columnType {
+	case ColumnBool:
+		if value.(bool) {
+			return append(buf, 1), nil
+		}
+		return append(buf, 0), nil
+	case ColumnLong:
+		return binary.LittleEndian.AppendUint64(buf, uint64(mvt.ToInt64(value))), nil
+	case ColumnULong:
+		return binary.LittleEndian.AppendUint64(buf, mvt.ToUint64(value)), nil
+	case ColumnDouble:
+		return binary.LittleEndian.AppendUint64(buf, math.Float64bits(mvt.ToFloat64(value))), nil
+	case ColumnJSON:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode JSON property: %w", err)
+		}
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(data)))
+		return append(buf, data...), nil
+	default:
+		text, err := mvt.ToString(value)
+		if err != nil {
+			return nil, err
+		}
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(text)))
+		return append(buf, text...), nil
+	}
+}
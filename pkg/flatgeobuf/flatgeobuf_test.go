@@ -0,0 +1,535 @@
+// pkg/flatgeobuf/flatgeobuf_test.go - Unit tests for the FlatGeobuf writer
+package flatgeobuf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/valpere/tile_to_json/pkg/mvt"
+)
+
+// table reads the fields of a FlatBuffer table
+type table struct {
+	buf []byte
+	pos int
+}
+
+func rootTable(buf []byte) table {
+	return table{buf: buf, pos: int(binary.LittleEndian.Uint32(buf))}
+}
+
+// field returns the position of a field slot, or 0 when it is absent
+func (t table) field(slot int) int {
+	vtable := t.pos - int(int32(binary.LittleEndian.Uint32(t.buf[t.pos:])))
+	if 4+2*slot >= int(binary.LittleEndian.Uint16(t.buf[vtable:])) {
+		return 0
+	}
+	if offset := int(binary.LittleEndian.Uint16(t.buf[vtable+4+2*slot:])); offset != 0 {
+		return t.pos + offset
+	}
+	return 0
+}
+
+func (t table) uint8(slot int, def uint8) uint8 {
+	if p := t.field(slot); p != 0 {
+		return t.buf[p]
+	}
+	return def
+}
+
+func (t table) uint16(slot int, def uint16) uint16 {
+	if p := t.field(slot); p != 0 {
+		return binary.LittleEndian.Uint16(t.buf[p:])
+	}
+	return def
+}
+
+func (t table) int32(slot int) int32 {
+	if p := t.field(slot); p != 0 {
+		return int32(binary.LittleEndian.Uint32(t.buf[p:]))
+	}
+	return 0
+}
+
+func (t table) uint64(slot int) uint64 {
+	if p := t.field(slot); p != 0 {
+		return binary.LittleEndian.Uint64(t.buf[p:])
+	}
+	return 0
+}
+
+// vector returns the position of a vector's first element and its length
+func (t table) vector(slot int) (int, int) {
+	p := t.field(slot)
+	if p == 0 {
+		return 0, 0
+	}
+	p += int(binary.LittleEndian.Uint32(t.buf[p:]))
+	return p + 4, int(binary.LittleEndian.Uint32(t.buf[p:]))
+}
+
+func (t table) string(slot int) string {
+	p, n := t.vector(slot)
+	return string(t.buf[p : p+n])
+}
+
+func (t table) bytes(slot int) []byte {
+	p, n := t.vector(slot)
+	return t.buf[p : p+n]
+}
+
+func (t table) float64s(slot int) []float64 {
+	p, n := t.vector(slot)
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = math.Float64frombits(binary.LittleEndian.Uint64(t.buf[p+8*i:]))
+	}
+	return values
+}
+
+func (t table) uint32s(slot int) []uint32 {
+	p, n := t.vector(slot)
+	values := make([]uint32, n)
+	for i := range values {
+		values[i] = binary.LittleEndian.Uint32(t.buf[p+4*i:])
+	}
+	return values
+}
+
+// child returns a table referred to by a field
+func (t table) child(slot int) table {
+	p := t.field(slot)
+	return table{buf: t.buf, pos: p + int(binary.LittleEndian.Uint32(t.buf[p:]))}
+}
+
+func (t table) tables(slot int) []table {
+	p, n := t.vector(slot)
+	tables := make([]table, n)
+	for i := range tables {
+		element := p + 4*i
+		tables[i] = table{buf: t.buf, pos: element + int(binary.LittleEndian.Uint32(t.buf[element:]))}
+	}
+	return tables
+}
+
+// file is a decoded FlatGeobuf file
+type file struct {
+	header   table
+	index    []node
+	features []table
+	offsets  []uint64 // Byte offset of each feature in the feature section
+}
+
+func readFile(t *testing.T, data []byte) *file {
+	t.Helper()
+
+	if !bytes.Equal(data[:8], Magic) {
+		t.Fatalf("Expected magic bytes, got %v", data[:8])
+	}
+	headerSize := int(binary.LittleEndian.Uint32(data[8:]))
+	f := &file{header: rootTable(data[12 : 12+headerSize])}
+	pos := 12 + headerSize
+
+	count := int(f.header.uint64(headerFeaturesCount))
+	if nodeSize := int(f.header.uint16(headerIndexNodeSize, DefaultIndexNodeSize)); nodeSize > 0 && count > 0 {
+		for i := 0; i < indexSize(count, nodeSize)/nodeLength; i++ {
+			n := node{offset: binary.LittleEndian.Uint64(data[pos+32:])}
+			for j := 0; j < 4; j++ {
+				v := math.Float64frombits(binary.LittleEndian.Uint64(data[pos+8*j:]))
+				if j < 2 {
+					n.bound.Min[j] = v
+				} else {
+					n.bound.Max[j-2] = v
+				}
+			}
+			f.index = append(f.index, n)
+			pos += nodeLength
+		}
+	}
+
+	start := pos
+	for pos < len(data) {
+		size := int(binary.LittleEndian.Uint32(data[pos:]))
+		f.offsets = append(f.offsets, uint64(pos-start))
+		f.features = append(f.features, rootTable(data[pos+4:pos+4+size]))
+		pos += 4 + size
+	}
+	if len(f.features) != count {
+		t.Fatalf("Expected %d features, found %d", count, len(f.features))
+	}
+	return f
+}
+
+// decodeGeometry decodes a geometry table, whose type is given by the header unless
+// the table has its own
+func decodeGeometry(g table, headerType GeometryType) orb.Geometry {
+	kind := GeometryType(g.uint8(geometryType, uint8(headerType)))
+
+	xy := g.float64s(geometryXY)
+	points := func(from, to int) []orb.Point {
+		ps := make([]orb.Point, 0, to-from)
+		for i := from; i < to; i++ {
+			ps = append(ps, orb.Point{xy[2*i], xy[2*i+1]})
+		}
+		return ps
+	}
+	rings := func() [][]orb.Point {
+		ends := g.uint32s(geometryEnds)
+		if len(ends) == 0 {
+			ends = []uint32{uint32(len(xy) / 2)}
+		}
+		var rs [][]orb.Point
+		from := 0
+		for _, end := range ends {
+			rs = append(rs, points(from, int(end)))
+			from = int(end)
+		}
+		return rs
+	}
+
+	switch kind {
+	case GeometryPoint:
+		return orb.Point{xy[0], xy[1]}
+	case GeometryMultiPoint:
+		return orb.MultiPoint(points(0, len(xy)/2))
+	case GeometryLineString:
+		return orb.LineString(points(0, len(xy)/2))
+	case GeometryMultiLineString:
+		var mls orb.MultiLineString
+		for _, r := range rings() {
+			mls = append(mls, r)
+		}
+		return mls
+	case GeometryPolygon:
+		var polygon orb.Polygon
+		for _, r := range rings() {
+			polygon = append(polygon, r)
+		}
+		return polygon
+	case GeometryMultiPolygon:
+		var mp orb.MultiPolygon
+		for _, part := range g.tables(geometryParts) {
+			mp = append(mp, decodeGeometry(part, GeometryPolygon).(orb.Polygon))
+		}
+		return mp
+	}
+	return nil
+}
+
+// decodeProperties decodes a feature's properties against the header columns
+func decodeProperties(data []byte, columns []Column) map[string]interface{} {
+	properties := make(map[string]interface{})
+	for len(data) > 0 {
+		column := columns[binary.LittleEndian.Uint16(data)]
+		data = data[2:]
+		switch column.Type {
+		case ColumnBool:
+			properties[column.Name] = data[0] == 1
+			data = data[1:]
+		case ColumnLong:
+			properties[column.Name] = int64(binary.LittleEndian.Uint64(data))
+			data = data[8:]
+		case ColumnULong:
+			properties[column.Name] = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case ColumnDouble:
+			properties[column.Name] = math.Float64frombits(binary.LittleEndian.Uint64(data))
+			data = data[8:]
+		default:
+			n := binary.LittleEndian.Uint32(data)
+			properties[column.Name] = string(data[4 : 4+n])
+			data = data[4+n:]
+		}
+	}
+	return properties
+}
+
+func (f *file) columns() []Column {
+	var columns []Column
+	for _, c := range f.header.tables(headerColumns) {
+		columns = append(columns, Column{Name: c.string(columnNameField), Type: ColumnType(c.uint8(columnTypeField, 0))})
+	}
+	return columns
+}
+
+func writeFile(t *testing.T, options Options, features []*geojson.Feature) []byte {
+	t.Helper()
+
+	w, err := NewWriter(options)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer w.Close()
+
+	for _, feature := range features {
+		if err := w.Write(feature); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	n, err := w.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("WriteTo() = %d, wrote %d bytes", n, buf.Len())
+	}
+	return buf.Bytes()
+}
+
+func newFeature(geometry orb.Geometry, properties map[string]interface{}) *geojson.Feature {
+	feature := geojson.NewFeature(geometry)
+	for key, value := range properties {
+		feature.Properties[key] = value
+	}
+	return feature
+}
+
+func TestColumnType(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []interface{}
+		want   ColumnType
+	}{
+		{"bool", []interface{}{true, false}, ColumnBool},
+		{"int", []interface{}{int64(1), 2, uint32(3)}, ColumnLong},
+		{"large uint", []interface{}{uint64(math.MaxUint64)}, ColumnULong},
+		{"float", []interface{}{1.5}, ColumnDouble},
+		{"mixed numbers", []interface{}{int64(1), 2.5}, ColumnDouble},
+		{"string", []interface{}{"a"}, ColumnString},
+		{"json", []interface{}{map[string]interface{}{"a": 1}, []interface{}{1}}, ColumnJSON},
+		{"mixed", []interface{}{"a", int64(1)}, ColumnString},
+		{"nulls ignored", []interface{}{nil, true}, ColumnBool},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var kinds uint8
+			for _, value := range tt.values {
+				if kind, ok := mvt.ValueKind(value); ok {
+					kinds |= kind
+				}
+			}
+			if got := columnType(kinds); got != tt.want {
+				t.Errorf("columnType() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLevelBounds(t *testing.T) {
+	tests := []struct {
+		items, nodeSize int
+		want            [][2]int
+	}{
+		{1, 16, [][2]int{{1, 2}, {0, 1}}},
+		{16, 16, [][2]int{{1, 17}, {0, 1}}},
+		{17, 16, [][2]int{{3, 20}, {1, 3}, {0, 1}}},
+		{5, 2, [][2]int{{6, 11}, {3, 6}, {1, 3}, {0, 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d/%d", tt.items, tt.nodeSize), func(t *testing.T) {
+			got := levelBounds(tt.items, tt.nodeSize)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("levelBounds() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHilbert(t *testing.T) {
+	// The first cells of the curve, and its end in the other corner on the x axis
+	tests := []struct {
+		x, y uint32
+		want uint32
+	}{
+		{0, 0, 0},
+		{1, 0, 1},
+		{1, 1, 2},
+		{0, 1, 3},
+		{0xFFFF, 0, 0xFFFFFFFF},
+	}
+
+	for _, tt := range tests {
+		if got := hilbert(tt.x, tt.y); got != tt.want {
+			t.Errorf("hilbert(%d, %d) = %d, want %d", tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	features := []*geojson.Feature{
+		newFeature(orb.Point{1, 2}, map[string]interface{}{"name": "a", "rank": int64(1), "open": true}),
+		newFeature(orb.LineString{{0, 0}, {3, 4}}, map[string]interface{}{"name": "b", "rank": 2.5}),
+		newFeature(orb.Polygon{
+			{{0, 0}, {4, 0}, {4, 4}, {0, 0}},
+			{{1, 1}, {2, 1}, {2, 2}, {1, 1}},
+		}, map[string]interface{}{"tags": map[string]interface{}{"k": "v"}}),
+		newFeature(orb.MultiPolygon{
+			{{{5, 5}, {6, 5}, {6, 6}, {5, 5}}},
+			{{{7, 7}, {8, 7}, {8, 8}, {7, 7}}},
+		}, nil),
+		newFeature(orb.MultiLineString{{{0, 1}, {1, 1}}, {{2, 2}, {3, 3}}}, map[string]interface{}{"name": nil}),
+		geojson.NewFeature(nil),
+	}
+
+	tests := []struct {
+		name     string
+		nodeSize int
+	}{
+		{"indexed", DefaultIndexNodeSize},
+		{"small nodes", 2},
+		{"no index", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := writeFile(t, Options{Name: "test", IndexNodeSize: tt.nodeSize, EPSG: 4326}, features)
+			f := readFile(t, data)
+
+			if got := f.header.string(headerName); got != "test" {
+				t.Errorf("Expected name test, got %q", got)
+			}
+			if got := GeometryType(f.header.uint8(headerGeometryType, 0)); got != GeometryUnknown {
+				t.Errorf("Expected unknown geometry type, got %d", got)
+			}
+			if got := f.header.float64s(headerEnvelope); fmt.Sprint(got) != "[0 0 8 8]" {
+				t.Errorf("Expected envelope [0 0 8 8], got %v", got)
+			}
+			if got := int(f.header.uint16(headerIndexNodeSize, DefaultIndexNodeSize)); got != tt.nodeSize {
+				t.Errorf("Expected index node size %d, got %d", tt.nodeSize, got)
+			}
+			if crs := f.header.child(headerCRS); crs.int32(crsCode) != 4326 || crs.string(crsOrg) != "EPSG" {
+				t.Errorf("Expected EPSG:4326, got %s:%d", crs.string(crsOrg), crs.int32(crsCode))
+			}
+
+			columns := f.columns()
+			wantColumns := []Column{{"name", ColumnString}, {"open", ColumnBool}, {"rank", ColumnDouble}, {"tags", ColumnJSON}}
+			if fmt.Sprint(columns) != fmt.Sprint(wantColumns) {
+				t.Fatalf("Expected columns %v, got %v", wantColumns, columns)
+			}
+
+			// The feature without geometry is skipped
+			if len(f.features) != 5 {
+				t.Fatalf("Expected 5 features, got %d", len(f.features))
+			}
+
+			found := make(map[string]bool)
+			for _, feature := range f.features {
+				g := decodeGeometry(feature.child(featureGeometry), GeometryUnknown)
+				properties := decodeProperties(feature.bytes(featureProperties), columns)
+				found[fmt.Sprintf("%v %v", g, properties)] = true
+			}
+
+			for _, want := range []string{
+				"[1 2] map[name:a open:true rank:1]",
+				"[[0 0] [3 4]] map[name:b rank:2.5]",
+				"[[[0 0] [4 0] [4 4] [0 0]] [[1 1] [2 1] [2 2] [1 1]]] map[tags:{\"k\":\"v\"}]",
+				"[[[[5 5] [6 5] [6 6] [5 5]]] [[[7 7] [8 7] [8 8] [7 7]]]] map[]",
+				"[[[0 1] [1 1]] [[2 2] [3 3]]] map[]",
+			} {
+				if !found[want] {
+					t.Errorf("Expected feature %s, got %v", want, found)
+				}
+			}
+		})
+	}
+}
+
+func TestWriterIndex(t *testing.T) {
+	var features []*geojson.Feature
+	for x := 0; x < 10; x++ {
+		for y := 0; y < 10; y++ {
+			features = append(features, newFeature(orb.Point{float64(x), float64(y)}, map[string]interface{}{"x": x, "y": y}))
+		}
+	}
+
+	data := writeFile(t, Options{IndexNodeSize: 4}, features)
+	f := readFile(t, data)
+
+	if got := GeometryType(f.header.uint8(headerGeometryType, 0)); got != GeometryPoint {
+		t.Errorf("Expected point geometry type, got %d", got)
+	}
+
+	levels := levelBounds(len(features), 4)
+	leafStart := levels[0][0]
+	featureAt := make(map[uint64]int)
+	for i, offset := range f.offsets {
+		featureAt[offset] = i
+	}
+
+	// Every leaf addresses a feature, in feature order
+	for i, leaf := range f.index[leafStart:] {
+		if leaf.offset != f.offsets[i] {
+			t.Fatalf("Leaf %d offset = %d, want %d", i, leaf.offset, f.offsets[i])
+		}
+	}
+
+	// Search the tree for the features in a box
+	search := orb.Bound{Min: orb.Point{2.5, 2.5}, Max: orb.Point{4.5, 3.5}}
+	var hits []int
+	queue := []int{0}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		if !f.index[n].bound.Intersects(search) {
+			continue
+		}
+		if n >= leafStart {
+			hits = append(hits, featureAt[f.index[n].offset])
+			continue
+		}
+
+		level := 0
+		for i, bounds := range levels {
+			if n >= bounds[0] && n < bounds[1] {
+				level = i - 1
+			}
+		}
+		for child := int(f.index[n].offset); child < int(f.index[n].offset)+4 && child < levels[level][1]; child++ {
+			queue = append(queue, child)
+		}
+	}
+
+	columns := f.columns()
+	found := make(map[string]bool)
+	for _, hit := range hits {
+		found[fmt.Sprint(decodeProperties(f.features[hit].bytes(featureProperties), columns))] = true
+	}
+	want := []string{"map[x:3 y:3]", "map[x:4 y:3]"}
+	if len(found) != len(want) {
+		t.Errorf("Expected %d hits, got %v", len(want), found)
+	}
+	for _, w := range want {
+		if !found[w] {
+			t.Errorf("Expected hit %s, got %v", w, found)
+		}
+	}
+}
+
+func TestWriterEmpty(t *testing.T) {
+	data := writeFile(t, Options{IndexNodeSize: DefaultIndexNodeSize}, nil)
+	f := readFile(t, data)
+
+	if got := f.header.uint16(headerIndexNodeSize, DefaultIndexNodeSize); got != 0 {
+		t.Errorf("Expected no index, got node size %d", got)
+	}
+	if f.header.field(headerEnvelope) != 0 {
+		t.Error("Expected no envelope")
+	}
+}
+
+func TestNewWriterInvalidNodeSize(t *testing.T) {
+	for _, size := range []int{-1, 1, math.MaxUint16 + 1} {
+		if _, err := NewWriter(Options{IndexNodeSize: size}); err == nil {
+			t.Errorf("Expected error for node size %d", size)
+		}
+	}
+}
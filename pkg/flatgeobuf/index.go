@@ -0,0 +1,154 @@
+// pkg/flatgeobuf/index.go - Packed Hilbert R-tree spatial index
+package flatgeobuf
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+
+	"github.com/paulmach/orb"
+)
+
+// DefaultIndexNodeSize is the number of children per index node used by most writers
+const DefaultIndexNodeSize = 16
+
+// nodeLength is the serialized size of an index node: four doubles and an offset
+const nodeLength = 40
+
+// node is an entry of the packed R-tree. A leaf's offset is the byte offset of its
+// feature in the feature section; a parent's is the index of its first child.
+type node struct {
+	bound  orb.Bound
+	offset uint64
+}
+
+// levelBounds returns the [start, end) node range of each tree level, leaves first.
+// The root is node 0 and the leaves are at the end of the array.
+func levelBounds(numItems, nodeSize int) [][2]int {
+	n := numItems
+	numNodes := n
+	levelNumNodes := []int{n}
+	for {
+		n = (n + nodeSize - 1) / nodeSize
+		numNodes += n
+		levelNumNodes = append(levelNumNodes, n)
+		if n == 1 {
+			break
+		}
+	}
+
+	bounds := make([][2]int, len(levelNumNodes))
+	offset := numNodes
+	for i, size := range levelNumNodes {
+		offset -= size
+		bounds[i] = [2]int{offset, offset + size}
+	}
+	return bounds
+}
+
+// indexSize returns the serialized size of the index for numItems features
+func indexSize(numItems, nodeSize int) int {
+	if numItems == 0 || nodeSize == 0 {
+		return 0
+	}
+	return levelBounds(numItems, nodeSize)[0][1] * nodeLength
+}
+
+// buildIndex builds the packed R-tree over leaves, which must already be in Hilbert order
+func buildIndex(leaves []node, nodeSize int) []node {
+	levels := levelBounds(len(leaves), nodeSize)
+	nodes := make([]node, levels[0][1])
+	copy(nodes[levels[0][0]:], leaves)
+
+	for i := 0; i < len(levels)-1; i++ {
+		parent := levels[i+1][0]
+		for child := levels[i][0]; child < levels[i][1]; child += nodeSize {
+			bound := nodes[child].bound
+			for j := child + 1; j < child+nodeSize && j < levels[i][1]; j++ {
+				bound = bound.Union(nodes[j].bound)
+			}
+			nodes[parent] = node{bound: bound, offset: uint64(child)}
+			parent++
+		}
+	}
+	return nodes
+}
+
+// encodeIndex serializes the index nodes
+func encodeIndex(nodes []node) []byte {
+	buf := make([]byte, 0, len(nodes)*nodeLength)
+	for _, n := range nodes {
+		for _, v := range []float64{n.bound.Min[0], n.bound.Min[1], n.bound.Max[0], n.bound.Max[1]} {
+			buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(v))
+		}
+		buf = binary.LittleEndian.AppendUint64(buf, n.offset)
+	}
+	return buf
+}
+
+// hilbertSort orders features by the Hilbert value of their bounds' centres within
+// extent
+func hilbertSort(features []*spooledFeature, extent orb.Bound) {
+	width := extent.Max[0] - extent.Min[0]
+	height := extent.Max[1] - extent.Min[1]
+
+	for _, feature := range features {
+		var x, y uint32
+		if width > 0 {
+			x = uint32(math.Floor(0xFFFF * (feature.bound.Center()[0] - extent.Min[0]) / width))
+		}
+		if height > 0 {
+			y = uint32(math.Floor(0xFFFF * (feature.bound.Center()[1] - extent.Min[1]) / height))
+		}
+		feature.hilbert = hilbert(x, y)
+	}
+
+	sort.SliceStable(features, func(i, j int) bool { return features[i].hilbert < features[j].hilbert })
+}
+
+// hilbert returns the position of 16-bit coordinates along a Hilbert curve, using
+// the bit-parallel method of the reference FlatGeobuf and flatbush implementations
+func hilbert(x, y uint32) uint32 {
+	a := x ^ y
+	b := 0xFFFF ^ a
+	c := 0xFFFF ^ (x | y)
+	d := x & (y ^ 0xFFFF)
+
+	A := a | (b >> 1)
+	B := (a >> 1) ^ a
+	C := ((c >> 1) ^ (b & (d >> 1))) ^ c
+	D := ((a & (c >> 1)) ^ (d >> 1)) ^ d
+
+	a, b, c, d = A, B, C, D
+	A = (a & (a >> 2)) ^ (b & (b >> 2))
+	B = (a & (b >> 2)) ^ (b & ((a ^ b) >> 2))
+	C ^= (a & (c >> 2)) ^ (b & (d >> 2))
+	D ^= (b & (c >> 2)) ^ ((a ^ b) & (d >> 2))
+
+	a, b, c, d = A, B, C, D
+	A = (a & (a >> 4)) ^ (b & (b >> 4))
+	B = (a & (b >> 4)) ^ (b & ((a ^ b) >> 4))
+	C ^= (a & (c >> 4)) ^ (b & (d >> 4))
+	D ^= (b & (c >> 4)) ^ ((a ^ b) & (d >> 4))
+
+	a, b, c, d = A, B, C, D
+	C ^= (a & (c >> 8)) ^ (b & (d >> 8))
+	D ^= (b & (c >> 8)) ^ ((a ^ b) & (d >> 8))
+
+	a = C ^ (C >> 1)
+	b = D ^ (D >> 1)
+
+	i0 := x ^ y
+	i1 := b | (0xFFFF ^ (i0 | a))
+
+	return (interleave(i1) << 1) | interleave(i0)
+}
+
+// interleave spreads the low 16 bits of v to the even bits
+func interleave(v uint32) uint32 {
+	v = (v | (v << 8)) & 0x00FF00FF
+	v = (v | (v << 4)) & 0x0F0F0F0F
+	v = (v | (v << 2)) & 0x33333333
+	v = (v | (v << 1)) & 0x55555555
+	return v
+}
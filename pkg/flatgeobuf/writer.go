@@ -0,0 +1,408 @@
+// pkg/flatgeobuf/writer.go - FlatGeobuf file writer
+package flatgeobuf
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/wkb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/valpere/tile_to_json/pkg/mvt"
+)
+
+// Options configures a FlatGeobuf file
+type Options struct {
+	Name          string // Dataset name written to the header
+	IndexNodeSize int    // Children per index node; 0 writes no spatial index
+	EPSG          int    // EPSG code of the coordinates; 0 writes no CRS
+}
+
+// Writer builds a FlatGeobuf file from features written in any order. The column schema
+// is inferred from all features, and the index needs every feature's bounds, so features
+// are spooled to a temporary file and the file is assembled by WriteTo. Features without
+// a geometry cannot be indexed and are skipped. Writer is safe for concurrent use.
+type Writer struct {
+	options      Options
+	spool        *os.File
+	spoolBuffer  *bufio.Writer
+	spoolLength  uint64
+	features     []*spooledFeature
+	kinds        map[string]uint8
+	geometryType GeometryType
+	extent       orb.Bound
+	mutex        sync.Mutex
+}
+
+// spooledFeature locates a feature's record in the spool file
+type spooledFeature struct {
+	bound   orb.Bound
+	offset  uint64
+	length  uint32
+	hilbert uint32
+}
+
+// NewWriter creates a writer
+func NewWriter(options Options) (*Writer, error) {
+	if options.IndexNodeSize == 1 || options.IndexNodeSize < 0 || options.IndexNodeSize > math.MaxUint16 {
+		return nil, fmt.Errorf("invalid index node size %d", options.IndexNodeSize)
+	}
+
+	spool, err := os.CreateTemp("", "flatgeobuf-*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spool file: %w", err)
+	}
+
+	return &Writer{
+		options:     options,
+		spool:       spool,
+		spoolBuffer: bufio.NewWriter(spool),
+		kinds:       make(map[string]uint8),
+	}, nil
+}
+
+// Write adds a feature to the file
+func (w *Writer) Write(feature *geojson.Feature) error {
+	if feature == nil || feature.Geometry == nil {
+		return nil
+	}
+
+	geometry, err := wkb.Marshal(feature.Geometry)
+	if err != nil {
+		return fmt.Errorf("failed to encode geometry: %w", err)
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.spool == nil {
+		return fmt.Errorf("writer is closed")
+	}
+
+	record := binary.AppendUvarint(nil, uint64(len(geometry)))
+	record = append(record, geometry...)
+	record, err = w.appendProperties(record, feature.Properties)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.spoolBuffer.Write(record); err != nil {
+		return fmt.Errorf("failed to spool feature: %w", err)
+	}
+
+	bound := feature.Geometry.Bound()
+	w.features = append(w.features, &spooledFeature{
+		bound:  bound,
+		offset: w.spoolLength,
+		length: uint32(len(record)),
+	})
+	w.spoolLength += uint64(len(record))
+
+	featureType := typeOf(feature.Geometry)
+	switch {
+	case len(w.features) == 1:
+		w.geometryType = featureType
+		w.extent = bound
+	case w.geometryType != featureType:
+		w.geometryType = GeometryUnknown
+		fallthrough
+	default:
+		w.extent = w.extent.Union(bound)
+	}
+	return nil
+}
+
+// FeatureCount returns the number of features written so far
+func (w *Writer) FeatureCount() int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return len(w.features)
+}
+
+// Columns returns the column schema inferred from the features written so far, sorted
+// by name
+func (w *Writer) Columns() []Column {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.columns()
+}
+
+func (w *Writer) columns() []Column {
+	columns := make([]Column, 0, len(w.kinds))
+	for name, kinds := range w.kinds {
+		columns = append(columns, Column{Name: name, Type: columnType(kinds)})
+	}
+	sort.Slice(columns, func(i, j int) bool { return columns[i].Name < columns[j].Name })
+	return columns
+}
+
+// WriteTo assembles the file and writes it to out. The features are written in Hilbert
+// order when the file has a spatial index.
+func (w *Writer) WriteTo(out io.Writer) (int64, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.spool == nil {
+		return 0, fmt.Errorf("writer is closed")
+	}
+	if err := w.spoolBuffer.Flush(); err != nil {
+		return 0, fmt.Errorf("failed to flush spool file: %w", err)
+	}
+
+	columns := w.columns()
+	columnIndex := make(map[string]int, len(columns))
+	for i, column := range columns {
+		columnIndex[column.Name] = i
+	}
+
+	indexNodeSize := w.options.IndexNodeSize
+	if len(w.features) == 0 {
+		indexNodeSize = 0
+	}
+
+	features := make([]*spooledFeature, len(w.features))
+	copy(features, w.features)
+	if indexNodeSize > 0 {
+		hilbertSort(features, w.extent)
+	}
+
+	// Encode the features to a second spool in their final order, so the index can be
+	// built from their offsets before any of them is written
+	encoded, err := os.CreateTemp("", "flatgeobuf-*.tmp")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create spool file: %w", err)
+	}
+	defer func() {
+		encoded.Close()
+		os.Remove(encoded.Name())
+	}()
+
+	encodedBuffer := bufio.NewWriter(encoded)
+	leaves := make([]node, len(features))
+	var featuresLength uint64
+	for i, feature := range features {
+		data, err := w.encodeSpooled(feature, columns, columnIndex)
+		if err != nil {
+			return 0, err
+		}
+
+		leaves[i] = node{bound: feature.bound, offset: featuresLength}
+		var size [4]byte
+		binary.LittleEndian.PutUint32(size[:], uint32(len(data)))
+		if _, err := encodedBuffer.Write(size[:]); err != nil {
+			return 0, fmt.Errorf("failed to spool feature: %w", err)
+		}
+		if _, err := encodedBuffer.Write(data); err != nil {
+			return 0, fmt.Errorf("failed to spool feature: %w", err)
+		}
+		featuresLength += uint64(len(data)) + 4
+	}
+	if err := encodedBuffer.Flush(); err != nil {
+		return 0, fmt.Errorf("failed to flush spool file: %w", err)
+	}
+
+	h := &header{
+		name:          w.options.Name,
+		envelope:      w.extent,
+		hasEnvelope:   len(features) > 0,
+		geometryType:  w.geometryType,
+		columns:       columns,
+		featuresCount: uint64(len(features)),
+		indexNodeSize: uint16(indexNodeSize),
+		epsg:          w.options.EPSG,
+	}
+	headerData := encodeHeader(h)
+
+	var headerSize [4]byte
+	binary.LittleEndian.PutUint32(headerSize[:], uint32(len(headerData)))
+
+	sections := [][]byte{Magic, headerSize[:], headerData}
+	if indexNodeSize > 0 {
+		sections = append(sections, encodeIndex(buildIndex(leaves, indexNodeSize)))
+	}
+
+	var written int64
+	for _, section := range sections {
+		n, err := out.Write(section)
+		written += int64(n)
+		if err != nil {
+			return written, fmt.Errorf("failed to write file: %w", err)
+		}
+	}
+
+	n, err := io.Copy(out, io.NewSectionReader(encoded, 0, int64(featuresLength)))
+	written += n
+	if err != nil {
+		return written, fmt.Errorf("failed to write features: %w", err)
+	}
+	return written, nil
+}
+
+// Close removes the spool file
+func (w *Writer) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.spool == nil {
+		return nil
+	}
+	err := w.spool.Close()
+	os.Remove(w.spool.Name())
+	w.spool = nil
+	return err
+}
+
+// Spooled property values carry their payload by kind (see mvt.ValueKind): bool one
+// byte, integers eight bytes, floats eight bytes of IEEE 754, string and JSON a length
+// and bytes.
+
+// appendProperties appends a feature's non-null properties to a spool record and
+// records their kinds for schema inference
+func (w *Writer) appendProperties(record []byte, properties geojson.Properties) ([]byte, error) {
+	names := make([]string, 0, len(properties))
+	for name, value := range properties {
+		if _, ok := mvt.ValueKind(value); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	record = binary.AppendUvarint(record, uint64(len(names)))
+	for _, name := range names {
+		value := properties[name]
+		kind, _ := mvt.ValueKind(value)
+		w.kinds[name] |= kind
+
+		record = binary.AppendUvarint(record, uint64(len(name)))
+		record = append(record, name...)
+		record = append(record, kind)
+
+		switch kind {
+		case mvt.KindBool:
+			if value.(bool) {
+				record = append(record, 1)
+			} else {
+				record = append(record, 0)
+			}
+		case mvt.KindInt, mvt.KindUint:
+			record = binary.LittleEndian.AppendUint64(record, uint64(mvt.ToInt64(value)))
+		case mvt.KindLargeUint:
+			record = binary.LittleEndian.AppendUint64(record, mvt.ToUint64(value))
+		case mvt.KindFloat, mvt.KindDouble:
+			record = binary.LittleEndian.AppendUint64(record, math.Float64bits(mvt.ToFloat64(value)))
+		case mvt.KindString:
+			text := value.(string)
+			record = binary.AppendUvarint(record, uint64(len(text)))
+			record = append(record, text...)
+		default:
+			data, err := json.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode property %s: %w", name, err)
+			}
+			record = binary.AppendUvarint(record, uint64(len(data)))
+			record = append(record, data...)
+		}
+	}
+	return record, nil
+}
+
+// encodeSpooled reads a spooled feature and encodes it against the final schema
+func (w *Writer) encodeSpooled(feature *spooledFeature, columns []Column, columnIndex map[string]int) ([]byte, error) {
+	record := make([]byte, feature.length)
+	if _, err := w.spool.ReadAt(record, int64(feature.offset)); err != nil {
+		return nil, fmt.Errorf("failed to read spooled feature: %w", err)
+	}
+	r := &recordReader{data: record}
+
+	geometry, err := wkb.Unmarshal(r.bytes(r.uvarint()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode spooled geometry: %w", err)
+	}
+
+	var properties []byte
+	count := r.uvarint()
+	for i := uint64(0); i < count; i++ {
+		name := string(r.bytes(r.uvarint()))
+		kind := r.byte()
+
+		var value interface{}
+		switch kind {
+		case mvt.KindBool:
+			value = r.byte() == 1
+		case mvt.KindInt, mvt.KindUint:
+			value = int64(r.uint64())
+		case mvt.KindLargeUint:
+			value = r.uint64()
+		case mvt.KindFloat, mvt.KindDouble:
+			value = math.Float64frombits(r.uint64())
+		case mvt.KindString:
+			value = string(r.bytes(r.uvarint()))
+		default:
+			value = json.RawMessage(r.bytes(r.uvarint()))
+		}
+		if r.err != nil {
+			return nil, fmt.Errorf("failed to decode spooled properties: %w", r.err)
+		}
+
+		index := columnIndex[name]
+		if properties, err = appendProperty(properties, uint16(index), columns[index].Type, value); err != nil {
+			return nil, fmt.Errorf("failed to encode property %s: %w", name, err)
+		}
+	}
+
+	return encodeFeature(geometry, properties, w.geometryType == GeometryUnknown)
+}
+
+// recordReader reads the fields of a spool record, remembering the first error
+type recordReader struct {
+	data []byte
+	err  error
+}
+
+func (r *recordReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.fail()
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *recordReader) bytes(n uint64) []byte {
+	if uint64(len(r.data)) < n {
+		r.fail()
+		return nil
+	}
+	v := r.data[:n]
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *recordReader) byte() byte {
+	if v := r.bytes(1); v != nil {
+		return v[0]
+	}
+	return 0
+}
+
+func (r *recordReader) uint64() uint64 {
+	if v := r.bytes(8); v != nil {
+		return binary.LittleEndian.Uint64(v)
+	}
+	return 0
+}
+
+func (r *recordReader) fail() {
+	if r.err == nil {
+		r.err = io.ErrUnexpectedEOF
+	}
+	r.data = nil
+}
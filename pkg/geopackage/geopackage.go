@@ -0,0 +1,376 @@
+// pkg/geopackage/geopackage.go - GeoPackage schema, geometry encoding and column types
+package geopackage
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/wkb"
+	"github.com/valpere/tile_to_json/pkg/mvt"
+)
+
+// ApplicationID and UserVersion identify a GeoPackage 1.4 database in its SQLite header
+const (
+	ApplicationID = 0x47504B47 // "GPKG"
+	UserVersion   = 10400
+)
+
+// GeometryColumn and FIDColumn are the geometry and primary key columns of every
+// feature table
+const (
+	GeometryColumn = "geom"
+	FIDColumn      = "fid"
+)
+
+// DefaultTable is the table of features without a layer name
+const DefaultTable = "features"
+
+// rtreeExtension is the definition URL of the R-tree spatial index extension
+const rtreeExtension = "http://www.geopackage.org/spec/#extension_rtree"
+
+// coreSchema creates the metadata tables every GeoPackage with features requires
+var coreSchema = []string{
+	`CREATE TABLE gpkg_spatial_ref_sys (
+  srs_name TEXT NOT NULL,
+  srs_id INTEGER PRIMARY KEY,
+  organization TEXT NOT NULL,
+  organization_coordsys_id INTEGER NOT NULL,
+  definition TEXT NOT NULL,
+  description TEXT
+)`,
+	`CREATE TABLE gpkg_contents (
+  table_name TEXT NOT NULL PRIMARY KEY,
+  data_type TEXT NOT NULL,
+  identifier TEXT UNIQUE,
+  description TEXT DEFAULT '',
+  last_change DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+  min_x DOUBLE,
+  min_y DOUBLE,
+  max_x DOUBLE,
+  max_y DOUBLE,
+  srs_id INTEGER,
+  CONSTRAINT fk_gc_r_srs_id FOREIGN KEY (srs_id) REFERENCES gpkg_spatial_ref_sys(srs_id)
+)`,
+	`CREATE TABLE gpkg_geometry_columns (
+  table_name TEXT NOT NULL,
+  column_name TEXT NOT NULL,
+  geometry_type_name TEXT NOT NULL,
+  srs_id INTEGER NOT NULL,
+  z TINYINT NOT NULL,
+  m TINYINT NOT NULL,
+  CONSTRAINT pk_geom_cols PRIMARY KEY (table_name, column_name),
+  CONSTRAINT uk_gc_table_name UNIQUE (table_name),
+  CONSTRAINT fk_gc_tn FOREIGN KEY (table_name) REFERENCES gpkg_contents(table_name),
+  CONSTRAINT fk_gc_srs FOREIGN KEY (srs_id) REFERENCES gpkg_spatial_ref_sys (srs_id)
+)`,
+	`CREATE TABLE gpkg_extensions (
+  table_name TEXT,
+  column_name TEXT,
+  extension_name TEXT NOT NULL,
+  definition TEXT NOT NULL,
+  scope TEXT NOT NULL,
+  CONSTRAINT ge_tce UNIQUE (table_name, column_name, extension_name)
+)`,
+}
+
+// spatialRefSys is a row of gpkg_spatial_ref_sys
+type spatialRefSys struct {
+	name         string
+	id           int
+	organization string
+	code         int
+	definition   string
+	description  string
+}
+
+const wgs84Definition = `GEOGCS["WGS 84",DATUM["WGS_1984",SPHEROID["WGS 84",6378137,298.257223563,AUTHORITY["EPSG","7030"]],AUTHORITY["EPSG","6326"]],` +
+	`PRIMEM["Greenwich",0,AUTHORITY["EPSG","8901"]],UNIT["degree",0.0174532925199433,AUTHORITY["EPSG","9122"]],` +
+	`AXIS["Latitude",NORTH],AXIS["Longitude",EAST],AUTHORITY["EPSG","4326"]]`
+
+const pseudoMercatorDefinition = `PROJCS["WGS 84 / Pseudo-Mercator",GEOGCS["WGS 84",DATUM["WGS_1984",SPHEROID["WGS 84",6378137,298.257223563,AUTHORITY["EPSG","7030"]],AUTHORITY["EPSG","6326"]],` +
+	`PRIMEM["Greenwich",0,AUTHORITY["EPSG","8901"]],UNIT["degree",0.0174532925199433,AUTHORITY["EPSG","9122"]],AUTHORITY["EPSG","4326"]],` +
+	`PROJECTION["Mercator_1SP"],PARAMETER["central_meridian",0],PARAMETER["scale_factor",1],PARAMETER["false_easting",0],PARAMETER["false_northing",0],` +
+	`UNIT["metre",1,AUTHORITY["EPSG","9001"]],AXIS["Easting",EAST],AXIS["Northing",NORTH],` +
+	`EXTENSION["PROJ4","+proj=merc +a=6378137 +b=6378137 +lat_ts=0 +lon_0=0 +x_0=0 +y_0=0 +k=1 +units=m +nadgrids=@null +wktext +no_defs"],` +
+	`AUTHORITY["EPSG","3857"]]`
+
+// requiredSpatialRefSys are the entries every GeoPackage must define
+var requiredSpatialRefSys = []spatialRefSys{
+	{"Undefined cartesian SRS", -1, "NONE", -1, "undefined", "undefined cartesian coordinate reference system"},
+	{"Undefined geographic SRS", 0, "NONE", 0, "undefined", "undefined geographic coordinate reference system"},
+	{"WGS 84 geodetic", 4326, "EPSG", 4326, wgs84Definition, "longitude/latitude coordinates in decimal degrees on the WGS 84 spheroid"},
+}
+
+// pseudoMercatorSpatialRefSys is added for Web Mercator output
+var pseudoMercatorSpatialRefSys = spatialRefSys{
+	"WGS 84 / Pseudo-Mercator", 3857, "EPSG", 3857, pseudoMercatorDefinition, "Web Mercator coordinates in meters",
+}
+
+// srsID returns the gpkg_spatial_ref_sys ID of an EPSG code; 0 is the undefined
+// cartesian SRS
+func srsID(epsg int) (int, error) {
+	switch epsg {
+	case 0:
+		return -1, nil
+	case 4326, 3857:
+		return epsg, nil
+	}
+	return 0, fmt.Errorf("unsupported CRS EPSG:%d", epsg)
+}
+
+// rtreeTriggers returns the statements keeping a table's R-tree index in sync with
+// later edits, as defined by the GeoPackage 1.4 R-tree extension. They call the
+// ST_* functions that GeoPackage readers such as GDAL register.
+func rtreeTriggers(table string) []string {
+	t := quoteIdentifier(table)
+	r := quoteIdentifier("rtree_" + table + "_" + GeometryColumn)
+	trigger := func(suffix string) string {
+		return quoteIdentifier("rtree_" + table + "_" + GeometryColumn + "_" + suffix)
+	}
+	bounds := `ST_MinX(NEW.geom), ST_MaxX(NEW.geom), ST_MinY(NEW.geom), ST_MaxY(NEW.geom)`
+
+	return []string{
+		`CREATE TRIGGER ` + trigger("insert") + ` AFTER INSERT ON ` + t + `
+  WHEN (NEW.geom NOT NULL AND NOT ST_IsEmpty(NEW.geom))
+BEGIN
+  INSERT OR REPLACE INTO ` + r + ` VALUES (NEW.fid, ` + bounds + `);
+END`,
+		`CREATE TRIGGER ` + trigger("update6") + ` AFTER UPDATE OF geom ON ` + t + `
+  WHEN OLD.fid = NEW.fid AND
+       (NEW.geom NOTNULL AND NOT ST_IsEmpty(NEW.geom)) AND
+       (OLD.geom NOTNULL AND NOT ST_IsEmpty(OLD.geom))
+BEGIN
+  UPDATE ` + r + ` SET
+    minx = ST_MinX(NEW.geom), maxx = ST_MaxX(NEW.geom),
+    miny = ST_MinY(NEW.geom), maxy = ST_MaxY(NEW.geom)
+  WHERE id = NEW.fid;
+END`,
+		`CREATE TRIGGER ` + trigger("update7") + ` AFTER UPDATE OF geom ON ` + t + `
+  WHEN OLD.fid = NEW.fid AND
+       (NEW.geom NOTNULL AND NOT ST_IsEmpty(NEW.geom)) AND
+       (OLD.geom ISNULL OR ST_IsEmpty(OLD.geom))
+BEGIN
+  INSERT INTO ` + r + ` VALUES (NEW.fid, ` + bounds + `);
+END`,
+		`CREATE TRIGGER ` + trigger("update2") + ` AFTER UPDATE OF geom ON ` + t + `
+  WHEN OLD.fid = NEW.fid AND
+       (NEW.geom ISNULL OR ST_IsEmpty(NEW.geom))
+BEGIN
+  DELETE FROM ` + r + ` WHERE id = OLD.fid;
+END`,
+		`CREATE TRIGGER ` + trigger("update5") + ` AFTER UPDATE ON ` + t + `
+  WHEN OLD.fid != NEW.fid AND
+       (NEW.geom NOTNULL AND NOT ST_IsEmpty(NEW.geom))
+BEGIN
+  DELETE FROM ` + r + ` WHERE id = OLD.fid;
+  INSERT OR REPLACE INTO ` + r + ` VALUES (NEW.fid, ` + bounds + `);
+END`,
+		`CREATE TRIGGER ` + trigger("update4") + ` AFTER UPDATE ON ` + t + `
+  WHEN OLD.fid != NEW.fid AND
+       (NEW.geom ISNULL OR ST_IsEmpty(NEW.geom))
+BEGIN
+  DELETE FROM ` + r + ` WHERE id IN (OLD.fid, NEW.fid);
+END`,
+		`CREATE TRIGGER ` + trigger("delete") + ` AFTER DELETE ON ` + t + `
+  WHEN OLD.geom NOT NULL
+BEGIN
+  DELETE FROM ` + r + ` WHERE id = OLD.fid;
+END`,
+	}
+}
+
+// quoteIdentifier quotes an SQL identifier
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// reservedPrefixes start the names of tables the writer or SQLite create itself
+var reservedPrefixes = []string{"gpkg_", "sqlite_", "rtree_", "spool_"}
+
+// uniqueName returns name, or name with a numeric suffix when it is already used.
+// SQLite compares identifiers case-insensitively, so used holds lower-cased names.
+// With reserved set, names with a reserved table prefix get a leading underscore.
+func uniqueName(name string, used map[string]bool, reserved bool) string {
+	if reserved {
+		for _, prefix := range reservedPrefixes {
+			if strings.HasPrefix(strings.ToLower(name), prefix) {
+				name = "_" + name
+				break
+			}
+		}
+	}
+
+	unique := name
+	for i := 2; used[strings.ToLower(unique)]; i++ {
+		unique = name + "_" + strconv.Itoa(i)
+	}
+	used[strings.ToLower(unique)] = true
+	return unique
+}
+
+// Geometry blob header flags
+const (
+	flagLittleEndian = 1
+	flagEnvelopeXY   = 1 << 1
+	flagEmpty        = 1 << 4
+)
+
+// encodeGeometry encodes a GeoPackage geometry blob: the "GP" header with the SRS ID
+// and, except for points, the geometry's envelope, followed by standard WKB
+func encodeGeometry(geometry orb.Geometry, srsID int) ([]byte, error) {
+	data, err := wkb.Marshal(geometry, binary.LittleEndian)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := byte(flagLittleEndian)
+	_, point := geometry.(orb.Point)
+	empty := isEmpty(geometry)
+	switch {
+	case empty:
+		flags |= flagEmpty
+	case !point:
+		flags |= flagEnvelopeXY
+	}
+
+	blob := make([]byte, 0, 8+32+len(data))
+	blob = append(blob, 'G', 'P', 0, flags)
+	blob = binary.LittleEndian.AppendUint32(blob, uint32(int32(srsID)))
+	if flags&flagEnvelopeXY != 0 {
+		bound := geometry.Bound()
+		for _, v := range []float64{bound.Min[0], bound.Max[0], bound.Min[1], bound.Max[1]} {
+			blob = binary.LittleEndian.AppendUint64(blob, math.Float64bits(v))
+		}
+	}
+	return append(blob, data...), nil
+}
+
+// isEmpty reports whether a geometry has no coordinates
+func isEmpty(geometry orb.Geometry) bool {
+	switch g := geometry.(type) {
+	case orb.Point:
+		return false
+	case orb.MultiPoint:
+		return len(g) == 0
+	case orb.LineString:
+		return len(g) == 0
+	case orb.MultiLineString:
+		for _, line := range g {
+			if len(line) > 0 {
+				return false
+			}
+		}
+		return true
+	case orb.Ring:
+		return len(g) == 0
+	case orb.Polygon:
+		return len(g) == 0 || len(g[0]) == 0
+	case orb.MultiPolygon:
+		for _, polygon := range g {
+			if !isEmpty(polygon) {
+				return false
+			}
+		}
+		return true
+	case orb.Collection:
+		for _, member := range g {
+			if !isEmpty(member) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// geometryTypeName returns the GeoPackage name of a geometry's type
+func geometryTypeName(geometry orb.Geometry) string {
+	switch geometry.(type) {
+	case orb.Point:
+		return "POINT"
+	case orb.MultiPoint:
+		return "MULTIPOINT"
+	case orb.LineString:
+		return "LINESTRING"
+	case orb.MultiLineString:
+		return "MULTILINESTRING"
+	case orb.Polygon, orb.Ring:
+		return "POLYGON"
+	case orb.MultiPolygon:
+		return "MULTIPOLYGON"
+	case orb.Collection:
+		return "GEOMETRYCOLLECTION"
+	default:
+		return "GEOMETRY"
+	}
+}
+
+// ColumnType is the declared type of a feature table column
+type ColumnType string
+
+// Column types
+const (
+	ColumnBoolean ColumnType = "BOOLEAN"
+	ColumnInteger ColumnType = "INTEGER"
+	ColumnFloat   ColumnType = "FLOAT"
+	ColumnDouble  ColumnType = "DOUBLE"
+	ColumnText    ColumnType = "TEXT"
+)
+
+// Column describes a property column of a feature table
+type Column struct {
+	Name string
+	Type ColumnType
+}
+
+// columnType infers a column type from the kinds of value found in it (see
+// mvt.ValueKind). Mixed numbers widen to doubles; unsigned integers beyond the int64
+// range SQLite integers hold and any other mixture are written as text.
+func columnType(kinds uint8) ColumnType {
+	switch kinds {
+	case mvt.KindBool:
+		return ColumnBoolean
+	case mvt.KindInt, mvt.KindUint, mvt.KindInt | mvt.KindUint:
+		return ColumnInteger
+	case mvt.KindFloat:
+		return ColumnFloat
+	}
+	if kinds&^(mvt.KindInt|mvt.KindUint|mvt.KindFloat|mvt.KindDouble) == 0 {
+		return ColumnDouble
+	}
+	return ColumnText
+}
+
+// spoolValue converts a property value of a kind to the value stored while spooling.
+// Spool columns have no type affinity, so SQLite keeps each value's storage class until
+// the feature table's declared types convert them.
+func spoolValue(value interface{}, kind uint8) (interface{}, error) {
+	switch kind {
+	case mvt.KindBool:
+		if value.(bool) {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case mvt.KindInt, mvt.KindUint:
+		return mvt.ToInt64(value), nil
+	case mvt.KindLargeUint:
+		return strconv.FormatUint(mvt.ToUint64(value), 10), nil
+	case mvt.KindFloat:
+		// The shortest decimal form keeps 0.1 from reading back as 0.10000000149
+		v, _ := strconv.ParseFloat(strconv.FormatFloat(float64(value.(float32)), 'g', -1, 32), 64)
+		return v, nil
+	case mvt.KindDouble, mvt.KindString:
+		return value, nil
+	default:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode JSON property: %w", err)
+		}
+		return string(data), nil
+	}
+}
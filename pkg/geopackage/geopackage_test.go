@@ -0,0 +1,281 @@
+// pkg/geopackage/geopackage_test.go - Unit tests for the GeoPackage writer
+package geopackage
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/wkb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/valpere/tile_to_json/pkg/mvt"
+)
+
+func TestColumnType(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []interface{}
+		expected ColumnType
+	}{
+		{"bool", []interface{}{true, false}, ColumnBoolean},
+		{"int", []interface{}{int64(-1), int64(2)}, ColumnInteger},
+		{"signed and unsigned", []interface{}{int64(-1), uint64(2)}, ColumnInteger},
+		{"large unsigned", []interface{}{uint64(math.MaxUint64)}, ColumnText},
+		{"float", []interface{}{float32(1.5)}, ColumnFloat},
+		{"double", []interface{}{1.5}, ColumnDouble},
+		{"float and double", []interface{}{float32(1.5), 2.5}, ColumnDouble},
+		{"int and double", []interface{}{int64(1), 2.5}, ColumnDouble},
+		{"string", []interface{}{"a"}, ColumnText},
+		{"string and int", []interface{}{"a", int64(1)}, ColumnText},
+		{"bool and int", []interface{}{true, int64(1)}, ColumnText},
+		{"json", []interface{}{map[string]interface{}{"a": 1}}, ColumnText},
+	}
+
+	for _, test := range tests {
+		var kinds uint8
+		for _, value := range test.values {
+			kind, ok := mvt.ValueKind(value)
+			if !ok {
+				t.Fatalf("%s: expected a kind for %v", test.name, value)
+			}
+			kinds |= kind
+		}
+		if got := columnType(kinds); got != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestEncodeGeometry(t *testing.T) {
+	tests := []struct {
+		name     string
+		geometry orb.Geometry
+		flags    byte
+		envelope []float64
+	}{
+		{"point", orb.Point{1, 2}, flagLittleEndian, nil},
+		{"line", orb.LineString{{0, 1}, {4, -2}}, flagLittleEndian | flagEnvelopeXY, []float64{0, 4, -2, 1}},
+		{"empty", orb.MultiPolygon{}, flagLittleEndian | flagEmpty, nil},
+	}
+
+	for _, test := range tests {
+		blob, err := encodeGeometry(test.geometry, 3857)
+		if err != nil {
+			t.Fatalf("%s: failed to encode geometry: %v", test.name, err)
+		}
+
+		if !bytes.Equal(blob[:4], []byte{'G', 'P', 0, test.flags}) {
+			t.Errorf("%s: expected header GP 00 %02x, got % x", test.name, test.flags, blob[:4])
+		}
+		if srs := int32(binary.LittleEndian.Uint32(blob[4:])); srs != 3857 {
+			t.Errorf("%s: expected SRS 3857, got %d", test.name, srs)
+		}
+
+		offset := 8
+		for i, expected := range test.envelope {
+			if v := math.Float64frombits(binary.LittleEndian.Uint64(blob[offset:])); v != expected {
+				t.Errorf("%s: expected envelope value %d to be %v, got %v", test.name, i, expected, v)
+			}
+			offset += 8
+		}
+
+		geometry, err := wkb.Unmarshal(blob[offset:])
+		if err != nil {
+			t.Fatalf("%s: failed to decode WKB: %v", test.name, err)
+		}
+		if !orb.Equal(geometry, test.geometry) {
+			t.Errorf("%s: expected geometry %v, got %v", test.name, test.geometry, geometry)
+		}
+	}
+}
+
+func TestUniqueName(t *testing.T) {
+	used := map[string]bool{"fid": true}
+	tests := []struct {
+		name     string
+		reserved bool
+		expected string
+	}{
+		{"roads", true, "roads"},
+		{"Roads", true, "Roads_2"},
+		{"roads", true, "roads_3"},
+		{"gpkg_contents", true, "_gpkg_contents"},
+		{"rtree_roads", false, "rtree_roads"},
+		{"FID", false, "FID_2"},
+	}
+
+	for _, test := range tests {
+		if got := uniqueName(test.name, used, test.reserved); got != test.expected {
+			t.Errorf("Expected %s for %s, got %s", test.expected, test.name, got)
+		}
+	}
+}
+
+func testFeature(geometry orb.Geometry, properties geojson.Properties) *geojson.Feature {
+	feature := geojson.NewFeature(geometry)
+	feature.Properties = properties
+	return feature
+}
+
+// writeGeoPackage writes features to a GeoPackage file and opens it
+func writeGeoPackage(t *testing.T, options Options, features []*geojson.Feature) *sql.DB {
+	t.Helper()
+
+	writer, err := NewWriter(options)
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	defer writer.Close()
+
+	for _, feature := range features {
+		if err := writer.Write(feature); err != nil {
+			t.Fatalf("Failed to write feature: %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := writer.WriteTo(&buf); err != nil {
+		t.Fatalf("Failed to write geopackage: %v", err)
+	}
+	if err := writer.Write(features[0]); err == nil {
+		t.Error("Expected an error writing after WriteTo")
+	}
+
+	path := filepath.Join(t.TempDir(), "test.gpkg")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to save geopackage: %v", err)
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("Failed to open geopackage: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func queryStrings(t *testing.T, db *sql.DB, query string) []string {
+	t.Helper()
+	rows, err := db.Query(query)
+	if err != nil {
+		t.Fatalf("Failed to query %q: %v", query, err)
+	}
+	defer rows.Close()
+
+	var result []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			t.Fatalf("Failed to scan %q: %v", query, err)
+		}
+		result = append(result, value)
+	}
+	return result
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	features := []*geojson.Feature{
+		testFeature(orb.Point{1, 2}, geojson.Properties{"_layer": "poi", "name": "a", "rank": int64(1), "open": true, "height": float32(0.1)}),
+		testFeature(orb.Point{3, 4}, geojson.Properties{"_layer": "poi", "name": "b", "rank": uint64(7), "Name": "dup", "fid": "x"}),
+		testFeature(orb.LineString{{0, 0}, {3, 4}}, geojson.Properties{"_layer": "roads", "width": int64(2)}),
+		testFeature(orb.MultiLineString{{{-1, 0}, {5, 6}}}, geojson.Properties{"_layer": "roads", "width": 2.5}),
+		testFeature(nil, geojson.Properties{"_layer": "roads", "tags": []interface{}{"x"}}),
+		testFeature(orb.Point{9, 9}, geojson.Properties{}),
+	}
+	lastChange := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	db := writeGeoPackage(t, Options{EPSG: 4326, Index: true, LayerProperty: "_layer", LastChange: lastChange}, features)
+
+	var applicationID, userVersion int
+	db.QueryRow("PRAGMA application_id").Scan(&applicationID)
+	db.QueryRow("PRAGMA user_version").Scan(&userVersion)
+	if applicationID != ApplicationID || userVersion != UserVersion {
+		t.Errorf("Expected application ID %x and user version %d, got %x and %d", ApplicationID, UserVersion, applicationID, userVersion)
+	}
+	if result := queryStrings(t, db, "PRAGMA integrity_check"); !reflect.DeepEqual(result, []string{"ok"}) {
+		t.Errorf("Expected integrity check ok, got %v", result)
+	}
+
+	tables := queryStrings(t, db, "SELECT table_name || ' ' || geometry_type_name || ' ' || srs_id FROM gpkg_geometry_columns ORDER BY table_name")
+	if expected := []string{"features POINT 4326", "poi POINT 4326", "roads GEOMETRY 4326"}; !reflect.DeepEqual(tables, expected) {
+		t.Errorf("Expected geometry columns %v, got %v", expected, tables)
+	}
+
+	contents := queryStrings(t, db, "SELECT printf('%s %s %g %g %g %g', table_name, last_change, min_x, min_y, max_x, max_y) FROM gpkg_contents WHERE table_name = 'roads'")
+	if expected := []string{"roads 2024-01-02T03:04:05.000Z -1 0 5 6"}; !reflect.DeepEqual(contents, expected) {
+		t.Errorf("Expected contents %v, got %v", expected, contents)
+	}
+
+	columns := queryStrings(t, db, "SELECT name || ' ' || type FROM pragma_table_info('poi')")
+	expectedColumns := []string{"fid INTEGER", "geom POINT", "Name_2 TEXT", "fid_2 TEXT", "height FLOAT", "name TEXT", "open BOOLEAN", "rank INTEGER"}
+	if !reflect.DeepEqual(columns, expectedColumns) {
+		t.Errorf("Expected poi columns %v, got %v", expectedColumns, columns)
+	}
+
+	rows := queryStrings(t, db, "SELECT printf('%d %s %s %s %d %d', fid, name, quote(Name_2), height, open, rank) FROM poi ORDER BY fid")
+	if expected := []string{"1 a NULL 0.1 1 1", "2 b 'dup'  0 7"}; !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Expected poi rows %v, got %v", expected, rows)
+	}
+
+	roads := queryStrings(t, db, "SELECT printf('%s %s %s', typeof(width), quote(width), quote(tags)) FROM roads ORDER BY fid")
+	if expected := []string{"real 2.0 NULL", "real 2.5 NULL", "null NULL '[\"x\"]'"}; !reflect.DeepEqual(roads, expected) {
+		t.Errorf("Expected roads rows %v, got %v", expected, roads)
+	}
+
+	index := queryStrings(t, db, "SELECT printf('%d %g %g %g %g', id, minx, maxx, miny, maxy) FROM rtree_roads_geom ORDER BY id")
+	if expected := []string{"1 0 3 0 4", "2 -1 5 0 6"}; !reflect.DeepEqual(index, expected) {
+		t.Errorf("Expected index %v, got %v", expected, index)
+	}
+	extensions := queryStrings(t, db, "SELECT table_name FROM gpkg_extensions WHERE extension_name = 'gpkg_rtree_index' ORDER BY table_name")
+	if expected := []string{"features", "poi", "roads"}; !reflect.DeepEqual(extensions, expected) {
+		t.Errorf("Expected indexed tables %v, got %v", expected, extensions)
+	}
+
+	if spool := queryStrings(t, db, "SELECT name FROM sqlite_master WHERE name LIKE 'spool_%'"); len(spool) != 0 {
+		t.Errorf("Expected spool tables to be dropped, got %v", spool)
+	}
+}
+
+func TestWriterNoIndex(t *testing.T) {
+	features := []*geojson.Feature{testFeature(orb.Point{1, 2}, geojson.Properties{"_layer": "poi"})}
+	db := writeGeoPackage(t, Options{EPSG: 3857, LayerProperty: "_layer"}, features)
+
+	if result := queryStrings(t, db, "SELECT name FROM sqlite_master WHERE name LIKE 'rtree%'"); len(result) != 0 {
+		t.Errorf("Expected no index, got %v", result)
+	}
+	if result := queryStrings(t, db, "SELECT srs_id FROM gpkg_spatial_ref_sys ORDER BY srs_id"); !reflect.DeepEqual(result, []string{"-1", "0", "3857", "4326"}) {
+		t.Errorf("Expected spatial reference systems -1, 0, 3857 and 4326, got %v", result)
+	}
+}
+
+func TestWriterEmpty(t *testing.T) {
+	writer, err := NewWriter(Options{Index: true})
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	defer writer.Close()
+
+	var buf bytes.Buffer
+	n, err := writer.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("Failed to write geopackage: %v", err)
+	}
+	if n != int64(buf.Len()) || !bytes.HasPrefix(buf.Bytes(), []byte("SQLite format 3\x00")) {
+		t.Errorf("Expected an SQLite database of %d bytes, got %d bytes", n, buf.Len())
+	}
+	if tables := writer.Tables(); len(tables) != 0 {
+		t.Errorf("Expected no tables, got %v", tables)
+	}
+}
+
+func TestNewWriterInvalidEPSG(t *testing.T) {
+	if writer, err := NewWriter(Options{EPSG: 27700}); err == nil {
+		writer.Close()
+		t.Error("Expected an error for EPSG:27700")
+	}
+}
@@ -0,0 +1,506 @@
+// pkg/geopackage/writer.go - GeoPackage file writer
+package geopackage
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/valpere/tile_to_json/pkg/mvt"
+
+	// Registers the pure Go "sqlite" database/sql driver
+	_ "modernc.org/sqlite"
+)
+
+// Options configures a GeoPackage
+type Options struct {
+	EPSG  int  // EPSG code of the coordinates, 4326 or 3857; 0 uses the undefined cartesian SRS
+	Index bool // Add an R-tree spatial index to every feature table
+
+	// LayerProperty names the property holding each feature's layer. Features are
+	// written to one table per layer and the property is not stored; features without
+	// it, or all features when it is empty, go to DefaultTable.
+	LayerProperty string
+
+	// LastChange is recorded as each table's last change; zero uses the current time
+	LastChange time.Time
+}
+
+// Writer builds a GeoPackage with a feature table per layer. Column types are inferred
+// from every feature of a layer, so features are first spooled into untyped tables of
+// a temporary database. WriteTo creates the feature tables, metadata and spatial
+// indexes, and writes a compacted copy of the database. Writer is safe for concurrent
+// use.
+type Writer struct {
+	options  Options
+	srsID    int
+	dir      string
+	db       *sql.DB
+	tx       *sql.Tx
+	layers   map[string]*layer
+	names    map[string]bool
+	count    int
+	finished bool
+	mutex    sync.Mutex
+}
+
+// layer is the spool table and inferred schema of a feature table
+type layer struct {
+	table         string
+	spool         string
+	columns       []*column
+	byProperty    map[string]*column
+	names         map[string]bool
+	insert        *sql.Stmt
+	geometryTypes map[string]bool
+	bound         orb.Bound
+	hasBound      bool
+}
+
+// column is a property column and the untyped spool column holding its values
+type column struct {
+	name  string
+	spool string
+	kinds uint8
+}
+
+// NewWriter creates a writer
+func NewWriter(options Options) (*Writer, error) {
+	id, err := srsID(options.EPSG)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "geopackage-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+
+	w := &Writer{
+		options: options,
+		srsID:   id,
+		dir:     dir,
+		layers:  make(map[string]*layer),
+		names:   make(map[string]bool),
+	}
+	if err := w.open(); err != nil {
+		w.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// open creates the spool database. It is a scratch file, so journaling and syncing
+// are turned off, and a single connection keeps the settings for every statement.
+func (w *Writer) open() error {
+	db, err := sql.Open("sqlite", filepath.Join(w.dir, "spool.gpkg"))
+	if err != nil {
+		return fmt.Errorf("failed to open spool database: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+	w.db = db
+
+	pragmas := []string{
+		"PRAGMA application_id = " + strconv.Itoa(ApplicationID),
+		"PRAGMA user_version = " + strconv.Itoa(UserVersion),
+		"PRAGMA journal_mode = OFF",
+		"PRAGMA synchronous = OFF",
+	}
+	for _, pragma := range pragmas {
+		if _, err := db.Exec(pragma); err != nil {
+			return fmt.Errorf("failed to configure spool database: %w", err)
+		}
+	}
+
+	if w.tx, err = db.Begin(); err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	return nil
+}
+
+// Write adds a feature to the table of its layer
+func (w *Writer) Write(feature *geojson.Feature) error {
+	if feature == nil {
+		return nil
+	}
+
+	var geometry []byte
+	if feature.Geometry != nil {
+		var err error
+		if geometry, err = encodeGeometry(feature.Geometry, w.srsID); err != nil {
+			return fmt.Errorf("failed to encode geometry: %w", err)
+		}
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.db == nil {
+		return fmt.Errorf("writer is closed")
+	}
+	if w.finished {
+		return fmt.Errorf("writer is finished")
+	}
+
+	name := ""
+	if w.options.LayerProperty != "" {
+		name, _ = feature.Properties[w.options.LayerProperty].(string)
+	}
+	l, err := w.layer(name)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(feature.Properties))
+	for key := range feature.Properties {
+		if key != w.options.LayerProperty || w.options.LayerProperty == "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	values := make(map[*column]interface{}, len(keys))
+	for _, key := range keys {
+		kind, ok := mvt.ValueKind(feature.Properties[key])
+		if !ok {
+			continue
+		}
+		value, err := spoolValue(feature.Properties[key], kind)
+		if err != nil {
+			return fmt.Errorf("failed to encode property %s: %w", key, err)
+		}
+		c, err := w.column(l, key)
+		if err != nil {
+			return err
+		}
+		c.kinds |= kind
+		values[c] = value
+	}
+
+	args := make([]interface{}, 5, 5+len(l.columns))
+	if geometry != nil {
+		args[0] = geometry
+		if !isEmpty(feature.Geometry) {
+			bound := feature.Geometry.Bound()
+			args[1], args[2], args[3], args[4] = bound.Min[0], bound.Max[0], bound.Min[1], bound.Max[1]
+			if l.hasBound {
+				bound = bound.Union(l.bound)
+			}
+			l.bound, l.hasBound = bound, true
+		}
+		l.geometryTypes[geometryTypeName(feature.Geometry)] = true
+	}
+	for _, c := range l.columns {
+		args = append(args, values[c])
+	}
+
+	if l.insert == nil {
+		if l.insert, err = w.tx.Prepare(insertStatement(l)); err != nil {
+			return fmt.Errorf("failed to prepare insert into %s: %w", l.table, err)
+		}
+	}
+	if _, err := l.insert.Exec(args...); err != nil {
+		return fmt.Errorf("failed to spool feature of layer %s: %w", l.table, err)
+	}
+	w.count++
+	return nil
+}
+
+// layer returns the state of a layer, creating its spool table on first use
+func (w *Writer) layer(name string) (*layer, error) {
+	if l, ok := w.layers[name]; ok {
+		return l, nil
+	}
+
+	table := name
+	if table == "" {
+		table = DefaultTable
+	}
+	l := &layer{
+		table:         uniqueName(table, w.names, true),
+		spool:         "spool_" + strconv.Itoa(len(w.layers)+1),
+		byProperty:    make(map[string]*column),
+		names:         map[string]bool{FIDColumn: true, GeometryColumn: true},
+		geometryTypes: make(map[string]bool),
+	}
+
+	statement := "CREATE TABLE " + l.spool + " (geom BLOB, minx REAL, maxx REAL, miny REAL, maxy REAL)"
+	if _, err := w.tx.Exec(statement); err != nil {
+		return nil, fmt.Errorf("failed to create spool table for layer %s: %w", l.table, err)
+	}
+	w.layers[name] = l
+	return l, nil
+}
+
+// column returns the column of a layer's property, adding it to the spool table on
+// first use. Property names that clash with the fid and geometry columns or, ignoring
+// case, with an earlier property get a numeric suffix.
+func (w *Writer) column(l *layer, property string) (*column, error) {
+	if c, ok := l.byProperty[property]; ok {
+		return c, nil
+	}
+
+	c := &column{
+		name:  uniqueName(property, l.names, false),
+		spool: "c" + strconv.Itoa(len(l.columns)+1),
+	}
+	if _, err := w.tx.Exec("ALTER TABLE " + l.spool + " ADD COLUMN " + c.spool); err != nil {
+		return nil, fmt.Errorf("failed to add column %s to layer %s: %w", property, l.table, err)
+	}
+	l.columns = append(l.columns, c)
+	l.byProperty[property] = c
+
+	if l.insert != nil {
+		l.insert.Close()
+		l.insert = nil
+	}
+	return c, nil
+}
+
+// insertStatement returns the statement spooling a feature of a layer
+func insertStatement(l *layer) string {
+	columns := []string{"geom", "minx", "maxx", "miny", "maxy"}
+	for _, c := range l.columns {
+		columns = append(columns, c.spool)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	return "INSERT INTO " + l.spool + " (" + strings.Join(columns, ", ") + ") VALUES (" + placeholders + ")"
+}
+
+// FeatureCount returns the number of features written so far
+func (w *Writer) FeatureCount() int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.count
+}
+
+// Tables returns the names of the feature tables, in name order
+func (w *Writer) Tables() []string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	tables := make([]string, 0, len(w.layers))
+	for _, l := range w.layers {
+		tables = append(tables, l.table)
+	}
+	sort.Strings(tables)
+	return tables
+}
+
+// Columns returns the property columns inferred for a layer's table so far, in name
+// order, or nil for an unknown layer
+func (w *Writer) Columns(layerName string) []Column {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	l, ok := w.layers[layerName]
+	if !ok {
+		return nil
+	}
+	return l.sortedColumns()
+}
+
+func (l *layer) sortedColumns() []Column {
+	columns := make([]Column, len(l.columns))
+	for i, c := range l.columns {
+		columns[i] = Column{Name: c.name, Type: columnType(c.kinds)}
+	}
+	sort.Slice(columns, func(i, j int) bool { return columns[i].Name < columns[j].Name })
+	return columns
+}
+
+// WriteTo finishes the GeoPackage and writes it to out. No features can be written
+// afterwards.
+func (w *Writer) WriteTo(out io.Writer) (int64, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.db == nil {
+		return 0, fmt.Errorf("writer is closed")
+	}
+	if !w.finished {
+		w.finished = true
+		if err := w.finish(); err != nil {
+			return 0, err
+		}
+	}
+
+	path := filepath.Join(w.dir, "output.gpkg")
+	os.Remove(path)
+	if _, err := w.db.Exec("VACUUM INTO ?", path); err != nil {
+		return 0, fmt.Errorf("failed to write geopackage: %w", err)
+	}
+	defer os.Remove(path)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open geopackage: %w", err)
+	}
+	defer file.Close()
+
+	n, err := io.Copy(out, file)
+	if err != nil {
+		return n, fmt.Errorf("failed to write geopackage: %w", err)
+	}
+	return n, nil
+}
+
+// finish creates the GeoPackage metadata and a typed feature table per layer from
+// its spool table, then commits
+func (w *Writer) finish() error {
+	tx := w.tx
+	w.tx = nil
+	defer tx.Rollback()
+
+	for _, statement := range coreSchema {
+		if _, err := tx.Exec(statement); err != nil {
+			return fmt.Errorf("failed to create geopackage tables: %w", err)
+		}
+	}
+
+	systems := requiredSpatialRefSys
+	if w.srsID == 3857 {
+		systems = append(systems, pseudoMercatorSpatialRefSys)
+	}
+	for _, srs := range systems {
+		_, err := tx.Exec("INSERT INTO gpkg_spatial_ref_sys (srs_name, srs_id, organization, organization_coordsys_id, definition, description) VALUES (?, ?, ?, ?, ?, ?)",
+			srs.name, srs.id, srs.organization, srs.code, srs.definition, srs.description)
+		if err != nil {
+			return fmt.Errorf("failed to add spatial reference system %d: %w", srs.id, err)
+		}
+	}
+
+	lastChange := w.options.LastChange
+	if lastChange.IsZero() {
+		lastChange = time.Now()
+	}
+
+	layers := make([]*layer, 0, len(w.layers))
+	for _, l := range w.layers {
+		layers = append(layers, l)
+	}
+	sort.Slice(layers, func(i, j int) bool { return layers[i].table < layers[j].table })
+
+	for _, l := range layers {
+		if l.insert != nil {
+			l.insert.Close()
+			l.insert = nil
+		}
+		if err := w.writeTable(tx, l, lastChange.UTC().Format("2006-01-02T15:04:05.000Z")); err != nil {
+			return fmt.Errorf("failed to write table %s: %w", l.table, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit geopackage: %w", err)
+	}
+	return nil
+}
+
+// writeTable creates a layer's feature table, registers it and fills its spatial index,
+// then drops the spool table. Feature IDs are the spool row IDs, in write order.
+func (w *Writer) writeTable(tx *sql.Tx, l *layer, lastChange string) error {
+	geometryType := "GEOMETRY"
+	if len(l.geometryTypes) == 1 {
+		for name := range l.geometryTypes {
+			geometryType = name
+		}
+	}
+
+	spoolColumns := make(map[string]string, len(l.columns))
+	for _, c := range l.columns {
+		spoolColumns[c.name] = c.spool
+	}
+
+	definitions := []string{
+		quoteIdentifier(FIDColumn) + " INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL",
+		quoteIdentifier(GeometryColumn) + " " + geometryType,
+	}
+	names := []string{quoteIdentifier(FIDColumn), quoteIdentifier(GeometryColumn)}
+	selects := []string{"rowid", "geom"}
+	for _, c := range l.sortedColumns() {
+		definitions = append(definitions, quoteIdentifier(c.Name)+" "+string(c.Type))
+		names = append(names, quoteIdentifier(c.Name))
+		selects = append(selects, spoolColumns[c.Name])
+	}
+
+	table := quoteIdentifier(l.table)
+	statements := []string{
+		"CREATE TABLE " + table + " (" + strings.Join(definitions, ", ") + ")",
+		"INSERT INTO " + table + " (" + strings.Join(names, ", ") + ") SELECT " + strings.Join(selects, ", ") + " FROM " + l.spool + " ORDER BY rowid",
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+
+	var minX, minY, maxX, maxY interface{}
+	if l.hasBound {
+		minX, minY, maxX, maxY = l.bound.Min[0], l.bound.Min[1], l.bound.Max[0], l.bound.Max[1]
+	}
+	_, err := tx.Exec("INSERT INTO gpkg_contents (table_name, data_type, identifier, description, last_change, min_x, min_y, max_x, max_y, srs_id) VALUES (?, 'features', ?, '', ?, ?, ?, ?, ?, ?)",
+		l.table, l.table, lastChange, minX, minY, maxX, maxY, w.srsID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO gpkg_geometry_columns (table_name, column_name, geometry_type_name, srs_id, z, m) VALUES (?, ?, ?, ?, 0, 0)",
+		l.table, GeometryColumn, geometryType, w.srsID)
+	if err != nil {
+		return err
+	}
+
+	if w.options.Index {
+		rtree := quoteIdentifier("rtree_" + l.table + "_" + GeometryColumn)
+		statements := []string{
+			"CREATE VIRTUAL TABLE " + rtree + " USING rtree(id, minx, maxx, miny, maxy)",
+			"INSERT INTO " + rtree + " (id, minx, maxx, miny, maxy) SELECT rowid, minx, maxx, miny, maxy FROM " + l.spool + " WHERE minx IS NOT NULL",
+		}
+		statements = append(statements, rtreeTriggers(l.table)...)
+		for _, statement := range statements {
+			if _, err := tx.Exec(statement); err != nil {
+				return err
+			}
+		}
+		_, err := tx.Exec("INSERT INTO gpkg_extensions (table_name, column_name, extension_name, definition, scope) VALUES (?, ?, 'gpkg_rtree_index', ?, 'write-only')",
+			l.table, GeometryColumn, rtreeExtension)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec("DROP TABLE " + l.spool)
+	return err
+}
+
+// Close removes the spool database
+func (w *Writer) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.dir == "" {
+		return nil
+	}
+
+	var err error
+	if w.tx != nil {
+		w.tx.Rollback()
+		w.tx = nil
+	}
+	if w.db != nil {
+		err = w.db.Close()
+		w.db = nil
+	}
+	os.RemoveAll(w.dir)
+	w.dir = ""
+	return err
+}
@@ -0,0 +1,159 @@
+// pkg/geoparquet/geoparquet.go - GeoParquet metadata and column type inference
+package geoparquet
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/paulmach/orb"
+	"github.com/valpere/tile_to_json/pkg/mvt"
+)
+
+// Version is the GeoParquet specification version written to the geo metadata
+const Version = "1.1.0"
+
+// GeometryColumn is the name of the WKB geometry column
+const GeometryColumn = "geometry"
+
+// pseudoMercatorPROJJSON describes EPSG:3857 for the geo metadata. WGS84 output omits
+// the CRS, which GeoParquet readers take as OGC:CRS84.
+const pseudoMercatorPROJJSON = `{"$schema":"https://proj.org/schemas/v0.7/projjson.schema.json","type":"ProjectedCRS","name":"WGS 84 / Pseudo-Mercator",` +
+	`"base_crs":{"name":"WGS 84","datum":{"type":"GeodeticReferenceFrame","name":"World Geodetic System 1984","ellipsoid":{"name":"WGS 84","semi_major_axis":6378137,"inverse_flattening":298.257223563}},` +
+	`"coordinate_system":{"subtype":"ellipsoidal","axis":[{"name":"Geodetic latitude","abbreviation":"Lat","direction":"north","unit":"degree"},{"name":"Geodetic longitude","abbreviation":"Lon","direction":"east","unit":"degree"}]},` +
+	`"id":{"authority":"EPSG","code":4326}},` +
+	`"conversion":{"name":"Popular Visualisation Pseudo-Mercator","method":{"name":"Popular Visualisation Pseudo Mercator","id":{"authority":"EPSG","code":1024}},"parameters":[` +
+	`{"name":"Latitude of natural origin","value":0,"unit":"degree","id":{"authority":"EPSG","code":8801}},` +
+	`{"name":"Longitude of natural origin","value":0,"unit":"degree","id":{"authority":"EPSG","code":8802}},` +
+	`{"name":"False easting","value":0,"unit":"metre","id":{"authority":"EPSG","code":8806}},` +
+	`{"name":"False northing","value":0,"unit":"metre","id":{"authority":"EPSG","code":8807}}]},` +
+	`"coordinate_system":{"subtype":"Cartesian","axis":[{"name":"Easting","abbreviation":"X","direction":"east","unit":"metre"},{"name":"Northing","abbreviation":"Y","direction":"north","unit":"metre"}]},` +
+	`"id":{"authority":"EPSG","code":3857}}`
+
+// geoMetadata is the file-level "geo" key-value metadata
+type geoMetadata struct {
+	Version       string                        `json:"version"`
+	PrimaryColumn string                        `json:"primary_column"`
+	Columns       map[string]*geoColumnMetadata `json:"columns"`
+}
+
+// geoColumnMetadata describes a geometry column
+type geoColumnMetadata struct {
+	Encoding      string          `json:"encoding"`
+	GeometryTypes []string        `json:"geometry_types"`
+	CRS           json.RawMessage `json:"crs,omitempty"`
+	BBox          []float64       `json:"bbox,omitempty"`
+}
+
+// newGeoMetadata encodes the geo metadata for the geometry types and bounds written,
+// in the coordinate system with an EPSG code; 0 records an unknown CRS
+func newGeoMetadata(geometryTypes map[string]bool, bound orb.Bound, hasBound bool, epsg int) (string, error) {
+	column := &geoColumnMetadata{
+		Encoding:      "WKB",
+		GeometryTypes: make([]string, 0, len(geometryTypes)),
+	}
+	for geometryType := range geometryTypes {
+		column.GeometryTypes = append(column.GeometryTypes, geometryType)
+	}
+	sort.Strings(column.GeometryTypes)
+
+	switch epsg {
+	case 4326:
+	case 3857:
+		column.CRS = json.RawMessage(pseudoMercatorPROJJSON)
+	case 0:
+		column.CRS = json.RawMessage("null")
+	default:
+		return "", fmt.Errorf("unsupported CRS EPSG:%d", epsg)
+	}
+
+	if hasBound {
+		column.BBox = []float64{bound.Min[0], bound.Min[1], bound.Max[0], bound.Max[1]}
+	}
+
+	data, err := json.Marshal(&geoMetadata{
+		Version:       Version,
+		PrimaryColumn: GeometryColumn,
+		Columns:       map[string]*geoColumnMetadata{GeometryColumn: column},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode geo metadata: %w", err)
+	}
+	return string(data), nil
+}
+
+// geometryTypeName returns the GeoParquet name of a geometry's type
+func geometryTypeName(geometry orb.Geometry) string {
+	switch geometry.(type) {
+	case orb.Point:
+		return "Point"
+	case orb.MultiPoint:
+		return "MultiPoint"
+	case orb.LineString:
+		return "LineString"
+	case orb.MultiLineString:
+		return "MultiLineString"
+	case orb.Polygon:
+		return "Polygon"
+	case orb.MultiPolygon:
+		return "MultiPolygon"
+	case orb.Collection:
+		return "GeometryCollection"
+	default:
+		return ""
+	}
+}
+
+// columnType infers a column type from the kinds of value found in it (see
+// mvt.ValueKind), keeping the attribute types decoded from the tiles. Signed and
+// unsigned integers together are signed unless the unsigned values overflow it; other
+// mixed numbers widen to doubles and any other mixture is written as strings.
+func columnType(kinds uint8) ColumnType {
+	switch kinds {
+	case mvt.KindBool:
+		return ColumnBoolean
+	case mvt.KindInt, mvt.KindInt | mvt.KindUint:
+		return ColumnInt64
+	case mvt.KindUint, mvt.KindLargeUint, mvt.KindUint | mvt.KindLargeUint:
+		return ColumnUint64
+	case mvt.KindFloat:
+		return ColumnFloat
+	case mvt.KindString:
+		return ColumnString
+	case mvt.KindJSON:
+		return ColumnJSON
+	}
+	if kinds&^(mvt.KindInt|mvt.KindUint|mvt.KindLargeUint|mvt.KindFloat|mvt.KindDouble) == 0 {
+		return ColumnDouble
+	}
+	return ColumnString
+}
+
+// addValue appends a property value, converted to the chunk's column type
+func (c *columnChunk) addValue(value interface{}) error {
+	switch c.column.Type {
+	case ColumnBoolean:
+		c.addBool(value.(bool))
+	case ColumnInt64:
+		c.addInt64(mvt.ToInt64(value))
+	case ColumnUint64:
+		c.addInt64(int64(mvt.ToUint64(value)))
+	case ColumnFloat:
+		c.addFloat(value.(float32))
+	case ColumnDouble:
+		c.addDouble(mvt.ToFloat64(value))
+	case ColumnJSON:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed to encode JSON property: %w", err)
+		}
+		c.addBytes(data)
+	default:
+		text, err := mvt.ToString(value)
+		if err != nil {
+			return err
+		}
+		c.addBytes([]byte(text))
+	}
+	return nil
+}
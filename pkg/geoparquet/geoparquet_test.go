@@ -0,0 +1,397 @@
+// pkg/geoparquet/geoparquet_test.go - Unit tests for the GeoParquet writer
+package geoparquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/wkb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/valpere/tile_to_json/pkg/mvt"
+)
+
+// thriftStructValue is a decoded Thrift struct, keyed by field ID
+type thriftStructValue map[int16]interface{}
+
+// thriftReader decodes Thrift compact protocol structs into generic values: integers
+// as int64, binaries as []byte, lists as []interface{} and structs as thriftStructValue
+type thriftReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *thriftReader) varint() uint64 {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		panic("bad varint")
+	}
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) int() int64 {
+	v := r.varint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) value(valueType byte) interface{} {
+	switch valueType {
+	case thriftTrue:
+		return true
+	case thriftFalse:
+		return false
+	case thriftByte:
+		r.pos++
+		return int64(int8(r.data[r.pos-1]))
+	case thriftI32, thriftI64:
+		return r.int()
+	case thriftBinary:
+		n := int(r.varint())
+		r.pos += n
+		return r.data[r.pos-n : r.pos]
+	case thriftList:
+		header := r.data[r.pos]
+		r.pos++
+		n := int(header >> 4)
+		if n == 15 {
+			n = int(r.varint())
+		}
+		list := make([]interface{}, n)
+		for i := range list {
+			// Booleans in lists are a byte each
+			if header&0x0F == thriftTrue {
+				r.pos++
+				list[i] = r.data[r.pos-1] == 1
+				continue
+			}
+			list[i] = r.value(header & 0x0F)
+		}
+		return list
+	case thriftStruct:
+		return r.readStruct()
+	}
+	panic(fmt.Sprintf("unsupported thrift type %d", valueType))
+}
+
+func (r *thriftReader) readStruct() thriftStructValue {
+	result := thriftStructValue{}
+	var id int16
+	for {
+		header := r.data[r.pos]
+		r.pos++
+		if header == 0 {
+			return result
+		}
+		if delta := header >> 4; delta != 0 {
+			id += int16(delta)
+		} else {
+			id = int16(r.int())
+		}
+		result[id] = r.value(header & 0x0F)
+	}
+}
+
+// readFooter checks a file's magic and decodes its FileMetaData
+func readFooter(t *testing.T, data []byte) thriftStructValue {
+	t.Helper()
+	if len(data) < 12 || !bytes.Equal(data[:4], magic) || !bytes.Equal(data[len(data)-4:], magic) {
+		t.Fatalf("Expected PAR1 magic at both ends, got %d bytes", len(data))
+	}
+	length := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	reader := &thriftReader{data: data[len(data)-8-length : len(data)-8]}
+	return reader.readStruct()
+}
+
+func TestThriftWriter(t *testing.T) {
+	w := newThriftWriter()
+	w.i32Field(1, -1)
+	w.i64Field(20, 3)
+	w.structField(21)
+	w.boolField(1, true)
+	w.endStruct()
+	w.listField(22, thriftBinary, 1)
+	w.binary([]byte("a"))
+
+	expected := []byte{
+		0x15, 0x01, // field 1 i32, zigzag(-1)
+		0x06, 0x28, 0x06, // field 20 i64 in long form, zigzag(3)
+		0x1C,       // field 21 struct
+		0x11, 0x00, // field 1 true, stop
+		0x19, 0x18, 0x01, 'a', // field 22 list of one binary
+		0x00, // stop
+	}
+	if got := w.bytes(); !bytes.Equal(got, expected) {
+		t.Errorf("Expected % x, got % x", expected, got)
+	}
+}
+
+func TestColumnType(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []interface{}
+		expected ColumnType
+	}{
+		{"bool", []interface{}{true, false}, ColumnBoolean},
+		{"int", []interface{}{int64(-1), int64(2)}, ColumnInt64},
+		{"uint", []interface{}{uint64(1)}, ColumnUint64},
+		{"signed and unsigned", []interface{}{int64(-1), uint64(2)}, ColumnInt64},
+		{"large unsigned", []interface{}{uint64(math.MaxUint64)}, ColumnUint64},
+		{"large unsigned and signed", []interface{}{uint64(math.MaxUint64), int64(-1)}, ColumnDouble},
+		{"float", []interface{}{float32(1.5)}, ColumnFloat},
+		{"double", []interface{}{1.5}, ColumnDouble},
+		{"float and double", []interface{}{float32(1.5), 2.5}, ColumnDouble},
+		{"int and double", []interface{}{int64(1), 2.5}, ColumnDouble},
+		{"string", []interface{}{"a"}, ColumnString},
+		{"string and int", []interface{}{"a", int64(1)}, ColumnString},
+		{"json", []interface{}{map[string]interface{}{"a": 1}}, ColumnJSON},
+	}
+
+	for _, test := range tests {
+		var kinds uint8
+		for _, value := range test.values {
+			kind, ok := mvt.ValueKind(value)
+			if !ok {
+				t.Fatalf("%s: expected a kind for %v", test.name, value)
+			}
+			kinds |= kind
+		}
+		if got := columnType(kinds); got != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestColumnChunkPage(t *testing.T) {
+	chunk := newColumnChunk(Column{Name: "b", Type: ColumnBoolean})
+	chunk.addBool(true)
+	chunk.addBool(false)
+	chunk.addNull()
+	chunk.addBool(true)
+
+	expected := []byte{
+		6, 0, 0, 0, // level length
+		4, 1, // two present
+		2, 0, // one null
+		2, 1, // one present
+		0x05, // true, false, true bit-packed
+	}
+	if got := chunk.page(); !bytes.Equal(got, expected) {
+		t.Errorf("Expected % x, got % x", expected, got)
+	}
+	if chunk.nulls != 1 {
+		t.Errorf("Expected 1 null, got %d", chunk.nulls)
+	}
+}
+
+func TestColumnChunkStats(t *testing.T) {
+	chunk := newColumnChunk(Column{Name: "n", Type: ColumnInt64})
+	for _, v := range []int64{3, -7, 12, 0} {
+		chunk.addInt64(v)
+	}
+
+	if min := int64(binary.LittleEndian.Uint64(chunk.min)); min != -7 {
+		t.Errorf("Expected min -7, got %d", min)
+	}
+	if max := int64(binary.LittleEndian.Uint64(chunk.max)); max != 12 {
+		t.Errorf("Expected max 12, got %d", max)
+	}
+}
+
+func testFeatures() []*geojson.Feature {
+	features := []*geojson.Feature{
+		geojson.NewFeature(orb.Point{1, 2}),
+		geojson.NewFeature(orb.LineString{{0, 0}, {4, 5}}),
+		geojson.NewFeature(orb.Point{-3, 1}),
+		geojson.NewFeature(nil),
+	}
+	features[0].Properties["name"] = "a"
+	features[0].Properties["count"] = int64(1)
+	features[1].Properties["count"] = uint64(2)
+	features[1].Properties["height"] = float32(1.5)
+	features[2].Properties["name"] = "c"
+	features[2].Properties["tags"] = []interface{}{"x"}
+	features[3].Properties["geometry"] = "dropped"
+	return features
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	writer, err := NewWriter(Options{RowGroupSize: 3, Compression: CompressionNone, EPSG: 4326})
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	defer writer.Close()
+
+	features := testFeatures()
+	for _, feature := range features {
+		if err := writer.Write(feature); err != nil {
+			t.Fatalf("Failed to write feature: %v", err)
+		}
+	}
+
+	expectedColumns := []Column{
+		{Name: "count", Type: ColumnInt64},
+		{Name: "height", Type: ColumnFloat},
+		{Name: "name", Type: ColumnString},
+		{Name: "tags", Type: ColumnJSON},
+		{Name: "geometry", Type: ColumnGeometry},
+	}
+	if columns := writer.Columns(); !reflect.DeepEqual(columns, expectedColumns) {
+		t.Errorf("Expected columns %v, got %v", expectedColumns, columns)
+	}
+
+	var buf bytes.Buffer
+	n, err := writer.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("Expected %d bytes written, got %d", buf.Len(), n)
+	}
+	data := buf.Bytes()
+	footer := readFooter(t, data)
+
+	if rows := footer[3].(int64); rows != 4 {
+		t.Errorf("Expected 4 rows, got %d", rows)
+	}
+
+	schema := footer[2].([]interface{})
+	if len(schema) != len(expectedColumns)+1 {
+		t.Fatalf("Expected %d schema elements, got %d", len(expectedColumns)+1, len(schema))
+	}
+	expectedPhysical := []int64{int64(physicalInt64), int64(physicalFloat), int64(physicalByteArray), int64(physicalByteArray), int64(physicalByteArray)}
+	for i, column := range expectedColumns {
+		element := schema[i+1].(thriftStructValue)
+		if name := string(element[4].([]byte)); name != column.Name {
+			t.Errorf("Expected column %s, got %s", column.Name, name)
+		}
+		if physical := element[1].(int64); physical != expectedPhysical[i] {
+			t.Errorf("Expected column %s physical type %d, got %d", column.Name, expectedPhysical[i], physical)
+		}
+	}
+
+	rowGroups := footer[4].([]interface{})
+	if len(rowGroups) != 2 {
+		t.Fatalf("Expected 2 row groups, got %d", len(rowGroups))
+	}
+	for i, expected := range []int64{3, 1} {
+		if rows := rowGroups[i].(thriftStructValue)[3].(int64); rows != expected {
+			t.Errorf("Expected row group %d to have %d rows, got %d", i, expected, rows)
+		}
+	}
+
+	// Read the first geometry of the first row group back from its uncompressed page
+	chunks := rowGroups[0].(thriftStructValue)[1].([]interface{})
+	offset := chunks[len(chunks)-1].(thriftStructValue)[2].(int64)
+	pageReader := &thriftReader{data: data[offset:]}
+	pageReader.readStruct()
+	page := data[int(offset)+pageReader.pos:]
+	levels := binary.LittleEndian.Uint32(page)
+	values := page[4+levels:]
+	geometry, err := wkb.Unmarshal(values[4 : 4+binary.LittleEndian.Uint32(values)])
+	if err != nil {
+		t.Fatalf("Failed to decode geometry: %v", err)
+	}
+	if !orb.Equal(geometry, features[0].Geometry) {
+		t.Errorf("Expected geometry %v, got %v", features[0].Geometry, geometry)
+	}
+
+	keyValues := footer[5].([]interface{})
+	keyValue := keyValues[0].(thriftStructValue)
+	if key := string(keyValue[1].([]byte)); key != "geo" {
+		t.Fatalf("Expected geo metadata, got %s", key)
+	}
+	var geo geoMetadata
+	if err := json.Unmarshal(keyValue[2].([]byte), &geo); err != nil {
+		t.Fatalf("Failed to decode geo metadata: %v", err)
+	}
+	column := geo.Columns[GeometryColumn]
+	if geo.Version != Version || geo.PrimaryColumn != GeometryColumn || column == nil {
+		t.Fatalf("Expected version %s with primary column %s, got %+v", Version, GeometryColumn, geo)
+	}
+	if expected := []string{"LineString", "Point"}; !reflect.DeepEqual(column.GeometryTypes, expected) {
+		t.Errorf("Expected geometry types %v, got %v", expected, column.GeometryTypes)
+	}
+	if expected := []float64{-3, 0, 4, 5}; !reflect.DeepEqual(column.BBox, expected) {
+		t.Errorf("Expected bbox %v, got %v", expected, column.BBox)
+	}
+	if column.CRS != nil {
+		t.Errorf("Expected no CRS for EPSG:4326, got %s", column.CRS)
+	}
+}
+
+func TestWriterCompression(t *testing.T) {
+	for _, codec := range []Codec{CompressionNone, CompressionSnappy, CompressionGzip, CompressionZstd} {
+		writer, err := NewWriter(Options{Compression: codec, EPSG: 3857})
+		if err != nil {
+			t.Fatalf("%s: failed to create writer: %v", codec, err)
+		}
+		for _, feature := range testFeatures() {
+			if err := writer.Write(feature); err != nil {
+				t.Fatalf("%s: failed to write feature: %v", codec, err)
+			}
+		}
+
+		var buf bytes.Buffer
+		if _, err := writer.WriteTo(&buf); err != nil {
+			t.Fatalf("%s: failed to write file: %v", codec, err)
+		}
+		writer.Close()
+
+		footer := readFooter(t, buf.Bytes())
+		chunks := footer[4].([]interface{})[0].(thriftStructValue)[1].([]interface{})
+		for _, chunk := range chunks {
+			metadata := chunk.(thriftStructValue)[3].(thriftStructValue)
+			if id := metadata[4].(int64); id != int64(codecIDs[codec]) {
+				t.Errorf("%s: expected codec %d, got %d", codec, codecIDs[codec], id)
+			}
+		}
+
+		var geo geoMetadata
+		json.Unmarshal(footer[5].([]interface{})[0].(thriftStructValue)[2].([]byte), &geo)
+		if crs := geo.Columns[GeometryColumn].CRS; !bytes.Contains(crs, []byte(`"code":3857`)) {
+			t.Errorf("%s: expected an EPSG:3857 CRS, got %s", codec, crs)
+		}
+	}
+}
+
+func TestWriterEmpty(t *testing.T) {
+	writer, err := NewWriter(Options{})
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	defer writer.Close()
+
+	var buf bytes.Buffer
+	if _, err := writer.WriteTo(&buf); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	footer := readFooter(t, buf.Bytes())
+	if rows := footer[3].(int64); rows != 0 {
+		t.Errorf("Expected 0 rows, got %d", rows)
+	}
+	if rowGroups := footer[4].([]interface{}); len(rowGroups) != 0 {
+		t.Errorf("Expected no row groups, got %d", len(rowGroups))
+	}
+}
+
+func TestNewWriterInvalidOptions(t *testing.T) {
+	tests := []Options{
+		{RowGroupSize: -1},
+		{Compression: "lz4"},
+		{EPSG: 27700},
+	}
+
+	for _, options := range tests {
+		if writer, err := NewWriter(options); err == nil {
+			writer.Close()
+			t.Errorf("Expected an error for options %+v", options)
+		}
+	}
+}
@@ -0,0 +1,465 @@
+// pkg/geoparquet/parquet.go - Parquet file encoding
+package geoparquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+)
+
+// magic starts and ends every Parquet file
+var magic = []byte("PAR1")
+
+// Parquet physical types
+const (
+	physicalBoolean   int32 = 0
+	physicalInt64     int32 = 2
+	physicalFloat     int32 = 4
+	physicalDouble    int32 = 5
+	physicalByteArray int32 = 6
+)
+
+// Parquet converted types, written alongside logical types for older readers
+const (
+	convertedUTF8   int32 = 0
+	convertedUint64 int32 = 14
+	convertedJSON   int32 = 19
+)
+
+// Parquet encodings, page types and repetitions
+const (
+	encodingPlain      int32 = 0
+	encodingRLE        int32 = 3
+	pageTypeData       int32 = 0
+	repetitionOptional int32 = 1
+)
+
+// Codec is a Parquet page compression codec
+type Codec string
+
+// Supported codecs
+const (
+	CompressionNone   Codec = "none"
+	CompressionSnappy Codec = "snappy"
+	CompressionGzip   Codec = "gzip"
+	CompressionZstd   Codec = "zstd"
+)
+
+// codecIDs maps codecs to their Parquet CompressionCodec values
+var codecIDs = map[Codec]int32{
+	CompressionNone:   0,
+	CompressionSnappy: 1,
+	CompressionGzip:   2,
+	CompressionZstd:   6,
+}
+
+// ParseCodec parses a codec name
+func ParseCodec(name string) (Codec, error) {
+	codec := Codec(name)
+	if _, ok := codecIDs[codec]; !ok {
+		return "", fmt.Errorf("unsupported parquet compression %q, must be none, snappy, gzip or zstd", name)
+	}
+	return codec, nil
+}
+
+// ColumnType is the type of a GeoParquet column
+type ColumnType string
+
+// Column types. Integers are 64-bit; unsigned integers keep the full uint64 range.
+const (
+	ColumnBoolean  ColumnType = "boolean"
+	ColumnInt64    ColumnType = "int64"
+	ColumnUint64   ColumnType = "uint64"
+	ColumnFloat    ColumnType = "float"
+	ColumnDouble   ColumnType = "double"
+	ColumnString   ColumnType = "string"
+	ColumnJSON     ColumnType = "json"
+	ColumnGeometry ColumnType = "geometry" // WKB
+)
+
+// Column describes a column of the file
+type Column struct {
+	Name string
+	Type ColumnType
+}
+
+// physicalType returns the Parquet physical type storing a column type
+func (t ColumnType) physicalType() int32 {
+	switch t {
+	case ColumnBoolean:
+		return physicalBoolean
+	case ColumnInt64, ColumnUint64:
+		return physicalInt64
+	case ColumnFloat:
+		return physicalFloat
+	case ColumnDouble:
+		return physicalDouble
+	default:
+		return physicalByteArray
+	}
+}
+
+// writeSchemaElement writes a column's SchemaElement. Every column is optional, so
+// features may lack any property or geometry.
+func (c Column) writeSchemaElement(w *thriftWriter) {
+	w.beginStruct()
+	w.i32Field(1, c.Type.physicalType())
+	w.i32Field(3, repetitionOptional)
+	w.binaryField(4, []byte(c.Name))
+
+	switch c.Type {
+	case ColumnString:
+		w.i32Field(6, convertedUTF8)
+		w.structField(10)
+		w.structField(1) // StringType
+		w.endStruct()
+		w.endStruct()
+	case ColumnJSON:
+		w.i32Field(6, convertedJSON)
+		w.structField(10)
+		w.structField(12) // JsonType
+		w.endStruct()
+		w.endStruct()
+	case ColumnUint64:
+		w.i32Field(6, convertedUint64)
+		w.structField(10)
+		w.structField(10) // IntType
+		w.byteField(1, 64)
+		w.boolField(2, false)
+		w.endStruct()
+		w.endStruct()
+	}
+	w.endStruct()
+}
+
+// columnChunk buffers one column of a row group
+type columnChunk struct {
+	column Column
+	levels []byte // Definition level of each row: 1 when the value is present
+	values []byte // Plain-encoded present values, except booleans
+	bools  []bool
+	nulls  int64
+
+	// Statistics for numeric columns, plain-encoded
+	min, max []byte
+}
+
+func newColumnChunk(column Column) *columnChunk {
+	return &columnChunk{column: column}
+}
+
+// reset empties the chunk for the next row group
+func (c *columnChunk) reset() {
+	c.levels = c.levels[:0]
+	c.values = c.values[:0]
+	c.bools = c.bools[:0]
+	c.nulls = 0
+	c.min, c.max = nil, nil
+}
+
+func (c *columnChunk) addNull() {
+	c.levels = append(c.levels, 0)
+	c.nulls++
+}
+
+func (c *columnChunk) addBool(v bool) {
+	c.levels = append(c.levels, 1)
+	c.bools = append(c.bools, v)
+}
+
+func (c *columnChunk) addInt64(v int64) {
+	c.levels = append(c.levels, 1)
+	c.values = binary.LittleEndian.AppendUint64(c.values, uint64(v))
+	c.updateStats(c.values[len(c.values)-8:])
+}
+
+func (c *columnChunk) addFloat(v float32) {
+	c.levels = append(c.levels, 1)
+	c.values = binary.LittleEndian.AppendUint32(c.values, math.Float32bits(v))
+	if !math.IsNaN(float64(v)) {
+		c.updateStats(c.values[len(c.values)-4:])
+	}
+}
+
+func (c *columnChunk) addDouble(v float64) {
+	c.levels = append(c.levels, 1)
+	c.values = binary.LittleEndian.AppendUint64(c.values, math.Float64bits(v))
+	if !math.IsNaN(v) {
+		c.updateStats(c.values[len(c.values)-8:])
+	}
+}
+
+func (c *columnChunk) addBytes(v []byte) {
+	c.levels = append(c.levels, 1)
+	c.values = binary.LittleEndian.AppendUint32(c.values, uint32(len(v)))
+	c.values = append(c.values, v...)
+}
+
+// updateStats widens the chunk's min and max with a plain-encoded numeric value
+func (c *columnChunk) updateStats(value []byte) {
+	if c.min == nil || c.less(value, c.min) {
+		c.min = append(c.min[:0], value...)
+	}
+	if c.max == nil || c.less(c.max, value) {
+		c.max = append(c.max[:0], value...)
+	}
+}
+
+// less compares plain-encoded values in the column type's sort order
+func (c *columnChunk) less(a, b []byte) bool {
+	switch c.column.Type {
+	case ColumnInt64:
+		return int64(binary.LittleEndian.Uint64(a)) < int64(binary.LittleEndian.Uint64(b))
+	case ColumnUint64:
+		return binary.LittleEndian.Uint64(a) < binary.LittleEndian.Uint64(b)
+	case ColumnFloat:
+		return math.Float32frombits(binary.LittleEndian.Uint32(a)) < math.Float32frombits(binary.LittleEndian.Uint32(b))
+	default:
+		return math.Float64frombits(binary.LittleEndian.Uint64(a)) < math.Float64frombits(binary.LittleEndian.Uint64(b))
+	}
+}
+
+// page returns the uncompressed data page: the definition levels, length-prefixed
+// and run-length encoded, followed by the values
+func (c *columnChunk) page() []byte {
+	var levels []byte
+	for i := 0; i < len(c.levels); {
+		run := 1
+		for i+run < len(c.levels) && c.levels[i+run] == c.levels[i] {
+			run++
+		}
+		levels = binary.AppendUvarint(levels, uint64(run)<<1)
+		levels = append(levels, c.levels[i])
+		i += run
+	}
+
+	page := binary.LittleEndian.AppendUint32(nil, uint32(len(levels)))
+	page = append(page, levels...)
+
+	if c.column.Type == ColumnBoolean {
+		packed := make([]byte, (len(c.bools)+7)/8)
+		for i, v := range c.bools {
+			if v {
+				packed[i/8] |= 1 << (i % 8)
+			}
+		}
+		return append(page, packed...)
+	}
+	return append(page, c.values...)
+}
+
+// chunkMetadata records where a column chunk was written, for the file footer
+type chunkMetadata struct {
+	column           Column
+	codec            Codec
+	numValues        int64
+	nulls            int64
+	min, max         []byte
+	offset           int64
+	uncompressedSize int64
+	compressedSize   int64
+}
+
+// rowGroupMetadata records a written row group
+type rowGroupMetadata struct {
+	chunks  []*chunkMetadata
+	numRows int64
+}
+
+// fileWriter writes a Parquet file row group by row group, then its footer
+type fileWriter struct {
+	out       io.Writer
+	offset    int64
+	codec     Codec
+	columns   []Column
+	rowGroups []*rowGroupMetadata
+	numRows   int64
+	zstd      *zstd.Encoder
+}
+
+// newFileWriter writes the file's leading magic and returns a writer for its row groups
+func newFileWriter(out io.Writer, columns []Column, codec Codec) (*fileWriter, error) {
+	f := &fileWriter{out: out, codec: codec, columns: columns}
+	if codec == CompressionZstd {
+		encoder, err := zstd.NewWriter(nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zstd encoder: %w", err)
+		}
+		f.zstd = encoder
+	}
+	if err := f.write(magic); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *fileWriter) write(data []byte) error {
+	n, err := f.out.Write(data)
+	f.offset += int64(n)
+	return err
+}
+
+// compress compresses a page with the file's codec
+func (f *fileWriter) compress(page []byte) ([]byte, error) {
+	switch f.codec {
+	case CompressionSnappy:
+		return s2.EncodeSnappy(nil, page), nil
+	case CompressionGzip:
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		if _, err := writer.Write(page); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case CompressionZstd:
+		return f.zstd.EncodeAll(page, nil), nil
+	default:
+		return page, nil
+	}
+}
+
+// writeRowGroup writes one row group, with each column chunk as a single data page
+func (f *fileWriter) writeRowGroup(chunks []*columnChunk, numRows int) error {
+	rowGroup := &rowGroupMetadata{numRows: int64(numRows)}
+
+	for _, chunk := range chunks {
+		page := chunk.page()
+		compressed, err := f.compress(page)
+		if err != nil {
+			return fmt.Errorf("failed to compress column %s: %w", chunk.column.Name, err)
+		}
+
+		header := newThriftWriter()
+		header.i32Field(1, pageTypeData)
+		header.i32Field(2, int32(len(page)))
+		header.i32Field(3, int32(len(compressed)))
+		header.structField(5)
+		header.i32Field(1, int32(numRows))
+		header.i32Field(2, encodingPlain)
+		header.i32Field(3, encodingRLE)
+		header.i32Field(4, encodingRLE)
+		header.endStruct()
+		headerData := header.bytes()
+
+		metadata := &chunkMetadata{
+			column:           chunk.column,
+			codec:            f.codec,
+			numValues:        int64(numRows),
+			nulls:            chunk.nulls,
+			min:              chunk.min,
+			max:              chunk.max,
+			offset:           f.offset,
+			uncompressedSize: int64(len(headerData) + len(page)),
+			compressedSize:   int64(len(headerData) + len(compressed)),
+		}
+		if err := f.write(headerData); err != nil {
+			return err
+		}
+		if err := f.write(compressed); err != nil {
+			return err
+		}
+		rowGroup.chunks = append(rowGroup.chunks, metadata)
+	}
+
+	f.rowGroups = append(f.rowGroups, rowGroup)
+	f.numRows += int64(numRows)
+	return nil
+}
+
+// close writes the footer, with the given key-value metadata
+func (f *fileWriter) close(metadata map[string]string, keys []string) error {
+	if f.zstd != nil {
+		defer f.zstd.Close()
+	}
+
+	w := newThriftWriter()
+	w.i32Field(1, 1)
+
+	w.listField(2, thriftStruct, len(f.columns)+1)
+	w.beginStruct()
+	w.binaryField(4, []byte("schema"))
+	w.i32Field(5, int32(len(f.columns)))
+	w.endStruct()
+	for _, column := range f.columns {
+		column.writeSchemaElement(w)
+	}
+
+	w.i64Field(3, f.numRows)
+
+	w.listField(4, thriftStruct, len(f.rowGroups))
+	for _, rowGroup := range f.rowGroups {
+		writeRowGroupMetadata(w, rowGroup)
+	}
+
+	w.listField(5, thriftStruct, len(keys))
+	for _, key := range keys {
+		w.beginStruct()
+		w.binaryField(1, []byte(key))
+		w.binaryField(2, []byte(metadata[key]))
+		w.endStruct()
+	}
+
+	w.binaryField(6, []byte("tile-to-json"))
+
+	footer := w.bytes()
+	if err := f.write(footer); err != nil {
+		return err
+	}
+	if err := f.write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer)))); err != nil {
+		return err
+	}
+	return f.write(magic)
+}
+
+// writeRowGroupMetadata writes a RowGroup struct as a list element
+func writeRowGroupMetadata(w *thriftWriter, rowGroup *rowGroupMetadata) {
+	var uncompressed, compressed int64
+	for _, chunk := range rowGroup.chunks {
+		uncompressed += chunk.uncompressedSize
+		compressed += chunk.compressedSize
+	}
+
+	w.beginStruct()
+	w.listField(1, thriftStruct, len(rowGroup.chunks))
+	for _, chunk := range rowGroup.chunks {
+		w.beginStruct()
+		w.i64Field(2, chunk.offset)
+		w.structField(3)
+		w.i32Field(1, chunk.column.Type.physicalType())
+		w.listField(2, thriftI32, 2)
+		w.i32(encodingPlain)
+		w.i32(encodingRLE)
+		w.listField(3, thriftBinary, 1)
+		w.binary([]byte(chunk.column.Name))
+		w.i32Field(4, codecIDs[chunk.codec])
+		w.i64Field(5, chunk.numValues)
+		w.i64Field(6, chunk.uncompressedSize)
+		w.i64Field(7, chunk.compressedSize)
+		w.i64Field(9, chunk.offset)
+		w.structField(12)
+		w.i64Field(3, chunk.nulls)
+		if chunk.max != nil {
+			w.binaryField(5, chunk.max)
+			w.binaryField(6, chunk.min)
+		}
+		w.endStruct()
+		w.endStruct()
+		w.endStruct()
+	}
+	w.i64Field(2, uncompressed)
+	w.i64Field(3, rowGroup.numRows)
+	if len(rowGroup.chunks) > 0 {
+		w.i64Field(5, rowGroup.chunks[0].offset)
+	}
+	w.i64Field(6, compressed)
+	w.endStruct()
+}
@@ -0,0 +1,122 @@
+// pkg/geoparquet/thrift.go - Thrift compact protocol encoding for Parquet metadata
+package geoparquet
+
+import "encoding/binary"
+
+// Thrift compact protocol type codes
+const (
+	thriftTrue   = 1
+	thriftFalse  = 2
+	thriftByte   = 3
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes Parquet's page headers and file metadata with the Thrift
+// compact protocol. Field IDs are delta-encoded against the previous field of the
+// enclosing struct, so the writer keeps one last ID per open struct.
+type thriftWriter struct {
+	buf  []byte
+	last []int16
+}
+
+// newThriftWriter creates a writer with the top-level struct open
+func newThriftWriter() *thriftWriter {
+	return &thriftWriter{last: []int16{0}}
+}
+
+// bytes closes the top-level struct and returns the encoding
+func (w *thriftWriter) bytes() []byte {
+	w.endStruct()
+	return w.buf
+}
+
+// field writes a field header
+func (w *thriftWriter) field(id int16, fieldType byte) {
+	top := len(w.last) - 1
+	if delta := id - w.last[top]; delta > 0 && delta <= 15 {
+		w.buf = append(w.buf, byte(delta)<<4|fieldType)
+	} else {
+		w.buf = append(w.buf, fieldType)
+		w.varint(uint64(zigzag(int64(id))))
+	}
+	w.last[top] = id
+}
+
+func (w *thriftWriter) varint(v uint64) {
+	w.buf = binary.AppendUvarint(w.buf, v)
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
+
+func (w *thriftWriter) boolField(id int16, v bool) {
+	if v {
+		w.field(id, thriftTrue)
+	} else {
+		w.field(id, thriftFalse)
+	}
+}
+
+func (w *thriftWriter) byteField(id int16, v int8) {
+	w.field(id, thriftByte)
+	w.buf = append(w.buf, byte(v))
+}
+
+func (w *thriftWriter) i32Field(id int16, v int32) {
+	w.field(id, thriftI32)
+	w.varint(zigzag(int64(v)))
+}
+
+func (w *thriftWriter) i64Field(id int16, v int64) {
+	w.field(id, thriftI64)
+	w.varint(zigzag(v))
+}
+
+func (w *thriftWriter) binaryField(id int16, v []byte) {
+	w.field(id, thriftBinary)
+	w.binary(v)
+}
+
+func (w *thriftWriter) binary(v []byte) {
+	w.varint(uint64(len(v)))
+	w.buf = append(w.buf, v...)
+}
+
+// listField writes the header of a list field with n elements of elementType. The
+// elements follow: i32 values, binaries, or structs each between beginStruct and
+// endStruct.
+func (w *thriftWriter) listField(id int16, elementType byte, n int) {
+	w.field(id, thriftList)
+	if n < 15 {
+		w.buf = append(w.buf, byte(n)<<4|elementType)
+		return
+	}
+	w.buf = append(w.buf, 0xF0|elementType)
+	w.varint(uint64(n))
+}
+
+func (w *thriftWriter) i32(v int32) {
+	w.varint(zigzag(int64(v)))
+}
+
+// structField opens a struct-valued field
+func (w *thriftWriter) structField(id int16) {
+	w.field(id, thriftStruct)
+	w.beginStruct()
+}
+
+// beginStruct opens a struct, such as a list element
+func (w *thriftWriter) beginStruct() {
+	w.last = append(w.last, 0)
+}
+
+// endStruct writes the stop field of the innermost open struct
+func (w *thriftWriter) endStruct() {
+	w.buf = append(w.buf, 0)
+	w.last = w.last[:len(w.last)-1]
+}
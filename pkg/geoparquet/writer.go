@@ -0,0 +1,405 @@
+// pkg/geoparquet/writer.go - GeoParquet file writer
+package geoparquet
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/wkb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/valpere/tile_to_json/pkg/mvt"
+)
+
+// Defaults used for zero Options fields
+const (
+	DefaultRowGroupSize = 65536
+	DefaultCompression  = CompressionSnappy
+)
+
+// Options configures a GeoParquet file
+type Options struct {
+	RowGroupSize int   // Rows per row group
+	Compression  Codec // Page compression codec
+	EPSG         int   // EPSG code of the coordinates, 4326 or 3857; 0 records an unknown CRS
+}
+
+// Writer builds a GeoParquet file from features. Parquet needs the schema before the
+// first row group, and the schema is inferred from every feature, so features are
+// spooled to a temporary file and the file is assembled by WriteTo. Geometries are
+// stored as WKB in the "geometry" column, after the property columns in name order;
+// a property with the geometry column's name is dropped. Writer is safe for
+// concurrent use.
+type Writer struct {
+	options       Options
+	spool         *os.File
+	spoolBuffer   *bufio.Writer
+	spoolLength   int64
+	count         int
+	kinds         map[string]uint8
+	geometryTypes map[string]bool
+	bound         orb.Bound
+	hasBound      bool
+	mutex         sync.Mutex
+}
+
+// NewWriter creates a writer
+func NewWriter(options Options) (*Writer, error) {
+	if options.RowGroupSize == 0 {
+		options.RowGroupSize = DefaultRowGroupSize
+	}
+	if options.RowGroupSize < 0 {
+		return nil, fmt.Errorf("invalid row group size %d", options.RowGroupSize)
+	}
+	if options.Compression == "" {
+		options.Compression = DefaultCompression
+	}
+	if _, err := ParseCodec(string(options.Compression)); err != nil {
+		return nil, err
+	}
+	if options.EPSG != 0 && options.EPSG != 4326 && options.EPSG != 3857 {
+		return nil, fmt.Errorf("unsupported CRS EPSG:%d", options.EPSG)
+	}
+
+	spool, err := os.CreateTemp("", "geoparquet-*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spool file: %w", err)
+	}
+
+	return &Writer{
+		options:       options,
+		spool:         spool,
+		spoolBuffer:   bufio.NewWriter(spool),
+		kinds:         make(map[string]uint8),
+		geometryTypes: make(map[string]bool),
+	}, nil
+}
+
+// Write adds a feature to the file
+func (w *Writer) Write(feature *geojson.Feature) error {
+	if feature == nil {
+		return nil
+	}
+
+	var geometry []byte
+	if feature.Geometry != nil {
+		var err error
+		if geometry, err = wkb.Marshal(feature.Geometry); err != nil {
+			return fmt.Errorf("failed to encode geometry: %w", err)
+		}
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.spool == nil {
+		return fmt.Errorf("writer is closed")
+	}
+
+	record := binary.AppendUvarint(nil, uint64(len(geometry)))
+	record = append(record, geometry...)
+	record, err := w.appendProperties(record, feature.Properties)
+	if err != nil {
+		return err
+	}
+
+	length := binary.AppendUvarint(nil, uint64(len(record)))
+	if _, err := w.spoolBuffer.Write(length); err != nil {
+		return fmt.Errorf("failed to spool feature: %w", err)
+	}
+	if _, err := w.spoolBuffer.Write(record); err != nil {
+		return fmt.Errorf("failed to spool feature: %w", err)
+	}
+	w.spoolLength += int64(len(length) + len(record))
+	w.count++
+
+	if feature.Geometry != nil {
+		if name := geometryTypeName(feature.Geometry); name != "" {
+			w.geometryTypes[name] = true
+		}
+		bound := feature.Geometry.Bound()
+		if w.hasBound {
+			bound = bound.Union(w.bound)
+		}
+		w.bound, w.hasBound = bound, true
+	}
+	return nil
+}
+
+// FeatureCount returns the number of features written so far
+func (w *Writer) FeatureCount() int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.count
+}
+
+// Columns returns the columns inferred from the features written so far
+func (w *Writer) Columns() []Column {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.columns()
+}
+
+func (w *Writer) columns() []Column {
+	columns := make([]Column, 0, len(w.kinds)+1)
+	for name, kinds := range w.kinds {
+		columns = append(columns, Column{Name: name, Type: columnType(kinds)})
+	}
+	sort.Slice(columns, func(i, j int) bool { return columns[i].Name < columns[j].Name })
+	return append(columns, Column{Name: GeometryColumn, Type: ColumnGeometry})
+}
+
+// WriteTo assembles the file and writes it to out, in the order features were written
+func (w *Writer) WriteTo(out io.Writer) (int64, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.spool == nil {
+		return 0, fmt.Errorf("writer is closed")
+	}
+	if err := w.spoolBuffer.Flush(); err != nil {
+		return 0, fmt.Errorf("failed to flush spool file: %w", err)
+	}
+
+	columns := w.columns()
+	chunks := make([]*columnChunk, len(columns))
+	columnIndex := make(map[string]int, len(columns))
+	for i, column := range columns {
+		chunks[i] = newColumnChunk(column)
+		columnIndex[column.Name] = i
+	}
+
+	file, err := newFileWriter(out, columns, w.options.Compression)
+	if err != nil {
+		return 0, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	reader := bufio.NewReader(io.NewSectionReader(w.spool, 0, w.spoolLength))
+	present := make([]bool, len(columns))
+	rows := 0
+	for i := 0; i < w.count; i++ {
+		length, err := binary.ReadUvarint(reader)
+		if err != nil {
+			return file.offset, fmt.Errorf("failed to read spooled feature: %w", err)
+		}
+		record := make([]byte, length)
+		if _, err := io.ReadFull(reader, record); err != nil {
+			return file.offset, fmt.Errorf("failed to read spooled feature: %w", err)
+		}
+
+		for j := range present {
+			present[j] = false
+		}
+		if err := w.decodeSpooled(record, chunks, columnIndex, present); err != nil {
+			return file.offset, err
+		}
+		for j, chunk := range chunks {
+			if !present[j] {
+				chunk.addNull()
+			}
+		}
+
+		rows++
+		if rows == w.options.RowGroupSize {
+			if err := file.writeRowGroup(chunks, rows); err != nil {
+				return file.offset, fmt.Errorf("failed to write row group: %w", err)
+			}
+			for _, chunk := range chunks {
+				chunk.reset()
+			}
+			rows = 0
+		}
+	}
+	if rows > 0 {
+		if err := file.writeRowGroup(chunks, rows); err != nil {
+			return file.offset, fmt.Errorf("failed to write row group: %w", err)
+		}
+	}
+
+	geo, err := newGeoMetadata(w.geometryTypes, w.bound, w.hasBound, w.options.EPSG)
+	if err != nil {
+		return file.offset, err
+	}
+	if err := file.close(map[string]string{"geo": geo}, []string{"geo"}); err != nil {
+		return file.offset, fmt.Errorf("failed to write file footer: %w", err)
+	}
+	return file.offset, nil
+}
+
+// Close removes the spool file
+func (w *Writer) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.spool == nil {
+		return nil
+	}
+	err := w.spool.Close()
+	os.Remove(w.spool.Name())
+	w.spool = nil
+	return err
+}
+
+// Spooled property values carry their payload by kind (see mvt.ValueKind): bool one
+// byte, integers eight bytes, float four and double eight bytes of IEEE 754, string
+// and JSON a length and bytes.
+
+// appendProperties appends a feature's non-null properties to a spool record and
+// records their kinds for schema inference
+func (w *Writer) appendProperties(record []byte, properties geojson.Properties) ([]byte, error) {
+	names := make([]string, 0, len(properties))
+	for name, value := range properties {
+		if _, ok := mvt.ValueKind(value); ok && name != GeometryColumn {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	record = binary.AppendUvarint(record, uint64(len(names)))
+	for _, name := range names {
+		value := properties[name]
+		kind, _ := mvt.ValueKind(value)
+		w.kinds[name] |= kind
+
+		record = binary.AppendUvarint(record, uint64(len(name)))
+		record = append(record, name...)
+		record = append(record, kind)
+
+		switch kind {
+		case mvt.KindBool:
+			if value.(bool) {
+				record = append(record, 1)
+			} else {
+				record = append(record, 0)
+			}
+		case mvt.KindInt:
+			record = binary.LittleEndian.AppendUint64(record, uint64(mvt.ToInt64(value)))
+		case mvt.KindUint, mvt.KindLargeUint:
+			record = binary.LittleEndian.AppendUint64(record, mvt.ToUint64(value))
+		case mvt.KindFloat:
+			record = binary.LittleEndian.AppendUint32(record, math.Float32bits(value.(float32)))
+		case mvt.KindDouble:
+			record = binary.LittleEndian.AppendUint64(record, math.Float64bits(value.(float64)))
+		case mvt.KindString:
+			text := value.(string)
+			record = binary.AppendUvarint(record, uint64(len(text)))
+			record = append(record, text...)
+		default:
+			data, err := json.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode property %s: %w", name, err)
+			}
+			record = binary.AppendUvarint(record, uint64(len(data)))
+			record = append(record, data...)
+		}
+	}
+	return record, nil
+}
+
+// decodeSpooled adds a spooled feature's geometry and properties to the row group's
+// column chunks, marking the columns it has values for
+func (w *Writer) decodeSpooled(record []byte, chunks []*columnChunk, columnIndex map[string]int, present []bool) error {
+	r := &recordReader{data: record}
+
+	if geometry := r.bytes(r.uvarint()); len(geometry) > 0 {
+		index := columnIndex[GeometryColumn]
+		chunks[index].addBytes(geometry)
+		present[index] = true
+	}
+
+	count := r.uvarint()
+	for i := uint64(0); i < count; i++ {
+		name := string(r.bytes(r.uvarint()))
+		kind := r.byte()
+
+		var value interface{}
+		switch kind {
+		case mvt.KindBool:
+			value = r.byte() == 1
+		case mvt.KindInt:
+			value = int64(r.uint64())
+		case mvt.KindUint, mvt.KindLargeUint:
+			value = r.uint64()
+		case mvt.KindFloat:
+			value = math.Float32frombits(uint32(r.uint64n(4)))
+		case mvt.KindDouble:
+			value = math.Float64frombits(r.uint64())
+		case mvt.KindString:
+			value = string(r.bytes(r.uvarint()))
+		default:
+			value = json.RawMessage(r.bytes(r.uvarint()))
+		}
+		if r.err != nil {
+			return fmt.Errorf("failed to decode spooled feature: %w", r.err)
+		}
+
+		index := columnIndex[name]
+		if err := chunks[index].addValue(value); err != nil {
+			return fmt.Errorf("failed to encode property %s: %w", name, err)
+		}
+		present[index] = true
+	}
+	return r.err
+}
+
+// recordReader reads the fields of a spool record, remembering the first error
+type recordReader struct {
+	data []byte
+	err  error
+}
+
+func (r *recordReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.fail()
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *recordReader) bytes(n uint64) []byte {
+	if uint64(len(r.data)) < n {
+		r.fail()
+		return nil
+	}
+	v := r.data[:n]
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *recordReader) byte() byte {
+	if v := r.bytes(1); v != nil {
+		return v[0]
+	}
+	return 0
+}
+
+func (r *recordReader) uint64() uint64 {
+	return r.uint64n(8)
+}
+
+// uint64n reads an n-byte little-endian unsigned integer
+func (r *recordReader) uint64n(n uint64) uint64 {
+	v := r.bytes(n)
+	if v == nil {
+		return 0
+	}
+	var buf [8]byte
+	copy(buf[:], v)
+	return binary.LittleEndian.Uint64(buf[:])
+}
+
+func (r *recordReader) fail() {
+	if r.err == nil {
+		r.err = io.ErrUnexpectedEOF
+	}
+	r.data = nil
+}
@@ -0,0 +1,279 @@
+// pkg/kml/kml.go - KML placemark, geometry and style encoding
+package kml
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+// Namespace is the KML 2.2 namespace
+const Namespace = "http://www.opengis.net/kml/2.2"
+
+// DefaultFolder is the folder holding features without a layer
+const DefaultFolder = "features"
+
+// NameProperty is the property shown as a placemark's label when it is a string
+const NameProperty = "name"
+
+// IDProperty is the extended data field holding a feature's ID
+const IDProperty = "_id"
+
+// Style is the simple style of a layer's placemarks. Colors are #RRGGBB or #RRGGBBAA;
+// empty colors and zero sizes are left to the viewer.
+type Style struct {
+	LineColor string  // Line and polygon outline color
+	LineWidth float64 // Line width in pixels
+	FillColor string  // Polygon fill color
+	Icon      string  // Point icon URL
+	IconColor string  // Color the point icon is tinted with
+	IconScale float64 // Point icon scale
+}
+
+// IsZero reports whether the style sets nothing
+func (s Style) IsZero() bool {
+	return s == Style{}
+}
+
+// Validate checks the style's colors and sizes
+func (s Style) Validate() error {
+	for _, color := range []string{s.LineColor, s.FillColor, s.IconColor} {
+		if color == "" {
+			continue
+		}
+		if _, err := ParseColor(color); err != nil {
+			return err
+		}
+	}
+	if s.LineWidth < 0 {
+		return fmt.Errorf("invalid line width %g", s.LineWidth)
+	}
+	if s.IconScale < 0 {
+		return fmt.Errorf("invalid icon scale %g", s.IconScale)
+	}
+	return nil
+}
+
+// ParseColor converts a #RRGGBB or #RRGGBBAA color to KML's aabbggrr order. Colors
+// without alpha are opaque.
+func ParseColor(color string) (string, error) {
+	digits := strings.TrimPrefix(color, "#")
+	if len(digits) != 6 && len(digits) != 8 || !strings.HasPrefix(color, "#") {
+		return "", fmt.Errorf("invalid color %q, must be #RRGGBB or #RRGGBBAA", color)
+	}
+	if _, err := hex.DecodeString(digits); err != nil {
+		return "", fmt.Errorf("invalid color %q, must be #RRGGBB or #RRGGBBAA", color)
+	}
+
+	digits = strings.ToLower(digits)
+	alpha := "ff"
+	if len(digits) == 8 {
+		alpha = digits[6:8]
+	}
+	return alpha + digits[4:6] + digits[2:4] + digits[0:2], nil
+}
+
+// writeStyle writes a shared style element
+func writeStyle(buf *bytes.Buffer, id string, style Style) {
+	fmt.Fprintf(buf, "    <Style id=\"%s\">\n", escape(id))
+	if style.Icon != "" || style.IconColor != "" || style.IconScale > 0 {
+		buf.WriteString("      <IconStyle>\n")
+		if style.IconColor != "" {
+			color, _ := ParseColor(style.IconColor)
+			fmt.Fprintf(buf, "        <color>%s</color>\n", color)
+		}
+		if style.IconScale > 0 {
+			fmt.Fprintf(buf, "        <scale>%s</scale>\n", formatFloat(style.IconScale))
+		}
+		if style.Icon != "" {
+			fmt.Fprintf(buf, "        <Icon><href>%s</href></Icon>\n", escape(style.Icon))
+		}
+		buf.WriteString("      </IconStyle>\n")
+	}
+	if style.LineColor != "" || style.LineWidth > 0 {
+		buf.WriteString("      <LineStyle>\n")
+		if style.LineColor != "" {
+			color, _ := ParseColor(style.LineColor)
+			fmt.Fprintf(buf, "        <color>%s</color>\n", color)
+		}
+		if style.LineWidth > 0 {
+			fmt.Fprintf(buf, "        <width>%s</width>\n", formatFloat(style.LineWidth))
+		}
+		buf.WriteString("      </LineStyle>\n")
+	}
+	if style.FillColor != "" {
+		color, _ := ParseColor(style.FillColor)
+		fmt.Fprintf(buf, "      <PolyStyle>\n        <color>%s</color>\n      </PolyStyle>\n", color)
+	}
+	buf.WriteString("    </Style>\n")
+}
+
+// encodePlacemark encodes a feature as a Placemark, leaving out the layer property
+func encodePlacemark(feature *geojson.Feature, layerProperty, styleID string) ([]byte, error) {
+	var geometry bytes.Buffer
+	if err := writeGeometry(&geometry, feature.Geometry); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("      <Placemark>\n")
+	if name, ok := feature.Properties[NameProperty].(string); ok && name != "" && NameProperty != layerProperty {
+		fmt.Fprintf(&buf, "        <name>%s</name>\n", escapeText(name))
+	}
+	if styleID != "" {
+		fmt.Fprintf(&buf, "        <styleUrl>#%s</styleUrl>\n", escape(styleID))
+	}
+
+	keys := make([]string, 0, len(feature.Properties))
+	for key := range feature.Properties {
+		if key != layerProperty || layerProperty == "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	if len(keys) > 0 || feature.ID != nil {
+		buf.WriteString("        <ExtendedData>\n")
+		if feature.ID != nil {
+			if err := writeData(&buf, IDProperty, feature.ID); err != nil {
+				return nil, err
+			}
+		}
+		for _, key := range keys {
+			if err := writeData(&buf, key, feature.Properties[key]); err != nil {
+				return nil, err
+			}
+		}
+		buf.WriteString("        </ExtendedData>\n")
+	}
+
+	if geometry.Len() > 0 {
+		buf.WriteString("        ")
+		buf.Write(geometry.Bytes())
+		buf.WriteString("\n")
+	}
+	buf.WriteString("      </Placemark>\n")
+	return buf.Bytes(), nil
+}
+
+// writeData writes an extended data field. Arrays and objects are written as JSON.
+func writeData(buf *bytes.Buffer, name string, value interface{}) error {
+	var text string
+	switch v := value.(type) {
+	case nil:
+	case string:
+		text = v
+	case bool:
+		text = strconv.FormatBool(v)
+	case float64:
+		text = strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		text = strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, json.Number:
+		text = fmt.Sprint(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("failed to encode property %s: %w", name, err)
+		}
+		text = string(data)
+	}
+	fmt.Fprintf(buf, "          <Data name=\"%s\"><value>%s</value></Data>\n", escape(name), escapeText(text))
+	return nil
+}
+
+// writeGeometry writes a geometry element; nil geometries write nothing
+func writeGeometry(buf *bytes.Buffer, geometry orb.Geometry) error {
+	switch g := geometry.(type) {
+	case nil:
+	case orb.Point:
+		buf.WriteString("<Point><coordinates>")
+		writeCoordinates(buf, []orb.Point{g})
+		buf.WriteString("</coordinates></Point>")
+	case orb.LineString:
+		buf.WriteString("<LineString><coordinates>")
+		writeCoordinates(buf, g)
+		buf.WriteString("</coordinates></LineString>")
+	case orb.Ring:
+		return writeGeometry(buf, orb.Polygon{g})
+	case orb.Bound:
+		return writeGeometry(buf, g.ToPolygon())
+	case orb.Polygon:
+		buf.WriteString("<Polygon>")
+		for i, ring := range g {
+			boundary := "innerBoundaryIs"
+			if i == 0 {
+				boundary = "outerBoundaryIs"
+			}
+			fmt.Fprintf(buf, "<%s><LinearRing><coordinates>", boundary)
+			writeCoordinates(buf, ring)
+			fmt.Fprintf(buf, "</coordinates></LinearRing></%s>", boundary)
+		}
+		buf.WriteString("</Polygon>")
+	case orb.MultiPoint:
+		buf.WriteString("<MultiGeometry>")
+		for _, p := range g {
+			writeGeometry(buf, p)
+		}
+		buf.WriteString("</MultiGeometry>")
+	case orb.MultiLineString:
+		buf.WriteString("<MultiGeometry>")
+		for _, line := range g {
+			writeGeometry(buf, line)
+		}
+		buf.WriteString("</MultiGeometry>")
+	case orb.MultiPolygon:
+		buf.WriteString("<MultiGeometry>")
+		for _, polygon := range g {
+			writeGeometry(buf, polygon)
+		}
+		buf.WriteString("</MultiGeometry>")
+	case orb.Collection:
+		buf.WriteString("<MultiGeometry>")
+		for _, child := range g {
+			if err := writeGeometry(buf, child); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("</MultiGeometry>")
+	default:
+		return fmt.Errorf("unsupported geometry type %T", geometry)
+	}
+	return nil
+}
+
+// writeCoordinates writes points as space-separated lon,lat tuples
+func writeCoordinates(buf *bytes.Buffer, points []orb.Point) {
+	for i, p := range points {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(formatFloat(p[0]))
+		buf.WriteByte(',')
+		buf.WriteString(formatFloat(p[1]))
+	}
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// escape escapes text for XML character data and attribute values
+func escape(text string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(text))
+	return buf.String()
+}
+
+// escapeText escapes text for XML character data, keeping line breaks readable
+func escapeText(text string) string {
+	return strings.ReplaceAll(escape(text), "&#xA;", "\n")
+}
@@ -0,0 +1,309 @@
+// pkg/kml/kml_test.go - Unit tests for the KML writer
+package kml
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		color    string
+		expected string
+		valid    bool
+	}{
+		{"#FF8000", "ff0080ff", true},
+		{"#ff800080", "800080ff", true},
+		{"#000000", "ff000000", true},
+		{"FF8000", "", false},
+		{"#FF80", "", false},
+		{"#GG8000", "", false},
+		{"", "", false},
+	}
+
+	for _, test := range tests {
+		got, err := ParseColor(test.color)
+		if test.valid != (err == nil) {
+			t.Errorf("Expected valid %v for %q, got error %v", test.valid, test.color, err)
+			continue
+		}
+		if got != test.expected {
+			t.Errorf("Expected %q for %q, got %q", test.expected, test.color, got)
+		}
+	}
+}
+
+func TestWriteGeometry(t *testing.T) {
+	tests := []struct {
+		name     string
+		geometry orb.Geometry
+		expected string
+	}{
+		{"nil", nil, ""},
+		{"point", orb.Point{-74.006, 40.7128}, "<Point><coordinates>-74.006,40.7128</coordinates></Point>"},
+		{"line", orb.LineString{{0, 0}, {1, 0.5}}, "<LineString><coordinates>0,0 1,0.5</coordinates></LineString>"},
+		{
+			"polygon with hole",
+			orb.Polygon{{{0, 0}, {4, 0}, {4, 4}, {0, 0}}, {{1, 1}, {2, 1}, {2, 2}, {1, 1}}},
+			"<Polygon><outerBoundaryIs><LinearRing><coordinates>0,0 4,0 4,4 0,0</coordinates></LinearRing></outerBoundaryIs>" +
+				"<innerBoundaryIs><LinearRing><coordinates>1,1 2,1 2,2 1,1</coordinates></LinearRing></innerBoundaryIs></Polygon>",
+		},
+		{
+			"multipoint",
+			orb.MultiPoint{{0, 0}, {1, 1}},
+			"<MultiGeometry><Point><coordinates>0,0</coordinates></Point><Point><coordinates>1,1</coordinates></Point></MultiGeometry>",
+		},
+		{
+			"collection",
+			orb.Collection{orb.Point{0, 0}, orb.LineString{{0, 0}, {1, 1}}},
+			"<MultiGeometry><Point><coordinates>0,0</coordinates></Point><LineString><coordinates>0,0 1,1</coordinates></LineString></MultiGeometry>",
+		},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := writeGeometry(&buf, test.geometry); err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if buf.String() != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.name, test.expected, buf.String())
+		}
+	}
+}
+
+// document mirrors the parts of a KML document the writer produces
+type document struct {
+	XMLName  xml.Name `xml:"http://www.opengis.net/kml/2.2 kml"`
+	Document struct {
+		Name        string `xml:"name"`
+		Description string `xml:"description"`
+		Styles      []struct {
+			ID        string  `xml:"id,attr"`
+			LineColor string  `xml:"LineStyle>color"`
+			LineWidth float64 `xml:"LineStyle>width"`
+			FillColor string  `xml:"PolyStyle>color"`
+			Icon      string  `xml:"IconStyle>Icon>href"`
+		} `xml:"Style"`
+		Folders []struct {
+			Name       string `xml:"name"`
+			Placemarks []struct {
+				Name     string `xml:"name"`
+				StyleURL string `xml:"styleUrl"`
+				Data     []struct {
+					Name  string `xml:"name,attr"`
+					Value string `xml:"value"`
+				} `xml:"ExtendedData>Data"`
+				Point      *struct{} `xml:"Point"`
+				LineString *struct {
+					Coordinates string `xml:"coordinates"`
+				} `xml:"LineString"`
+				Polygon *struct{} `xml:"Polygon"`
+			} `xml:"Placemark"`
+		} `xml:"Folder"`
+	} `xml:"Document"`
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	writer, err := NewWriter(Options{
+		Name:          "Field survey",
+		LayerProperty: "_layer",
+		Styles: map[string]Style{
+			"roads": {LineColor: "#FF0000", LineWidth: 2},
+			"*":     {Icon: "https://example.com/pin.png"},
+			"water": {FillColor: "#0000FF80"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	defer writer.Close()
+
+	features := []*geojson.Feature{
+		{Type: "Feature", Geometry: orb.LineString{{0, 0}, {1, 1}}, Properties: geojson.Properties{"_layer": "Roads", "name": "Main & 1st", "lanes": int64(2)}},
+		{Type: "Feature", Geometry: orb.Point{2, 2}, Properties: geojson.Properties{"_layer": "places", "tags": []interface{}{"a", "b"}}, ID: float64(7)},
+		{Type: "Feature", Geometry: orb.Polygon{{{0, 0}, {1, 0}, {1, 1}, {0, 0}}}, Properties: geojson.Properties{"_layer": "water"}},
+		{Type: "Feature", Geometry: orb.LineString{{1, 1}, {2, 2}}, Properties: geojson.Properties{"_layer": "Roads", "oneway": true}},
+		{Type: "Feature", Geometry: orb.Point{3, 3}, Properties: geojson.Properties{}},
+	}
+	for _, feature := range features {
+		if err := writer.Write(feature); err != nil {
+			t.Fatalf("Failed to write feature: %v", err)
+		}
+	}
+	writer.SetDescription("Tile 6/33/30\nFeatures: 5")
+
+	if writer.FeatureCount() != 5 {
+		t.Errorf("Expected 5 features, got %d", writer.FeatureCount())
+	}
+	if expected := []string{"Roads", "places", "water", DefaultFolder}; !reflect.DeepEqual(writer.Folders(), expected) {
+		t.Errorf("Expected folders %v, got %v", expected, writer.Folders())
+	}
+
+	var buf bytes.Buffer
+	n, err := writer.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("Failed to write document: %v", err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("Expected %d bytes reported, got %d", buf.Len(), n)
+	}
+	if !strings.Contains(buf.String(), "<description>Tile 6/33/30\nFeatures: 5</description>") {
+		t.Errorf("Expected the description with a literal line break, got %s", buf.String())
+	}
+
+	var doc document
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Failed to parse document: %v\n%s", err, buf.String())
+	}
+
+	if doc.Document.Name != "Field survey" || doc.Document.Description != "Tile 6/33/30\nFeatures: 5" {
+		t.Errorf("Expected the document name and description, got %q and %q", doc.Document.Name, doc.Document.Description)
+	}
+
+	// Every folder has a style: roads ignoring case, water its own, the rest "*"
+	if len(doc.Document.Styles) != 4 {
+		t.Fatalf("Expected 4 styles, got %d", len(doc.Document.Styles))
+	}
+	styles := doc.Document.Styles
+	if styles[0].LineColor != "ff0000ff" || styles[0].LineWidth != 2 {
+		t.Errorf("Expected the roads line style, got %+v", styles[0])
+	}
+	if styles[1].Icon != "https://example.com/pin.png" || styles[3].Icon != "https://example.com/pin.png" {
+		t.Errorf("Expected the default icon style, got %+v and %+v", styles[1], styles[3])
+	}
+	if styles[2].FillColor != "80ff0000" {
+		t.Errorf("Expected the water fill color, got %+v", styles[2])
+	}
+
+	folders := doc.Document.Folders
+	if len(folders) != 4 {
+		t.Fatalf("Expected 4 folders, got %d", len(folders))
+	}
+	roads := folders[0]
+	if roads.Name != "Roads" || len(roads.Placemarks) != 2 {
+		t.Fatalf("Expected 2 Roads placemarks, got %+v", roads)
+	}
+	first := roads.Placemarks[0]
+	if first.Name != "Main & 1st" || first.StyleURL != "#"+styles[0].ID {
+		t.Errorf("Expected the name label and style, got %+v", first)
+	}
+	if first.LineString == nil || first.LineString.Coordinates != "0,0 1,1" {
+		t.Errorf("Expected a LineString, got %+v", first.LineString)
+	}
+	data := map[string]string{}
+	for _, d := range first.Data {
+		data[d.Name] = d.Value
+	}
+	if expected := map[string]string{"lanes": "2", "name": "Main & 1st"}; !reflect.DeepEqual(data, expected) {
+		t.Errorf("Expected extended data %v without the layer, got %v", expected, data)
+	}
+	if roads.Placemarks[1].Data[0].Value != "true" {
+		t.Errorf("Expected a boolean value, got %+v", roads.Placemarks[1].Data)
+	}
+
+	place := folders[1].Placemarks[0]
+	if place.Point == nil || len(place.Data) != 2 {
+		t.Fatalf("Expected a point with two data fields, got %+v", place)
+	}
+	if place.Data[0].Name != IDProperty || place.Data[0].Value != "7" || place.Data[1].Value != `["a","b"]` {
+		t.Errorf("Expected the ID and a JSON array, got %+v", place.Data)
+	}
+	if folders[2].Placemarks[0].Polygon == nil {
+		t.Errorf("Expected a polygon placemark")
+	}
+	if folders[3].Name != DefaultFolder || len(folders[3].Placemarks[0].Data) != 0 {
+		t.Errorf("Expected a default folder placemark without data, got %+v", folders[3])
+	}
+}
+
+func TestWriterKMZ(t *testing.T) {
+	writer, err := NewWriter(Options{Name: "tile", KMZ: true})
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	defer writer.Close()
+
+	if err := writer.Write(&geojson.Feature{Type: "Feature", Geometry: orb.Point{1, 2}, Properties: geojson.Properties{"_layer": "places"}}); err != nil {
+		t.Fatalf("Failed to write feature: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := writer.WriteTo(&buf); err != nil {
+		t.Fatalf("Failed to write kmz: %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Failed to open kmz: %v", err)
+	}
+	if len(archive.File) != 1 || archive.File[0].Name != KMZEntry {
+		t.Fatalf("Expected a single %s entry, got %d entries", KMZEntry, len(archive.File))
+	}
+	entry, err := archive.File[0].Open()
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", KMZEntry, err)
+	}
+	defer entry.Close()
+	content, err := io.ReadAll(entry)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", KMZEntry, err)
+	}
+
+	var doc document
+	if err := xml.Unmarshal(content, &doc); err != nil {
+		t.Fatalf("Failed to parse document: %v", err)
+	}
+	// Without a layer property every feature goes to the default folder, keeping _layer
+	folders := doc.Document.Folders
+	if len(folders) != 1 || folders[0].Name != DefaultFolder || len(folders[0].Placemarks) != 1 {
+		t.Fatalf("Expected one placemark in the default folder, got %+v", folders)
+	}
+	if d := folders[0].Placemarks[0].Data; len(d) != 1 || d[0].Name != "_layer" || d[0].Value != "places" {
+		t.Errorf("Expected the _layer property as data, got %+v", d)
+	}
+	if !strings.HasPrefix(string(content), xmlHeader) {
+		t.Errorf("Expected an XML declaration, got %.40s", content)
+	}
+}
+
+func TestWriterEmpty(t *testing.T) {
+	writer, err := NewWriter(Options{})
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	defer writer.Close()
+
+	var buf bytes.Buffer
+	if _, err := writer.WriteTo(&buf); err != nil {
+		t.Fatalf("Failed to write document: %v", err)
+	}
+
+	expected := xmlHeader + "<kml xmlns=\"" + Namespace + "\">\n  <Document>\n  </Document>\n</kml>\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestNewWriterInvalidStyle(t *testing.T) {
+	tests := []Style{
+		{LineColor: "red"},
+		{FillColor: "#12345"},
+		{LineWidth: -1},
+		{IconScale: -0.5},
+	}
+
+	for _, style := range tests {
+		if _, err := NewWriter(Options{Styles: map[string]Style{"roads": style}}); err == nil {
+			t.Errorf("Expected an error for style %+v", style)
+		}
+	}
+}
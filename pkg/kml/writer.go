@@ -0,0 +1,297 @@
+// pkg/kml/writer.go - KML and KMZ document writer
+package kml
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/paulmach/orb/geojson"
+)
+
+// KMZEntry is the name of the document inside a KMZ archive
+const KMZEntry = "doc.kml"
+
+// Options configures a KML document
+type Options struct {
+	Name string // Document name
+
+	// LayerProperty names the property holding each feature's layer. Features are
+	// written to one Folder per layer and the property is not stored; features without
+	// it, or all features when it is empty, go to DefaultFolder.
+	LayerProperty string
+
+	// Styles maps layer names to the style of their placemarks. Names match ignoring
+	// case, and "*" styles all other layers.
+	Styles map[string]Style
+
+	// KMZ writes the document zipped as a KMZ archive
+	KMZ bool
+}
+
+// Writer builds a KML document with a Folder per layer from features written in any
+// order. Placemarks are encoded as they arrive and spooled to a temporary file, so
+// WriteTo can write each layer's placemarks together. Writer is safe for concurrent
+// use.
+type Writer struct {
+	options     Options
+	description string
+	spool       *os.File
+	spoolBuffer *bufio.Writer
+	spoolLength int64
+	folders     []*folder
+	byName      map[string]*folder
+	count       int
+	mutex       sync.Mutex
+}
+
+// folder locates the spooled placemarks of a layer
+type folder struct {
+	name       string
+	styleID    string
+	placemarks []spooledPlacemark
+}
+
+type spooledPlacemark struct {
+	offset int64
+	length int
+}
+
+// NewWriter creates a writer
+func NewWriter(options Options) (*Writer, error) {
+	for layer, style := range options.Styles {
+		if err := style.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid style for layer %s: %w", layer, err)
+		}
+	}
+
+	spool, err := os.CreateTemp("", "kml-*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spool file: %w", err)
+	}
+
+	return &Writer{
+		options:     options,
+		spool:       spool,
+		spoolBuffer: bufio.NewWriter(spool),
+		byName:      make(map[string]*folder),
+	}, nil
+}
+
+// SetDescription sets the document description
+func (w *Writer) SetDescription(description string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.description = description
+}
+
+// Write adds a feature to the folder of its layer
+func (w *Writer) Write(feature *geojson.Feature) error {
+	if feature == nil {
+		return nil
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.spool == nil {
+		return fmt.Errorf("writer is closed")
+	}
+
+	name := ""
+	if w.options.LayerProperty != "" {
+		name, _ = feature.Properties[w.options.LayerProperty].(string)
+	}
+	if name == "" {
+		name = DefaultFolder
+	}
+	f := w.folder(name)
+
+	placemark, err := encodePlacemark(feature, w.options.LayerProperty, f.styleID)
+	if err != nil {
+		return err
+	}
+	if _, err := w.spoolBuffer.Write(placemark); err != nil {
+		return fmt.Errorf("failed to spool placemark: %w", err)
+	}
+
+	f.placemarks = append(f.placemarks, spooledPlacemark{offset: w.spoolLength, length: len(placemark)})
+	w.spoolLength += int64(len(placemark))
+	w.count++
+	return nil
+}
+
+// folder returns the folder of a layer, creating it with its style on first use
+func (w *Writer) folder(name string) *folder {
+	if f, ok := w.byName[name]; ok {
+		return f
+	}
+	f := &folder{name: name}
+	if _, ok := w.style(name); ok {
+		f.styleID = fmt.Sprintf("style-%d", len(w.folders)+1)
+	}
+	w.byName[name] = f
+	w.folders = append(w.folders, f)
+	return f
+}
+
+// style returns the style of a layer: its own, matched exactly or ignoring case, or
+// the "*" style
+func (w *Writer) style(layer string) (Style, bool) {
+	if style, ok := w.options.Styles[layer]; ok && !style.IsZero() {
+		return style, true
+	}
+
+	keys := make([]string, 0, len(w.options.Styles))
+	for key := range w.options.Styles {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if strings.EqualFold(key, layer) && !w.options.Styles[key].IsZero() {
+			return w.options.Styles[key], true
+		}
+	}
+
+	if style, ok := w.options.Styles["*"]; ok && !style.IsZero() {
+		return style, true
+	}
+	return Style{}, false
+}
+
+// FeatureCount returns the number of features written
+func (w *Writer) FeatureCount() int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.count
+}
+
+// Folders returns the folder names in the order they were first written
+func (w *Writer) Folders() []string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	names := make([]string, len(w.folders))
+	for i, f := range w.folders {
+		names[i] = f.name
+	}
+	return names
+}
+
+// WriteTo writes the document, zipped when the writer is configured for KMZ
+func (w *Writer) WriteTo(out io.Writer) (int64, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.spool == nil {
+		return 0, fmt.Errorf("writer is closed")
+	}
+	if err := w.spoolBuffer.Flush(); err != nil {
+		return 0, fmt.Errorf("failed to flush spool: %w", err)
+	}
+
+	counter := &countingWriter{w: out}
+	if !w.options.KMZ {
+		err := w.writeDocument(counter)
+		return counter.n, err
+	}
+
+	archive := zip.NewWriter(counter)
+	entry, err := archive.Create(KMZEntry)
+	if err != nil {
+		return counter.n, fmt.Errorf("failed to create kmz entry: %w", err)
+	}
+	if err := w.writeDocument(entry); err != nil {
+		return counter.n, err
+	}
+	if err := archive.Close(); err != nil {
+		return counter.n, fmt.Errorf("failed to finish kmz: %w", err)
+	}
+	return counter.n, nil
+}
+
+// writeDocument writes the KML document, reading each folder's placemarks back from
+// the spool
+func (w *Writer) writeDocument(out io.Writer) error {
+	buffered := bufio.NewWriter(out)
+
+	var header bytes.Buffer
+	header.WriteString(xmlHeader)
+	fmt.Fprintf(&header, "<kml xmlns=\"%s\">\n  <Document>\n", Namespace)
+	if w.options.Name != "" {
+		fmt.Fprintf(&header, "    <name>%s</name>\n", escapeText(w.options.Name))
+	}
+	if w.description != "" {
+		fmt.Fprintf(&header, "    <description>%s</description>\n", escapeText(w.description))
+	}
+	for _, f := range w.folders {
+		if f.styleID != "" {
+			style, _ := w.style(f.name)
+			writeStyle(&header, f.styleID, style)
+		}
+	}
+	if _, err := buffered.Write(header.Bytes()); err != nil {
+		return fmt.Errorf("failed to write document header: %w", err)
+	}
+
+	var placemark []byte
+	for _, f := range w.folders {
+		fmt.Fprintf(buffered, "    <Folder>\n      <name>%s</name>\n", escapeText(f.name))
+		for _, p := range f.placemarks {
+			if cap(placemark) < p.length {
+				placemark = make([]byte, p.length)
+			}
+			placemark = placemark[:p.length]
+			if _, err := w.spool.ReadAt(placemark, p.offset); err != nil {
+				return fmt.Errorf("failed to read spooled placemark: %w", err)
+			}
+			if _, err := buffered.Write(placemark); err != nil {
+				return fmt.Errorf("failed to write placemark: %w", err)
+			}
+		}
+		buffered.WriteString("    </Folder>\n")
+	}
+
+	buffered.WriteString("  </Document>\n</kml>\n")
+	if err := buffered.Flush(); err != nil {
+		return fmt.Errorf("failed to write document: %w", err)
+	}
+	return nil
+}
+
+// Close removes the spool file
+func (w *Writer) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.spool == nil {
+		return nil
+	}
+	name := w.spool.Name()
+	err := w.spool.Close()
+	w.spool = nil
+	if removeErr := os.Remove(name); err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+const xmlHeader = "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n"
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
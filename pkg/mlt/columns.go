@@ -0,0 +1,255 @@
+// pkg/mlt/columns.go - MLT ID and property column decoding
+package mlt
+
+import (
+	"fmt"
+)
+
+// fsstEscape marks a literal byte in FSST compressed data
+const fsstEscape = 255
+
+// decodeScalarColumn decodes an ID, boolean, integer or float column into one value
+// per feature, nil where an optional column has no value
+func decodeScalarColumn(r *reader, col column) ([]interface{}, error) {
+	present, err := readPresent(r, col)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := readStream(r)
+	if err != nil {
+		return nil, err
+	}
+	if s.kind != streamData {
+		return nil, fmt.Errorf("expected data stream, got stream type %d", s.kind)
+	}
+
+	var values []interface{}
+	switch col.kind &^ columnOptionalBit {
+	case columnID, columnLongID, columnUint8, columnUint32, columnUint64:
+		ints, err := s.ints(false)
+		if err != nil {
+			return nil, err
+		}
+		values = make([]interface{}, len(ints))
+		for i, value := range ints {
+			values[i] = uint64(value)
+		}
+	case columnInt8, columnInt32, columnInt64:
+		ints, err := s.ints(true)
+		if err != nil {
+			return nil, err
+		}
+		values = make([]interface{}, len(ints))
+		for i, value := range ints {
+			values[i] = value
+		}
+	case columnBool:
+		bools, err := s.bools()
+		if err != nil {
+			return nil, err
+		}
+		values = make([]interface{}, len(bools))
+		for i, value := range bools {
+			values[i] = value
+		}
+	case columnFloat32, columnFloat64:
+		double := col.kind&^columnOptionalBit == columnFloat64
+		floats, err := s.floats(double)
+		if err != nil {
+			return nil, err
+		}
+		values = make([]interface{}, len(floats))
+		for i, value := range floats {
+			if double {
+				values[i] = value
+			} else {
+				values[i] = float32(value)
+			}
+		}
+	default:
+		return nil, &UnsupportedError{What: fmt.Sprintf("column type %d", col.kind)}
+	}
+
+	return applyPresent(values, present)
+}
+
+// decodeStringColumn decodes a plain, dictionary or FSST dictionary string column
+func decodeStringColumn(r *reader, col column) ([]interface{}, error) {
+	streamCount, err := r.varint()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stream count: %w", err)
+	}
+
+	present, err := readPresent(r, col)
+	if err != nil {
+		return nil, err
+	}
+	if present != nil {
+		streamCount--
+	}
+
+	var lengths, offsets, data, symbolLengths, symbolTable *stream
+	for i := 0; i < streamCount; i++ {
+		s, err := readStream(r)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case s.kind == streamLength && (s.subtype == lengthVarBinary || s.subtype == lengthDictionary):
+			lengths = s
+		case s.kind == streamLength && s.subtype == lengthSymbol:
+			symbolLengths = s
+		case s.kind == streamOffset && s.subtype == offsetString:
+			offsets = s
+		case s.kind == streamData && (s.subtype == dictionaryNone || s.subtype == dictionarySingle):
+			data = s
+		case s.kind == streamData && s.subtype == dictionaryFSST:
+			symbolTable = s
+		case s.kind == streamData && s.subtype == dictionaryShared:
+			return nil, &UnsupportedError{What: "shared string dictionary"}
+		default:
+			return nil, fmt.Errorf("unexpected stream type %d/%d in string column", s.kind, s.subtype)
+		}
+	}
+
+	if lengths == nil || data == nil {
+		return nil, fmt.Errorf("string column is missing its length or data stream")
+	}
+
+	corpus := data.data
+	if symbolTable != nil {
+		if symbolLengths == nil {
+			return nil, fmt.Errorf("FSST string column is missing its symbol lengths")
+		}
+		symbolSizes, err := symbolLengths.ints(false)
+		if err != nil {
+			return nil, fmt.Errorf("symbol lengths: %w", err)
+		}
+		if corpus, err = decodeFSST(corpus, symbolTable.data, symbolSizes); err != nil {
+			return nil, err
+		}
+	}
+
+	sizes, err := lengths.ints(false)
+	if err != nil {
+		return nil, fmt.Errorf("string lengths: %w", err)
+	}
+	strings, err := splitStrings(corpus, sizes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without an offset stream the strings are the values themselves
+	if offsets == nil {
+		values := make([]interface{}, len(strings))
+		for i, value := range strings {
+			values[i] = value
+		}
+		return applyPresent(values, present)
+	}
+
+	indexes, err := offsets.ints(false)
+	if err != nil {
+		return nil, fmt.Errorf("string offsets: %w", err)
+	}
+	values := make([]interface{}, len(indexes))
+	for i, index := range indexes {
+		if uint64(index) >= uint64(len(strings)) {
+			return nil, fmt.Errorf("string offset %d outside dictionary of %d entries", index, len(strings))
+		}
+		values[i] = strings[index]
+	}
+	return applyPresent(values, present)
+}
+
+// readPresent reads the present stream of an optional column
+func readPresent(r *reader, col column) ([]bool, error) {
+	if !col.optional {
+		return nil, nil
+	}
+
+	s, err := readStream(r)
+	if err != nil {
+		return nil, err
+	}
+	if s.kind != streamPresent {
+		return nil, fmt.Errorf("expected present stream, got stream type %d", s.kind)
+	}
+	return s.bools()
+}
+
+// applyPresent spreads the values of an optional column over the features marked
+// present, leaving nil for the others
+func applyPresent(values []interface{}, present []bool) ([]interface{}, error) {
+	if present == nil {
+		return values, nil
+	}
+
+	result := make([]interface{}, len(present))
+	next := 0
+	for i, isPresent := range present {
+		if !isPresent {
+			continue
+		}
+		if next >= len(values) {
+			return nil, fmt.Errorf("present stream marks more than %d values", len(values))
+		}
+		result[i] = values[next]
+		next++
+	}
+	if next != len(values) {
+		return nil, fmt.Errorf("present stream marks %d values, column has %d", next, len(values))
+	}
+	return result, nil
+}
+
+// splitStrings splits concatenated string data by lengths
+func splitStrings(data []byte, lengths []int64) ([]string, error) {
+	strings := make([]string, len(lengths))
+	pos := 0
+	for i, length := range lengths {
+		if length < 0 || length > int64(len(data)-pos) {
+			return nil, fmt.Errorf("string length %d exceeds remaining %d bytes", length, len(data)-pos)
+		}
+		strings[i] = string(data[pos : pos+int(length)])
+		pos += int(length)
+	}
+	if pos != len(data) {
+		return nil, fmt.Errorf("string data has %d unused bytes", len(data)-pos)
+	}
+	return strings, nil
+}
+
+// decodeFSST decompresses FSST data: each byte selects a symbol from the table, except
+// the escape byte, which is followed by a literal byte
+func decodeFSST(data, table []byte, symbolLengths []int64) ([]byte, error) {
+	symbols := make([][]byte, len(symbolLengths))
+	pos := 0
+	for i, length := range symbolLengths {
+		if length < 1 || length > 8 || length > int64(len(table)-pos) {
+			return nil, fmt.Errorf("invalid FSST symbol length %d", length)
+		}
+		symbols[i] = table[pos : pos+int(length)]
+		pos += int(length)
+	}
+
+	result := make([]byte, 0, len(data)*2)
+	for i := 0; i < len(data); i++ {
+		code := data[i]
+		if code == fsstEscape {
+			i++
+			if i >= len(data) {
+				return nil, fmt.Errorf("truncated FSST escape")
+			}
+			result = append(result, data[i])
+			continue
+		}
+		if int(code) >= len(symbols) {
+			return nil, fmt.Errorf("FSST code %d outside symbol table of %d entries", code, len(symbols))
+		}
+		result = append(result, symbols[code]...)
+	}
+	return result, nil
+}
@@ -0,0 +1,310 @@
+// pkg/mlt/geometry.go - MLT geometry column decoding
+package mlt
+
+import (
+	"fmt"
+
+	"github.com/paulmach/orb"
+)
+
+// Geometry types
+const (
+	geometryPoint           = 0
+	geometryLineString      = 1
+	geometryPolygon         = 2
+	geometryMultiPoint      = 3
+	geometryMultiLineString = 4
+	geometryMultiPolygon    = 5
+)
+
+// decodeGeometryColumn decodes a geometry column into one geometry per feature. The
+// first stream holds the geometry types; the topology streams that follow give the
+// number of geometries of multi-geometries, rings per polygon (or vertices per line
+// when the column has no polygons), and vertices per ring or, in columns with
+// polygons, per line.
+func decodeGeometryColumn(r *reader) ([]orb.Geometry, error) {
+	streamCount, err := r.varint()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stream count: %w", err)
+	}
+	if streamCount == 0 {
+		return nil, fmt.Errorf("missing geometry type stream")
+	}
+
+	typeStream, err := readStream(r)
+	if err != nil {
+		return nil, err
+	}
+	if typeStream.kind != streamData || typeStream.subtype != dictionaryNone {
+		return nil, fmt.Errorf("expected geometry type stream, got stream type %d/%d", typeStream.kind, typeStream.subtype)
+	}
+	types, err := typeStream.ints(false)
+	if err != nil {
+		return nil, fmt.Errorf("geometry types: %w", err)
+	}
+
+	d := &geometryDecoder{}
+	for i := 1; i < streamCount; i++ {
+		s, err := readStream(r)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case s.kind == streamLength && s.subtype == lengthGeometries:
+			d.geometries, err = s.ints(false)
+		case s.kind == streamLength && s.subtype == lengthParts:
+			d.parts, err = s.ints(false)
+		case s.kind == streamLength && s.subtype == lengthRings:
+			d.rings, err = s.ints(false)
+		case s.kind == streamLength && s.subtype == lengthTriangles,
+			s.kind == streamOffset && s.subtype == offsetIndex:
+			// Pre-tessellated polygons also carry their outlines, which are used instead
+		case s.kind == streamOffset && s.subtype == offsetVertex:
+			d.offsets, err = s.ints(false)
+		case s.kind == streamData && s.subtype == dictionaryVertex:
+			d.vertices, err = decodeVertexBuffer(s)
+		case s.kind == streamData && s.subtype == dictionaryMorton:
+			d.vertices, err = decodeMortonVertices(s)
+		default:
+			err = fmt.Errorf("unexpected stream type %d/%d in geometry column", s.kind, s.subtype)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	geometries := make([]orb.Geometry, len(types))
+	for i, geometryType := range types {
+		if geometries[i], err = d.geometry(geometryType); err != nil {
+			return nil, fmt.Errorf("feature %d: %w", i, err)
+		}
+	}
+	return geometries, nil
+}
+
+// geometryDecoder walks the topology and vertex streams of a geometry column
+type geometryDecoder struct {
+	geometries []int64
+	parts      []int64
+	rings      []int64
+	offsets    []int64
+	vertices   []orb.Point
+
+	geometryIndex int
+	partIndex     int
+	ringIndex     int
+	vertexIndex   int
+}
+
+// geometry decodes the next geometry of a type
+func (d *geometryDecoder) geometry(geometryType int64) (orb.Geometry, error) {
+	switch geometryType {
+	case geometryPoint:
+		return d.point()
+	case geometryLineString:
+		return d.lineString()
+	case geometryPolygon:
+		return d.polygon()
+	case geometryMultiPoint:
+		n, err := d.next(d.geometries, &d.geometryIndex, "geometry")
+		if err != nil {
+			return nil, err
+		}
+		points, err := d.points(n)
+		return orb.MultiPoint(points), err
+	case geometryMultiLineString:
+		n, err := d.next(d.geometries, &d.geometryIndex, "geometry")
+		if err != nil {
+			return nil, err
+		}
+		result := make(orb.MultiLineString, n)
+		for i := range result {
+			if result[i], err = d.lineString(); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	case geometryMultiPolygon:
+		n, err := d.next(d.geometries, &d.geometryIndex, "geometry")
+		if err != nil {
+			return nil, err
+		}
+		result := make(orb.MultiPolygon, n)
+		for i := range result {
+			if result[i], err = d.polygon(); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("unknown geometry type %d", geometryType)
+	}
+}
+
+// lineString decodes a line, whose vertex count is in the rings stream when the column
+// contains polygons and in the parts stream otherwise
+func (d *geometryDecoder) lineString() (orb.LineString, error) {
+	var n int
+	var err error
+	if d.rings != nil {
+		n, err = d.next(d.rings, &d.ringIndex, "ring")
+	} else {
+		n, err = d.next(d.parts, &d.partIndex, "part")
+	}
+	if err != nil {
+		return nil, err
+	}
+	points, err := d.points(n)
+	return orb.LineString(points), err
+}
+
+// polygon decodes a polygon, closing its rings
+func (d *geometryDecoder) polygon() (orb.Polygon, error) {
+	n, err := d.next(d.parts, &d.partIndex, "part")
+	if err != nil {
+		return nil, err
+	}
+
+	polygon := make(orb.Polygon, n)
+	for i := range polygon {
+		count, err := d.next(d.rings, &d.ringIndex, "ring")
+		if err != nil {
+			return nil, err
+		}
+		points, err := d.points(count)
+		if err != nil {
+			return nil, err
+		}
+		ring := orb.Ring(points)
+		if len(ring) > 0 && !ring.Closed() {
+			ring = append(ring, ring[0])
+		}
+		polygon[i] = ring
+	}
+	return polygon, nil
+}
+
+// point decodes the next vertex
+func (d *geometryDecoder) point() (orb.Point, error) {
+	index := d.vertexIndex
+	if d.offsets != nil {
+		if d.vertexIndex >= len(d.offsets) {
+			return orb.Point{}, fmt.Errorf("vertex offsets exhausted")
+		}
+		index = int(d.offsets[d.vertexIndex])
+	}
+	if index < 0 || index >= len(d.vertices) {
+		return orb.Point{}, fmt.Errorf("vertex %d outside buffer of %d vertices", index, len(d.vertices))
+	}
+	d.vertexIndex++
+	return d.vertices[index], nil
+}
+
+// points decodes the next n vertices
+func (d *geometryDecoder) points(n int) ([]orb.Point, error) {
+	points := make([]orb.Point, n)
+	for i := range points {
+		point, err := d.point()
+		if err != nil {
+			return nil, err
+		}
+		points[i] = point
+	}
+	return points, nil
+}
+
+// next returns the next count of a topology stream
+func (d *geometryDecoder) next(values []int64, index *int, name string) (int, error) {
+	if *index >= len(values) {
+		return 0, fmt.Errorf("%s counts exhausted", name)
+	}
+	value := values[*index]
+	*index++
+	if value < 0 || value > d.maxCount() {
+		return 0, fmt.Errorf("invalid %s count %d", name, value)
+	}
+	return int(value), nil
+}
+
+// maxCount bounds any count in the column: each counted part, ring or vertex takes an
+// entry of one of the streams, so no count can exceed the longest of them
+func (d *geometryDecoder) maxCount() int64 {
+	longest := len(d.vertices)
+	for _, values := range [][]int64{d.geometries, d.parts, d.rings, d.offsets} {
+		if len(values) > longest {
+			longest = len(values)
+		}
+	}
+	return int64(longest)
+}
+
+// decodeVertexBuffer decodes interleaved x, y vertex coordinates, optionally delta
+// encoded per component
+func decodeVertexBuffer(s *stream) ([]orb.Point, error) {
+	raw, err := s.varints()
+	if err != nil {
+		return nil, err
+	}
+	if len(raw)%2 != 0 {
+		return nil, fmt.Errorf("vertex buffer has an odd number of coordinates")
+	}
+
+	var coordinates []int64
+	switch {
+	case s.logical1 == logicalNone && s.logical2 == logicalNone:
+		coordinates = decodePlain(raw, true)
+	case s.logical1 == logicalComponentwiseDelta && s.logical2 == logicalNone:
+		coordinates = decodePlain(raw, true)
+		for i := 2; i < len(coordinates); i++ {
+			coordinates[i] += coordinates[i-2]
+		}
+	default:
+		return nil, s.unsupportedLogical()
+	}
+
+	vertices := make([]orb.Point, len(coordinates)/2)
+	for i := range vertices {
+		vertices[i] = orb.Point{float64(coordinates[2*i]), float64(coordinates[2*i+1])}
+	}
+	return vertices, nil
+}
+
+// decodeMortonVertices decodes a vertex dictionary of Morton codes, optionally delta
+// encoded, shifting the coordinates back by the stream's coordinate shift
+func decodeMortonVertices(s *stream) ([]orb.Point, error) {
+	raw, err := s.varints()
+	if err != nil {
+		return nil, err
+	}
+
+	var codes []int64
+	switch s.logical2 {
+	case logicalNone:
+		codes = decodePlain(raw, false)
+	case logicalDelta:
+		codes = prefixSum(decodePlain(raw, true))
+	default:
+		return nil, s.unsupportedLogical()
+	}
+	if s.numBits <= 0 || s.numBits > 32 {
+		return nil, fmt.Errorf("invalid Morton bit count %d", s.numBits)
+	}
+
+	vertices := make([]orb.Point, len(codes))
+	for i, code := range codes {
+		x := deinterleave(uint64(code), s.numBits)
+		y := deinterleave(uint64(code)>>1, s.numBits)
+		vertices[i] = orb.Point{float64(x - int64(s.coordinateShift)), float64(y - int64(s.coordinateShift))}
+	}
+	return vertices, nil
+}
+
+// deinterleave collects every other bit of a Morton code, starting at bit 0
+func deinterleave(code uint64, bits int) int64 {
+	var value int64
+	for i := 0; i < bits; i++ {
+		value |= int64(code>>(2*i)&1) << i
+	}
+	return value
+}
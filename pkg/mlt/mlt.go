@@ -0,0 +1,321 @@
+// pkg/mlt/mlt.go - MapLibre Tile (MLT) decoding
+package mlt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/paulmach/orb"
+)
+
+// Version is the MLT layer encoding version decoded by this package
+const Version = 1
+
+// layerTagBasic identifies a layer with embedded column metadata; layers with other
+// tags are skipped
+const layerTagBasic = 1
+
+// Layer is a decoded MLT feature table
+type Layer struct {
+	Name     string
+	Extent   int
+	Features []*Feature
+}
+
+// Feature is a decoded MLT feature. Geometry is in tile coordinates with y pointing
+// down, as in MVT. ID is a uint64 or nil. Property values are string, bool, int64,
+// uint64, float32 or float64; absent optional values are left out.
+type Feature struct {
+	ID         interface{}
+	Geometry   orb.Geometry
+	Properties map[string]interface{}
+}
+
+// UnsupportedError reports a valid MLT encoding this decoder does not implement
+type UnsupportedError struct {
+	What string
+}
+
+// Error implements the error interface
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("unsupported MLT encoding: %s", e.What)
+}
+
+// Column types. Property columns carry a name; odd property types are nullable and
+// start with a present stream.
+const (
+	columnID          = 0
+	columnOptID       = 1
+	columnLongID      = 2
+	columnOptLongID   = 3
+	columnGeometry    = 4
+	columnBool        = 10
+	columnInt8        = 12
+	columnUint8       = 14
+	columnInt32       = 16
+	columnUint32      = 18
+	columnInt64       = 20
+	columnUint64      = 22
+	columnFloat32     = 24
+	columnFloat64     = 26
+	columnString      = 28
+	columnSharedDict  = 30
+	columnOptionalBit = 1
+)
+
+// column describes a column of a feature table
+type column struct {
+	kind     uint64
+	name     string
+	optional bool
+}
+
+// Decode decodes the layers of an MLT tile
+func Decode(data []byte) ([]*Layer, error) {
+	r := &reader{data: data}
+	var layers []*Layer
+
+	for !r.done() {
+		size, err := r.varint()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read layer size: %w", err)
+		}
+		body, err := r.bytes(size)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read layer: %w", err)
+		}
+
+		layerReader := &reader{data: body}
+		tag, err := layerReader.varint()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read layer tag: %w", err)
+		}
+		if tag != layerTagBasic {
+			continue
+		}
+
+		layer, err := decodeLayer(layerReader)
+		if err != nil {
+			return nil, fmt.Errorf("layer %d: %w", len(layers), err)
+		}
+		layers = append(layers, layer)
+	}
+
+	return layers, nil
+}
+
+// IsMLT reports whether data is structured as an MLT tile: a sequence of size-prefixed
+// layers that exactly fills the payload, with at least one basic layer. Some MVT
+// payloads also fit that structure, so payloads that are well-formed MVT are never
+// reported as MLT.
+func IsMLT(data []byte) bool {
+	if isMVT(data) {
+		return false
+	}
+
+	r := &reader{data: data}
+	basic := false
+	for !r.done() {
+		size, err := r.varint()
+		if err != nil || size == 0 {
+			return false
+		}
+		body, err := r.bytes(size)
+		if err != nil {
+			return false
+		}
+		tag, n := binary.Uvarint(body)
+		if n <= 0 {
+			return false
+		}
+		if tag == layerTagBasic {
+			basic = true
+		}
+	}
+	return basic
+}
+
+// mvtLayerKey is the protobuf key of an MVT layer: field 3, length-delimited
+const mvtLayerKey = 3<<3 | 2
+
+// isMVT reports whether data is a sequence of MVT layer fields that exactly fills the
+// payload
+func isMVT(data []byte) bool {
+	r := &reader{data: data}
+	for !r.done() {
+		key, err := r.varint()
+		if err != nil || key != mvtLayerKey {
+			return false
+		}
+		size, err := r.varint()
+		if err != nil {
+			return false
+		}
+		if _, err := r.bytes(size); err != nil {
+			return false
+		}
+	}
+	return len(data) > 0
+}
+
+// decodeLayer decodes the header and columns of a basic layer
+func decodeLayer(r *reader) (*Layer, error) {
+	name, err := r.string()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read name: %w", err)
+	}
+	extent, err := r.varint()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read extent: %w", err)
+	}
+	columnCount, err := r.varint()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read column count: %w", err)
+	}
+	if columnCount > len(r.data) {
+		return nil, fmt.Errorf("column count %d exceeds layer size", columnCount)
+	}
+
+	columns := make([]column, columnCount)
+	for i := range columns {
+		kind, err := r.varint()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read column %d type: %w", i, err)
+		}
+		columns[i] = column{kind: uint64(kind)}
+		switch {
+		case kind == columnOptID || kind == columnOptLongID:
+			columns[i].optional = true
+		case kind >= columnBool:
+			columns[i].optional = kind&columnOptionalBit != 0
+			if columns[i].name, err = r.string(); err != nil {
+				return nil, fmt.Errorf("failed to read column %d name: %w", i, err)
+			}
+		}
+	}
+
+	layer := &Layer{Name: name, Extent: extent}
+	var ids []interface{}
+	var properties [][]interface{}
+	var propertyNames []string
+
+	for _, col := range columns {
+		switch {
+		case col.kind <= columnOptLongID:
+			if ids, err = decodeScalarColumn(r, col); err != nil {
+				return nil, fmt.Errorf("id column: %w", err)
+			}
+		case col.kind == columnGeometry:
+			geometries, err := decodeGeometryColumn(r)
+			if err != nil {
+				return nil, fmt.Errorf("geometry column: %w", err)
+			}
+			layer.Features = make([]*Feature, len(geometries))
+			for i, geometry := range geometries {
+				layer.Features[i] = &Feature{Geometry: geometry, Properties: make(map[string]interface{})}
+			}
+		case col.kind&^columnOptionalBit == columnString:
+			values, err := decodeStringColumn(r, col)
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %w", col.name, err)
+			}
+			properties = append(properties, values)
+			propertyNames = append(propertyNames, col.name)
+		case col.kind >= columnBool && col.kind < columnString:
+			values, err := decodeScalarColumn(r, col)
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %w", col.name, err)
+			}
+			properties = append(properties, values)
+			propertyNames = append(propertyNames, col.name)
+		case col.kind&^columnOptionalBit == columnSharedDict:
+			return nil, &UnsupportedError{What: fmt.Sprintf("shared dictionary column %s", col.name)}
+		default:
+			return nil, &UnsupportedError{What: fmt.Sprintf("column type %d", col.kind)}
+		}
+	}
+
+	if layer.Features == nil {
+		return nil, fmt.Errorf("layer %s has no geometry column", name)
+	}
+
+	if ids != nil {
+		if len(ids) != len(layer.Features) {
+			return nil, fmt.Errorf("id column has %d values for %d features", len(ids), len(layer.Features))
+		}
+		for i, id := range ids {
+			layer.Features[i].ID = id
+		}
+	}
+
+	for i, values := range properties {
+		if len(values) != len(layer.Features) {
+			return nil, fmt.Errorf("column %s has %d values for %d features",
+				propertyNames[i], len(values), len(layer.Features))
+		}
+		for j, value := range values {
+			if value != nil {
+				layer.Features[j].Properties[propertyNames[i]] = value
+			}
+		}
+	}
+
+	return layer, nil
+}
+
+// reader reads varints and byte ranges from a buffer
+type reader struct {
+	data []byte
+	pos  int
+}
+
+// done reports whether the buffer is exhausted
+func (r *reader) done() bool {
+	return r.pos >= len(r.data)
+}
+
+// varint reads an unsigned varint that must fit an int
+func (r *reader) varint() (int, error) {
+	value, err := r.uvarint()
+	if err != nil {
+		return 0, err
+	}
+	if value > math.MaxInt32 {
+		return 0, fmt.Errorf("value %d out of range", value)
+	}
+	return int(value), nil
+}
+
+// uvarint reads an unsigned varint
+func (r *reader) uvarint() (uint64, error) {
+	value, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		return 0, fmt.Errorf("invalid varint at offset %d", r.pos)
+	}
+	r.pos += n
+	return value, nil
+}
+
+// bytes reads the next n bytes
+func (r *reader) bytes(n int) ([]byte, error) {
+	if n < 0 || n > len(r.data)-r.pos {
+		return nil, fmt.Errorf("%d bytes needed at offset %d, %d available", n, r.pos, len(r.data)-r.pos)
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+// string reads a length-prefixed UTF-8 string
+func (r *reader) string() (string, error) {
+	n, err := r.varint()
+	if err != nil {
+		return "", err
+	}
+	b, err := r.bytes(n)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
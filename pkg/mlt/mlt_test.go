@@ -0,0 +1,303 @@
+// pkg/mlt/mlt_test.go - Unit tests for MLT decoding
+package mlt
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/paulmach/orb"
+)
+
+// zz zigzag encodes a signed integer
+func zz(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+// varints encodes values as consecutive varints
+func varints(values ...uint64) []byte {
+	var b []byte
+	for _, v := range values {
+		b = binary.AppendUvarint(b, v)
+	}
+	return b
+}
+
+// testStream encodes a stream header, optional RLE or Morton header fields, and data
+func testStream(kind, subtype, logical1, logical2, physical, numValues int, data []byte, extra ...uint64) []byte {
+	b := []byte{byte(kind<<4 | subtype), byte(logical1<<5 | logical2<<2 | physical)}
+	b = binary.AppendUvarint(b, uint64(numValues))
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	b = append(b, varints(extra...)...)
+	return append(b, data...)
+}
+
+// testString encodes a length-prefixed string
+func testString(s string) []byte {
+	return append(binary.AppendUvarint(nil, uint64(len(s))), s...)
+}
+
+// testLayer encodes a size-prefixed basic layer
+func testLayer(name string, extent int, columns [][]byte, body ...[]byte) []byte {
+	content := varints(layerTagBasic)
+	content = append(content, testString(name)...)
+	content = append(content, varints(uint64(extent), uint64(len(columns)))...)
+	for _, column := range columns {
+		content = append(content, column...)
+	}
+	for _, part := range body {
+		content = append(content, part...)
+	}
+	return append(binary.AppendUvarint(nil, uint64(len(content))), content...)
+}
+
+// testColumn encodes column metadata
+func testColumn(kind int, name string) []byte {
+	b := varints(uint64(kind))
+	if kind >= columnBool {
+		b = append(b, testString(name)...)
+	}
+	return b
+}
+
+func TestDecode(t *testing.T) {
+	// Point, LineString, Polygon and MultiPoint; with a polygon present, the line's
+	// vertex count comes from the rings stream
+	coordinates := []int64{10, 20, 0, 0, 5, 5, 10, 0, 0, 0, 10, 0, 10, 10, 1, 1, 2, 2}
+	var deltas []uint64
+	for i, c := range coordinates {
+		if i >= 2 {
+			c -= coordinates[i-2]
+		}
+		deltas = append(deltas, zz(c))
+	}
+	geometry := varints(5)
+	geometry = append(geometry, testStream(streamData, dictionaryNone, logicalNone, logicalNone, physicalVarint, 4, varints(0, 1, 2, 3))...)
+	geometry = append(geometry, testStream(streamLength, lengthGeometries, logicalNone, logicalNone, physicalVarint, 1, varints(2))...)
+	geometry = append(geometry, testStream(streamLength, lengthParts, logicalNone, logicalNone, physicalVarint, 1, varints(1))...)
+	geometry = append(geometry, testStream(streamLength, lengthRings, logicalNone, logicalNone, physicalVarint, 2, varints(3, 3))...)
+	geometry = append(geometry, testStream(streamData, dictionaryVertex, logicalComponentwiseDelta, logicalNone, physicalVarint, len(deltas), varints(deltas...))...)
+
+	ids := testStream(streamData, dictionaryNone, logicalDelta, logicalNone, physicalVarint, 4, varints(zz(100), zz(1), zz(1), zz(1)))
+
+	names := varints(4)
+	names = append(names, testStream(streamPresent, 0, logicalNone, logicalNone, physicalNone, 4, []byte{0xff, 0x05})...)
+	names = append(names, testStream(streamOffset, offsetString, logicalNone, logicalNone, physicalVarint, 2, varints(1, 0))...)
+	names = append(names, testStream(streamLength, lengthDictionary, logicalNone, logicalNone, physicalVarint, 2, varints(4, 4))...)
+	names = append(names, testStream(streamData, dictionarySingle, logicalNone, logicalNone, physicalNone, 8, []byte("cafepark"))...)
+
+	ranks := testStream(streamData, dictionaryNone, logicalRLE, logicalNone, physicalVarint, 4, varints(3, 1, zz(7), zz(-2)), 2, 4)
+	open := testStream(streamData, dictionaryNone, logicalNone, logicalNone, physicalNone, 4, []byte{0xff, 0x0d})
+
+	var heights []byte
+	for _, h := range []float64{1.5, 2, 0, 12.25} {
+		heights = binary.LittleEndian.AppendUint64(heights, math.Float64bits(h))
+	}
+	heightStream := testStream(streamData, dictionaryNone, logicalNone, logicalNone, physicalNone, 4, heights)
+
+	data := testLayer("pois", 4096,
+		[][]byte{
+			testColumn(columnID, ""),
+			testColumn(columnGeometry, ""),
+			testColumn(columnString|columnOptionalBit, "name"),
+			testColumn(columnInt32, "rank"),
+			testColumn(columnBool, "open"),
+			testColumn(columnFloat64, "height"),
+		},
+		ids, geometry, names, ranks, open, heightStream,
+	)
+	data = append(data, testLayer("empty", 512, [][]byte{testColumn(columnGeometry, "")},
+		append(varints(1), testStream(streamData, dictionaryNone, logicalNone, logicalNone, physicalVarint, 0, nil)...))...)
+
+	if !IsMLT(data) {
+		t.Error("Expected data to be detected as MLT")
+	}
+
+	layers, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(layers) != 2 || layers[0].Name != "pois" || layers[0].Extent != 4096 || layers[1].Extent != 512 {
+		t.Fatalf("Unexpected layers: %+v", layers)
+	}
+
+	features := layers[0].Features
+	if len(features) != 4 {
+		t.Fatalf("Expected 4 features, got %d", len(features))
+	}
+
+	wantGeometries := []orb.Geometry{
+		orb.Point{10, 20},
+		orb.LineString{{0, 0}, {5, 5}, {10, 0}},
+		orb.Polygon{{{0, 0}, {10, 0}, {10, 10}, {0, 0}}},
+		orb.MultiPoint{{1, 1}, {2, 2}},
+	}
+	wantProperties := []map[string]interface{}{
+		{"name": "park", "rank": int64(7), "open": true, "height": 1.5},
+		{"rank": int64(7), "open": false, "height": float64(2)},
+		{"name": "cafe", "rank": int64(7), "open": true, "height": float64(0)},
+		{"rank": int64(-2), "open": true, "height": 12.25},
+	}
+	for i, feature := range features {
+		if feature.ID != uint64(100+i) {
+			t.Errorf("Feature %d: ID = %v, want %d", i, feature.ID, 100+i)
+		}
+		if !reflect.DeepEqual(feature.Geometry, wantGeometries[i]) {
+			t.Errorf("Feature %d: geometry = %v, want %v", i, feature.Geometry, wantGeometries[i])
+		}
+		if !reflect.DeepEqual(feature.Properties, wantProperties[i]) {
+			t.Errorf("Feature %d: properties = %v, want %v", i, feature.Properties, wantProperties[i])
+		}
+	}
+}
+
+func TestDecodeFSSTStrings(t *testing.T) {
+	geometry := varints(2)
+	geometry = append(geometry, testStream(streamData, dictionaryNone, logicalRLE, logicalNone, physicalVarint, 2, varints(3, geometryPoint), 1, 3)...)
+	geometry = append(geometry, testStream(streamData, dictionaryVertex, logicalNone, logicalNone, physicalVarint, 6, varints(zz(1), zz(2), zz(3), zz(4), zz(5), zz(6)))...)
+
+	// Symbols "ab" and "c"; the escape byte introduces the literal "x"
+	classes := varints(4)
+	classes = append(classes, testStream(streamLength, lengthSymbol, logicalNone, logicalNone, physicalVarint, 2, varints(2, 1))...)
+	classes = append(classes, testStream(streamData, dictionaryFSST, logicalNone, logicalNone, physicalNone, 2, []byte("abc"))...)
+	classes = append(classes, testStream(streamLength, lengthVarBinary, logicalNone, logicalNone, physicalVarint, 3, varints(3, 3, 1))...)
+	classes = append(classes, testStream(streamData, dictionarySingle, logicalNone, logicalNone, physicalNone, 6, []byte{0, 1, 1, 0, fsstEscape, 'x'})...)
+
+	data := testLayer("places", 4096,
+		[][]byte{testColumn(columnGeometry, ""), testColumn(columnString, "class")},
+		geometry, classes,
+	)
+
+	layers, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var got []interface{}
+	for _, feature := range layers[0].Features {
+		got = append(got, feature.Properties["class"])
+	}
+	if want := []interface{}{"abc", "cab", "x"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected classes %v, got %v", want, got)
+	}
+	if p := layers[0].Features[2].Geometry; p != (orb.Point{5, 6}) {
+		t.Errorf("Expected third point at 5,6, got %v", p)
+	}
+}
+
+func TestDecodeMortonVertices(t *testing.T) {
+	// x=3 (0b11), y=1 (0b01) interleave to 0b0111; x=0, y=2 to 0b1000. Delta encoded,
+	// with a coordinate shift of 1.
+	s := &stream{
+		logical1:        logicalMorton,
+		logical2:        logicalDelta,
+		physical:        physicalVarint,
+		numValues:       2,
+		numBits:         2,
+		coordinateShift: 1,
+		data:            varints(zz(7), zz(1)),
+	}
+
+	vertices, err := decodeMortonVertices(s)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if want := []orb.Point{{2, 0}, {-1, 1}}; !reflect.DeepEqual(vertices, want) {
+		t.Errorf("Expected %v, got %v", want, vertices)
+	}
+}
+
+func TestDecodeUnsupported(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream []byte
+	}{
+		{"FastPFOR", testStream(streamData, dictionaryNone, logicalNone, logicalNone, physicalFastPFOR, 1, []byte{0, 0, 0, 1})},
+		{"pseudodecimal", testStream(streamData, dictionaryNone, logicalPDE, logicalNone, physicalVarint, 1, varints(1))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			geometry := append(varints(1), testStream(streamData, dictionaryNone, logicalNone, logicalNone, physicalVarint, 0, nil)...)
+			data := testLayer("roads", 4096,
+				[][]byte{testColumn(columnGeometry, ""), testColumn(columnUint32, "lanes")},
+				geometry, tt.stream,
+			)
+
+			_, err := Decode(data)
+			var unsupported *UnsupportedError
+			if !errors.As(err, &unsupported) {
+				t.Errorf("Expected unsupported encoding error, got %v", err)
+			}
+		})
+	}
+}
+
+// collidingMVT is an MVT tile, layer "d" with a line feature, whose bytes also read as
+// a sequence of size-prefixed MLT layers with a basic layer
+var collidingMVT = []byte{
+	0x1a, 0x34, 0x0a, 0x01, 0x64, 0x12, 0x14, 0x12, 0x04, 0x00, 0x00, 0x01, 0x01, 0x18, 0x02, 0x22,
+	0x0a, 0x09, 0xd4, 0x39, 0x88, 0x0a, 0x0a, 0xeb, 0x29, 0xce, 0x13, 0x1a, 0x01, 0x6b, 0x1a, 0x01,
+	0x73, 0x22, 0x09, 0x19, 0x00, 0x00, 0x00, 0x00, 0x00, 0x28, 0x80, 0x40, 0x22, 0x03, 0x0a, 0x01,
+	0x79, 0x28, 0x80, 0x20, 0x78, 0x02,
+}
+
+func TestIsMLT(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{"empty", nil, false},
+		{"mvt layer", []byte{0x1a, 0x05, 0x0a, 0x01, 'a', 0x78, 0x02}, false},
+		{"truncated", []byte{0x10, 0x01}, false},
+		{"unknown tag only", []byte{0x02, 0x07, 0x00}, false},
+		{"basic layer", []byte{0x02, 0x01, 0x00}, true},
+		{"mvt with mlt structure", collidingMVT, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsMLT(tt.data); got != tt.want {
+				t.Errorf("IsMLT() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeOversizedCounts(t *testing.T) {
+	// A multipoint whose count, taken from a huge delta-encoded geometry length, is
+	// only checked against a vertex dictionary of one vertex
+	geometry := varints(4)
+	geometry = append(geometry, testStream(streamData, dictionaryNone, logicalNone, logicalNone, physicalVarint, 1, varints(geometryMultiPoint))...)
+	geometry = append(geometry, testStream(streamLength, lengthGeometries, logicalNone, logicalNone, physicalVarint, 1, varints(math.MaxInt32))...)
+	geometry = append(geometry, testStream(streamOffset, offsetVertex, logicalNone, logicalNone, physicalVarint, 1, varints(0))...)
+	geometry = append(geometry, testStream(streamData, dictionaryVertex, logicalNone, logicalNone, physicalVarint, 2, varints(2, 2))...)
+
+	tests := []struct {
+		name   string
+		column []byte
+		body   []byte
+	}{
+		{"varint values", testColumn(columnID, ""),
+			testStream(streamData, dictionaryNone, logicalNone, logicalNone, physicalVarint, math.MaxInt32, []byte{0x01})},
+		{"rle values", testColumn(columnID, ""),
+			testStream(streamData, dictionaryNone, logicalRLE, logicalNone, physicalVarint, 2, varints(1, 7), 1, math.MaxInt32)},
+		{"boolean values", testColumn(columnBool, "open"),
+			testStream(streamData, dictionaryNone, logicalNone, logicalNone, physicalNone, math.MaxInt32, []byte{0x00, 0x01})},
+		{"float values", testColumn(columnFloat64, "height"),
+			testStream(streamData, dictionaryNone, logicalNone, logicalNone, physicalNone, math.MaxInt32, make([]byte, 8))},
+		{"geometry count", testColumn(columnGeometry, ""), geometry},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := testLayer("bad", 4096, [][]byte{tt.column}, tt.body)
+			if _, err := Decode(data); err == nil {
+				t.Error("Expected an error for counts the data cannot hold, got nil")
+			}
+		})
+	}
+}
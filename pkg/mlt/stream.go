@@ -0,0 +1,334 @@
+// pkg/mlt/stream.go - MLT stream decoding
+package mlt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Physical stream types, stored in the high nibble of a stream's first byte
+const (
+	streamPresent = 0
+	streamData    = 1
+	streamOffset  = 2
+	streamLength  = 3
+)
+
+// Dictionary types of data streams
+const (
+	dictionaryNone   = 0
+	dictionarySingle = 1
+	dictionaryShared = 2
+	dictionaryVertex = 3
+	dictionaryMorton = 4
+	dictionaryFSST   = 5
+)
+
+// Offset types of offset streams
+const (
+	offsetVertex = 0
+	offsetIndex  = 1
+	offsetString = 2
+	offsetKey    = 3
+)
+
+// Length types of length streams
+const (
+	lengthVarBinary  = 0
+	lengthGeometries = 1
+	lengthParts      = 2
+	lengthRings      = 3
+	lengthTriangles  = 4
+	lengthSymbol     = 5
+	lengthDictionary = 6
+)
+
+// Logical level techniques
+const (
+	logicalNone               = 0
+	logicalDelta              = 1
+	logicalComponentwiseDelta = 2
+	logicalRLE                = 3
+	logicalMorton             = 4
+	logicalPDE                = 5
+)
+
+// Physical level techniques
+const (
+	physicalNone     = 0
+	physicalFastPFOR = 1
+	physicalVarint   = 2
+	physicalALP      = 3
+)
+
+// logicalNames and physicalNames name techniques in error messages
+var (
+	logicalNames  = []string{"none", "delta", "componentwise delta", "RLE", "Morton", "pseudodecimal"}
+	physicalNames = []string{"none", "FastPFOR", "varint", "ALP"}
+)
+
+// stream is a stream's metadata and encoded bytes
+type stream struct {
+	kind            int
+	subtype         int
+	logical1        int
+	logical2        int
+	physical        int
+	numValues       int
+	runs            int
+	numRleValues    int
+	numBits         int
+	coordinateShift int
+	data            []byte
+}
+
+// readStream reads a stream header and its data
+func readStream(r *reader) (*stream, error) {
+	header, err := r.bytes(2)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stream header: %w", err)
+	}
+
+	s := &stream{
+		kind:     int(header[0] >> 4),
+		subtype:  int(header[0] & 0x0f),
+		logical1: int(header[1] >> 5),
+		logical2: int(header[1] >> 2 & 0x07),
+		physical: int(header[1] & 0x03),
+	}
+
+	if s.numValues, err = r.varint(); err != nil {
+		return nil, fmt.Errorf("failed to read stream value count: %w", err)
+	}
+	byteLength, err := r.varint()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stream length: %w", err)
+	}
+
+	if s.logical1 == logicalRLE || s.logical2 == logicalRLE {
+		if s.runs, err = r.varint(); err != nil {
+			return nil, fmt.Errorf("failed to read RLE run count: %w", err)
+		}
+		if s.numRleValues, err = r.varint(); err != nil {
+			return nil, fmt.Errorf("failed to read RLE value count: %w", err)
+		}
+	}
+	if s.logical1 == logicalMorton {
+		if s.numBits, err = r.varint(); err != nil {
+			return nil, fmt.Errorf("failed to read Morton bit count: %w", err)
+		}
+		if s.coordinateShift, err = r.varint(); err != nil {
+			return nil, fmt.Errorf("failed to read Morton coordinate shift: %w", err)
+		}
+	}
+
+	if s.data, err = r.bytes(byteLength); err != nil {
+		return nil, fmt.Errorf("failed to read stream data: %w", err)
+	}
+	return s, nil
+}
+
+// varints decodes the stream's physical varint values
+func (s *stream) varints() ([]uint64, error) {
+	if s.physical != physicalVarint {
+		return nil, &UnsupportedError{What: fmt.Sprintf("%s physical encoding of integers", techniqueName(physicalNames, s.physical))}
+	}
+	// Each varint takes at least one byte
+	if s.numValues > len(s.data) {
+		return nil, fmt.Errorf("stream declares %d values in %d bytes", s.numValues, len(s.data))
+	}
+
+	values := make([]uint64, 0, s.numValues)
+	for pos := 0; pos < len(s.data); {
+		value, n := binary.Uvarint(s.data[pos:])
+		if n <= 0 {
+			return nil, fmt.Errorf("invalid varint in stream at offset %d", pos)
+		}
+		values = append(values, value)
+		pos += n
+	}
+	if len(values) != s.numValues {
+		return nil, fmt.Errorf("stream has %d values, header declares %d", len(values), s.numValues)
+	}
+	return values, nil
+}
+
+// ints decodes an integer stream. Signed values are returned as int64; unsigned
+// values are returned as their uint64 bit pattern.
+func (s *stream) ints(signed bool) ([]int64, error) {
+	raw, err := s.varints()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case s.logical1 == logicalNone && s.logical2 == logicalNone:
+		return decodePlain(raw, signed), nil
+	case s.logical1 == logicalDelta && s.logical2 == logicalNone:
+		return prefixSum(decodePlain(raw, true)), nil
+	case s.logical1 == logicalRLE && s.logical2 == logicalNone:
+		expanded, err := s.expandRuns(raw)
+		if err != nil {
+			return nil, err
+		}
+		return decodePlain(expanded, signed), nil
+	case s.logical1 == logicalDelta && s.logical2 == logicalRLE:
+		expanded, err := s.expandRuns(raw)
+		if err != nil {
+			return nil, err
+		}
+		return prefixSum(decodePlain(expanded, true)), nil
+	default:
+		return nil, s.unsupportedLogical()
+	}
+}
+
+// expandRuns decodes RLE values: run lengths followed by the value of each run
+func (s *stream) expandRuns(raw []uint64) ([]uint64, error) {
+	if s.runs*2 != len(raw) {
+		return nil, fmt.Errorf("RLE stream has %d values for %d runs", len(raw), s.runs)
+	}
+
+	total := uint64(0)
+	for _, length := range raw[:s.runs] {
+		if length > uint64(s.numRleValues) || total+length > uint64(s.numRleValues) {
+			return nil, fmt.Errorf("RLE runs exceed %d values", s.numRleValues)
+		}
+		total += length
+	}
+	if total != uint64(s.numRleValues) {
+		return nil, fmt.Errorf("RLE stream decodes to %d values, header declares %d", total, s.numRleValues)
+	}
+
+	values := make([]uint64, 0, s.numRleValues)
+	for i := 0; i < s.runs; i++ {
+		for j := uint64(0); j < raw[i]; j++ {
+			values = append(values, raw[s.runs+i])
+		}
+	}
+	return values, nil
+}
+
+// unsupportedLogical describes the stream's unsupported logical technique combination
+func (s *stream) unsupportedLogical() error {
+	what := techniqueName(logicalNames, s.logical1)
+	if s.logical2 != logicalNone {
+		what += " + " + techniqueName(logicalNames, s.logical2)
+	}
+	return &UnsupportedError{What: fmt.Sprintf("%s logical encoding", what)}
+}
+
+// bools decodes a byte-RLE encoded bitset of numValues bits, as used by present and
+// boolean streams
+func (s *stream) bools() ([]bool, error) {
+	if s.physical != physicalNone || s.logical1 != logicalNone {
+		return nil, &UnsupportedError{What: "boolean stream encoding"}
+	}
+
+	bytes, err := decodeByteRLE(s.data, (s.numValues+7)/8)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]bool, s.numValues)
+	for i := range values {
+		values[i] = bytes[i/8]>>(i%8)&1 != 0
+	}
+	return values, nil
+}
+
+// floats decodes a stream of little-endian 32-bit or 64-bit floats
+func (s *stream) floats(double bool) ([]float64, error) {
+	if s.physical != physicalNone || s.logical1 != logicalNone {
+		return nil, &UnsupportedError{What: fmt.Sprintf("%s physical encoding of floats", techniqueName(physicalNames, s.physical))}
+	}
+
+	size := 4
+	if double {
+		size = 8
+	}
+	if len(s.data) != s.numValues*size {
+		return nil, fmt.Errorf("float stream has %d bytes for %d values", len(s.data), s.numValues)
+	}
+
+	values := make([]float64, s.numValues)
+	for i := range values {
+		if double {
+			values[i] = math.Float64frombits(binary.LittleEndian.Uint64(s.data[i*8:]))
+		} else {
+			values[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(s.data[i*4:])))
+		}
+	}
+	return values, nil
+}
+
+// decodeByteRLE decodes ORC-style byte RLE: a control byte below 128 repeats the next
+// byte control+3 times, otherwise 256-control literal bytes follow
+func decodeByteRLE(data []byte, count int) ([]byte, error) {
+	// A two-byte run expands to at most 130 bytes
+	if count > len(data)*65 {
+		return nil, fmt.Errorf("byte RLE stream of %d bytes cannot hold %d bytes", len(data), count)
+	}
+
+	values := make([]byte, 0, count)
+	for pos := 0; pos < len(data) && len(values) < count; {
+		control := data[pos]
+		pos++
+		if control < 128 {
+			if pos >= len(data) {
+				return nil, fmt.Errorf("truncated byte RLE run")
+			}
+			for i := 0; i < int(control)+3; i++ {
+				values = append(values, data[pos])
+			}
+			pos++
+			continue
+		}
+
+		literals := 256 - int(control)
+		if pos+literals > len(data) {
+			return nil, fmt.Errorf("truncated byte RLE literals")
+		}
+		values = append(values, data[pos:pos+literals]...)
+		pos += literals
+	}
+	if len(values) < count {
+		return nil, fmt.Errorf("byte RLE stream decodes to %d bytes, expected %d", len(values), count)
+	}
+	return values[:count], nil
+}
+
+// decodePlain interprets raw varints, zigzag decoding them when signed
+func decodePlain(raw []uint64, signed bool) []int64 {
+	values := make([]int64, len(raw))
+	for i, value := range raw {
+		if signed {
+			values[i] = zigzag(value)
+		} else {
+			values[i] = int64(value)
+		}
+	}
+	return values
+}
+
+// prefixSum turns deltas into absolute values in place
+func prefixSum(values []int64) []int64 {
+	for i := 1; i < len(values); i++ {
+		values[i] += values[i-1]
+	}
+	return values
+}
+
+// zigzag decodes a zigzag encoded integer
+func zigzag(value uint64) int64 {
+	return int64(value>>1) ^ -int64(value&1)
+}
+
+// techniqueName returns a technique's name for error messages
+func techniqueName(names []string, technique int) string {
+	if technique >= 0 && technique < len(names) {
+		return names[technique]
+	}
+	return fmt.Sprintf("technique %d", technique)
+}
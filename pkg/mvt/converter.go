@@ -6,34 +6,66 @@ import (
 	"fmt"
 	"log"
 	"math"
+	"sort"
+	"strings"
 
 	"github.com/paulmach/orb"
 	"github.com/paulmach/orb/geojson"
-	"github.com/paulmach/orb/simplify"
 )
 
 // Converter handles conversion of Mapbox Vector Tiles to GeoJSON format
 type Converter struct {
-	decoder *Decoder
-	options *ConversionOptions
+	decoder     *Decoder
+	options     *ConversionOptions
+	filters     map[string]*Filter
+	filterKeys  map[string]string // Lowercased filter layer keys
+	promoteKeys map[string]string // Lowercased promote ID layer keys
+	area        *preparedArea
 }
 
 // ConversionOptions configures the conversion process
 type ConversionOptions struct {
-	IncludeMetadata  bool     `json:"include_metadata"`  // Include tile metadata in output
-	LayerFilter      []string `json:"layer_filter,omitempty"`      // Only include specified layers
-	PropertyFilter   []string `json:"property_filter,omitempty"`   // Only include specified properties
-	SimplifyGeometry bool     `json:"simplify_geometry"`           // Simplify geometries using Douglas-Peucker
-	CoordinateSystem string   `json:"coordinate_system"`           // "web-mercator" or "wgs84"
+	IncludeMetadata     bool                   `json:"include_metadata"`               // Include tile metadata in output
+	LayerFilter         []string               `json:"layer_filter,omitempty"`         // Only include specified layers
+	ExcludeLayers       []string               `json:"exclude_layers,omitempty"`       // Skip specified layers
+	PropertyFilter      []string               `json:"property_filter,omitempty"`      // Only include specified properties
+	SimplifyGeometry    bool                   `json:"simplify_geometry"`              // Simplify geometries
+	SimplifyAlgorithm   string                 `json:"simplify_algorithm,omitempty"`   // "douglas-peucker", "visvalingam" or "radial"
+	SimplifyTolerance   float64                `json:"simplify_tolerance,omitempty"`   // Tolerance in SimplifyUnits (default 1.0)
+	SimplifyUnits       string                 `json:"simplify_units,omitempty"`       // "pixels" at the tile's zoom or "map" units
+	PreserveTopology    bool                   `json:"preserve_topology"`              // Simplify shared polygon edges identically
+	PrecisionMode       string                 `json:"precision_mode,omitempty"`       // "", "fixed" or "auto" (from tile resolution)
+	CoordinatePrecision int                    `json:"coordinate_precision,omitempty"` // Decimal places for "fixed" precision mode
+	CoordinateSystem    string                 `json:"coordinate_system"`              // "web-mercator" or "wgs84"
+	RFC7946             bool                   `json:"rfc7946"`                        // Strict RFC 7946 output (forces WGS84, repairs geometry)
+	LargeIntAsString    bool                   `json:"large_int_as_string"`            // Emit integers beyond ±2^53 as strings
+	Filters             map[string]interface{} `json:"filters,omitempty"`              // Mapbox GL filter expression per layer ("*" for all)
+	AreaOfInterest      orb.MultiPolygon       `json:"-"`                              // Keep only features intersecting this WGS84 area
+	ClipToArea          bool                   `json:"clip_to_area"`                   // Clip kept features to AreaOfInterest
+	LayerProperty       string                 `json:"layer_property,omitempty"`       // Property holding the layer name (default "_layer")
+	OmitLayerProperty   bool                   `json:"omit_layer_property"`            // Do not add the layer name property
+	Properties          []PropertyOp           `json:"properties,omitempty"`           // Property pipeline applied to the final features
+	Strict              bool                   `json:"strict"`                         // Fail the tile instead of returning warnings
+	PromoteID           map[string]string      `json:"promote_id,omitempty"`           // Property promoted to the feature ID per layer ("*" for all)
+	SyntheticIDs        bool                   `json:"synthetic_ids"`                  // Derive stable IDs for features that have none
+	LabelPoints         string                 `json:"label_points,omitempty"`         // "", "property" or "layer": a label point per polygon
+	LabelAlgorithm      string                 `json:"label_algorithm,omitempty"`      // "polylabel" (default), "centroid" or "representative"
+	LabelPrecision      float64                `json:"label_precision,omitempty"`      // Polylabel precision in pixels at the tile's zoom (default 1.0)
+	LabelProperty       string                 `json:"label_property,omitempty"`       // Property holding the label point (default "_label")
+	LabelLayerSuffix    string                 `json:"label_layer_suffix,omitempty"`   // Appended to the source layer name (default "_label")
 }
 
 // ConversionMetadata contains metadata about the conversion process
 type ConversionMetadata struct {
-	Layers       []string `json:"layers"`
-	FeatureCount int      `json:"feature_count"`
-	Version      int      `json:"version"`
-	Extent       int      `json:"extent"`
-	TileID       string   `json:"tile_id"`
+	Layers       []string     `json:"layers"`
+	FeatureCount int          `json:"feature_count"`
+	Version      int          `json:"version"`
+	Extent       int          `json:"extent"`
+	TileID       string       `json:"tile_id"`
+	Format       TileFormat   `json:"format"`
+	Repairs      *RepairStats `json:"repairs,omitempty"`
+	Schema       Schema       `json:"schema,omitempty"`
+	Warnings     []Warning    `json:"warnings,omitempty"`
 }
 
 // Coordinate system constants
@@ -49,7 +81,7 @@ func NewConverter() *Converter {
 		SimplifyGeometry: false,
 		CoordinateSystem: CoordSystemWebMercator,
 	}
-	
+
 	if err := ValidateConversionOptions(options); err != nil {
 		log.Printf("Warning: invalid default options: %v", err)
 	}
@@ -66,64 +98,134 @@ func NewConverterWithOptions(options *ConversionOptions) (*Converter, error) {
 		return nil, fmt.Errorf("invalid conversion options: %w", err)
 	}
 
+	filters, err := compileFilters(options.Filters)
+	if err != nil {
+		return nil, fmt.Errorf("invalid conversion options: %w", err)
+	}
+
+	// RFC 7946 requires WGS84 coordinates
+	if options.RFC7946 && options.CoordinateSystem != CoordSystemWGS84 {
+		strict := *options
+		strict.CoordinateSystem = CoordSystemWGS84
+		options = &strict
+	}
+
+	var area *preparedArea
+	if len(options.AreaOfInterest) > 0 {
+		area = newPreparedArea(options.AreaOfInterest, options.CoordinateSystem)
+	}
+
+	filterKeys := make([]string, 0, len(filters))
+	for key := range filters {
+		filterKeys = append(filterKeys, key)
+	}
+	promoteKeys := make([]string, 0, len(options.PromoteID))
+	for key := range options.PromoteID {
+		promoteKeys = append(promoteKeys, key)
+	}
+
 	return &Converter{
-		decoder: NewDecoder(),
-		options: options,
+		decoder:     NewDecoder(),
+		options:     options,
+		filters:     filters,
+		filterKeys:  foldLayerKeys(filterKeys),
+		promoteKeys: foldLayerKeys(promoteKeys),
+		area:        area,
 	}, nil
 }
 
-// Convert transforms MVT binary data to GeoJSON format
+// Convert transforms MVT or MLT binary data to GeoJSON format, detecting the tile
+// format from the data
 func (c *Converter) Convert(data []byte, z, x, y int) (map[string]interface{}, *ConversionMetadata, error) {
-	// Decode the MVT data
-	decodedTile, err := c.decoder.Decode(data, z, x, y)
+	return c.ConvertFormat(data, DetectTileFormat(data, ""), z, x, y)
+}
+
+// ConvertFormat transforms tile data of a known format to GeoJSON format
+func (c *Converter) ConvertFormat(data []byte, format TileFormat, z, x, y int) (map[string]interface{}, *ConversionMetadata, error) {
+	// Decode the tile data
+	decodedTile, err := c.decoder.DecodeFormat(data, format, z, x, y)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to decode MVT: %w", err)
+		return nil, nil, fmt.Errorf("failed to decode %s: %w", strings.ToUpper(string(format)), err)
 	}
 
+	return c.convertTile(decodedTile, z, x, y)
+}
+
+// convertTile transforms a decoded tile to GeoJSON format
+func (c *Converter) convertTile(decodedTile *DecodedTile, z, x, y int) (map[string]interface{}, *ConversionMetadata, error) {
 	// Create GeoJSON FeatureCollection
 	featureCollection := &geojson.FeatureCollection{
 		Type:     "FeatureCollection",
 		Features: make([]*geojson.Feature, 0),
 	}
 
-	var conversionErrors []error
+	tileID := decodedTile.TileID.String()
+	var warnings []Warning
 
-	// Process each layer
-	for layerName, layer := range decodedTile.Layers {
+	var ids *syntheticIDs
+	if c.options.SyntheticIDs {
+		ids = newSyntheticIDs(z, x, y)
+	}
+
+	// Source layer of each feature, for layer-specific property steps and label layers
+	var featureLayers map[*geojson.Feature]string
+	if len(c.options.Properties) > 0 || c.options.LabelPoints == LabelModeLayer {
+		featureLayers = make(map[*geojson.Feature]string)
+	}
+
+	// Process each layer in encoded order so output is reproducible
+	layerNames := decodedTile.OrderedLayerNames()
+	for _, layerName := range layerNames {
 		// Apply layer filter if specified
-		if len(c.options.LayerFilter) > 0 && !c.contains(c.options.LayerFilter, layerName) {
+		if !c.includeLayer(layerName) {
 			continue
 		}
 
+		layer := decodedTile.Layers[layerName]
+
+		filter := c.layerFilter(layerName)
+
 		// Convert layer features to GeoJSON
-		for _, feature := range layer.Features {
+		for index, feature := range layer.Features {
 			// Skip features with nil geometry
 			if feature.Geometry == nil {
-				log.Printf("Warning: skipping feature with nil geometry in layer %s", layerName)
+				warnings = append(warnings, Warning{
+					Tile:    tileID,
+					Layer:   layerName,
+					Feature: index,
+					Code:    WarningNilGeometry,
+					Reason:  "feature has no geometry",
+				})
 				continue
 			}
 
-			geoJSONFeature, err := c.convertFeatureToGeoJSON(feature, layerName)
-			if err != nil {
-				conversionErrors = append(conversionErrors, fmt.Errorf("layer %s: %w", layerName, err))
+			// Apply attribute filter if specified
+			if filter != nil && !filter.Matches(feature, z) {
 				continue
 			}
 
-			// Apply geometry simplification if enabled
-			if c.options.SimplifyGeometry && geoJSONFeature.Geometry != nil {
-				geoJSONFeature.Geometry = simplify.DouglasPeucker(1.0).Simplify(geoJSONFeature.Geometry)
+			geoJSONFeature, err := c.convertFeatureToGeoJSON(feature, layerName)
+			if err != nil {
+				warnings = append(warnings, Warning{
+					Tile:    tileID,
+					Layer:   layerName,
+					Feature: index,
+					Code:    WarningConversionError,
+					Reason:  err.Error(),
+				})
+				continue
 			}
+			c.assignID(geoJSONFeature, feature, layerName, ids)
 
 			featureCollection.Features = append(featureCollection.Features, geoJSONFeature)
+			if featureLayers != nil {
+				featureLayers[geoJSONFeature] = layerName
+			}
 		}
 	}
 
-	// Log conversion errors for debugging
-	if len(conversionErrors) > 0 {
-		log.Printf("Conversion completed with %d errors", len(conversionErrors))
-		for _, err := range conversionErrors {
-			log.Printf("Conversion error: %v", err)
-		}
+	if c.options.Strict && len(warnings) > 0 {
+		return nil, nil, &StrictError{Warnings: warnings}
 	}
 
 	// Convert to coordinate system if specified
@@ -131,13 +233,56 @@ func (c *Converter) Convert(data []byte, z, x, y int) (map[string]interface{}, *
 		c.transformToWGS84(featureCollection)
 	}
 
+	// Apply the spatial filter in output coordinates
+	if c.area != nil {
+		featureCollection.Features = c.filterByArea(featureCollection.Features)
+	}
+
+	// Simplify in output coordinates so the tolerance matches the output units
+	if c.options.SimplifyGeometry {
+		featureCollection.Features = c.simplifyFeatures(featureCollection.Features, z)
+	}
+
+	// Round coordinates last so rounding duplicates can be removed from the final geometry
+	if c.options.PrecisionMode != PrecisionModeNone {
+		featureCollection.Features = c.roundFeatures(featureCollection.Features, z, decodedTile.Extent)
+	}
+
+	// Repair geometry after rounding, which can itself produce invalid rings
+	var repairs *RepairStats
+	if c.options.RFC7946 {
+		roundFactor := 0
+		if c.options.PrecisionMode != PrecisionModeNone {
+			roundFactor = int(math.Pow10(c.coordinatePrecision(z, decodedTile.Extent)))
+		}
+		repairs = &RepairStats{}
+		featureCollection.Features = repairFeatures(featureCollection.Features, roundFactor, repairs)
+	}
+
+	// Label the final polygons, before the property pipeline so it can act on labels
+	if c.options.LabelPoints != LabelModeNone {
+		featureCollection.Features = c.addLabelPoints(featureCollection.Features, featureLayers, z, decodedTile.Extent)
+		if c.options.LabelPoints == LabelModeLayer {
+			layerNames = c.appendLabelLayers(layerNames, featureCollection.Features, featureLayers)
+		}
+	}
+
+	// Transform properties last so computed values reflect the final geometry
+	if len(c.options.Properties) > 0 {
+		c.applyProperties(featureCollection.Features, featureLayers, z, x, y)
+	}
+
 	// Create metadata
 	metadata := &ConversionMetadata{
-		Layers:       decodedTile.GetLayerNames(),
+		Layers:       layerNames,
 		FeatureCount: len(featureCollection.Features),
 		Version:      decodedTile.Version,
 		Extent:       decodedTile.Extent,
-		TileID:       decodedTile.TileID.String(),
+		TileID:       tileID,
+		Format:       decodedTile.Format,
+		Repairs:      repairs,
+		Schema:       c.buildSchema(decodedTile),
+		Warnings:     warnings,
 	}
 
 	// Convert to map for JSON serialization
@@ -146,6 +291,12 @@ func (c *Converter) Convert(data []byte, z, x, y int) (map[string]interface{}, *
 		"features": featureCollection.Features,
 	}
 
+	if c.options.RFC7946 {
+		if bbox := CollectionBBox(featureCollection.Features); bbox != nil {
+			result["bbox"] = bbox
+		}
+	}
+
 	// Add metadata if requested
 	if c.options.IncludeMetadata {
 		result["metadata"] = metadata
@@ -165,6 +316,9 @@ func (c *Converter) convertFeatureToGeoJSON(feature *DecodedFeature, layerName s
 	// Set feature ID if present
 	if feature.ID != nil {
 		geoJSONFeature.ID = feature.ID
+		if c.options.LargeIntAsString {
+			geoJSONFeature.ID = largeIntegerAsString(feature.ID)
+		}
 	}
 
 	// Set properties
@@ -176,11 +330,20 @@ func (c *Converter) convertFeatureToGeoJSON(feature *DecodedFeature, layerName s
 		if len(c.options.PropertyFilter) > 0 && !c.contains(c.options.PropertyFilter, key) {
 			continue
 		}
+		if c.options.LargeIntAsString {
+			value = largeIntegerAsString(value)
+		}
 		properties[key] = value
 	}
 
 	// Add layer name to properties
-	properties["_layer"] = layerName
+	if !c.options.OmitLayerProperty {
+		layerProperty := c.options.LayerProperty
+		if layerProperty == "" {
+			layerProperty = DefaultLayerProperty
+		}
+		properties[layerProperty] = layerName
+	}
 
 	geoJSONFeature.Properties = properties
 
@@ -198,18 +361,16 @@ func (c *Converter) transformToWGS84(featureCollection *geojson.FeatureCollectio
 
 // transformGeometryToWGS84 transforms a single geometry from Web Mercator to WGS84
 func (c *Converter) transformGeometryToWGS84(geometry orb.Geometry) orb.Geometry {
-	const webMercatorMax = 20037508.342789244
-
 	transform := func(point orb.Point) orb.Point {
 		x, y := point[0], point[1]
-		
+
 		// Convert Web Mercator to WGS84 using correct formulas
 		lon := (x / webMercatorMax) * 180.0
-		
+
 		// Correct Web Mercator to latitude conversion
 		lat := y / webMercatorMax
-		lat = 180.0/math.Pi * (2*math.Atan(math.Exp(lat*math.Pi)) - math.Pi/2.0)
-		
+		lat = 180.0 / math.Pi * (2*math.Atan(math.Exp(lat*math.Pi)) - math.Pi/2.0)
+
 		return orb.Point{lon, lat}
 	}
 
@@ -226,6 +387,43 @@ func (c *Converter) contains(slice []string, item string) bool {
 	return false
 }
 
+// includeLayer reports whether a layer passes the layer include and exclude lists
+func (c *Converter) includeLayer(layerName string) bool {
+	if len(c.options.LayerFilter) > 0 && !c.contains(c.options.LayerFilter, layerName) {
+		return false
+	}
+	return !c.contains(c.options.ExcludeLayers, layerName)
+}
+
+// layerFilter returns the filter for a layer, matched exactly or ignoring case, falling
+// back to the all-layers filter
+func (c *Converter) layerFilter(layerName string) *Filter {
+	if filter, exists := c.filters[layerName]; exists {
+		return filter
+	}
+
+	if key, ok := c.filterKeys[strings.ToLower(layerName)]; ok {
+		return c.filters[key]
+	}
+	return c.filters[FilterAllLayers]
+}
+
+// foldLayerKeys maps lowercased per-layer option keys to the keys themselves, the
+// first in sorted order winning when keys differ only in case. Configuration files are
+// read with their map keys lowercased, so keys that do not match a layer exactly are
+// looked up this way.
+func foldLayerKeys(keys []string) map[string]string {
+	sort.Strings(keys)
+	folded := make(map[string]string, len(keys))
+	for _, key := range keys {
+		lower := strings.ToLower(key)
+		if _, exists := folded[lower]; !exists {
+			folded[lower] = key
+		}
+	}
+	return folded
+}
+
 // ConvertToGeoJSONString converts MVT data to a GeoJSON string
 func (c *Converter) ConvertToGeoJSONString(data []byte, z, x, y int, pretty bool) (string, error) {
 	result, _, err := c.Convert(data, z, x, y)
@@ -250,8 +448,76 @@ func (c *Converter) ConvertToGeoJSONString(data []byte, z, x, y int, pretty bool
 // ValidateConversionOptions validates the conversion options
 func ValidateConversionOptions(options *ConversionOptions) error {
 	if options.CoordinateSystem != CoordSystemWebMercator && options.CoordinateSystem != CoordSystemWGS84 {
-		return fmt.Errorf("invalid coordinate system: %s, must be '%s' or '%s'", 
+		return fmt.Errorf("invalid coordinate system: %s, must be '%s' or '%s'",
 			options.CoordinateSystem, CoordSystemWebMercator, CoordSystemWGS84)
 	}
+
+	switch options.SimplifyAlgorithm {
+	case "", SimplifyDouglasPeucker, SimplifyVisvalingam, SimplifyRadial:
+	default:
+		return fmt.Errorf("invalid simplify algorithm: %s, must be '%s', '%s' or '%s'",
+			options.SimplifyAlgorithm, SimplifyDouglasPeucker, SimplifyVisvalingam, SimplifyRadial)
+	}
+
+	switch options.SimplifyUnits {
+	case "", ToleranceUnitPixels, ToleranceUnitMap:
+	default:
+		return fmt.Errorf("invalid simplify units: %s, must be '%s' or '%s'",
+			options.SimplifyUnits, ToleranceUnitPixels, ToleranceUnitMap)
+	}
+
+	if options.SimplifyTolerance < 0 {
+		return fmt.Errorf("simplify tolerance must be non-negative")
+	}
+
+	switch options.PrecisionMode {
+	case PrecisionModeNone, PrecisionModeFixed, PrecisionModeAuto:
+	default:
+		return fmt.Errorf("invalid precision mode: %s, must be '%s' or '%s'",
+			options.PrecisionMode, PrecisionModeFixed, PrecisionModeAuto)
+	}
+
+	if options.CoordinatePrecision < 0 || options.CoordinatePrecision > MaxCoordinatePrecision {
+		return fmt.Errorf("coordinate precision must be between 0 and %d", MaxCoordinatePrecision)
+	}
+
+	if _, err := compileFilters(options.Filters); err != nil {
+		return err
+	}
+
+	for layer, property := range options.PromoteID {
+		if property == "" {
+			return fmt.Errorf("promote ID for layer %s names no property", layer)
+		}
+	}
+
+	switch options.LabelPoints {
+	case LabelModeNone, LabelModeProperty, LabelModeLayer:
+	default:
+		return fmt.Errorf("invalid label points mode: %s, must be '%s' or '%s'",
+			options.LabelPoints, LabelModeProperty, LabelModeLayer)
+	}
+
+	switch options.LabelAlgorithm {
+	case "", LabelPolylabel, LabelCentroid, LabelRepresentative:
+	default:
+		return fmt.Errorf("invalid label algorithm: %s, must be '%s', '%s' or '%s'",
+			options.LabelAlgorithm, LabelPolylabel, LabelCentroid, LabelRepresentative)
+	}
+
+	if options.LabelPrecision < 0 {
+		return fmt.Errorf("label precision must be non-negative")
+	}
+
+	if options.ClipToArea && len(options.AreaOfInterest) == 0 {
+		return fmt.Errorf("clipping requires an area of interest")
+	}
+
+	for i := range options.Properties {
+		if err := options.Properties[i].Validate(); err != nil {
+			return fmt.Errorf("property step %d: %w", i+1, err)
+		}
+	}
+
 	return nil
 }
@@ -2,9 +2,11 @@
 package mvt
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
 )
 
 func TestNewConverter(t *testing.T) {
@@ -57,6 +59,14 @@ func TestValidateConversionOptions(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "promote ID without property",
+			options: &ConversionOptions{
+				CoordinateSystem: CoordSystemWebMercator,
+				PromoteID:        map[string]string{"buildings": ""},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -168,3 +178,135 @@ func abs(x float64) float64 {
 	}
 	return x
 }
+
+func TestConvertWarnings(t *testing.T) {
+	decodedTile := &DecodedTile{
+		Layers: map[string]*DecodedLayer{
+			"roads": {
+				Name: "roads",
+				Features: []*DecodedFeature{
+					{Tags: map[string]interface{}{"class": "motorway"}, Geometry: orb.LineString{{0, 0}, {1, 1}}},
+					{Tags: map[string]interface{}{"class": "path"}},
+				},
+			},
+		},
+		Extent:  4096,
+		Version: 2,
+		TileID:  TileID{Z: 14, X: 8192, Y: 5461},
+	}
+
+	converter := NewConverter()
+	result, metadata, err := converter.convertTile(decodedTile, 14, 8192, 5461)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if features := result["features"].([]*geojson.Feature); len(features) != 1 {
+		t.Errorf("Expected 1 feature, got %d", len(features))
+	}
+	want := Warning{Tile: "14/8192/5461", Layer: "roads", Feature: 1, Code: WarningNilGeometry, Reason: "feature has no geometry"}
+	if len(metadata.Warnings) != 1 || metadata.Warnings[0] != want {
+		t.Errorf("Expected warning %+v, got %+v", want, metadata.Warnings)
+	}
+
+	strict, err := NewConverterWithOptions(&ConversionOptions{CoordinateSystem: CoordSystemWebMercator, Strict: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	_, _, err = strict.convertTile(decodedTile, 14, 8192, 5461)
+	var strictErr *StrictError
+	if !errors.As(err, &strictErr) || len(strictErr.Warnings) != 1 {
+		t.Errorf("Expected strict error with 1 warning, got %v", err)
+	}
+}
+
+func TestConvertFeatureIDs(t *testing.T) {
+	point := orb.Point{10, 20}
+	decodedTile := &DecodedTile{
+		Layers: map[string]*DecodedLayer{
+			"buildings": {
+				Name: "buildings",
+				Features: []*DecodedFeature{
+					{ID: uint64(1), Tags: map[string]interface{}{"osm_id": int64(900)}, Geometry: point},
+					{ID: uint64(2), Geometry: point},
+				},
+			},
+			"pois": {
+				Name: "pois",
+				Features: []*DecodedFeature{
+					{Tags: map[string]interface{}{"ref": true}, Geometry: point},
+					{Geometry: point},
+					{Geometry: point},
+				},
+			},
+		},
+		LayerOrder: []string{"buildings", "pois"},
+		Extent:     4096,
+		TileID:     TileID{Z: 14, X: 8192, Y: 5461},
+	}
+
+	converter, err := NewConverterWithOptions(&ConversionOptions{
+		CoordinateSystem: CoordSystemWebMercator,
+		PromoteID:        map[string]string{"buildings": "osm_id", PromoteAllLayers: "ref"},
+		SyntheticIDs:     true,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	convert := func() []interface{} {
+		result, _, err := converter.convertTile(decodedTile, 14, 8192, 5461)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		var ids []interface{}
+		for _, feature := range result["features"].([]*geojson.Feature) {
+			ids = append(ids, feature.ID)
+		}
+		return ids
+	}
+
+	ids := convert()
+	if ids[0] != int64(900) || ids[1] != uint64(2) || ids[2] != "true" {
+		t.Errorf("Expected promoted IDs 900, 2 and \"true\", got %v", ids[:3])
+	}
+
+	synthetic, ok := ids[3].(string)
+	if !ok || len(synthetic) == 0 || ids[4] != synthetic+"-1" {
+		t.Errorf("Expected a synthetic ID and its duplicate suffix, got %v and %v", ids[3], ids[4])
+	}
+	if again := convert(); again[3] != ids[3] || again[4] != ids[4] {
+		t.Errorf("Expected synthetic IDs to be stable, got %v then %v", ids[3:], again[3:])
+	}
+
+	// The tile is part of the hash
+	result, _, err := converter.convertTile(decodedTile, 14, 8193, 5461)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if other := result["features"].([]*geojson.Feature)[3].ID; other == ids[3] {
+		t.Errorf("Expected a different synthetic ID in another tile, got %v", other)
+	}
+}
+
+func TestPromotePropertyCase(t *testing.T) {
+	// Configuration files are read with lowercased map keys
+	converter, err := NewConverterWithOptions(&ConversionOptions{
+		CoordinateSystem: CoordSystemWebMercator,
+		PromoteID:        map[string]string{"buildings": "osm_id", "Roads": "ref", "roads": "name", PromoteAllLayers: "id"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := map[string]string{
+		"Buildings": "osm_id",
+		"roads":     "name",
+		"ROADS":     "ref",
+		"Water":     "id",
+	}
+	for layer, want := range tests {
+		if got := converter.promoteProperty(layer); got != want {
+			t.Errorf("Expected layer %s to promote %s, got %s", layer, want, got)
+		}
+	}
+}
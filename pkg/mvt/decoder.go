@@ -3,6 +3,7 @@ package mvt
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/paulmach/orb"
 	"github.com/paulmach/orb/encoding/mvt"
@@ -29,10 +30,12 @@ func NewDecoderWithExtent(extent int) *Decoder {
 
 // DecodedTile represents a decoded MVT tile with its layers and metadata
 type DecodedTile struct {
-	Layers  map[string]*DecodedLayer `json:"layers"`
-	Extent  int                      `json:"extent"`
-	Version int                      `json:"version"`
-	TileID  TileID                   `json:"tile_id"`
+	Layers     map[string]*DecodedLayer `json:"layers"`
+	LayerOrder []string                 `json:"layer_order,omitempty"` // Layer names in encoded order
+	Format     TileFormat               `json:"format"`
+	Extent     int                      `json:"extent"`
+	Version    int                      `json:"version"`
+	TileID     TileID                   `json:"tile_id"`
 }
 
 // DecodedLayer represents a single layer within an MVT tile
@@ -47,6 +50,7 @@ type DecodedLayer struct {
 type DecodedFeature struct {
 	ID       interface{}            `json:"id,omitempty"`
 	Tags     map[string]interface{} `json:"tags"`
+	TagTypes map[string]string      `json:"tag_types,omitempty"`
 	Type     string                 `json:"type"`
 	Geometry orb.Geometry           `json:"geometry"`
 }
@@ -58,21 +62,46 @@ type TileID struct {
 	Y int `json:"y"`
 }
 
-// Decode decodes a Mapbox Vector Tile from binary Protocol Buffer data
+// Decode decodes a vector tile, detecting from its bytes whether it is a Mapbox Vector
+// Tile or a MapLibre Tile
 func (d *Decoder) Decode(data []byte, z, x, y int) (*DecodedTile, error) {
+	return d.DecodeFormat(data, DetectTileFormat(data, ""), z, x, y)
+}
+
+// DecodeFormat decodes a vector tile of a known format
+func (d *Decoder) DecodeFormat(data []byte, format TileFormat, z, x, y int) (*DecodedTile, error) {
 	if len(data) == 0 {
 		return nil, fmt.Errorf("empty tile data")
 	}
 
+	switch format {
+	case TileFormatMVT:
+		return d.decodeMVT(data, z, x, y)
+	case TileFormatMLT:
+		return d.decodeMLT(data, z, x, y)
+	default:
+		return nil, fmt.Errorf("unsupported tile format: %s", format)
+	}
+}
+
+// decodeMVT decodes a Mapbox Vector Tile from binary Protocol Buffer data
+func (d *Decoder) decodeMVT(data []byte, z, x, y int) (*DecodedTile, error) {
 	// Use orb library to unmarshal MVT data
 	layers, err := mvt.Unmarshal(data)
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal MVT data: %w", err)
 	}
 
+	// orb widens every number to float64; rescan IDs and attributes with their MVT types
+	typed, err := decodeTypedAttributes(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode MVT attributes: %w", err)
+	}
+
 	// Create the decoded tile structure
 	decodedTile := &DecodedTile{
 		Layers:  make(map[string]*DecodedLayer),
+		Format:  TileFormatMVT,
 		Extent:  d.extent,
 		Version: 2,
 		TileID: TileID{
@@ -83,7 +112,7 @@ func (d *Decoder) Decode(data []byte, z, x, y int) (*DecodedTile, error) {
 	}
 
 	// Process each layer - layers is mvt.Layers which can be ranged over
-	for _, layer := range layers {
+	for i, layer := range layers {
 		decodedLayer := &DecodedLayer{
 			Name:     layer.Name,
 			Features: make([]*DecodedFeature, 0, len(layer.Features)),
@@ -91,47 +120,65 @@ func (d *Decoder) Decode(data []byte, z, x, y int) (*DecodedTile, error) {
 			Version:  int(layer.Version),
 		}
 
+		// Layers declare their own extent; fall back to the decoder's for tiles that omit it
+		extent := d.extent
+		if layer.Extent > 0 {
+			extent = int(layer.Extent)
+		}
+
 		// Process each feature - layer.Features is []*geojson.Feature
-		for _, feature := range layer.Features {
+		for j, feature := range layer.Features {
 			decodedFeature := &DecodedFeature{
 				ID:       feature.ID,
 				Tags:     feature.Properties,
-				Geometry: d.transformGeometry(feature.Geometry, z, x, y),
+				Geometry: d.transformGeometry(feature.Geometry, extent, z, x, y),
 			}
 
-			// Determine geometry type
-			switch feature.Geometry.(type) {
-			case orb.Point:
-				decodedFeature.Type = "Point"
-			case orb.MultiPoint:
-				decodedFeature.Type = "MultiPoint"
-			case orb.LineString:
-				decodedFeature.Type = "LineString"
-			case orb.MultiLineString:
-				decodedFeature.Type = "MultiLineString"
-			case orb.Polygon:
-				decodedFeature.Type = "Polygon"
-			case orb.MultiPolygon:
-				decodedFeature.Type = "MultiPolygon"
-			default:
-				decodedFeature.Type = "Unknown"
+			if i < len(typed) && j < len(typed[i]) {
+				decodedFeature.ID = typed[i][j].id
+				decodedFeature.Tags = typed[i][j].tags
+				decodedFeature.TagTypes = typed[i][j].types
 			}
 
+			decodedFeature.Type = geometryTypeName(feature.Geometry)
+
 			decodedLayer.Features = append(decodedLayer.Features, decodedFeature)
 		}
 
+		if _, exists := decodedTile.Layers[layer.Name]; !exists {
+			decodedTile.LayerOrder = append(decodedTile.LayerOrder, layer.Name)
+		}
 		decodedTile.Layers[layer.Name] = decodedLayer
 	}
 
 	return decodedTile, nil
 }
 
+// geometryTypeName returns the GeoJSON type name of a tile geometry
+func geometryTypeName(geometry orb.Geometry) string {
+	switch geometry.(type) {
+	case orb.Point:
+		return "Point"
+	case orb.MultiPoint:
+		return "MultiPoint"
+	case orb.LineString:
+		return "LineString"
+	case orb.MultiLineString:
+		return "MultiLineString"
+	case orb.Polygon:
+		return "Polygon"
+	case orb.MultiPolygon:
+		return "MultiPolygon"
+	default:
+		return "Unknown"
+	}
+}
+
 // transformGeometry converts tile coordinates to geographic coordinates
-func (d *Decoder) transformGeometry(geometry orb.Geometry, z, x, y int) orb.Geometry {
+func (d *Decoder) transformGeometry(geometry orb.Geometry, extent, z, x, y int) orb.Geometry {
 	numTiles := 1 << uint(z)
 	n := float64(numTiles)
-	tileSize := float64(d.extent)
-	const webMercatorMax = 20037508.342789244
+	tileSize := float64(extent)
 
 	transform := func(point orb.Point) orb.Point {
 		tileX := point[0] / tileSize
@@ -192,12 +239,32 @@ func transformGeometry(geom orb.Geometry, transform func(orb.Point) orb.Point) o
 	}
 }
 
-// GetLayerNames returns layer names
+// GetLayerNames returns layer names in sorted order
 func (dt *DecodedTile) GetLayerNames() []string {
 	names := make([]string, 0, len(dt.Layers))
 	for name := range dt.Layers {
 		names = append(names, name)
 	}
+	sort.Strings(names)
+	return names
+}
+
+// OrderedLayerNames returns layer names in the order they were encoded in the tile.
+// Layers missing from LayerOrder, as in tiles built by hand, follow in sorted order.
+func (dt *DecodedTile) OrderedLayerNames() []string {
+	names := make([]string, 0, len(dt.Layers))
+	seen := make(map[string]bool, len(dt.Layers))
+	for _, name := range dt.LayerOrder {
+		if _, exists := dt.Layers[name]; exists && !seen[name] {
+			names = append(names, name)
+			seen[name] = true
+		}
+	}
+	for _, name := range dt.GetLayerNames() {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
 	return names
 }
 
@@ -2,9 +2,12 @@
 package mvt
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/mvt"
+	"github.com/paulmach/orb/geojson"
 )
 
 func TestNewDecoder(t *testing.T) {
@@ -109,6 +112,118 @@ func TestDecodedTileGetLayerNames(t *testing.T) {
 	}
 }
 
+func TestDecodeLayerOrder(t *testing.T) {
+	var layers mvt.Layers
+	for _, name := range []string{"water", "roads", "places"} {
+		feature := geojson.NewFeature(orb.Point{10, 10})
+		layers = append(layers, &mvt.Layer{Name: name, Version: 2, Extent: 4096, Features: []*geojson.Feature{feature}})
+	}
+	data, err := mvt.Marshal(layers)
+	if err != nil {
+		t.Fatalf("Failed to encode tile: %v", err)
+	}
+
+	decoded, err := NewDecoder().Decode(data, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	encoded := []string{"water", "roads", "places"}
+	if names := decoded.OrderedLayerNames(); !reflect.DeepEqual(names, encoded) {
+		t.Errorf("Expected encoded layer order %v, got %v", encoded, names)
+	}
+
+	_, metadata, err := NewConverter().Convert(data, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(metadata.Layers, encoded) {
+		t.Errorf("Expected metadata layers %v, got %v", encoded, metadata.Layers)
+	}
+}
+
+// mltPoint is a MapLibre Tile with a layer "p" holding one point at tile coordinates 1,1
+var mltPoint = []byte{
+	0x13,                  // layer size
+	0x01,                  // basic layer tag
+	0x01, 'p', 0x80, 0x20, // name, extent 4096
+	0x01, 0x04, // one geometry column
+	0x02,                         // geometry streams
+	0x10, 0x02, 0x01, 0x01, 0x00, // geometry types: point
+	0x13, 0x02, 0x02, 0x02, 0x02, 0x02, // vertex buffer: 1,1 zigzag encoded
+}
+
+// collidingMVT is an MVT tile, layer "d" with a line feature, whose bytes also read as
+// a sequence of size-prefixed MLT layers
+var collidingMVT = []byte{
+	0x1a, 0x34, 0x0a, 0x01, 0x64, 0x12, 0x14, 0x12, 0x04, 0x00, 0x00, 0x01, 0x01, 0x18, 0x02, 0x22,
+	0x0a, 0x09, 0xd4, 0x39, 0x88, 0x0a, 0x0a, 0xeb, 0x29, 0xce, 0x13, 0x1a, 0x01, 0x6b, 0x1a, 0x01,
+	0x73, 0x22, 0x09, 0x19, 0x00, 0x00, 0x00, 0x00, 0x00, 0x28, 0x80, 0x40, 0x22, 0x03, 0x0a, 0x01,
+	0x79, 0x28, 0x80, 0x20, 0x78, 0x02,
+}
+
+func TestDetectTileFormat(t *testing.T) {
+	mvtData, err := mvt.Marshal(mvt.Layers{{Name: "p", Version: 2, Extent: 4096,
+		Features: []*geojson.Feature{geojson.NewFeature(orb.Point{1, 1})}}})
+	if err != nil {
+		t.Fatalf("Failed to encode tile: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		data        []byte
+		contentType string
+		want        TileFormat
+	}{
+		{"mvt bytes", mvtData, "", TileFormatMVT},
+		{"mlt bytes", mltPoint, "", TileFormatMLT},
+		{"generic content type", mltPoint, "application/x-protobuf", TileFormatMLT},
+		{"mvt with mlt structure", collidingMVT, "application/x-protobuf", TileFormatMVT},
+		{"mlt content type", mvtData, "application/vnd.maplibre-vector-tile", TileFormatMLT},
+		{"mvt content type", mltPoint, "application/vnd.mapbox-vector-tile; charset=binary", TileFormatMVT},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectTileFormat(tt.data, tt.contentType); got != tt.want {
+				t.Errorf("DetectTileFormat() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeMVTWithMLTStructure(t *testing.T) {
+	tile, err := NewDecoder().Decode(collidingMVT, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("Failed to decode tile: %v", err)
+	}
+	if tile.Format != TileFormatMVT {
+		t.Errorf("Expected format %s, got %s", TileFormatMVT, tile.Format)
+	}
+	layer, ok := tile.Layers["d"]
+	if !ok || len(layer.Features) != 1 {
+		t.Fatalf("Expected layer d with 1 feature, got %v", tile.Layers)
+	}
+}
+
+func TestConvertMLT(t *testing.T) {
+	result, metadata, err := NewConverter().Convert(mltPoint, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if metadata.Format != TileFormatMLT || !reflect.DeepEqual(metadata.Layers, []string{"p"}) {
+		t.Errorf("Unexpected metadata: %+v", metadata)
+	}
+
+	features := result["features"].([]*geojson.Feature)
+	if len(features) != 1 {
+		t.Fatalf("Expected 1 feature, got %d", len(features))
+	}
+	want := NewDecoder().transformGeometry(orb.Point{1, 1}, 4096, 0, 0, 0)
+	if features[0].Geometry != want || features[0].Properties["_layer"] != "p" {
+		t.Errorf("Expected point %v in layer p, got %v %v", want, features[0].Geometry, features[0].Properties)
+	}
+}
+
 func TestDecodedTileIsEmpty(t *testing.T) {
 	// Empty tile
 	emptyTile := &DecodedTile{
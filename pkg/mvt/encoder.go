@@ -0,0 +1,595 @@
+// pkg/mvt/encoder.go - GeoJSON to Mapbox Vector Tile encoding implementation
+package mvt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/clip"
+	"github.com/paulmach/orb/encoding/mvt"
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/orb/planar"
+)
+
+// Encoder defaults and limits
+const (
+	DefaultEncodeExtent = 4096       // Tile extent in integer units
+	DefaultEncodeBuffer = 64         // Clip buffer in extent units (1/64 of a 4096 tile)
+	DefaultEncodeLayer  = "features" // Layer for features without a layer property
+	MaxEncodeZoom       = 24         // Highest supported zoom level
+)
+
+// Encoder handles encoding of GeoJSON features into Mapbox Vector Tiles
+type Encoder struct {
+	options *EncoderOptions
+}
+
+// EncoderOptions configures the encoding process
+type EncoderOptions struct {
+	Extent            uint32 `json:"extent"`              // Tile extent in integer units
+	Buffer            int    `json:"buffer"`              // Clip buffer around the tile in extent units
+	LayerProperty     string `json:"layer_property"`      // Property naming a feature's layer ("" puts all features in DefaultLayer)
+	DefaultLayer      string `json:"default_layer"`       // Layer for features without LayerProperty
+	KeepLayerProperty bool   `json:"keep_layer_property"` // Keep LayerProperty as a tile attribute
+	CoordinateSystem  string `json:"coordinate_system"`   // Input coordinates, "web-mercator" or "wgs84"
+	Gzip              bool   `json:"gzip"`                // Gzip the encoded tiles
+}
+
+// encodeEntry is an input feature prepared for encoding into any number of tiles
+type encodeEntry struct {
+	id         interface{}
+	layer      string
+	properties geojson.Properties
+	geometry   orb.Geometry // Web Mercator
+	bound      orb.Bound
+}
+
+// DefaultEncoderOptions returns options that encode output of the convert command
+// with its default settings back into equivalent tiles
+func DefaultEncoderOptions() *EncoderOptions {
+	return &EncoderOptions{
+		Extent:           DefaultEncodeExtent,
+		Buffer:           DefaultEncodeBuffer,
+		LayerProperty:    DefaultLayerProperty,
+		DefaultLayer:     DefaultEncodeLayer,
+		CoordinateSystem: CoordSystemWebMercator,
+	}
+}
+
+// NewEncoder creates a new GeoJSON to MVT encoder with default options
+func NewEncoder() *Encoder {
+	return &Encoder{options: DefaultEncoderOptions()}
+}
+
+// NewEncoderWithOptions creates an encoder with custom options
+func NewEncoderWithOptions(options *EncoderOptions) (*Encoder, error) {
+	if err := ValidateEncoderOptions(options); err != nil {
+		return nil, fmt.Errorf("invalid encoder options: %w", err)
+	}
+
+	return &Encoder{options: options}, nil
+}
+
+// Encode encodes the features into the tile at z/x/y. Features outside the tile and its
+// buffer are skipped, so the result may be a valid tile without layers.
+func (e *Encoder) Encode(features []*geojson.Feature, z, x, y int) ([]byte, error) {
+	if err := validateEncodeTile(z, x, y); err != nil {
+		return nil, err
+	}
+
+	entries, err := e.prepare(features)
+	if err != nil {
+		return nil, err
+	}
+	inTile := make([]*encodeEntry, 0, len(entries))
+	for _, entry := range entries {
+		minX, minY, maxX, maxY := e.tileRange(entry.bound, z)
+		if x >= minX && x <= maxX && y >= minY && y <= maxY {
+			inTile = append(inTile, entry)
+		}
+	}
+
+	layers, _ := e.encodeTile(inTile, z, x, y)
+	return e.marshal(layers)
+}
+
+// EncodeRange encodes the features into every tile they touch from minZoom to maxZoom
+// and passes each non-empty tile to emit, in z/x/y order
+func (e *Encoder) EncodeRange(features []*geojson.Feature, minZoom, maxZoom int, emit func(tile TileID, data []byte) error) error {
+	if minZoom < 0 || maxZoom > MaxEncodeZoom || minZoom > maxZoom {
+		return fmt.Errorf("invalid zoom range %d-%d, must be within 0-%d", minZoom, maxZoom, MaxEncodeZoom)
+	}
+
+	entries, err := e.prepare(features)
+	if err != nil {
+		return err
+	}
+	for z := minZoom; z <= maxZoom; z++ {
+		buckets := make(map[TileID][]*encodeEntry)
+		for _, entry := range entries {
+			minX, minY, maxX, maxY := e.tileRange(entry.bound, z)
+			for x := minX; x <= maxX; x++ {
+				for y := minY; y <= maxY; y++ {
+					tile := TileID{Z: z, X: x, Y: y}
+					buckets[tile] = append(buckets[tile], entry)
+				}
+			}
+		}
+
+		tiles := make([]TileID, 0, len(buckets))
+		for tile := range buckets {
+			tiles = append(tiles, tile)
+		}
+		sort.Slice(tiles, func(i, j int) bool {
+			if tiles[i].X != tiles[j].X {
+				return tiles[i].X < tiles[j].X
+			}
+			return tiles[i].Y < tiles[j].Y
+		})
+
+		for _, tile := range tiles {
+			layers, count := e.encodeTile(buckets[tile], tile.Z, tile.X, tile.Y)
+			if count == 0 {
+				continue
+			}
+
+			data, err := e.marshal(layers)
+			if err != nil {
+				return fmt.Errorf("failed to encode tile %d/%d/%d: %w", tile.Z, tile.X, tile.Y, err)
+			}
+			if err := emit(tile, data); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// prepare projects the features to Web Mercator and resolves their layers, IDs and
+// attributes
+func (e *Encoder) prepare(features []*geojson.Feature) ([]*encodeEntry, error) {
+	entries := make([]*encodeEntry, 0, len(features))
+	for i, feature := range features {
+		if feature == nil || feature.Geometry == nil {
+			continue
+		}
+
+		id, err := encodeID(feature.ID)
+		if err != nil {
+			return nil, fmt.Errorf("feature %d: %w", i, err)
+		}
+
+		layer := e.options.DefaultLayer
+		if name, ok := feature.Properties[e.options.LayerProperty].(string); ok && name != "" {
+			layer = name
+		}
+		properties := e.encodeProperties(feature.Properties)
+
+		// MVT features hold a single geometry, so collections become one feature per member
+		geometries := []orb.Geometry{feature.Geometry}
+		if collection, ok := feature.Geometry.(orb.Collection); ok {
+			geometries = collection
+		}
+
+		for _, geometry := range geometries {
+			if e.options.CoordinateSystem == CoordSystemWGS84 {
+				geometry = applyGeometryTransform(geometry, wgs84ToWebMercator)
+			}
+			entries = append(entries, &encodeEntry{
+				id:         id,
+				layer:      layer,
+				properties: properties,
+				geometry:   geometry,
+				bound:      geometry.Bound(),
+			})
+		}
+	}
+	return entries, nil
+}
+
+// encodeID converts a feature ID to the unsigned integer MVT holds. Numeric strings,
+// as written for large IDs by --large-int-as-string, are accepted; other IDs fail
+// rather than being dropped.
+func encodeID(id interface{}) (interface{}, error) {
+	switch v := id.(type) {
+	case nil:
+		return nil, nil
+	case uint64:
+		return v, nil
+	case uint:
+		return uint64(v), nil
+	case uint8, uint16, uint32:
+		return uint64(ToInt64(v)), nil
+	case int, int8, int16, int32, int64:
+		if n := ToInt64(v); n >= 0 {
+			return uint64(n), nil
+		}
+	case float32, float64:
+		if f := ToFloat64(v); f >= 0 && f <= maxSafeInteger && f == math.Trunc(f) {
+			return uint64(f), nil
+		}
+	case json.Number:
+		if n, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			return n, nil
+		}
+		if f, err := v.Float64(); err == nil {
+			return encodeID(f)
+		}
+	case string:
+		if n, err := strconv.ParseUint(v, 10, 64); err == nil {
+			return n, nil
+		}
+	}
+	return nil, fmt.Errorf("ID %v is not an unsigned integer within the 64-bit range", id)
+}
+
+// encodeProperties converts GeoJSON properties into values MVT can hold. Nulls are
+// dropped, whole numbers become integers and nested values are stored as JSON text.
+func (e *Encoder) encodeProperties(properties geojson.Properties) geojson.Properties {
+	result := make(geojson.Properties, len(properties))
+	for key, value := range properties {
+		if key == e.options.LayerProperty && !e.options.KeepLayerProperty {
+			continue
+		}
+
+		switch v := value.(type) {
+		case nil:
+			continue
+		case float64:
+			result[key] = encodeNumber(v)
+		case json.Number:
+			if n, err := v.Int64(); err == nil {
+				result[key] = n
+			} else if n, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+				result[key] = n
+			} else if f, err := v.Float64(); err == nil {
+				result[key] = encodeNumber(f)
+			} else {
+				result[key] = v.String()
+			}
+		case string, bool, float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+			result[key] = v
+		default:
+			data, err := json.Marshal(v)
+			if err != nil {
+				continue
+			}
+			result[key] = string(data)
+		}
+	}
+	return result
+}
+
+// encodeNumber stores a whole JSON number as an integer, when it is exactly one
+func encodeNumber(v float64) interface{} {
+	if v == math.Trunc(v) && math.Abs(v) <= maxSafeInteger {
+		return int64(v)
+	}
+	return v
+}
+
+// encodeTile projects, clips and snaps the entries into tile layers, returning the
+// layers and the number of features they hold
+func (e *Encoder) encodeTile(entries []*encodeEntry, z, x, y int) (mvt.Layers, int) {
+	n := float64(uint64(1) << uint(z))
+	extent := float64(e.options.Extent)
+	buffer := float64(e.options.Buffer)
+	bound := orb.Bound{Min: orb.Point{-buffer, -buffer}, Max: orb.Point{extent + buffer, extent + buffer}}
+
+	// Inverse of the decoder's tile to Web Mercator transform
+	project := func(point orb.Point) orb.Point {
+		tileX := ((point[0]/webMercatorMax+1)/2*n - float64(x)) * extent
+		tileY := ((1-point[1]/webMercatorMax)/2*n - float64(y)) * extent
+		return orb.Point{tileX, tileY}
+	}
+
+	byLayer := make(map[string]*mvt.Layer)
+	count := 0
+	for _, entry := range entries {
+		geometry := clip.Geometry(bound, applyGeometryTransform(entry.geometry, project))
+		if geometry == nil {
+			continue
+		}
+		if geometry = snapGeometry(geometry); geometry == nil {
+			continue
+		}
+
+		layer, exists := byLayer[entry.layer]
+		if !exists {
+			layer = &mvt.Layer{Name: entry.layer, Version: 2, Extent: e.options.Extent}
+			byLayer[entry.layer] = layer
+		}
+
+		feature := geojson.NewFeature(geometry)
+		feature.ID = entry.id
+		feature.Properties = entry.properties
+		layer.Features = append(layer.Features, feature)
+		count++
+	}
+
+	names := make([]string, 0, len(byLayer))
+	for name := range byLayer {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	layers := make(mvt.Layers, 0, len(names))
+	for _, name := range names {
+		layers = append(layers, byLayer[name])
+	}
+	return layers, count
+}
+
+// marshal serializes the layers, gzipping them when configured
+func (e *Encoder) marshal(layers mvt.Layers) ([]byte, error) {
+	if e.options.Gzip {
+		return mvt.MarshalGzipped(layers)
+	}
+	return mvt.Marshal(layers)
+}
+
+// tileRange returns the tiles at zoom z touched by a Web Mercator bound and the clip buffer
+func (e *Encoder) tileRange(bound orb.Bound, z int) (minX, minY, maxX, maxY int) {
+	n := float64(uint64(1) << uint(z))
+	margin := float64(e.options.Buffer) / float64(e.options.Extent)
+
+	clamp := func(value float64) int {
+		return int(math.Max(0, math.Min(n-1, math.Floor(value))))
+	}
+
+	minX = clamp((bound.Min[0]/webMercatorMax+1)/2*n - margin)
+	maxX = clamp((bound.Max[0]/webMercatorMax+1)/2*n + margin)
+	minY = clamp((1-bound.Max[1]/webMercatorMax)/2*n - margin)
+	maxY = clamp((1-bound.Min[1]/webMercatorMax)/2*n + margin)
+	return minX, minY, maxX, maxY
+}
+
+// snapGeometry rounds a tile space geometry to integer coordinates, removing repeated
+// points and collapsed parts, and orients polygon rings as MVT requires: exterior rings
+// with positive area in tile coordinates, interior rings with negative area. Returns nil
+// when nothing remains.
+func snapGeometry(geometry orb.Geometry) orb.Geometry {
+	switch g := geometry.(type) {
+	case orb.Point:
+		return snapPoint(g)
+	case orb.MultiPoint:
+		result := make(orb.MultiPoint, len(g))
+		for i, point := range g {
+			result[i] = snapPoint(point)
+		}
+		return result
+	case orb.LineString:
+		if line := snapLine(g); line != nil {
+			return line
+		}
+	case orb.MultiLineString:
+		result := make(orb.MultiLineString, 0, len(g))
+		for _, line := range g {
+			if snapped := snapLine(line); snapped != nil {
+				result = append(result, snapped)
+			}
+		}
+		return collapseMultiLineString(result)
+	case orb.Ring:
+		return snapGeometry(orb.Polygon{g})
+	case orb.Polygon:
+		if polygon := snapPolygon(g); polygon != nil {
+			return polygon
+		}
+	case orb.MultiPolygon:
+		result := make(orb.MultiPolygon, 0, len(g))
+		for _, polygon := range g {
+			if snapped := snapPolygon(polygon); snapped != nil {
+				result = append(result, snapped)
+			}
+		}
+		return collapseMultiPolygon(result)
+	case orb.Bound:
+		return snapGeometry(g.ToPolygon())
+	}
+	return nil
+}
+
+// snapPoint rounds a point to integer tile coordinates
+func snapPoint(point orb.Point) orb.Point {
+	return orb.Point{math.Round(point[0]), math.Round(point[1])}
+}
+
+// snapPoints rounds points and drops consecutive duplicates
+func snapPoints(points []orb.Point) []orb.Point {
+	result := make([]orb.Point, 0, len(points))
+	for _, point := range points {
+		snapped := snapPoint(point)
+		if len(result) > 0 && result[len(result)-1] == snapped {
+			continue
+		}
+		result = append(result, snapped)
+	}
+	return result
+}
+
+// snapLine snaps a line string, returning nil when it collapses to a single point
+func snapLine(line orb.LineString) orb.LineString {
+	result := orb.LineString(snapPoints(line))
+	if len(result) < 2 {
+		return nil
+	}
+	return result
+}
+
+// snapPolygon snaps and orients a polygon, dropping collapsed holes. Returns nil when
+// the exterior ring collapses.
+func snapPolygon(polygon orb.Polygon) orb.Polygon {
+	result := make(orb.Polygon, 0, len(polygon))
+	for i, ring := range polygon {
+		snapped := orb.Ring(snapPoints(ring))
+		if len(snapped) > 0 && snapped[0] != snapped[len(snapped)-1] {
+			snapped = append(snapped, snapped[0])
+		}
+		if len(snapped) < 4 || planar.Area(snapped) == 0 {
+			if i == 0 {
+				return nil
+			}
+			continue
+		}
+
+		want := orb.CCW
+		if i > 0 {
+			want = orb.CW
+		}
+		if snapped.Orientation() != want {
+			snapped.Reverse()
+		}
+		result = append(result, snapped)
+	}
+	return result
+}
+
+// validateEncodeTile checks that z/x/y addresses an existing tile
+func validateEncodeTile(z, x, y int) error {
+	if z < 0 || z > MaxEncodeZoom {
+		return fmt.Errorf("invalid zoom level %d, must be within 0-%d", z, MaxEncodeZoom)
+	}
+	n := 1 << uint(z)
+	if x < 0 || x >= n || y < 0 || y >= n {
+		return fmt.Errorf("invalid tile %d/%d/%d, x and y must be within 0-%d", z, x, y, n-1)
+	}
+	return nil
+}
+
+// ValidateEncoderOptions validates the encoder options
+func ValidateEncoderOptions(options *EncoderOptions) error {
+	if options.CoordinateSystem != CoordSystemWebMercator && options.CoordinateSystem != CoordSystemWGS84 {
+		return fmt.Errorf("invalid coordinate system: %s, must be '%s' or '%s'",
+			options.CoordinateSystem, CoordSystemWebMercator, CoordSystemWGS84)
+	}
+
+	if options.Extent == 0 {
+		return fmt.Errorf("extent must be positive")
+	}
+
+	if options.Buffer < 0 {
+		return fmt.Errorf("buffer must be non-negative")
+	}
+
+	if options.DefaultLayer == "" {
+		return fmt.Errorf("default layer name must not be empty")
+	}
+
+	return nil
+}
+
+// ReadFeatures reads GeoJSON features from a FeatureCollection, Feature or bare geometry
+// document, or from newline-delimited GeoJSON (including RFC 8142 text sequences)
+func ReadFeatures(r io.Reader) ([]*geojson.Feature, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GeoJSON: %w", err)
+	}
+
+	// A single document, possibly spread over many lines
+	trimmed := bytes.TrimSpace(data)
+	if json.Valid(trimmed) {
+		return parseGeoJSONDocument(trimmed)
+	}
+
+	var features []*geojson.Feature
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(bytes.TrimLeft(scanner.Bytes(), "\x1e"))
+		if len(text) == 0 {
+			continue
+		}
+
+		parsed, err := parseGeoJSONDocument(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		features = append(features, parsed...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read GeoJSON: %w", err)
+	}
+
+	return features, nil
+}
+
+// parseGeoJSONDocument parses a single GeoJSON object into features
+func parseGeoJSONDocument(data []byte) ([]*geojson.Feature, error) {
+	var header struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("invalid GeoJSON: %w", err)
+	}
+
+	switch header.Type {
+	case "FeatureCollection":
+		collection, err := geojson.UnmarshalFeatureCollection(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid feature collection: %w", err)
+		}
+		var exact struct {
+			Features []exactFeature `json:"features"`
+		}
+		if err := decodeExact(data, &exact); err != nil || len(exact.Features) != len(collection.Features) {
+			return nil, fmt.Errorf("invalid feature collection: cannot read feature IDs and properties")
+		}
+		for i, feature := range collection.Features {
+			exact.Features[i].apply(feature)
+		}
+		return collection.Features, nil
+	case "Feature":
+		feature, err := geojson.UnmarshalFeature(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid feature: %w", err)
+		}
+		var exact exactFeature
+		if err := decodeExact(data, &exact); err != nil {
+			return nil, fmt.Errorf("invalid feature: %w", err)
+		}
+		exact.apply(feature)
+		return []*geojson.Feature{feature}, nil
+	case "":
+		return nil, fmt.Errorf("invalid GeoJSON: missing type")
+	default:
+		geometry, err := geojson.UnmarshalGeometry(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid geometry: %w", err)
+		}
+		return []*geojson.Feature{geojson.NewFeature(geometry.Geometry())}, nil
+	}
+}
+
+// exactFeature holds the ID and properties of a feature with numbers kept as
+// json.Number, so integers beyond 2^53 are not rounded to the nearest double
+type exactFeature struct {
+	ID         interface{}            `json:"id"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// apply replaces a parsed feature's ID and properties with the exact ones
+func (f *exactFeature) apply(feature *geojson.Feature) {
+	feature.ID = f.ID
+	if f.Properties != nil {
+		feature.Properties = f.Properties
+	}
+}
+
+// decodeExact decodes JSON keeping numbers as json.Number
+func decodeExact(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
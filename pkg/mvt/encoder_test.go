@@ -0,0 +1,255 @@
+// pkg/mvt/encoder_test.go - Unit tests for GeoJSON to MVT encoding
+package mvt
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+func TestEncoderRoundTrip(t *testing.T) {
+	road := geojson.NewFeature(orb.LineString{{-8238310, 4969803}, {-8237310, 4970803}})
+	road.ID = 7.0
+	road.Properties = geojson.Properties{"_layer": "roads", "lanes": 2.0, "name": "Broadway", "note": nil}
+
+	park := geojson.NewFeature(orb.Polygon{{{-8239500, 4969000}, {-8239500, 4969500}, {-8239000, 4969500}, {-8239000, 4969000}, {-8239500, 4969000}}})
+	park.Properties = geojson.Properties{"_layer": "parks", "tags": map[string]interface{}{"leisure": "park"}}
+
+	data, err := NewEncoder().Encode([]*geojson.Feature{road, park}, 14, 4823, 6160)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tile, err := NewDecoder().Decode(data, 14, 4823, 6160)
+	if err != nil {
+		t.Fatalf("Failed to decode encoded tile: %v", err)
+	}
+	if names := tile.GetLayerNames(); len(names) != 2 {
+		t.Fatalf("Expected 2 layers, got %v", names)
+	}
+
+	roads := tile.Layers["roads"].Features
+	if len(roads) != 1 {
+		t.Fatalf("Expected 1 road, got %d", len(roads))
+	}
+	if roads[0].ID != uint64(7) || roads[0].Tags["lanes"] != int64(2) || roads[0].Tags["name"] != "Broadway" {
+		t.Errorf("Unexpected road attributes: id=%v tags=%v", roads[0].ID, roads[0].Tags)
+	}
+	if _, exists := roads[0].Tags["_layer"]; exists {
+		t.Error("Expected layer property to be consumed")
+	}
+	if _, exists := roads[0].Tags["note"]; exists {
+		t.Error("Expected null property to be dropped")
+	}
+
+	// One tile unit at zoom 14 is about 0.6m
+	start := roads[0].Geometry.(orb.LineString)[0]
+	if math.Abs(start[0]+8238310) > 1 || math.Abs(start[1]-4969803) > 1 {
+		t.Errorf("Expected start near the input point, got %v", start)
+	}
+
+	parks := tile.Layers["parks"].Features
+	if len(parks) != 1 || parks[0].Type != "Polygon" {
+		t.Fatalf("Expected 1 polygon park, got %v", parks)
+	}
+	if parks[0].Tags["tags"] != `{"leisure":"park"}` {
+		t.Errorf("Expected nested property as JSON text, got %v", parks[0].Tags["tags"])
+	}
+}
+
+func TestEncoderClipsWithBuffer(t *testing.T) {
+	encoder, err := NewEncoderWithOptions(&EncoderOptions{
+		Extent:           256,
+		Buffer:           8,
+		DefaultLayer:     "lines",
+		CoordinateSystem: CoordSystemWGS84,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	line := geojson.NewFeature(orb.LineString{{-170, 0.1}, {170, 0.1}})
+	data, err := encoder.Encode([]*geojson.Feature{line}, 1, 1, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tile, err := NewDecoderWithExtent(256).Decode(data, 1, 1, 0)
+	if err != nil {
+		t.Fatalf("Failed to decode encoded tile: %v", err)
+	}
+	features := tile.Layers["lines"].Features
+	if len(features) != 1 {
+		t.Fatalf("Expected 1 feature, got %d", len(features))
+	}
+
+	// The buffer reaches 8 units west of the tile; the line ends inside it at 170°
+	bound := features[0].Geometry.Bound()
+	unit := webMercatorMax / 256
+	if math.Abs(bound.Min[0]+8*unit) > 1e-6 || math.Abs(bound.Max[0]-webMercatorMax*170/180) > unit {
+		t.Errorf("Expected line clipped to the buffered tile, got %v", bound)
+	}
+}
+
+func TestEncodeRange(t *testing.T) {
+	point := geojson.NewFeature(orb.Point{10, 10})
+	encoder, _ := NewEncoderWithOptions(&EncoderOptions{
+		Extent:           DefaultEncodeExtent,
+		DefaultLayer:     DefaultEncodeLayer,
+		CoordinateSystem: CoordSystemWGS84,
+	})
+
+	var tiles []TileID
+	err := encoder.EncodeRange([]*geojson.Feature{point}, 0, 3, func(tile TileID, data []byte) error {
+		tiles = append(tiles, tile)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	want := []TileID{{0, 0, 0}, {1, 1, 0}, {2, 2, 1}, {3, 4, 3}}
+	if len(tiles) != len(want) {
+		t.Fatalf("Expected tiles %v, got %v", want, tiles)
+	}
+	for i := range want {
+		if tiles[i] != want[i] {
+			t.Errorf("Tile %d = %v, want %v", i, tiles[i], want[i])
+		}
+	}
+
+	if err := encoder.EncodeRange(nil, 3, 1, nil); err == nil {
+		t.Error("Expected error for inverted zoom range")
+	}
+}
+
+func TestSnapPolygon(t *testing.T) {
+	// Clockwise exterior in tile coordinates with a counter-clockwise hole and a collapsed hole
+	polygon := orb.Polygon{
+		{{0, 0}, {0, 10.2}, {10, 10}, {10, 0}, {0, 0}},
+		square(2, 2, 4, 4),
+		{{6, 6}, {6.2, 6.1}, {6.1, 6.2}, {6, 6}},
+	}
+
+	snapped := snapPolygon(polygon)
+	if len(snapped) != 2 {
+		t.Fatalf("Expected collapsed hole to be dropped, got %v", snapped)
+	}
+	if snapped[0].Orientation() != orb.CCW || snapped[1].Orientation() != orb.CW {
+		t.Errorf("Expected exterior with positive and hole with negative area, got %v", snapped)
+	}
+	if snapped[0][1] != (orb.Point{10, 0}) && snapped[0][1] != (orb.Point{0, 10}) {
+		t.Errorf("Expected integer coordinates, got %v", snapped[0])
+	}
+
+	if snapPolygon(orb.Polygon{{{0, 0}, {0.2, 0}, {0.2, 0.2}, {0, 0}}}) != nil {
+		t.Error("Expected collapsed polygon to be dropped")
+	}
+}
+
+func TestReadFeatures(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  int
+	}{
+		{"feature collection", `{"type":"FeatureCollection","features":[
+			{"type":"Feature","properties":{},"geometry":{"type":"Point","coordinates":[0,0]}},
+			{"type":"Feature","properties":{},"geometry":{"type":"Point","coordinates":[1,1]}}]}`, 2},
+		{"geometry", `{"type":"LineString","coordinates":[[0,0],[1,1]]}`, 1},
+		{"ndjson", "{\"type\":\"Feature\",\"properties\":{},\"geometry\":{\"type\":\"Point\",\"coordinates\":[0,0]}}\n\n" +
+			"{\"type\":\"Feature\",\"properties\":{},\"geometry\":{\"type\":\"Point\",\"coordinates\":[1,1]}}\n", 2},
+		{"text sequence", "\x1e{\"type\":\"Point\",\"coordinates\":[0,0]}\n\x1e{\"type\":\"Point\",\"coordinates\":[1,1]}\n", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			features, err := ReadFeatures(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(features) != tt.want {
+				t.Errorf("Expected %d features, got %d", tt.want, len(features))
+			}
+		})
+	}
+
+	if _, err := ReadFeatures(strings.NewReader("{\"type\":\"Point\"}\nnot json\n")); err == nil {
+		t.Error("Expected error for invalid line")
+	}
+}
+
+func TestEncoderLargeIntegers(t *testing.T) {
+	input := `{"type":"Feature","id":12345678901234567,"geometry":{"type":"Point","coordinates":[1,2]},"properties":{"big":12345678901234567,"max":18446744073709551615,"neg":-9007199254740993,"ratio":0.5}}`
+	features, err := ReadFeatures(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	data, err := NewEncoder().Encode(features, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	tile, err := NewDecoder().Decode(data, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("Failed to decode encoded tile: %v", err)
+	}
+
+	decoded := tile.Layers[DefaultEncodeLayer].Features
+	if len(decoded) != 1 {
+		t.Fatalf("Expected 1 feature, got %d", len(decoded))
+	}
+	if decoded[0].ID != uint64(12345678901234567) {
+		t.Errorf("Expected ID 12345678901234567, got %v", decoded[0].ID)
+	}
+	tags := decoded[0].Tags
+	if tags["big"] != int64(12345678901234567) || tags["max"] != uint64(math.MaxUint64) ||
+		tags["neg"] != int64(-9007199254740993) || tags["ratio"] != 0.5 {
+		t.Errorf("Unexpected properties: %v", tags)
+	}
+}
+
+func TestEncoderFeatureIDs(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{"none", nil, nil, false},
+		{"whole float", 7.0, uint64(7), false},
+		{"numeric string", "18446744073709551615", uint64(math.MaxUint64), false},
+		{"fractional", 1.5, nil, true},
+		{"negative", -1.0, nil, true},
+		{"text", "abc", nil, true},
+		{"beyond uint64", "18446744073709551616", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feature := geojson.NewFeature(orb.Point{1, 2})
+			feature.ID = tt.id
+			data, err := NewEncoder().Encode([]*geojson.Feature{feature}, 0, 0, 0)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for ID %v", tt.id)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			tile, err := NewDecoder().Decode(data, 0, 0, 0)
+			if err != nil {
+				t.Fatalf("Failed to decode encoded tile: %v", err)
+			}
+			if got := tile.Layers[DefaultEncodeLayer].Features[0].ID; got != tt.want {
+				t.Errorf("Expected ID %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
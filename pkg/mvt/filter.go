@@ -0,0 +1,449 @@
+// pkg/mvt/filter.go - Mapbox GL style feature filter expressions
+package mvt
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// FilterAllLayers is the filter key that applies to layers without their own filter
+const FilterAllLayers = "*"
+
+// Filter is a compiled Mapbox GL style filter expression. Both the expression syntax,
+// e.g. ["in", ["get", "class"], ["literal", ["motorway", "trunk"]]], and the legacy
+// filter syntax, e.g. ["in", "class", "motorway", "trunk"], are supported.
+type Filter struct {
+	eval filterFunc
+}
+
+// filterContext is the feature state a filter is evaluated against
+type filterContext struct {
+	properties   map[string]interface{}
+	id           interface{}
+	geometryType string
+	zoom         float64
+}
+
+// filterFunc evaluates a compiled (sub)expression
+type filterFunc func(ctx *filterContext) interface{}
+
+// ParseFilter compiles a filter expression given as JSON text
+func ParseFilter(text string) (*Filter, error) {
+	var expr interface{}
+	if err := json.Unmarshal([]byte(text), &expr); err != nil {
+		return nil, fmt.Errorf("invalid filter JSON: %w", err)
+	}
+	return CompileFilter(expr)
+}
+
+// CompileFilter compiles a decoded filter expression. Strings are parsed as JSON text,
+// so filters can come from either YAML lists or command line strings.
+func CompileFilter(expr interface{}) (*Filter, error) {
+	if text, ok := expr.(string); ok {
+		return ParseFilter(text)
+	}
+
+	eval, err := compileExpression(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &Filter{eval: eval}, nil
+}
+
+// Matches reports whether a decoded feature passes the filter at the given zoom
+func (f *Filter) Matches(feature *DecodedFeature, zoom int) bool {
+	ctx := &filterContext{
+		properties:   feature.Tags,
+		id:           feature.ID,
+		geometryType: baseGeometryType(feature.Type),
+		zoom:         float64(zoom),
+	}
+	result, _ := f.eval(ctx).(bool)
+	return result
+}
+
+// compileFilters compiles the per-layer filters of the conversion options
+func compileFilters(filters map[string]interface{}) (map[string]*Filter, error) {
+	compiled := make(map[string]*Filter, len(filters))
+	for layer, expr := range filters {
+		filter, err := CompileFilter(expr)
+		if err != nil {
+			return nil, fmt.Errorf("filter for layer %s: %w", layer, err)
+		}
+		compiled[layer] = filter
+	}
+	return compiled, nil
+}
+
+// compileExpression compiles an expression or literal value
+func compileExpression(expr interface{}) (filterFunc, error) {
+	args, ok := expr.([]interface{})
+	if !ok {
+		value := normalizeLiteral(expr)
+		return func(*filterContext) interface{} { return value }, nil
+	}
+
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty expression")
+	}
+
+	op, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("expression operator must be a string, got %v; wrap array values in [\"literal\", ...]", args[0])
+	}
+	args = args[1:]
+
+	switch op {
+	case "literal":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("literal expects 1 argument, got %d", len(args))
+		}
+		value := normalizeLiteral(args[0])
+		return func(*filterContext) interface{} { return value }, nil
+
+	case "get":
+		key, err := stringArgument(op, args)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx *filterContext) interface{} { return ctx.properties[key] }, nil
+
+	case "has", "!has":
+		key, err := stringArgument(op, args)
+		if err != nil {
+			return nil, err
+		}
+		negate := op == "!has"
+		return func(ctx *filterContext) interface{} {
+			_, exists := lookupKey(ctx, key)
+			return exists != negate
+		}, nil
+
+	case "zoom":
+		return func(ctx *filterContext) interface{} { return ctx.zoom }, nil
+
+	case "geometry-type":
+		return func(ctx *filterContext) interface{} { return ctx.geometryType }, nil
+
+	case "id":
+		return func(ctx *filterContext) interface{} { return normalizeLiteral(ctx.id) }, nil
+
+	case "!":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("! expects 1 argument, got %d", len(args))
+		}
+		operand, err := compileExpression(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx *filterContext) interface{} { return operand(ctx) != true }, nil
+
+	case "all", "any", "none":
+		return compileCombinator(op, args)
+
+	case "==", "!=", "<", "<=", ">", ">=":
+		return compileComparison(op, args)
+
+	case "in", "!in":
+		return compileIn(op, args)
+
+	case "match":
+		return compileMatch(args)
+
+	default:
+		return nil, fmt.Errorf("unsupported filter operator: %s", op)
+	}
+}
+
+// compileCombinator compiles all/any/none over boolean subexpressions
+func compileCombinator(op string, args []interface{}) (filterFunc, error) {
+	operands := make([]filterFunc, len(args))
+	for i, arg := range args {
+		operand, err := compileExpression(arg)
+		if err != nil {
+			return nil, err
+		}
+		operands[i] = operand
+	}
+
+	return func(ctx *filterContext) interface{} {
+		for _, operand := range operands {
+			matched := operand(ctx) == true
+			switch {
+			case op == "all" && !matched:
+				return false
+			case op == "any" && matched:
+				return true
+			case op == "none" && matched:
+				return false
+			}
+		}
+		return op != "any"
+	}, nil
+}
+
+// compileComparison compiles a comparison in either legacy ["==", key, value]
+// or expression ["==", expr, expr] form. A string compared with an expression, as in
+// ["==", "park", ["get", "class"]], is a literal rather than a legacy key.
+func compileComparison(op string, args []interface{}) (filterFunc, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("%s expects 2 arguments, got %d", op, len(args))
+	}
+
+	var left, right filterFunc
+	_, rightIsExpression := args[1].([]interface{})
+	if key, ok := args[0].(string); ok && !rightIsExpression {
+		left = legacyKey(key)
+		value := normalizeLiteral(args[1])
+		right = func(*filterContext) interface{} { return value }
+	} else {
+		var err error
+		if left, err = compileExpression(args[0]); err != nil {
+			return nil, err
+		}
+		if right, err = compileExpression(args[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(ctx *filterContext) interface{} {
+		a, b := left(ctx), right(ctx)
+		switch op {
+		case "==":
+			return valuesEqual(a, b)
+		case "!=":
+			return !valuesEqual(a, b)
+		}
+
+		cmp, ok := compareValues(a, b)
+		if !ok {
+			return false
+		}
+		switch op {
+		case "<":
+			return cmp < 0
+		case "<=":
+			return cmp <= 0
+		case ">":
+			return cmp > 0
+		default:
+			return cmp >= 0
+		}
+	}, nil
+}
+
+// compileIn compiles membership tests. The expression form ["in", needle, haystack]
+// tests array membership or substring containment; the legacy form
+// ["in", key, v1, v2, ...] tests whether a property equals any of the values.
+func compileIn(op string, args []interface{}) (filterFunc, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("%s expects at least 1 argument", op)
+	}
+
+	_, haystackIsExpression := args[len(args)-1].([]interface{})
+	if op == "in" && len(args) == 2 && haystackIsExpression {
+		needle, err := compileExpression(args[0])
+		if err != nil {
+			return nil, err
+		}
+		haystack, err := compileExpression(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx *filterContext) interface{} {
+			return containsValue(haystack(ctx), needle(ctx))
+		}, nil
+	}
+
+	key, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("%s expects a property key as its first argument", op)
+	}
+	left := legacyKey(key)
+	values := make([]interface{}, len(args)-1)
+	for i, value := range args[1:] {
+		values[i] = normalizeLiteral(value)
+	}
+	negate := op == "!in"
+
+	return func(ctx *filterContext) interface{} {
+		return containsValue(values, left(ctx)) != negate
+	}, nil
+}
+
+// compileMatch compiles ["match", input, labels, output, ..., fallback]
+func compileMatch(args []interface{}) (filterFunc, error) {
+	if len(args) < 4 || len(args)%2 != 0 {
+		return nil, fmt.Errorf("match expects an input, label/output pairs and a fallback")
+	}
+
+	input, err := compileExpression(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	type matchCase struct {
+		labels []interface{}
+		output filterFunc
+	}
+	cases := make([]matchCase, 0, (len(args)-2)/2)
+	for i := 1; i < len(args)-1; i += 2 {
+		labels, ok := args[i].([]interface{})
+		if !ok {
+			labels = []interface{}{args[i]}
+		}
+		for j := range labels {
+			labels[j] = normalizeLiteral(labels[j])
+		}
+		output, err := compileExpression(args[i+1])
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, matchCase{labels: labels, output: output})
+	}
+
+	fallback, err := compileExpression(args[len(args)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx *filterContext) interface{} {
+		value := input(ctx)
+		for _, c := range cases {
+			if containsValue(c.labels, value) {
+				return c.output(ctx)
+			}
+		}
+		return fallback(ctx)
+	}, nil
+}
+
+// legacyKey resolves a legacy filter key, including the special $type and $id keys
+func legacyKey(key string) filterFunc {
+	return func(ctx *filterContext) interface{} {
+		value, _ := lookupKey(ctx, key)
+		return value
+	}
+}
+
+// lookupKey returns a property value or the value of a special legacy key
+func lookupKey(ctx *filterContext, key string) (interface{}, bool) {
+	switch key {
+	case "$type":
+		return ctx.geometryType, true
+	case "$id":
+		return normalizeLiteral(ctx.id), ctx.id != nil
+	}
+	value, exists := ctx.properties[key]
+	return normalizeLiteral(value), exists
+}
+
+// stringArgument returns the single string argument of an operator
+func stringArgument(op string, args []interface{}) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("%s expects 1 argument, got %d", op, len(args))
+	}
+	key, ok := args[0].(string)
+	if !ok {
+		return "", fmt.Errorf("%s expects a string argument, got %v", op, args[0])
+	}
+	return key, nil
+}
+
+// normalizeLiteral converts numeric values of any Go type to float64
+func normalizeLiteral(value interface{}) interface{} {
+	if number, ok := toNumber(value); ok {
+		return number
+	}
+	if values, ok := value.([]interface{}); ok {
+		normalized := make([]interface{}, len(values))
+		for i, v := range values {
+			normalized[i] = normalizeLiteral(v)
+		}
+		return normalized
+	}
+	return value
+}
+
+// toNumber converts a numeric value to float64
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// valuesEqual compares two values, treating all numeric types alike
+func valuesEqual(a, b interface{}) bool {
+	a, b = normalizeLiteral(a), normalizeLiteral(b)
+	if _, ok := a.([]interface{}); ok {
+		return false
+	}
+	if _, ok := b.([]interface{}); ok {
+		return false
+	}
+	return a == b
+}
+
+// compareValues orders two numbers or two strings. It reports false for other types.
+func compareValues(a, b interface{}) (int, bool) {
+	if x, ok := toNumber(a); ok {
+		y, ok := toNumber(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	}
+
+	x, ok := a.(string)
+	if !ok {
+		return 0, false
+	}
+	y, ok := b.(string)
+	if !ok {
+		return 0, false
+	}
+	return strings.Compare(x, y), true
+}
+
+// containsValue reports whether haystack, an array or string, contains needle
+func containsValue(haystack, needle interface{}) bool {
+	switch h := haystack.(type) {
+	case []interface{}:
+		for _, value := range h {
+			if valuesEqual(value, needle) {
+				return true
+			}
+		}
+	case string:
+		if s, ok := needle.(string); ok {
+			return strings.Contains(h, s)
+		}
+	}
+	return false
+}
+
+// baseGeometryType maps multi-geometry types to their single forms, as Mapbox GL does
+func baseGeometryType(geometryType string) string {
+	return strings.TrimPrefix(geometryType, "Multi")
+}
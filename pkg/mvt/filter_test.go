@@ -0,0 +1,139 @@
+// pkg/mvt/filter_test.go - Unit tests for filter expressions
+package mvt
+
+import (
+	"testing"
+
+	"github.com/paulmach/orb"
+)
+
+func TestFilterMatches(t *testing.T) {
+	feature := &DecodedFeature{
+		ID:       uint64(42),
+		Tags:     map[string]interface{}{"class": "motorway", "lanes": int64(4), "oneway": true, "ref": "A1"},
+		Type:     "MultiLineString",
+		Geometry: orb.MultiLineString{{{0, 0}, {1, 1}}},
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		zoom int
+		want bool
+	}{
+		{"expression equality", `["==", ["get", "class"], "motorway"]`, 14, true},
+		{"legacy equality", `["==", "class", "trunk"]`, 14, false},
+		{"legacy not equal", `["!=", "class", "trunk"]`, 14, true},
+		{"numeric comparison across types", `[">=", ["get", "lanes"], 4]`, 14, true},
+		{"legacy less than", `["<", "lanes", 3]`, 14, false},
+		{"expression in literal", `["in", ["get", "class"], ["literal", ["motorway", "trunk"]]]`, 14, true},
+		{"legacy in", `["in", "class", "primary", "secondary"]`, 14, false},
+		{"legacy not in", `["!in", "class", "primary", "secondary"]`, 14, true},
+		{"substring in", `["in", "A", ["get", "ref"]]`, 14, true},
+		{"literal compared with expression", `["==", "motorway", ["get", "class"]]`, 14, true},
+		{"literal not equal to expression", `["!=", "trunk", ["get", "class"]]`, 14, true},
+		{"literal less than expression", `["<", "A0", ["get", "ref"]]`, 14, true},
+		{"has", `["has", "oneway"]`, 14, true},
+		{"not has", `["!has", "name"]`, 14, true},
+		{"all", `["all", ["has", "ref"], ["==", ["get", "oneway"], true]]`, 14, true},
+		{"any", `["any", ["has", "name"], ["==", "class", "trunk"]]`, 14, false},
+		{"none", `["none", ["has", "name"], ["==", "class", "trunk"]]`, 14, true},
+		{"not", `["!", ["has", "name"]]`, 14, true},
+		{"geometry type", `["==", ["geometry-type"], "LineString"]`, 14, true},
+		{"legacy type", `["==", "$type", "Polygon"]`, 14, false},
+		{"id", `["==", ["id"], 42]`, 14, true},
+		{"zoom below", `[">=", ["zoom"], 12]`, 10, false},
+		{"zoom above", `[">=", ["zoom"], 12]`, 12, true},
+		{"match", `["match", ["get", "class"], ["motorway", "trunk"], true, false]`, 14, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := ParseFilter(tt.expr)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got := filter.Matches(feature, tt.zoom); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{"invalid json", `["==", "class"`},
+		{"unknown operator", `["near", "class", 1]`},
+		{"wrong arity", `["==", "class"]`},
+		{"unwrapped array", `["in", ["get", "class"], [["motorway"]]]`},
+		{"empty", `[]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseFilter(tt.expr); err == nil {
+				t.Errorf("Expected error for %s", tt.expr)
+			}
+		})
+	}
+}
+
+func TestLayerFilterFallback(t *testing.T) {
+	converter, err := NewConverterWithOptions(&ConversionOptions{
+		CoordinateSystem: CoordSystemWebMercator,
+		Filters: map[string]interface{}{
+			"roads":         `["==", "class", "motorway"]`,
+			FilterAllLayers: []interface{}{"has", "name"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	named := &DecodedFeature{Tags: map[string]interface{}{"name": "x"}, Type: "Point"}
+	if !converter.layerFilter("pois").Matches(named, 14) {
+		t.Error("Expected all-layers filter to apply to pois")
+	}
+	if converter.layerFilter("roads").Matches(named, 14) {
+		t.Error("Expected roads filter to override the all-layers filter")
+	}
+}
+
+func TestLayerFilterCase(t *testing.T) {
+	// Configuration files are read with lowercased map keys
+	converter, err := NewConverterWithOptions(&ConversionOptions{
+		CoordinateSystem: CoordSystemWebMercator,
+		Filters: map[string]interface{}{
+			"buildings":     []interface{}{"has", "height"},
+			"Roads":         []interface{}{"has", "ref"},
+			"roads":         []interface{}{"has", "name"},
+			FilterAllLayers: []interface{}{"has", "class"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		layer string
+		tags  map[string]interface{}
+		want  bool
+	}{
+		{"Buildings", map[string]interface{}{"height": 10.0}, true},
+		{"Buildings", map[string]interface{}{"class": "house"}, false},
+		{"roads", map[string]interface{}{"name": "x"}, true},
+		{"Roads", map[string]interface{}{"name": "x"}, false},
+		{"ROADS", map[string]interface{}{"ref": "A1"}, true},
+		{"Water", map[string]interface{}{"class": "lake"}, true},
+	}
+
+	for _, tt := range tests {
+		feature := &DecodedFeature{Tags: tt.tags, Type: "Point"}
+		if got := converter.layerFilter(tt.layer).Matches(feature, 14); got != tt.want {
+			t.Errorf("Layer %s with %v: expected %v, got %v", tt.layer, tt.tags, tt.want, got)
+		}
+	}
+}
@@ -1,7 +1,14 @@
 // pkg/mvt/geometry.go - Shared geometry transformation utilities
 package mvt
 
-import "github.com/paulmach/orb"
+import (
+	"math"
+
+	"github.com/paulmach/orb"
+)
+
+// webMercatorMax is the half-width of the Web Mercator world in meters
+const webMercatorMax = 20037508.342789244
 
 // applyGeometryTransform applies a transformation function to all coordinates in a geometry
 func applyGeometryTransform(geom orb.Geometry, transform func(orb.Point) orb.Point) orb.Geometry {
@@ -48,3 +55,11 @@ func applyGeometryTransform(geom orb.Geometry, transform func(orb.Point) orb.Poi
 		return geom
 	}
 }
+
+// wgs84ToWebMercator projects a longitude/latitude point to Web Mercator meters
+func wgs84ToWebMercator(point orb.Point) orb.Point {
+	lat := math.Max(math.Min(point[1], 85.0511287798066), -85.0511287798066)
+	x := point[0] / 180.0 * webMercatorMax
+	y := math.Log(math.Tan(math.Pi/4+lat*math.Pi/360.0)) / math.Pi * webMercatorMax
+	return orb.Point{x, y}
+}
@@ -0,0 +1,111 @@
+// pkg/mvt/ids.go - Feature ID promotion and synthetic IDs
+package mvt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	"github.com/paulmach/orb/encoding/wkb"
+	"github.com/paulmach/orb/geojson"
+)
+
+// PromoteAllLayers is the promote ID key that applies to layers without their own entry
+const PromoteAllLayers = "*"
+
+// ParsePromoteIDSpec splits a "layer=property" promote ID flag. A property without a
+// layer prefix applies to all layers.
+func ParsePromoteIDSpec(spec string) (string, string) {
+	spec = strings.TrimSpace(spec)
+	if layer, property, found := strings.Cut(spec, "="); found {
+		return strings.TrimSpace(layer), strings.TrimSpace(property)
+	}
+	return PromoteAllLayers, spec
+}
+
+// promoteProperty returns the property promoted to the feature ID for a layer, matched
+// exactly or ignoring case, falling back to the all-layers property
+func (c *Converter) promoteProperty(layerName string) string {
+	if property, ok := c.options.PromoteID[layerName]; ok {
+		return property
+	}
+
+	if key, ok := c.promoteKeys[strings.ToLower(layerName)]; ok {
+		return c.options.PromoteID[key]
+	}
+	return c.options.PromoteID[PromoteAllLayers]
+}
+
+// promotedID returns the value of the layer's promoted property as a GeoJSON ID, or
+// nil when the feature does not have it. IDs must be strings or numbers, so other
+// values are formatted as strings.
+func (c *Converter) promotedID(feature *DecodedFeature, layerName string) interface{} {
+	property := c.promoteProperty(layerName)
+	if property == "" {
+		return nil
+	}
+
+	value, ok := feature.Tags[property]
+	if !ok || value == nil {
+		return nil
+	}
+	switch v := value.(type) {
+	case string, float32, float64, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// syntheticIDs assigns stable IDs to features of one tile that have none
+type syntheticIDs struct {
+	tile string
+	seen map[string]int
+}
+
+// newSyntheticIDs creates an ID generator for tile z/x/y
+func newSyntheticIDs(z, x, y int) *syntheticIDs {
+	return &syntheticIDs{
+		tile: fmt.Sprintf("%d/%d/%d", z, x, y),
+		seen: make(map[string]int),
+	}
+}
+
+// id returns the synthetic ID of a feature: a hex FNV-64a hash of its layer, tile and
+// decoded geometry. Features identical in all three get "-1", "-2"... suffixes in
+// encoded order, so IDs stay unique within the tile and stable across runs.
+func (s *syntheticIDs) id(feature *DecodedFeature, layerName string) string {
+	h := fnv.New64a()
+	h.Write([]byte(layerName))
+	h.Write([]byte{0})
+	h.Write([]byte(s.tile))
+	h.Write([]byte{0})
+	if geometry, err := wkb.Marshal(feature.Geometry, binary.LittleEndian); err == nil {
+		h.Write(geometry)
+	}
+
+	id := strconv.FormatUint(h.Sum64(), 16)
+	count := s.seen[id]
+	s.seen[id] = count + 1
+	if count > 0 {
+		id = fmt.Sprintf("%s-%d", id, count)
+	}
+	return id
+}
+
+// assignID sets the promoted or synthetic ID of a converted feature. A promoted
+// property takes precedence over the tile's feature ID, as with Mapbox promoteId.
+func (c *Converter) assignID(geoJSONFeature *geojson.Feature, feature *DecodedFeature, layerName string, ids *syntheticIDs) {
+	if id := c.promotedID(feature, layerName); id != nil {
+		geoJSONFeature.ID = id
+		if c.options.LargeIntAsString {
+			geoJSONFeature.ID = largeIntegerAsString(id)
+		}
+	}
+
+	if geoJSONFeature.ID == nil && ids != nil {
+		geoJSONFeature.ID = ids.id(feature, layerName)
+	}
+}
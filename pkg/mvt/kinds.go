@@ -0,0 +1,131 @@
+// pkg/mvt/kinds.go - Property value kinds for typed output columns
+package mvt
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Property value kinds, from which output formats infer typed columns. Kinds are bit
+// flags, so the kinds found in a column combine with |. Single and double precision
+// floats stay distinct, as do signed integers, unsigned integers within the int64
+// range and unsigned integers beyond it.
+const (
+	KindBool uint8 = 1 << iota
+	KindInt
+	KindUint
+	KindLargeUint // Unsigned beyond the int64 range
+	KindFloat
+	KindDouble
+	KindString
+	KindJSON // Arrays, objects and other values, encoded as JSON
+)
+
+// ValueKind classifies a property value, returning false for nil values
+func ValueKind(value interface{}) (uint8, bool) {
+	switch v := value.(type) {
+	case nil:
+		return 0, false
+	case bool:
+		return KindBool, true
+	case int, int8, int16, int32, int64:
+		return KindInt, true
+	case uint8, uint16, uint32:
+		return KindUint, true
+	case uint:
+		if uint64(v) > math.MaxInt64 {
+			return KindLargeUint, true
+		}
+		return KindUint, true
+	case uint64:
+		if v > math.MaxInt64 {
+			return KindLargeUint, true
+		}
+		return KindUint, true
+	case float32:
+		return KindFloat, true
+	case float64:
+		return KindDouble, true
+	case string:
+		return KindString, true
+	default:
+		return KindJSON, true
+	}
+}
+
+// ToInt64 converts an integer property value. Unsigned values beyond the int64 range
+// wrap, so callers check for KindLargeUint first.
+func ToInt64(value interface{}) int64 {
+	switch v := value.(type) {
+	case int:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	case uint:
+		return int64(v)
+	case uint8:
+		return int64(v)
+	case uint16:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case uint64:
+		return int64(v)
+	}
+	return 0
+}
+
+// ToUint64 converts an unsigned integer property value
+func ToUint64(value interface{}) uint64 {
+	switch v := value.(type) {
+	case uint:
+		return uint64(v)
+	case uint64:
+		return v
+	}
+	return uint64(ToInt64(value))
+}
+
+// ToFloat64 converts a numeric property value
+func ToFloat64(value interface{}) float64 {
+	switch v := value.(type) {
+	case float32:
+		return float64(v)
+	case float64:
+		return v
+	case uint, uint64:
+		return float64(ToUint64(v))
+	}
+	return float64(ToInt64(value))
+}
+
+// ToString formats a property value for a string column. Numbers are written in full
+// without exponents, and arrays and objects as JSON.
+func ToString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v), nil
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode property: %w", err)
+		}
+		return string(data), nil
+	}
+}
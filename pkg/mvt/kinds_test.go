@@ -0,0 +1,75 @@
+// pkg/mvt/kinds_test.go - Unit tests for property value kinds
+package mvt
+
+import (
+	"math"
+	"testing"
+)
+
+func TestValueKind(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  uint8
+	}{
+		{"bool", true, KindBool},
+		{"int", 1, KindInt},
+		{"negative int64", int64(-1), KindInt},
+		{"uint32", uint32(1), KindUint},
+		{"uint64 in int64 range", uint64(math.MaxInt64), KindUint},
+		{"uint64 beyond int64 range", uint64(math.MaxInt64 + 1), KindLargeUint},
+		{"uint beyond int64 range", uint(math.MaxUint64), KindLargeUint},
+		{"float32", float32(1.5), KindFloat},
+		{"float64", 1.5, KindDouble},
+		{"string", "a", KindString},
+		{"array", []interface{}{1}, KindJSON},
+		{"object", map[string]interface{}{"a": 1}, KindJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ValueKind(tt.value)
+			if !ok || got != tt.want {
+				t.Errorf("ValueKind(%v) = %d, %v, want %d", tt.value, got, ok, tt.want)
+			}
+		})
+	}
+
+	if _, ok := ValueKind(nil); ok {
+		t.Error("Expected nil to have no kind")
+	}
+}
+
+func TestValueConversions(t *testing.T) {
+	if got := ToInt64(uint32(7)); got != 7 {
+		t.Errorf("ToInt64(uint32(7)) = %d, want 7", got)
+	}
+	if got := ToUint64(uint64(math.MaxUint64)); got != math.MaxUint64 {
+		t.Errorf("ToUint64(MaxUint64) = %d, want %d", got, uint64(math.MaxUint64))
+	}
+	if got := ToFloat64(uint64(math.MaxUint64)); got != float64(math.MaxUint64) {
+		t.Errorf("ToFloat64(MaxUint64) = %v, want %v", got, float64(math.MaxUint64))
+	}
+	if got := ToFloat64(int8(-3)); got != -3 {
+		t.Errorf("ToFloat64(int8(-3)) = %v, want -3", got)
+	}
+
+	texts := []struct {
+		value interface{}
+		want  string
+	}{
+		{"a", "a"},
+		{false, "false"},
+		{float32(0.1), "0.1"},
+		{1e21, "1000000000000000000000"},
+		{int64(-5), "-5"},
+		{uint64(math.MaxUint64), "18446744073709551615"},
+		{[]interface{}{1, "a"}, `[1,"a"]`},
+	}
+	for _, tt := range texts {
+		got, err := ToString(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("ToString(%v) = %q, %v, want %q", tt.value, got, err, tt.want)
+		}
+	}
+}
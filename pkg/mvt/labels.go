@@ -0,0 +1,310 @@
+// pkg/mvt/labels.go - Label points for polygon features
+package mvt
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/orb/planar"
+)
+
+// Label point output modes
+const (
+	LabelModeNone     = ""         // No label points
+	LabelModeProperty = "property" // Add the label point to each polygon as an [x, y] property
+	LabelModeLayer    = "layer"    // Add a Point feature per polygon to a separate label layer
+)
+
+// Label point algorithms
+const (
+	LabelPolylabel      = "polylabel"      // Pole of inaccessibility: the interior point farthest from the outline
+	LabelCentroid       = "centroid"       // Area centroid, which can fall outside concave polygons
+	LabelRepresentative = "representative" // Midpoint of the widest interior span on a central scanline
+)
+
+// Label point defaults
+const (
+	DefaultLabelProperty    = "_label"
+	DefaultLabelLayerSuffix = "_label"
+	DefaultLabelPrecision   = 1.0
+)
+
+// addLabelPoints computes a label point for each polygon feature, either storing it
+// as a property or appending it as a Point feature of the source layer's label layer.
+// Label features inherit the polygon's ID and properties and are recorded in layers.
+func (c *Converter) addLabelPoints(features []*geojson.Feature, layers map[*geojson.Feature]string, z, extent int) []*geojson.Feature {
+	precision := c.options.LabelPrecision
+	if precision <= 0 {
+		precision = DefaultLabelPrecision
+	}
+	precision *= c.pixelSize(z)
+
+	factor := 0
+	if c.options.PrecisionMode != PrecisionModeNone {
+		factor = int(math.Pow10(c.coordinatePrecision(z, extent)))
+	}
+
+	var labels []*geojson.Feature
+	for _, feature := range features {
+		if feature.Geometry == nil || !isPolygonal(feature.Geometry) {
+			continue
+		}
+
+		point, ok := labelPoint(feature.Geometry, c.options.LabelAlgorithm, precision)
+		if !ok {
+			continue
+		}
+		if factor > 0 {
+			point = orb.Round(point, factor).(orb.Point)
+		}
+
+		if c.options.LabelPoints == LabelModeProperty {
+			name := c.options.LabelProperty
+			if name == "" {
+				name = DefaultLabelProperty
+			}
+			if feature.Properties == nil {
+				feature.Properties = make(geojson.Properties)
+			}
+			feature.Properties[name] = []float64{point[0], point[1]}
+			continue
+		}
+
+		label := geojson.NewFeature(point)
+		label.ID = feature.ID
+		label.Properties = feature.Properties.Clone()
+		layer := layers[feature] + c.labelLayerSuffix()
+		if !c.options.OmitLayerProperty {
+			label.Properties[c.layerProperty()] = layer
+		}
+		layers[label] = layer
+		labels = append(labels, label)
+	}
+
+	return append(features, labels...)
+}
+
+// appendLabelLayers adds the label layers of the label features to the layer names,
+// each after its source layer
+func (c *Converter) appendLabelLayers(layerNames []string, features []*geojson.Feature, layers map[*geojson.Feature]string) []string {
+	present := make(map[string]bool)
+	for _, feature := range features {
+		if _, ok := feature.Geometry.(orb.Point); ok {
+			present[layers[feature]] = true
+		}
+	}
+
+	var result []string
+	for _, name := range layerNames {
+		result = append(result, name)
+		if label := name + c.labelLayerSuffix(); present[label] {
+			result = append(result, label)
+		}
+	}
+	return result
+}
+
+// labelLayerSuffix returns the suffix naming a source layer's label layer
+func (c *Converter) labelLayerSuffix() string {
+	if c.options.LabelLayerSuffix == "" {
+		return DefaultLabelLayerSuffix
+	}
+	return c.options.LabelLayerSuffix
+}
+
+// layerProperty returns the name of the layer name property
+func (c *Converter) layerProperty() string {
+	if c.options.LayerProperty == "" {
+		return DefaultLayerProperty
+	}
+	return c.options.LayerProperty
+}
+
+// labelPoint computes the label point of a polygon or multipolygon. Polylabel and the
+// representative point use the largest polygon of a multipolygon.
+func labelPoint(geometry orb.Geometry, algorithm string, precision float64) (orb.Point, bool) {
+	if algorithm == LabelCentroid {
+		centroid, area := planar.CentroidArea(geometry)
+		return centroid, area > 0
+	}
+
+	polygon := largestPolygon(geometry)
+	if len(polygon) == 0 || len(polygon[0]) < 4 {
+		return orb.Point{}, false
+	}
+
+	if algorithm == LabelRepresentative {
+		return representativePoint(polygon)
+	}
+	return polylabel(polygon, precision), true
+}
+
+// largestPolygon returns a polygon, or the part of a multipolygon with the largest area
+func largestPolygon(geometry orb.Geometry) orb.Polygon {
+	switch g := geometry.(type) {
+	case orb.Polygon:
+		return g
+	case orb.MultiPolygon:
+		var largest orb.Polygon
+		largestArea := -1.0
+		for _, polygon := range g {
+			if area := planar.Area(polygon); area > largestArea {
+				largest, largestArea = polygon, area
+			}
+		}
+		return largest
+	default:
+		return nil
+	}
+}
+
+// representativePoint returns the midpoint of the widest interior span of a
+// horizontal scanline through the middle of the polygon. The scanline is placed
+// between vertices, so it never passes through one and the point is always inside.
+func representativePoint(polygon orb.Polygon) (orb.Point, bool) {
+	bound := polygon.Bound()
+	centre := (bound.Min[1] + bound.Max[1]) / 2
+
+	low, high := bound.Min[1], bound.Max[1]
+	for _, ring := range polygon {
+		for _, p := range ring {
+			if p[1] <= centre && p[1] > low {
+				low = p[1]
+			}
+			if p[1] > centre && p[1] < high {
+				high = p[1]
+			}
+		}
+	}
+	scanY := (low + high) / 2
+
+	var crossings []float64
+	for _, ring := range polygon {
+		for i := 0; i < len(ring)-1; i++ {
+			a, b := ring[i], ring[i+1]
+			if (a[1] > scanY) != (b[1] > scanY) {
+				crossings = append(crossings, a[0]+(scanY-a[1])*(b[0]-a[0])/(b[1]-a[1]))
+			}
+		}
+	}
+	sort.Float64s(crossings)
+
+	// Crossings pair up into interior spans
+	best, width := 0.0, -1.0
+	for i := 0; i+1 < len(crossings); i += 2 {
+		if span := crossings[i+1] - crossings[i]; span > width {
+			best, width = (crossings[i]+crossings[i+1])/2, span
+		}
+	}
+	if width < 0 {
+		return orb.Point{}, false
+	}
+	return orb.Point{best, scanY}, true
+}
+
+// polylabel finds the pole of inaccessibility of a polygon to within precision, by
+// subdividing cells in order of the best distance they could contain (Mapbox's
+// polylabel algorithm)
+func polylabel(polygon orb.Polygon, precision float64) orb.Point {
+	bound := polygon.Bound()
+	width := bound.Max[0] - bound.Min[0]
+	height := bound.Max[1] - bound.Min[1]
+	// Cells are no smaller than the precision, so slivers do not need a vast number of
+	// initial cells
+	cellSize := math.Max(math.Min(width, height), precision)
+	if cellSize == 0 {
+		return bound.Min
+	}
+
+	queue := &cellQueue{}
+	half := cellSize / 2
+	for x := bound.Min[0]; x < bound.Max[0]; x += cellSize {
+		for y := bound.Min[1]; y < bound.Max[1]; y += cellSize {
+			heap.Push(queue, newLabelCell(orb.Point{x + half, y + half}, half, polygon))
+		}
+	}
+
+	// Start from the centroid, or the bound centre when it is better
+	centroid, _ := planar.CentroidArea(polygon)
+	best := newLabelCell(centroid, 0, polygon)
+	if centre := newLabelCell(bound.Center(), 0, polygon); centre.distance > best.distance {
+		best = centre
+	}
+
+	for queue.Len() > 0 {
+		cell := heap.Pop(queue).(*labelCell)
+		if cell.distance > best.distance {
+			best = cell
+		}
+		if cell.max-best.distance <= precision {
+			continue
+		}
+
+		half = cell.half / 2
+		for _, offset := range [4][2]float64{{-1, -1}, {1, -1}, {-1, 1}, {1, 1}} {
+			centre := orb.Point{cell.centre[0] + offset[0]*half, cell.centre[1] + offset[1]*half}
+			heap.Push(queue, newLabelCell(centre, half, polygon))
+		}
+	}
+
+	return best.centre
+}
+
+// labelCell is a square polylabel search cell
+type labelCell struct {
+	centre   orb.Point
+	half     float64 // Half the cell's side
+	distance float64 // Signed distance from the centre to the outline, negative outside
+	max      float64 // Largest distance any point in the cell can have
+}
+
+// newLabelCell creates a search cell and evaluates its centre
+func newLabelCell(centre orb.Point, half float64, polygon orb.Polygon) *labelCell {
+	distance := signedDistance(centre, polygon)
+	return &labelCell{
+		centre:   centre,
+		half:     half,
+		distance: distance,
+		max:      distance + half*math.Sqrt2,
+	}
+}
+
+// signedDistance returns the distance from a point to a polygon's outline, negative
+// when the point is outside the polygon
+func signedDistance(point orb.Point, polygon orb.Polygon) float64 {
+	inside := false
+	minSquared := math.Inf(1)
+
+	for _, ring := range polygon {
+		for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+			a, b := ring[i], ring[j]
+			if (a[1] > point[1]) != (b[1] > point[1]) &&
+				point[0] < (b[0]-a[0])*(point[1]-a[1])/(b[1]-a[1])+a[0] {
+				inside = !inside
+			}
+			minSquared = math.Min(minSquared, planar.DistanceFromSegmentSquared(a, b, point))
+		}
+	}
+
+	if inside {
+		return math.Sqrt(minSquared)
+	}
+	return -math.Sqrt(minSquared)
+}
+
+// cellQueue is a max-heap of cells ordered by their potential distance
+type cellQueue []*labelCell
+
+func (q cellQueue) Len() int            { return len(q) }
+func (q cellQueue) Less(i, j int) bool  { return q[i].max > q[j].max }
+func (q cellQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *cellQueue) Push(x interface{}) { *q = append(*q, x.(*labelCell)) }
+func (q *cellQueue) Pop() interface{} {
+	old := *q
+	cell := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return cell
+}
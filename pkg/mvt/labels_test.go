@@ -0,0 +1,157 @@
+// pkg/mvt/labels_test.go - Unit tests for polygon label points
+package mvt
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/orb/planar"
+)
+
+// uShape is a concave polygon whose centroid lies in the notch, outside the polygon
+var uShape = orb.Polygon{{{0, 0}, {10, 0}, {10, 10}, {8, 10}, {8, 2}, {2, 2}, {2, 10}, {0, 10}, {0, 0}}}
+
+func TestLabelPoint(t *testing.T) {
+	tests := []struct {
+		name      string
+		geometry  orb.Geometry
+		algorithm string
+		inside    bool
+	}{
+		{"polylabel concave", uShape, LabelPolylabel, true},
+		{"representative concave", uShape, LabelRepresentative, true},
+		{"centroid concave", uShape, LabelCentroid, false},
+		{"polylabel default", uShape, "", true},
+		{"multipolygon uses largest part", orb.MultiPolygon{
+			{{{20, 20}, {21, 20}, {21, 21}, {20, 21}, {20, 20}}},
+			uShape,
+		}, LabelPolylabel, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			point, ok := labelPoint(tt.geometry, tt.algorithm, 0.01)
+			if !ok {
+				t.Fatal("Expected a label point")
+			}
+			if inside := planar.PolygonContains(uShape, point); inside != tt.inside {
+				t.Errorf("Label point %v: inside = %v, want %v", point, inside, tt.inside)
+			}
+		})
+	}
+}
+
+func TestPolylabel(t *testing.T) {
+	// With a hole near one corner, the pole moves towards the opposite corner, where
+	// the largest inscribed circle has a radius of about 3.515
+	polygon := orb.Polygon{
+		{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}},
+		{{1, 1}, {1, 4}, {4, 4}, {4, 1}, {1, 1}},
+	}
+
+	point := polylabel(polygon, 0.001)
+	if distance := signedDistance(point, polygon); distance < 3.51 {
+		t.Errorf("Expected a point about 3.515 units from the outline, got %v at %v", point, distance)
+	}
+
+	square := orb.Polygon{{{0, 0}, {4, 0}, {4, 4}, {0, 4}, {0, 0}}}
+	if point := polylabel(square, 0.001); math.Abs(point[0]-2) > 0.01 || math.Abs(point[1]-2) > 0.01 {
+		t.Errorf("Expected the square's centre, got %v", point)
+	}
+
+	// A sliver is covered by cells of the precision's size, not of its height
+	sliver := orb.Polygon{{{0, 0}, {4096, 0}, {4096, 0.000001}, {0, 0.000001}, {0, 0}}}
+	point = polylabel(sliver, 1)
+	if !sliver.Bound().Pad(1).Contains(point) {
+		t.Errorf("Expected a point on the sliver, got %v", point)
+	}
+}
+
+func TestAddLabelPoints(t *testing.T) {
+	tests := []struct {
+		name    string
+		options ConversionOptions
+	}{
+		{"property", ConversionOptions{LabelPoints: LabelModeProperty, LabelProperty: "label"}},
+		{"layer", ConversionOptions{LabelPoints: LabelModeLayer}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.options.CoordinateSystem = CoordSystemWebMercator
+			converter, err := NewConverterWithOptions(&tt.options)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			building := geojson.NewFeature(uShape)
+			building.ID = uint64(7)
+			building.Properties = geojson.Properties{"_layer": "buildings", "height": 12}
+			road := geojson.NewFeature(orb.LineString{{0, 0}, {5, 5}})
+			road.Properties = geojson.Properties{"_layer": "roads"}
+			layers := map[*geojson.Feature]string{building: "buildings", road: "roads"}
+
+			features := converter.addLabelPoints([]*geojson.Feature{building, road}, layers, 20, 4096)
+
+			if tt.options.LabelPoints == LabelModeProperty {
+				if len(features) != 2 {
+					t.Fatalf("Expected 2 features, got %d", len(features))
+				}
+				label, ok := building.Properties["label"].([]float64)
+				if !ok || !planar.PolygonContains(uShape, orb.Point{label[0], label[1]}) {
+					t.Errorf("Expected an interior label property, got %v", building.Properties["label"])
+				}
+				if _, exists := road.Properties["label"]; exists {
+					t.Error("Expected no label on the line feature")
+				}
+				return
+			}
+
+			if len(features) != 3 {
+				t.Fatalf("Expected the label feature appended, got %d features", len(features))
+			}
+			label := features[2]
+			if _, ok := label.Geometry.(orb.Point); !ok || label.ID != uint64(7) {
+				t.Errorf("Expected a point inheriting the polygon's ID, got %v with ID %v", label.Geometry, label.ID)
+			}
+			want := geojson.Properties{"_layer": "buildings_label", "height": 12}
+			if !reflect.DeepEqual(label.Properties, want) {
+				t.Errorf("Expected properties %v, got %v", want, label.Properties)
+			}
+			if building.Properties["_layer"] != "buildings" {
+				t.Error("Expected the polygon's properties to be left unchanged")
+			}
+
+			names := converter.appendLabelLayers([]string{"buildings", "roads"}, features, layers)
+			if want := []string{"buildings", "buildings_label", "roads"}; !reflect.DeepEqual(names, want) {
+				t.Errorf("Expected layers %v, got %v", want, names)
+			}
+		})
+	}
+}
+
+func TestValidateLabelOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options ConversionOptions
+		wantErr bool
+	}{
+		{"layer polylabel", ConversionOptions{LabelPoints: LabelModeLayer, LabelAlgorithm: LabelPolylabel}, false},
+		{"property representative", ConversionOptions{LabelPoints: LabelModeProperty, LabelAlgorithm: LabelRepresentative}, false},
+		{"unknown mode", ConversionOptions{LabelPoints: "both"}, true},
+		{"unknown algorithm", ConversionOptions{LabelPoints: LabelModeLayer, LabelAlgorithm: "medial-axis"}, true},
+		{"negative precision", ConversionOptions{LabelPoints: LabelModeLayer, LabelPrecision: -1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.options.CoordinateSystem = CoordSystemWebMercator
+			if err := ValidateConversionOptions(&tt.options); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateConversionOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+// pkg/mvt/mlt.go - MapLibre Tile input
+package mvt
+
+import (
+	"fmt"
+	"mime"
+
+	"github.com/valpere/tile_to_json/pkg/mlt"
+)
+
+// TileFormat identifies the encoding of a vector tile
+type TileFormat string
+
+// Tile format constants
+const (
+	TileFormatMVT TileFormat = "mvt"
+	TileFormatMLT TileFormat = "mlt"
+)
+
+// Media types identifying MapLibre Tiles
+var mltContentTypes = map[string]bool{
+	"application/vnd.maplibre-vector-tile": true,
+	"application/vnd.maplibre-tile":        true,
+	"application/x-maplibre-tile":          true,
+}
+
+// DetectTileFormat determines a tile's format from its Content-Type, when it names
+// one, and otherwise from the payload bytes. Generic types such as
+// application/x-protobuf, which servers send for either format, are not conclusive.
+func DetectTileFormat(data []byte, contentType string) TileFormat {
+	if contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err == nil {
+			if mltContentTypes[mediaType] {
+				return TileFormatMLT
+			}
+			if mediaType == "application/vnd.mapbox-vector-tile" {
+				return TileFormatMVT
+			}
+		}
+	}
+
+	if mlt.IsMLT(data) {
+		return TileFormatMLT
+	}
+	return TileFormatMVT
+}
+
+// decodeMLT decodes a MapLibre Tile into the same structure as a Mapbox Vector Tile
+func (d *Decoder) decodeMLT(data []byte, z, x, y int) (*DecodedTile, error) {
+	layers, err := mlt.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode MLT data: %w", err)
+	}
+
+	decodedTile := &DecodedTile{
+		Layers:  make(map[string]*DecodedLayer),
+		Format:  TileFormatMLT,
+		Extent:  d.extent,
+		Version: mlt.Version,
+		TileID: TileID{
+			Z: z,
+			X: x,
+			Y: y,
+		},
+	}
+
+	for _, layer := range layers {
+		decodedLayer := &DecodedLayer{
+			Name:     layer.Name,
+			Features: make([]*DecodedFeature, 0, len(layer.Features)),
+			Extent:   layer.Extent,
+			Version:  mlt.Version,
+		}
+
+		extent := d.extent
+		if layer.Extent > 0 {
+			extent = layer.Extent
+		}
+
+		for _, feature := range layer.Features {
+			decodedFeature := &DecodedFeature{
+				ID:       feature.ID,
+				Tags:     feature.Properties,
+				TagTypes: make(map[string]string, len(feature.Properties)),
+				Type:     geometryTypeName(feature.Geometry),
+				Geometry: d.transformGeometry(feature.Geometry, extent, z, x, y),
+			}
+			for key, value := range feature.Properties {
+				decodedFeature.TagTypes[key] = mltValueType(value)
+			}
+			decodedLayer.Features = append(decodedLayer.Features, decodedFeature)
+		}
+
+		if _, exists := decodedTile.Layers[layer.Name]; !exists {
+			decodedTile.LayerOrder = append(decodedTile.LayerOrder, layer.Name)
+		}
+		decodedTile.Layers[layer.Name] = decodedLayer
+	}
+
+	return decodedTile, nil
+}
+
+// mltValueType maps a decoded MLT property value to the equivalent MVT value type
+func mltValueType(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return ValueTypeString
+	case float32:
+		return ValueTypeFloat
+	case float64:
+		return ValueTypeDouble
+	case int64:
+		return ValueTypeInt
+	case uint64:
+		return ValueTypeUint
+	case bool:
+		return ValueTypeBool
+	default:
+		return ValueTypeString
+	}
+}
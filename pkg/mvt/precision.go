@@ -0,0 +1,139 @@
+// pkg/mvt/precision.go - Coordinate precision control
+package mvt
+
+import (
+	"math"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+// Precision mode constants
+const (
+	PrecisionModeNone  = ""      // Keep full floating point precision
+	PrecisionModeFixed = "fixed" // Round to CoordinatePrecision decimal places
+	PrecisionModeAuto  = "auto"  // Derive decimal places from the tile's resolution
+)
+
+// MaxCoordinatePrecision is the largest supported number of decimal places
+const MaxCoordinatePrecision = 15
+
+// coordinatePrecision returns the number of decimal places to round to for a tile
+// at zoom z with the given extent
+func (c *Converter) coordinatePrecision(z, extent int) int {
+	if c.options.PrecisionMode == PrecisionModeFixed {
+		return c.options.CoordinatePrecision
+	}
+
+	// One tile unit in output coordinates; rounding to half of it keeps every
+	// distinct tile coordinate distinct after rounding
+	tiles := float64(uint64(1) << uint(z))
+	unit := 2 * webMercatorMax / (tiles * float64(extent))
+	if c.options.CoordinateSystem == CoordSystemWGS84 {
+		unit = 360.0 / (tiles * float64(extent))
+	}
+
+	decimals := int(math.Ceil(math.Log10(2 / unit)))
+	if decimals < 0 {
+		return 0
+	}
+	if decimals > MaxCoordinatePrecision {
+		return MaxCoordinatePrecision
+	}
+	return decimals
+}
+
+// roundFeatures rounds feature coordinates and drops features whose geometry collapses
+func (c *Converter) roundFeatures(features []*geojson.Feature, z, extent int) []*geojson.Feature {
+	factor := int(math.Pow10(c.coordinatePrecision(z, extent)))
+
+	result := features[:0]
+	for _, feature := range features {
+		if feature.Geometry == nil {
+			continue
+		}
+
+		feature.Geometry = removeDuplicateVertices(orb.Round(feature.Geometry, factor))
+		if feature.Geometry == nil {
+			continue
+		}
+
+		result = append(result, feature)
+	}
+
+	return result
+}
+
+// removeDuplicateVertices removes consecutive duplicate vertices and drops parts
+// that become degenerate. It returns nil when nothing valid remains.
+func removeDuplicateVertices(geom orb.Geometry) orb.Geometry {
+	switch g := geom.(type) {
+	case orb.LineString:
+		if line := dedupeLine(g); len(line) >= 2 {
+			return line
+		}
+		return nil
+	case orb.MultiLineString:
+		result := make(orb.MultiLineString, 0, len(g))
+		for _, line := range g {
+			if line = dedupeLine(line); len(line) >= 2 {
+				result = append(result, line)
+			}
+		}
+		if len(result) == 0 {
+			return nil
+		}
+		return result
+	case orb.Polygon:
+		if polygon := dedupePolygon(g); polygon != nil {
+			return polygon
+		}
+		return nil
+	case orb.MultiPolygon:
+		result := make(orb.MultiPolygon, 0, len(g))
+		for _, polygon := range g {
+			if polygon = dedupePolygon(polygon); polygon != nil {
+				result = append(result, polygon)
+			}
+		}
+		if len(result) == 0 {
+			return nil
+		}
+		return result
+	default:
+		return geom
+	}
+}
+
+// dedupePolygon removes duplicate vertices from every ring, dropping collapsed holes.
+// It returns nil when the exterior ring collapses.
+func dedupePolygon(polygon orb.Polygon) orb.Polygon {
+	result := make(orb.Polygon, 0, len(polygon))
+	for i, ring := range polygon {
+		ring = orb.Ring(dedupeLine(orb.LineString(ring)))
+		if len(ring) < 4 {
+			if i == 0 {
+				return nil
+			}
+			continue
+		}
+		result = append(result, ring)
+	}
+	return result
+}
+
+// dedupeLine removes consecutive duplicate points in place
+func dedupeLine(line orb.LineString) orb.LineString {
+	if len(line) < 2 {
+		return line
+	}
+
+	count := 1
+	for i := 1; i < len(line); i++ {
+		if line[i] != line[count-1] {
+			line[count] = line[i]
+			count++
+		}
+	}
+	return line[:count]
+}
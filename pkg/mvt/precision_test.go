@@ -0,0 +1,82 @@
+// pkg/mvt/precision_test.go - Unit tests for coordinate precision control
+package mvt
+
+import (
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+func TestCoordinatePrecision(t *testing.T) {
+	tests := []struct {
+		name    string
+		options *ConversionOptions
+		z       int
+		want    int
+	}{
+		{"fixed", &ConversionOptions{CoordinateSystem: CoordSystemWGS84, PrecisionMode: PrecisionModeFixed, CoordinatePrecision: 3}, 14, 3},
+		{"auto wgs84 z14", &ConversionOptions{CoordinateSystem: CoordSystemWGS84, PrecisionMode: PrecisionModeAuto}, 14, 6},
+		{"auto wgs84 z0", &ConversionOptions{CoordinateSystem: CoordSystemWGS84, PrecisionMode: PrecisionModeAuto}, 0, 2},
+		{"auto web mercator z14", &ConversionOptions{CoordinateSystem: CoordSystemWebMercator, PrecisionMode: PrecisionModeAuto}, 14, 1},
+		{"auto web mercator z0", &ConversionOptions{CoordinateSystem: CoordSystemWebMercator, PrecisionMode: PrecisionModeAuto}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter, err := NewConverterWithOptions(tt.options)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got := converter.coordinatePrecision(tt.z, 4096); got != tt.want {
+				t.Errorf("coordinatePrecision() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoundFeatures(t *testing.T) {
+	converter, _ := NewConverterWithOptions(&ConversionOptions{
+		CoordinateSystem:    CoordSystemWGS84,
+		PrecisionMode:       PrecisionModeFixed,
+		CoordinatePrecision: 2,
+	})
+
+	features := []*geojson.Feature{
+		geojson.NewFeature(orb.LineString{{1.001, 1.001}, {1.002, 1.002}, {1.5, 1.5}}),
+		geojson.NewFeature(orb.LineString{{2.001, 2.001}, {2.002, 2.002}}),
+		geojson.NewFeature(orb.Point{3.14159, 2.71828}),
+	}
+
+	result := converter.roundFeatures(features, 14, 4096)
+	if len(result) != 2 {
+		t.Fatalf("Expected collapsed line to be dropped, got %d features", len(result))
+	}
+
+	line := result[0].Geometry.(orb.LineString)
+	if len(line) != 2 || line[0] != (orb.Point{1, 1}) || line[1] != (orb.Point{1.5, 1.5}) {
+		t.Errorf("Expected duplicate vertices removed, got %v", line)
+	}
+
+	if point := result[1].Geometry.(orb.Point); point != (orb.Point{3.14, 2.72}) {
+		t.Errorf("Expected rounded point, got %v", point)
+	}
+}
+
+func TestRemoveDuplicateVerticesPolygon(t *testing.T) {
+	polygon := orb.Polygon{
+		{{0, 0}, {10, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}},
+		{{1, 1}, {1, 1}, {1, 1}, {1, 1}},
+	}
+
+	result, ok := removeDuplicateVertices(polygon).(orb.Polygon)
+	if !ok {
+		t.Fatal("Expected polygon result")
+	}
+	if len(result) != 1 {
+		t.Errorf("Expected collapsed hole to be dropped, got %d rings", len(result))
+	}
+	if len(result[0]) != 5 {
+		t.Errorf("Expected 5 exterior vertices, got %d", len(result[0]))
+	}
+}
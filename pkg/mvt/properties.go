@@ -0,0 +1,332 @@
+// pkg/mvt/properties.go - Declarative property transformation pipeline
+package mvt
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/orb/planar"
+)
+
+// DefaultLayerProperty is the property holding a feature's source layer name
+const DefaultLayerProperty = "_layer"
+
+// Property operation constants
+const (
+	PropertyOpRename  = "rename"  // Rename From to To
+	PropertyOpDrop    = "drop"    // Drop keys matching Pattern (path.Match glob)
+	PropertyOpCast    = "cast"    // Convert Key to Type
+	PropertyOpSet     = "set"     // Set Key to the constant Value
+	PropertyOpCompute = "compute" // Set Key to the result of Function
+)
+
+// Cast type constants
+const (
+	CastString = "string"
+	CastInt    = "int"
+	CastFloat  = "float"
+	CastBool   = "bool"
+)
+
+// Computed property function constants. Area and length are geodesic, in square
+// meters and meters; the centroid is a [longitude, latitude] pair.
+const (
+	ComputeArea        = "area"
+	ComputeLength      = "length"
+	ComputeCentroid    = "centroid"
+	ComputeVertexCount = "vertex_count"
+	ComputeTileZ       = "tile_z"
+	ComputeTileX       = "tile_x"
+	ComputeTileY       = "tile_y"
+	ComputeTile        = "tile"
+)
+
+// PropertyOp is a single step of the property pipeline. Steps run in order, so later
+// steps see the results of earlier ones.
+type PropertyOp struct {
+	Op       string      `json:"op" mapstructure:"op"`
+	Key      string      `json:"key,omitempty" mapstructure:"key"`
+	From     string      `json:"from,omitempty" mapstructure:"from"`
+	To       string      `json:"to,omitempty" mapstructure:"to"`
+	Pattern  string      `json:"pattern,omitempty" mapstructure:"pattern"`
+	Type     string      `json:"type,omitempty" mapstructure:"type"`
+	Value    interface{} `json:"value,omitempty" mapstructure:"value"`
+	Function string      `json:"function,omitempty" mapstructure:"function"`
+	Layers   []string    `json:"layers,omitempty" mapstructure:"layers"` // Restrict the step to these layers
+}
+
+// Validate checks that the operation is known and has the fields it needs
+func (op *PropertyOp) Validate() error {
+	switch op.Op {
+	case PropertyOpRename:
+		if op.From == "" || op.To == "" {
+			return fmt.Errorf("rename requires from and to")
+		}
+	case PropertyOpDrop:
+		if _, err := path.Match(op.Pattern, ""); err != nil || op.Pattern == "" {
+			return fmt.Errorf("drop requires a valid pattern, got %q", op.Pattern)
+		}
+	case PropertyOpCast:
+		if op.Key == "" {
+			return fmt.Errorf("cast requires a key")
+		}
+		switch op.Type {
+		case CastString, CastInt, CastFloat, CastBool:
+		default:
+			return fmt.Errorf("invalid cast type: %s, must be '%s', '%s', '%s' or '%s'",
+				op.Type, CastString, CastInt, CastFloat, CastBool)
+		}
+	case PropertyOpSet:
+		if op.Key == "" {
+			return fmt.Errorf("set requires a key")
+		}
+	case PropertyOpCompute:
+		if op.Key == "" {
+			return fmt.Errorf("compute requires a key")
+		}
+		switch op.Function {
+		case ComputeArea, ComputeLength, ComputeCentroid, ComputeVertexCount,
+			ComputeTileZ, ComputeTileX, ComputeTileY, ComputeTile:
+		default:
+			return fmt.Errorf("invalid compute function: %s", op.Function)
+		}
+	default:
+		return fmt.Errorf("invalid property operation: %s", op.Op)
+	}
+	return nil
+}
+
+// ParsePropertyOp parses the compact command line form of a property operation:
+//
+//	rename:FROM=TO  drop:PATTERN  cast:KEY=TYPE  set:KEY=VALUE  compute:KEY=FUNCTION
+//
+// Values given to set are parsed as JSON when possible and kept as strings otherwise.
+func ParsePropertyOp(spec string) (PropertyOp, error) {
+	name, args, found := strings.Cut(spec, ":")
+	if !found {
+		return PropertyOp{}, fmt.Errorf("invalid property operation %q, expected op:arguments", spec)
+	}
+
+	op := PropertyOp{Op: name}
+	left, right, hasValue := strings.Cut(args, "=")
+	switch name {
+	case PropertyOpRename:
+		op.From, op.To = left, right
+	case PropertyOpDrop:
+		op.Pattern = args
+		hasValue = true
+	case PropertyOpCast:
+		op.Key, op.Type = left, right
+	case PropertyOpSet:
+		op.Key, op.Value = left, parseConstant(right)
+	case PropertyOpCompute:
+		op.Key, op.Function = left, right
+	}
+
+	if !hasValue {
+		return PropertyOp{}, fmt.Errorf("invalid property operation %q, expected %s:KEY=VALUE", spec, name)
+	}
+	if err := op.Validate(); err != nil {
+		return PropertyOp{}, err
+	}
+	return op, nil
+}
+
+// parseConstant interprets a command line constant as a number or boolean where possible
+func parseConstant(text string) interface{} {
+	if text == "true" || text == "false" {
+		return text == "true"
+	}
+	if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil {
+		return f
+	}
+	return text
+}
+
+// applyProperties runs the property pipeline on the final features of a tile
+func (c *Converter) applyProperties(features []*geojson.Feature, layers map[*geojson.Feature]string, z, x, y int) {
+	for _, feature := range features {
+		if feature.Properties == nil {
+			feature.Properties = make(geojson.Properties)
+		}
+		layer := layers[feature]
+
+		var wgs84 orb.Geometry
+		for i := range c.options.Properties {
+			op := &c.options.Properties[i]
+			if len(op.Layers) > 0 && !c.contains(op.Layers, layer) {
+				continue
+			}
+
+			props := feature.Properties
+			switch op.Op {
+			case PropertyOpRename:
+				if value, exists := props[op.From]; exists {
+					delete(props, op.From)
+					props[op.To] = value
+				}
+			case PropertyOpDrop:
+				for key := range props {
+					if matched, _ := path.Match(op.Pattern, key); matched {
+						delete(props, key)
+					}
+				}
+			case PropertyOpCast:
+				if value, exists := props[op.Key]; exists {
+					props[op.Key] = castValue(value, op.Type)
+				}
+			case PropertyOpSet:
+				props[op.Key] = op.Value
+			case PropertyOpCompute:
+				if wgs84 == nil && feature.Geometry != nil {
+					wgs84 = feature.Geometry
+					if c.options.CoordinateSystem == CoordSystemWebMercator {
+						wgs84 = c.transformGeometryToWGS84(feature.Geometry)
+					}
+				}
+				props[op.Key] = computeProperty(op.Function, wgs84, z, x, y)
+			}
+		}
+	}
+}
+
+// castValue converts a value to the given type, returning nil when it cannot be converted
+func castValue(value interface{}, castType string) interface{} {
+	switch castType {
+	case CastString:
+		switch v := value.(type) {
+		case string:
+			return v
+		case nil:
+			return nil
+		default:
+			return fmt.Sprint(v)
+		}
+	case CastInt:
+		switch v := value.(type) {
+		case int64, uint64:
+			return v
+		case string:
+			if i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+				return i
+			}
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				return int64(f)
+			}
+		case bool:
+			if v {
+				return int64(1)
+			}
+			return int64(0)
+		default:
+			if f, ok := toNumber(v); ok && !math.IsNaN(f) && !math.IsInf(f, 0) {
+				return int64(f)
+			}
+		}
+	case CastFloat:
+		switch v := value.(type) {
+		case string:
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				return f
+			}
+		case bool:
+			if v {
+				return 1.0
+			}
+			return 0.0
+		default:
+			if f, ok := toNumber(v); ok {
+				return f
+			}
+		}
+	case CastBool:
+		switch v := value.(type) {
+		case bool:
+			return v
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+				return b
+			}
+		default:
+			if f, ok := toNumber(v); ok {
+				return f != 0
+			}
+		}
+	}
+	return nil
+}
+
+// computeProperty evaluates a computed property function for a WGS84 geometry
+func computeProperty(function string, geometry orb.Geometry, z, x, y int) interface{} {
+	switch function {
+	case ComputeTileZ:
+		return z
+	case ComputeTileX:
+		return x
+	case ComputeTileY:
+		return y
+	case ComputeTile:
+		return fmt.Sprintf("%d/%d/%d", z, x, y)
+	}
+
+	if geometry == nil {
+		return nil
+	}
+
+	switch function {
+	case ComputeArea:
+		return geo.Area(geometry)
+	case ComputeLength:
+		switch geometry.(type) {
+		case orb.LineString, orb.MultiLineString:
+			return geo.Length(geometry)
+		}
+		return 0.0
+	case ComputeCentroid:
+		centroid, _ := planar.CentroidArea(geometry)
+		return []float64{centroid[0], centroid[1]}
+	case ComputeVertexCount:
+		return vertexCount(geometry)
+	}
+	return nil
+}
+
+// vertexCount returns the number of coordinates in a geometry
+func vertexCount(geometry orb.Geometry) int {
+	switch g := geometry.(type) {
+	case orb.Point:
+		return 1
+	case orb.MultiPoint:
+		return len(g)
+	case orb.LineString:
+		return len(g)
+	case orb.MultiLineString:
+		count := 0
+		for _, line := range g {
+			count += len(line)
+		}
+		return count
+	case orb.Polygon:
+		count := 0
+		for _, ring := range g {
+			count += len(ring)
+		}
+		return count
+	case orb.MultiPolygon:
+		count := 0
+		for _, polygon := range g {
+			count += vertexCount(polygon)
+		}
+		return count
+	}
+	return 0
+}
@@ -0,0 +1,116 @@
+// pkg/mvt/properties_test.go - Unit tests for the property pipeline
+package mvt
+
+import (
+	"math"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+func TestApplyProperties(t *testing.T) {
+	converter, err := NewConverterWithOptions(&ConversionOptions{
+		CoordinateSystem: CoordSystemWGS84,
+		Properties: []PropertyOp{
+			{Op: PropertyOpRename, From: "name_en", To: "name"},
+			{Op: PropertyOpDrop, Pattern: "name_*"},
+			{Op: PropertyOpCast, Key: "population", Type: CastInt},
+			{Op: PropertyOpCast, Key: "bogus", Type: CastFloat},
+			{Op: PropertyOpSet, Key: "source", Value: "osm"},
+			{Op: PropertyOpCompute, Key: "vertices", Function: ComputeVertexCount},
+			{Op: PropertyOpCompute, Key: "tile", Function: ComputeTile},
+			{Op: PropertyOpCompute, Key: "area", Function: ComputeArea, Layers: []string{"buildings"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	feature := geojson.NewFeature(orb.Polygon{{{0, 0}, {0.01, 0}, {0.01, 0.01}, {0, 0.01}, {0, 0}}})
+	feature.Properties = geojson.Properties{
+		"name_en":    "Town Hall",
+		"name_de":    "Rathaus",
+		"population": "1200",
+		"bogus":      "n/a",
+	}
+
+	converter.applyProperties([]*geojson.Feature{feature}, map[*geojson.Feature]string{feature: "buildings"}, 14, 1, 2)
+
+	props := feature.Properties
+	if props["name"] != "Town Hall" {
+		t.Errorf("Expected renamed name, got %v", props["name"])
+	}
+	if _, exists := props["name_de"]; exists {
+		t.Error("Expected name_de to be dropped")
+	}
+	if props["population"] != int64(1200) {
+		t.Errorf("Expected population cast to int64, got %v (%T)", props["population"], props["population"])
+	}
+	if value, exists := props["bogus"]; !exists || value != nil {
+		t.Errorf("Expected failed cast to yield null, got %v", value)
+	}
+	if props["source"] != "osm" {
+		t.Errorf("Expected constant source, got %v", props["source"])
+	}
+	if props["vertices"] != 5 {
+		t.Errorf("Expected 5 vertices, got %v", props["vertices"])
+	}
+	if props["tile"] != "14/1/2" {
+		t.Errorf("Expected tile 14/1/2, got %v", props["tile"])
+	}
+
+	// 0.01° x 0.01° at the equator is roughly 1113m x 1113m
+	if area, ok := props["area"].(float64); !ok || math.Abs(area-1.239e6)/1.239e6 > 0.01 {
+		t.Errorf("Expected geodesic area near 1.239e6 m², got %v", props["area"])
+	}
+}
+
+func TestLayerPropertyName(t *testing.T) {
+	feature := &DecodedFeature{Tags: map[string]interface{}{}, Geometry: orb.Point{0, 0}}
+
+	renamed, _ := NewConverterWithOptions(&ConversionOptions{CoordinateSystem: CoordSystemWebMercator, LayerProperty: "layer"})
+	result, _ := renamed.convertFeatureToGeoJSON(feature, "roads")
+	if result.Properties["layer"] != "roads" {
+		t.Errorf("Expected layer property 'layer', got %v", result.Properties)
+	}
+
+	omitted, _ := NewConverterWithOptions(&ConversionOptions{CoordinateSystem: CoordSystemWebMercator, OmitLayerProperty: true})
+	result, _ = omitted.convertFeatureToGeoJSON(feature, "roads")
+	if len(result.Properties) != 0 {
+		t.Errorf("Expected no layer property, got %v", result.Properties)
+	}
+}
+
+func TestParsePropertyOp(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    PropertyOp
+		wantErr bool
+	}{
+		{spec: "rename:a=b", want: PropertyOp{Op: PropertyOpRename, From: "a", To: "b"}},
+		{spec: "drop:name_*", want: PropertyOp{Op: PropertyOpDrop, Pattern: "name_*"}},
+		{spec: "cast:pop=int", want: PropertyOp{Op: PropertyOpCast, Key: "pop", Type: CastInt}},
+		{spec: "set:rank=3", want: PropertyOp{Op: PropertyOpSet, Key: "rank", Value: int64(3)}},
+		{spec: "compute:len=length", want: PropertyOp{Op: PropertyOpCompute, Key: "len", Function: ComputeLength}},
+		{spec: "cast:pop=decimal", wantErr: true},
+		{spec: "compute:x=perimeter", wantErr: true},
+		{spec: "rename:a", wantErr: true},
+		{spec: "uppercase:a=b", wantErr: true},
+		{spec: "nonsense", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParsePropertyOp(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePropertyOp() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (got.Op != tt.want.Op || got.Key != tt.want.Key || got.From != tt.want.From ||
+				got.To != tt.want.To || got.Pattern != tt.want.Pattern || got.Type != tt.want.Type ||
+				got.Function != tt.want.Function || got.Value != tt.want.Value) {
+				t.Errorf("ParsePropertyOp() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
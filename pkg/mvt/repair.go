@@ -0,0 +1,321 @@
+// pkg/mvt/repair.go - RFC 7946 geometry repair
+package mvt
+
+import (
+	"math"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/clip"
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/orb/planar"
+)
+
+// RepairStats counts the geometry repairs applied in RFC 7946 mode
+type RepairStats struct {
+	RingsReoriented        int `json:"rings_reoriented"`
+	RingsClosed            int `json:"rings_closed"`
+	DegenerateRingsDropped int `json:"degenerate_rings_dropped"`
+	ZeroLengthLinesDropped int `json:"zero_length_lines_dropped"`
+	AntimeridianSplits     int `json:"antimeridian_splits"`
+	FeaturesDropped        int `json:"features_dropped"`
+}
+
+// Add accumulates the counts of another set of repair statistics
+func (s *RepairStats) Add(other *RepairStats) {
+	if other == nil {
+		return
+	}
+	s.RingsReoriented += other.RingsReoriented
+	s.RingsClosed += other.RingsClosed
+	s.DegenerateRingsDropped += other.DegenerateRingsDropped
+	s.ZeroLengthLinesDropped += other.ZeroLengthLinesDropped
+	s.AntimeridianSplits += other.AntimeridianSplits
+	s.FeaturesDropped += other.FeaturesDropped
+}
+
+// Total returns the total number of repairs
+func (s *RepairStats) Total() int {
+	return s.RingsReoriented + s.RingsClosed + s.DegenerateRingsDropped +
+		s.ZeroLengthLinesDropped + s.AntimeridianSplits + s.FeaturesDropped
+}
+
+// Longitude bands used to split geometries at the antimeridian
+var (
+	worldBound = orb.Bound{Min: orb.Point{-180, -90}, Max: orb.Point{180, 90}}
+	eastBound  = orb.Bound{Min: orb.Point{180, -90}, Max: orb.Point{540, 90}}
+	westBound  = orb.Bound{Min: orb.Point{-540, -90}, Max: orb.Point{-180, 90}}
+)
+
+// repairFeatures makes WGS84 features conform to RFC 7946: rings are closed and follow
+// the right-hand rule, degenerate parts are dropped, geometries crossing the antimeridian
+// are split and every feature gets a bbox. roundFactor, when positive, is applied to
+// coordinates created by splitting.
+func repairFeatures(features []*geojson.Feature, roundFactor int, stats *RepairStats) []*geojson.Feature {
+	result := features[:0]
+	for _, feature := range features {
+		if feature.Geometry == nil {
+			stats.FeaturesDropped++
+			continue
+		}
+
+		geometry, split := splitAntimeridian(feature.Geometry)
+		if split {
+			stats.AntimeridianSplits++
+			if roundFactor > 0 {
+				geometry = orb.Round(geometry, roundFactor)
+			}
+		}
+
+		geometry = repairGeometry(geometry, stats)
+		if geometry == nil {
+			stats.FeaturesDropped++
+			continue
+		}
+
+		feature.Geometry = geometry
+		feature.BBox = featureBBox(geometry, split)
+		result = append(result, feature)
+	}
+
+	return result
+}
+
+// repairGeometry closes and orients rings and drops degenerate parts.
+// It returns nil when nothing valid remains.
+func repairGeometry(geom orb.Geometry, stats *RepairStats) orb.Geometry {
+	switch g := geom.(type) {
+	case orb.LineString:
+		if lineHasLength(g) {
+			return g
+		}
+		stats.ZeroLengthLinesDropped++
+		return nil
+	case orb.MultiLineString:
+		result := make(orb.MultiLineString, 0, len(g))
+		for _, line := range g {
+			if lineHasLength(line) {
+				result = append(result, line)
+			} else {
+				stats.ZeroLengthLinesDropped++
+			}
+		}
+		if len(result) == 0 {
+			return nil
+		}
+		if len(result) == 1 {
+			return result[0]
+		}
+		return result
+	case orb.Polygon:
+		if polygon := repairPolygon(g, stats); polygon != nil {
+			return polygon
+		}
+		return nil
+	case orb.MultiPolygon:
+		result := make(orb.MultiPolygon, 0, len(g))
+		for _, polygon := range g {
+			if polygon = repairPolygon(polygon, stats); polygon != nil {
+				result = append(result, polygon)
+			}
+		}
+		if len(result) == 0 {
+			return nil
+		}
+		if len(result) == 1 {
+			return result[0]
+		}
+		return result
+	default:
+		return geom
+	}
+}
+
+// repairPolygon closes and orients the rings of a polygon using the right-hand rule:
+// exterior rings counterclockwise, holes clockwise. It returns nil when the exterior
+// ring is degenerate.
+func repairPolygon(polygon orb.Polygon, stats *RepairStats) orb.Polygon {
+	result := make(orb.Polygon, 0, len(polygon))
+	for i, ring := range polygon {
+		if len(ring) > 0 && ring[0] != ring[len(ring)-1] {
+			ring = append(ring, ring[0])
+			stats.RingsClosed++
+		}
+
+		if len(ring) < 4 || planar.Area(ring) == 0 {
+			stats.DegenerateRingsDropped++
+			if i == 0 {
+				return nil
+			}
+			continue
+		}
+
+		want := orb.CW
+		if i == 0 {
+			want = orb.CCW
+		}
+		if ring.Orientation() != want {
+			ring.Reverse()
+			stats.RingsReoriented++
+		}
+
+		result = append(result, ring)
+	}
+	return result
+}
+
+// lineHasLength reports whether a line has at least two distinct points
+func lineHasLength(line orb.LineString) bool {
+	for i := 1; i < len(line); i++ {
+		if line[i] != line[0] {
+			return true
+		}
+	}
+	return false
+}
+
+// splitAntimeridian splits a geometry extending beyond ±180° longitude into parts
+// wrapped back into the valid range. It reports whether the geometry was split.
+func splitAntimeridian(geom orb.Geometry) (orb.Geometry, bool) {
+	bound := geom.Bound()
+	if bound.Min[0] >= -180 && bound.Max[0] <= 180 {
+		return geom, false
+	}
+
+	switch g := geom.(type) {
+	case orb.Point:
+		return orb.Point{wrapLongitude(g[0]), g[1]}, false
+	case orb.MultiPoint:
+		result := make(orb.MultiPoint, len(g))
+		for i, p := range g {
+			result[i] = orb.Point{wrapLongitude(p[0]), p[1]}
+		}
+		return result, false
+	}
+
+	var parts []orb.Geometry
+	for _, band := range []struct {
+		bound orb.Bound
+		shift float64
+	}{{worldBound, 0}, {eastBound, -360}, {westBound, 360}} {
+		part := clip.Geometry(band.bound, orb.Clone(geom))
+		if part == nil {
+			continue
+		}
+		if band.shift != 0 {
+			shift := band.shift
+			part = applyGeometryTransform(part, func(p orb.Point) orb.Point {
+				return orb.Point{p[0] + shift, p[1]}
+			})
+		}
+		parts = append(parts, part)
+	}
+
+	return mergeParts(parts), len(parts) > 1
+}
+
+// mergeParts combines clipped parts of the same dimension into a single multi-geometry
+func mergeParts(parts []orb.Geometry) orb.Geometry {
+	if len(parts) == 1 {
+		return parts[0]
+	}
+
+	var lines orb.MultiLineString
+	var polygons orb.MultiPolygon
+	for _, part := range parts {
+		switch g := part.(type) {
+		case orb.LineString:
+			lines = append(lines, g)
+		case orb.MultiLineString:
+			lines = append(lines, g...)
+		case orb.Polygon:
+			polygons = append(polygons, g)
+		case orb.MultiPolygon:
+			polygons = append(polygons, g...)
+		}
+	}
+
+	if len(polygons) > 0 {
+		return polygons
+	}
+	if len(lines) > 0 {
+		return lines
+	}
+	return nil
+}
+
+// featureBBox computes an RFC 7946 bbox. Split geometries that touch both sides of
+// the antimeridian get a bbox whose west edge is greater than its east edge.
+func featureBBox(geom orb.Geometry, split bool) geojson.BBox {
+	bound := geom.Bound()
+	if !split {
+		return geojson.NewBBox(bound)
+	}
+
+	east, west := orb.Bound{}, orb.Bound{}
+	hasEast, hasWest := false, false
+	forEachPart(geom, func(part orb.Geometry) {
+		b := part.Bound()
+		if b.Center()[0] >= 0 {
+			east = extendBound(east, b, hasEast)
+			hasEast = true
+		} else {
+			west = extendBound(west, b, hasWest)
+			hasWest = true
+		}
+	})
+
+	if !hasEast || !hasWest {
+		return geojson.NewBBox(bound)
+	}
+	return geojson.BBox{east.Min[0], bound.Min[1], west.Max[0], bound.Max[1]}
+}
+
+// forEachPart calls fn for each component of a multi-geometry
+func forEachPart(geom orb.Geometry, fn func(orb.Geometry)) {
+	switch g := geom.(type) {
+	case orb.MultiLineString:
+		for _, line := range g {
+			fn(line)
+		}
+	case orb.MultiPolygon:
+		for _, polygon := range g {
+			fn(polygon)
+		}
+	default:
+		fn(geom)
+	}
+}
+
+// extendBound unions two bounds, using b alone when acc is not yet initialized
+func extendBound(acc, b orb.Bound, initialized bool) orb.Bound {
+	if !initialized {
+		return b
+	}
+	return acc.Union(b)
+}
+
+// wrapLongitude wraps a longitude into the [-180, 180] range
+func wrapLongitude(lon float64) float64 {
+	if lon >= -180 && lon <= 180 {
+		return lon
+	}
+	return math.Mod(math.Mod(lon+180, 360)+360, 360) - 180
+}
+
+// CollectionBBox computes the bbox enclosing all feature geometries
+func CollectionBBox(features []*geojson.Feature) geojson.BBox {
+	var bound orb.Bound
+	initialized := false
+	for _, feature := range features {
+		if feature.Geometry == nil {
+			continue
+		}
+		bound = extendBound(bound, feature.Geometry.Bound(), initialized)
+		initialized = true
+	}
+
+	if !initialized {
+		return nil
+	}
+	return geojson.NewBBox(bound)
+}
@@ -0,0 +1,131 @@
+// pkg/mvt/repair_test.go - Unit tests for RFC 7946 geometry repair
+package mvt
+
+import (
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+func TestRepairPolygon(t *testing.T) {
+	tests := []struct {
+		name      string
+		polygon   orb.Polygon
+		wantRings int
+		wantStats RepairStats
+	}{
+		{
+			name:      "clockwise exterior is reversed",
+			polygon:   orb.Polygon{{{0, 0}, {0, 1}, {1, 1}, {1, 0}, {0, 0}}},
+			wantRings: 1,
+			wantStats: RepairStats{RingsReoriented: 1},
+		},
+		{
+			name:      "unclosed ring is closed",
+			polygon:   orb.Polygon{{{0, 0}, {1, 0}, {1, 1}, {0, 1}}},
+			wantRings: 1,
+			wantStats: RepairStats{RingsClosed: 1},
+		},
+		{
+			name: "counterclockwise hole is reversed",
+			polygon: orb.Polygon{
+				{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}},
+				{{1, 1}, {2, 1}, {2, 2}, {1, 2}, {1, 1}},
+			},
+			wantRings: 2,
+			wantStats: RepairStats{RingsReoriented: 1},
+		},
+		{
+			name: "degenerate hole is dropped",
+			polygon: orb.Polygon{
+				{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}},
+				{{1, 1}, {2, 2}, {3, 3}, {1, 1}},
+			},
+			wantRings: 1,
+			wantStats: RepairStats{DegenerateRingsDropped: 1},
+		},
+		{
+			name:      "degenerate exterior drops polygon",
+			polygon:   orb.Polygon{{{0, 0}, {1, 0}, {0, 0}}},
+			wantRings: 0,
+			wantStats: RepairStats{DegenerateRingsDropped: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := &RepairStats{}
+			result := repairPolygon(tt.polygon, stats)
+
+			if len(result) != tt.wantRings {
+				t.Fatalf("Expected %d rings, got %d", tt.wantRings, len(result))
+			}
+			if *stats != tt.wantStats {
+				t.Errorf("Expected stats %+v, got %+v", tt.wantStats, *stats)
+			}
+			for i, ring := range result {
+				want := orb.CW
+				if i == 0 {
+					want = orb.CCW
+				}
+				if !ring.Closed() || ring.Orientation() != want {
+					t.Errorf("Ring %d not closed with orientation %v: %v", i, want, ring)
+				}
+			}
+		})
+	}
+}
+
+func TestRepairFeaturesAntimeridian(t *testing.T) {
+	features := []*geojson.Feature{
+		geojson.NewFeature(orb.Polygon{{{170, 0}, {190, 0}, {190, 10}, {170, 10}, {170, 0}}}),
+		geojson.NewFeature(orb.LineString{{10, 10}, {10, 10}}),
+	}
+
+	stats := &RepairStats{}
+	result := repairFeatures(features, 0, stats)
+
+	if len(result) != 1 {
+		t.Fatalf("Expected zero-length line to be dropped, got %d features", len(result))
+	}
+	if stats.AntimeridianSplits != 1 || stats.ZeroLengthLinesDropped != 1 || stats.FeaturesDropped != 1 {
+		t.Errorf("Unexpected stats %+v", *stats)
+	}
+
+	multiPolygon, ok := result[0].Geometry.(orb.MultiPolygon)
+	if !ok || len(multiPolygon) != 2 {
+		t.Fatalf("Expected split into a two-part multipolygon, got %v", result[0].Geometry)
+	}
+
+	bound := multiPolygon.Bound()
+	if bound.Min[0] < -180 || bound.Max[0] > 180 {
+		t.Errorf("Expected longitudes within [-180, 180], got %v", bound)
+	}
+
+	want := geojson.BBox{170, 0, -170, 10}
+	if len(result[0].BBox) != 4 {
+		t.Fatalf("Expected 4-element bbox, got %v", result[0].BBox)
+	}
+	for i := range want {
+		if result[0].BBox[i] != want[i] {
+			t.Errorf("Expected bbox %v, got %v", want, result[0].BBox)
+			break
+		}
+	}
+}
+
+func TestNewConverterRFC7946ForcesWGS84(t *testing.T) {
+	options := &ConversionOptions{CoordinateSystem: CoordSystemWebMercator, RFC7946: true}
+
+	converter, err := NewConverterWithOptions(options)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if converter.options.CoordinateSystem != CoordSystemWGS84 {
+		t.Errorf("Expected WGS84 coordinate system, got %s", converter.options.CoordinateSystem)
+	}
+	if options.CoordinateSystem != CoordSystemWebMercator {
+		t.Error("Expected caller's options to be left unchanged")
+	}
+}
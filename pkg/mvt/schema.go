@@ -0,0 +1,109 @@
+// pkg/mvt/schema.go - Per-layer attribute schema
+package mvt
+
+import "sort"
+
+// Schema describes the attributes of each layer, keyed by layer name
+type Schema map[string]*LayerSchema
+
+// LayerSchema describes the attributes found in a layer's features
+type LayerSchema struct {
+	FeatureCount int                         `json:"feature_count"`
+	Attributes   map[string]*AttributeSchema `json:"attributes"`
+}
+
+// AttributeSchema describes a single attribute key. An attribute is nullable
+// when some features of the layer do not carry it.
+type AttributeSchema struct {
+	Types    []string `json:"types"`
+	Count    int      `json:"count"`
+	Nullable bool     `json:"nullable"`
+}
+
+// addFeature records the attributes of one feature
+func (s *LayerSchema) addFeature(types map[string]string) {
+	s.FeatureCount++
+	for key, valueType := range types {
+		attribute, exists := s.Attributes[key]
+		if !exists {
+			attribute = &AttributeSchema{}
+			s.Attributes[key] = attribute
+		}
+		attribute.Count++
+		attribute.addType(valueType)
+	}
+}
+
+// finalize derives nullability from the attribute and feature counts
+func (s *LayerSchema) finalize() {
+	for _, attribute := range s.Attributes {
+		attribute.Nullable = attribute.Count < s.FeatureCount
+	}
+}
+
+// addType records a value type, keeping Types sorted and unique
+func (a *AttributeSchema) addType(valueType string) {
+	i := sort.SearchStrings(a.Types, valueType)
+	if i < len(a.Types) && a.Types[i] == valueType {
+		return
+	}
+	a.Types = append(a.Types, "")
+	copy(a.Types[i+1:], a.Types[i:])
+	a.Types[i] = valueType
+}
+
+// Merge folds another schema, for example from a different tile, into s
+func (s Schema) Merge(other Schema) {
+	for name, layer := range other {
+		merged, exists := s[name]
+		if !exists {
+			merged = &LayerSchema{Attributes: make(map[string]*AttributeSchema)}
+			s[name] = merged
+		}
+
+		merged.FeatureCount += layer.FeatureCount
+		for key, attribute := range layer.Attributes {
+			target, exists := merged.Attributes[key]
+			if !exists {
+				target = &AttributeSchema{}
+				merged.Attributes[key] = target
+			}
+			target.Count += attribute.Count
+			for _, valueType := range attribute.Types {
+				target.addType(valueType)
+			}
+		}
+		merged.finalize()
+	}
+}
+
+// buildSchema computes the attribute schema of a decoded tile, honouring the
+// converter's layer and property filters
+func (c *Converter) buildSchema(tile *DecodedTile) Schema {
+	schema := make(Schema)
+
+	for name, layer := range tile.Layers {
+		if !c.includeLayer(name) {
+			continue
+		}
+
+		layerSchema := &LayerSchema{Attributes: make(map[string]*AttributeSchema)}
+		for _, feature := range layer.Features {
+			types := feature.TagTypes
+			if len(c.options.PropertyFilter) > 0 {
+				types = make(map[string]string, len(feature.TagTypes))
+				for key, valueType := range feature.TagTypes {
+					if c.contains(c.options.PropertyFilter, key) {
+						types[key] = valueType
+					}
+				}
+			}
+			layerSchema.addFeature(types)
+		}
+		layerSchema.finalize()
+
+		schema[name] = layerSchema
+	}
+
+	return schema
+}
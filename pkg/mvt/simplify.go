@@ -0,0 +1,102 @@
+// pkg/mvt/simplify.go - Configurable geometry simplification
+package mvt
+
+import (
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/orb/planar"
+	"github.com/paulmach/orb/simplify"
+)
+
+// Simplification algorithm constants
+const (
+	SimplifyDouglasPeucker = "douglas-peucker"
+	SimplifyVisvalingam    = "visvalingam"
+	SimplifyRadial         = "radial"
+)
+
+// Simplification tolerance unit constants
+const (
+	ToleranceUnitPixels = "pixels" // Screen pixels at the tile's zoom level
+	ToleranceUnitMap    = "map"    // Units of the output coordinate system
+)
+
+// DefaultSimplifyTolerance is used when simplification is enabled without a tolerance
+const DefaultSimplifyTolerance = 1.0
+
+// tilePixelSize is the rendered size of a tile in screen pixels
+const tilePixelSize = 256
+
+// newSimplifier creates the configured simplifier for a tile at zoom level z
+func (c *Converter) newSimplifier(z int) orb.Simplifier {
+	tolerance := c.simplifyTolerance(z)
+
+	switch c.options.SimplifyAlgorithm {
+	case SimplifyVisvalingam:
+		// Visvalingam-Whyatt works on triangle areas rather than distances
+		return simplify.VisvalingamThreshold(tolerance * tolerance)
+	case SimplifyRadial:
+		return simplify.Radial(planar.Distance, tolerance)
+	default:
+		return simplify.DouglasPeucker(tolerance)
+	}
+}
+
+// simplifyTolerance resolves the configured tolerance into output coordinate units
+func (c *Converter) simplifyTolerance(z int) float64 {
+	tolerance := c.options.SimplifyTolerance
+	if tolerance <= 0 {
+		tolerance = DefaultSimplifyTolerance
+	}
+
+	if c.options.SimplifyUnits == ToleranceUnitMap {
+		return tolerance
+	}
+	return tolerance * c.pixelSize(z)
+}
+
+// pixelSize returns the width of one screen pixel at zoom z in output coordinate units
+func (c *Converter) pixelSize(z int) float64 {
+	pixels := float64(uint64(1)<<uint(z)) * tilePixelSize
+	if c.options.CoordinateSystem == CoordSystemWGS84 {
+		return 360.0 / pixels
+	}
+	return 2 * webMercatorMax / pixels
+}
+
+// simplifyFeatures simplifies feature geometries and drops features that collapse entirely
+func (c *Converter) simplifyFeatures(features []*geojson.Feature, z int) []*geojson.Feature {
+	simplifier := c.newSimplifier(z)
+
+	if c.options.PreserveTopology {
+		simplifyPreservingTopology(features, simplifier)
+	}
+
+	result := features[:0]
+	for _, feature := range features {
+		if feature.Geometry == nil {
+			continue
+		}
+
+		if !c.options.PreserveTopology || !isPolygonal(feature.Geometry) {
+			feature.Geometry = simplifier.Simplify(feature.Geometry)
+			if feature.Geometry == nil {
+				continue
+			}
+		}
+
+		result = append(result, feature)
+	}
+
+	return result
+}
+
+// isPolygonal reports whether a geometry is a polygon or multipolygon
+func isPolygonal(geometry orb.Geometry) bool {
+	switch geometry.(type) {
+	case orb.Polygon, orb.MultiPolygon:
+		return true
+	default:
+		return false
+	}
+}
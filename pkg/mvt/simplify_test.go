@@ -0,0 +1,136 @@
+// pkg/mvt/simplify_test.go - Unit tests for geometry simplification
+package mvt
+
+import (
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+func TestSimplifyTolerance(t *testing.T) {
+	tests := []struct {
+		name    string
+		options *ConversionOptions
+		z       int
+		want    float64
+	}{
+		{
+			name:    "pixels in web mercator at z0",
+			options: &ConversionOptions{CoordinateSystem: CoordSystemWebMercator, SimplifyTolerance: 1, SimplifyUnits: ToleranceUnitPixels},
+			z:       0,
+			want:    2 * webMercatorMax / 256,
+		},
+		{
+			name:    "pixels in wgs84 at z1",
+			options: &ConversionOptions{CoordinateSystem: CoordSystemWGS84, SimplifyTolerance: 2, SimplifyUnits: ToleranceUnitPixels},
+			z:       1,
+			want:    2 * 360.0 / 512,
+		},
+		{
+			name:    "map units ignore zoom",
+			options: &ConversionOptions{CoordinateSystem: CoordSystemWebMercator, SimplifyTolerance: 5, SimplifyUnits: ToleranceUnitMap},
+			z:       14,
+			want:    5,
+		},
+		{
+			name:    "default tolerance",
+			options: &ConversionOptions{CoordinateSystem: CoordSystemWebMercator, SimplifyUnits: ToleranceUnitMap},
+			z:       10,
+			want:    DefaultSimplifyTolerance,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter, err := NewConverterWithOptions(tt.options)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got := converter.simplifyTolerance(tt.z); abs(got-tt.want) > 1e-9 {
+				t.Errorf("simplifyTolerance() = %f, want %f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimplifyPreservingTopology(t *testing.T) {
+	// Two squares sharing a wiggly vertical edge at x=10
+	shared := []orb.Point{{10, 0}, {10.1, 2}, {9.9, 4}, {10.1, 6}, {9.9, 8}, {10, 10}}
+
+	left := orb.Ring{{0, 0}}
+	left = append(left, shared...)
+	left = append(left, orb.Point{0, 10}, orb.Point{0, 0})
+
+	right := orb.Ring{{20, 0}, {20, 10}}
+	for i := len(shared) - 1; i >= 0; i-- {
+		right = append(right, shared[i])
+	}
+	right = append(right, orb.Point{20, 0})
+
+	features := []*geojson.Feature{
+		geojson.NewFeature(orb.Polygon{left}),
+		geojson.NewFeature(orb.Polygon{right}),
+	}
+
+	converter, _ := NewConverterWithOptions(&ConversionOptions{
+		CoordinateSystem:  CoordSystemWebMercator,
+		SimplifyGeometry:  true,
+		SimplifyTolerance: 0.5,
+		SimplifyUnits:     ToleranceUnitMap,
+		PreserveTopology:  true,
+	})
+
+	result := converter.simplifyFeatures(features, 0)
+	if len(result) != 2 {
+		t.Fatalf("Expected 2 features, got %d", len(result))
+	}
+
+	onEdge := func(ring orb.Ring) map[orb.Point]bool {
+		points := make(map[orb.Point]bool)
+		for _, p := range ring {
+			if p[0] > 5 && p[0] < 15 {
+				points[p] = true
+			}
+		}
+		return points
+	}
+
+	leftEdge := onEdge(result[0].Geometry.(orb.Polygon)[0])
+	rightEdge := onEdge(result[1].Geometry.(orb.Polygon)[0])
+
+	if len(leftEdge) != len(rightEdge) {
+		t.Fatalf("Shared edge diverged: left %v, right %v", leftEdge, rightEdge)
+	}
+	for p := range leftEdge {
+		if !rightEdge[p] {
+			t.Errorf("Vertex %v missing from right polygon's shared edge", p)
+		}
+	}
+	if len(leftEdge) >= len(shared) {
+		t.Errorf("Expected shared edge to be simplified, got %d vertices", len(leftEdge))
+	}
+}
+
+func TestValidateSimplifyOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options *ConversionOptions
+		wantErr bool
+	}{
+		{"defaults", &ConversionOptions{CoordinateSystem: CoordSystemWebMercator}, false},
+		{"visvalingam", &ConversionOptions{CoordinateSystem: CoordSystemWebMercator, SimplifyAlgorithm: SimplifyVisvalingam}, false},
+		{"unknown algorithm", &ConversionOptions{CoordinateSystem: CoordSystemWebMercator, SimplifyAlgorithm: "chaikin"}, true},
+		{"unknown units", &ConversionOptions{CoordinateSystem: CoordSystemWebMercator, SimplifyUnits: "inches"}, true},
+		{"negative tolerance", &ConversionOptions{CoordinateSystem: CoordSystemWebMercator, SimplifyTolerance: -1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateConversionOptions(tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateConversionOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
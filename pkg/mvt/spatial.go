@@ -0,0 +1,769 @@
+// pkg/mvt/spatial.go - Area of interest filtering and clipping
+package mvt
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/orb/planar"
+)
+
+// Point locations relative to an area
+const (
+	locationOutside  = -1
+	locationBoundary = 0
+	locationInside   = 1
+)
+
+// maxIndexCells caps the number of grid cells per axis of a prepared area
+const maxIndexCells = 256
+
+// LoadAreaOfInterest reads a GeoJSON file holding a Polygon or MultiPolygon geometry,
+// Feature or FeatureCollection and returns all of its polygons as a MultiPolygon
+func LoadAreaOfInterest(path string) (orb.MultiPolygon, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read area of interest: %w", err)
+	}
+
+	var geometries []orb.Geometry
+	if collection, err := geojson.UnmarshalFeatureCollection(data); err == nil && collection.Type == "FeatureCollection" {
+		for _, feature := range collection.Features {
+			geometries = append(geometries, feature.Geometry)
+		}
+	} else if feature, err := geojson.UnmarshalFeature(data); err == nil && feature.Type == "Feature" {
+		geometries = append(geometries, feature.Geometry)
+	} else if geometry, err := geojson.UnmarshalGeometry(data); err == nil {
+		geometries = append(geometries, geometry.Geometry())
+	} else {
+		return nil, fmt.Errorf("failed to parse area of interest %s as GeoJSON", path)
+	}
+
+	var area orb.MultiPolygon
+	for _, geometry := range geometries {
+		switch g := geometry.(type) {
+		case orb.Polygon:
+			area = append(area, g)
+		case orb.MultiPolygon:
+			area = append(area, g...)
+		case nil:
+		default:
+			return nil, fmt.Errorf("area of interest must contain only polygons, found %s", g.GeoJSONType())
+		}
+	}
+
+	if len(area) == 0 {
+		return nil, fmt.Errorf("area of interest %s contains no polygons", path)
+	}
+
+	return area, nil
+}
+
+// segment is a directed line segment
+type segment struct {
+	a, b orb.Point
+}
+
+// bound returns the bounding box of the segment
+func (s segment) bound() orb.Bound {
+	return orb.Bound{Min: s.a, Max: s.a}.Extend(s.b)
+}
+
+// preparedArea indexes the edges of an area of interest for fast point location and
+// segment intersection queries against large polygons
+type preparedArea struct {
+	polygons orb.MultiPolygon
+	bound    orb.Bound
+	edges    []segment
+
+	// Edges bucketed by horizontal band for ray casting
+	bands      [][]int
+	bandHeight float64
+
+	// Edges bucketed by grid cell for intersection candidates
+	cells        [][]int
+	nx, ny       int
+	cellW, cellH float64
+}
+
+// prepareArea builds the edge indexes for an area. Rings are normalised so that
+// exteriors are counterclockwise and holes clockwise.
+func prepareArea(area orb.MultiPolygon) *preparedArea {
+	p := &preparedArea{
+		polygons: make(orb.MultiPolygon, 0, len(area)),
+		bound:    area.Bound(),
+	}
+
+	for _, polygon := range area {
+		normalized := orientPolygon(polygon)
+		p.polygons = append(p.polygons, normalized)
+		for _, ring := range normalized {
+			for i := 0; i+1 < len(ring); i++ {
+				if ring[i] != ring[i+1] {
+					p.edges = append(p.edges, segment{ring[i], ring[i+1]})
+				}
+			}
+		}
+	}
+
+	size := int(math.Ceil(math.Sqrt(float64(len(p.edges)))))
+	size = max(1, min(size, maxIndexCells))
+	p.nx, p.ny = size, size
+	p.cellW = math.Max(p.bound.Max[0]-p.bound.Min[0], 1e-12) / float64(p.nx)
+	p.cellH = math.Max(p.bound.Max[1]-p.bound.Min[1], 1e-12) / float64(p.ny)
+	p.bandHeight = p.cellH
+
+	p.bands = make([][]int, p.ny)
+	p.cells = make([][]int, p.nx*p.ny)
+
+	for i, edge := range p.edges {
+		minX, minY, maxX, maxY := p.cellRange(edge.bound())
+		for y := minY; y <= maxY; y++ {
+			p.bands[y] = append(p.bands[y], i)
+			for x := minX; x <= maxX; x++ {
+				p.cells[y*p.nx+x] = append(p.cells[y*p.nx+x], i)
+			}
+		}
+	}
+
+	return p
+}
+
+// cellRange returns the grid cells covered by a bound, clamped to the grid
+func (p *preparedArea) cellRange(b orb.Bound) (int, int, int, int) {
+	clamp := func(v, n int) int { return max(0, min(v, n-1)) }
+	minX := clamp(int((b.Min[0]-p.bound.Min[0])/p.cellW), p.nx)
+	maxX := clamp(int((b.Max[0]-p.bound.Min[0])/p.cellW), p.nx)
+	minY := clamp(int((b.Min[1]-p.bound.Min[1])/p.cellH), p.ny)
+	maxY := clamp(int((b.Max[1]-p.bound.Min[1])/p.cellH), p.ny)
+	return minX, minY, maxX, maxY
+}
+
+// candidateEdges calls fn once for every edge whose grid cells overlap the bound.
+// It does not modify the index, so a prepared area can be shared between goroutines.
+func (p *preparedArea) candidateEdges(b orb.Bound, fn func(edge segment)) {
+	if !b.Intersects(p.bound) {
+		return
+	}
+
+	minX, minY, maxX, maxY := p.cellRange(b)
+
+	// Edges spanning several cells are only reported once
+	var seen map[int]bool
+	if minX != maxX || minY != maxY {
+		seen = make(map[int]bool)
+	}
+
+	for y := minY; y <= maxY; y++ {
+		for x := minX; x <= maxX; x++ {
+			for _, i := range p.cells[y*p.nx+x] {
+				if seen != nil {
+					if seen[i] {
+						continue
+					}
+					seen[i] = true
+				}
+				if p.edges[i].bound().Intersects(b) {
+					fn(p.edges[i])
+				}
+			}
+		}
+	}
+}
+
+// locate reports whether a point lies inside, outside or on the boundary of the area
+func (p *preparedArea) locate(point orb.Point) int {
+	if !p.bound.Contains(point) {
+		return locationOutside
+	}
+
+	band := max(0, min(int((point[1]-p.bound.Min[1])/p.bandHeight), p.ny-1))
+	inside := false
+	for _, i := range p.bands[band] {
+		edge := p.edges[i]
+		if onSegment(edge, point) {
+			return locationBoundary
+		}
+		if rayCrosses(edge, point) {
+			inside = !inside
+		}
+	}
+
+	if inside {
+		return locationInside
+	}
+	return locationOutside
+}
+
+// intersects reports whether a geometry intersects the area
+func (p *preparedArea) intersects(geom orb.Geometry) bool {
+	if geom == nil || !geom.Bound().Intersects(p.bound) {
+		return false
+	}
+
+	switch g := geom.(type) {
+	case orb.Point:
+		return p.locate(g) != locationOutside
+	case orb.MultiPoint:
+		for _, point := range g {
+			if p.locate(point) != locationOutside {
+				return true
+			}
+		}
+		return false
+	case orb.LineString:
+		return p.lineIntersects(g)
+	case orb.MultiLineString:
+		for _, line := range g {
+			if p.lineIntersects(line) {
+				return true
+			}
+		}
+		return false
+	case orb.Polygon:
+		return p.polygonIntersects(g)
+	case orb.MultiPolygon:
+		for _, polygon := range g {
+			if p.polygonIntersects(polygon) {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+// lineIntersects reports whether any vertex of a line lies in the area or any segment
+// crosses its boundary
+func (p *preparedArea) lineIntersects(line orb.LineString) bool {
+	for _, point := range line {
+		if p.locate(point) != locationOutside {
+			return true
+		}
+	}
+
+	for i := 0; i+1 < len(line); i++ {
+		s := segment{line[i], line[i+1]}
+		crosses := false
+		p.candidateEdges(s.bound(), func(edge segment) {
+			if !crosses && len(intersectSegments(s, edge)) > 0 {
+				crosses = true
+			}
+		})
+		if crosses {
+			return true
+		}
+	}
+
+	return false
+}
+
+// polygonIntersects reports whether a polygon intersects the area, including the case
+// where the area lies entirely within the polygon
+func (p *preparedArea) polygonIntersects(polygon orb.Polygon) bool {
+	if len(polygon) == 0 {
+		return false
+	}
+
+	for _, ring := range polygon {
+		if p.lineIntersects(orb.LineString(ring)) {
+			return true
+		}
+	}
+
+	for _, areaPolygon := range p.polygons {
+		if len(areaPolygon) > 0 && len(areaPolygon[0]) > 0 && planar.PolygonContains(polygon, areaPolygon[0][0]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clip returns the part of a geometry inside the area, or nil when nothing remains
+func (p *preparedArea) clip(geom orb.Geometry) orb.Geometry {
+	switch g := geom.(type) {
+	case orb.Point:
+		if p.locate(g) != locationOutside {
+			return g
+		}
+		return nil
+	case orb.MultiPoint:
+		result := make(orb.MultiPoint, 0, len(g))
+		for _, point := range g {
+			if p.locate(point) != locationOutside {
+				result = append(result, point)
+			}
+		}
+		return collapseMultiPoint(result)
+	case orb.LineString:
+		return collapseMultiLineString(p.clipLine(g))
+	case orb.MultiLineString:
+		var result orb.MultiLineString
+		for _, line := range g {
+			result = append(result, p.clipLine(line)...)
+		}
+		return collapseMultiLineString(result)
+	case orb.Polygon:
+		return collapseMultiPolygon(p.clipPolygon(g))
+	case orb.MultiPolygon:
+		var result orb.MultiPolygon
+		for _, polygon := range g {
+			result = append(result, p.clipPolygon(polygon)...)
+		}
+		return collapseMultiPolygon(result)
+	default:
+		return geom
+	}
+}
+
+// clipLine splits a line at the area boundary and keeps the pieces inside it
+func (p *preparedArea) clipLine(line orb.LineString) orb.MultiLineString {
+	var result orb.MultiLineString
+	var current orb.LineString
+
+	flush := func() {
+		if len(current) >= 2 {
+			result = append(result, current)
+		}
+		current = nil
+	}
+
+	for i := 0; i+1 < len(line); i++ {
+		s := segment{line[i], line[i+1]}
+		var splits []orb.Point
+		p.candidateEdges(s.bound(), func(edge segment) {
+			splits = append(splits, intersectSegments(s, edge)...)
+		})
+
+		points := splitPoints(s, splits)
+		for j := 0; j+1 < len(points); j++ {
+			mid := orb.Point{(points[j][0] + points[j+1][0]) / 2, (points[j][1] + points[j+1][1]) / 2}
+			if p.locate(mid) == locationOutside {
+				flush()
+				continue
+			}
+			if len(current) == 0 || current[len(current)-1] != points[j] {
+				flush()
+				current = orb.LineString{points[j]}
+			}
+			current = append(current, points[j+1])
+		}
+	}
+	flush()
+
+	return result
+}
+
+// clipPolygon intersects a polygon with the area. Both boundaries are split at their
+// intersections; subject pieces inside the area and area pieces inside the subject are
+// kept and reassembled into rings.
+func (p *preparedArea) clipPolygon(polygon orb.Polygon) orb.MultiPolygon {
+	if len(polygon) == 0 {
+		return nil
+	}
+
+	subjectBound := polygon.Bound()
+	if !subjectBound.Intersects(p.bound) {
+		return nil
+	}
+
+	subject := orientPolygon(polygon)
+	var subjectEdges []segment
+	for _, ring := range subject {
+		for i := 0; i+1 < len(ring); i++ {
+			if ring[i] != ring[i+1] {
+				subjectEdges = append(subjectEdges, segment{ring[i], ring[i+1]})
+			}
+		}
+	}
+
+	// Area edges near the subject, with the split points of both edge sets
+	var areaEdges []segment
+	areaIndex := make(map[segment]int)
+	p.candidateEdges(subjectBound, func(edge segment) {
+		areaIndex[edge] = len(areaEdges)
+		areaEdges = append(areaEdges, edge)
+	})
+
+	subjectSplits := make([][]orb.Point, len(subjectEdges))
+	areaSplits := make([][]orb.Point, len(areaEdges))
+	for i, s := range subjectEdges {
+		p.candidateEdges(s.bound(), func(edge segment) {
+			j, exists := areaIndex[edge]
+			if !exists {
+				return
+			}
+			points := intersectSegments(s, edge)
+			subjectSplits[i] = append(subjectSplits[i], points...)
+			areaSplits[j] = append(areaSplits[j], points...)
+		})
+	}
+
+	subjectPieces := splitEdges(subjectEdges, subjectSplits)
+	areaPieces := splitEdges(areaEdges, areaSplits)
+
+	areaPieceSet := make(map[segment]bool, len(areaPieces))
+	for _, piece := range areaPieces {
+		areaPieceSet[piece] = true
+	}
+	subjectPieceSet := make(map[segment]bool, len(subjectPieces))
+	for _, piece := range subjectPieces {
+		subjectPieceSet[piece] = true
+	}
+
+	var kept []segment
+	for _, piece := range subjectPieces {
+		// Coincident boundaries are kept once when both interiors lie on the same side
+		if areaPieceSet[piece] {
+			kept = append(kept, piece)
+			continue
+		}
+		if areaPieceSet[segment{piece.b, piece.a}] {
+			continue
+		}
+		if p.locate(midpoint(piece)) != locationOutside {
+			kept = append(kept, piece)
+		}
+	}
+	for _, piece := range areaPieces {
+		if subjectPieceSet[piece] || subjectPieceSet[segment{piece.b, piece.a}] {
+			continue
+		}
+		if locatePolygon(subject, midpoint(piece)) == locationInside {
+			kept = append(kept, piece)
+		}
+	}
+
+	return assemblePolygons(kept)
+}
+
+// splitEdges splits every edge at its split points
+func splitEdges(edges []segment, splits [][]orb.Point) []segment {
+	pieces := make([]segment, 0, len(edges))
+	for i, edge := range edges {
+		points := splitPoints(edge, splits[i])
+		for j := 0; j+1 < len(points); j++ {
+			pieces = append(pieces, segment{points[j], points[j+1]})
+		}
+	}
+	return pieces
+}
+
+// splitPoints returns the segment's endpoints with the split points between them,
+// ordered along the segment and without duplicates
+func splitPoints(s segment, splits []orb.Point) []orb.Point {
+	points := make([]orb.Point, 0, len(splits)+2)
+	points = append(points, s.a)
+	for _, point := range splits {
+		if point != s.a && point != s.b {
+			points = append(points, point)
+		}
+	}
+	points = append(points, s.b)
+
+	if len(points) > 3 {
+		inner := points[1 : len(points)-1]
+		sort.Slice(inner, func(i, j int) bool {
+			return distanceSquared(s.a, inner[i]) < distanceSquared(s.a, inner[j])
+		})
+	}
+
+	result := points[:1]
+	for _, point := range points[1:] {
+		if point != result[len(result)-1] {
+			result = append(result, point)
+		}
+	}
+	return result
+}
+
+// assemblePolygons links directed boundary pieces into rings and groups the
+// counterclockwise shells with the clockwise holes they contain
+func assemblePolygons(pieces []segment) orb.MultiPolygon {
+	outgoing := make(map[orb.Point][]int, len(pieces))
+	for i, piece := range pieces {
+		outgoing[piece.a] = append(outgoing[piece.a], i)
+	}
+
+	used := make([]bool, len(pieces))
+	var shells, holes []orb.Ring
+
+	for start := range pieces {
+		if used[start] {
+			continue
+		}
+
+		ring := orb.Ring{pieces[start].a}
+		current := start
+		closed := false
+		for !used[current] {
+			used[current] = true
+			end := pieces[current].b
+			ring = append(ring, end)
+			if end == ring[0] {
+				closed = true
+				break
+			}
+
+			next := -1
+			for _, candidate := range outgoing[end] {
+				if !used[candidate] {
+					next = candidate
+					break
+				}
+			}
+			if next < 0 {
+				break
+			}
+			current = next
+		}
+
+		if !closed || len(ring) < 4 {
+			continue
+		}
+
+		area := planar.Area(ring)
+		switch {
+		case area > 0:
+			shells = append(shells, ring)
+		case area < 0:
+			holes = append(holes, ring)
+		}
+	}
+
+	result := make(orb.MultiPolygon, len(shells))
+	for i, shell := range shells {
+		result[i] = orb.Polygon{shell}
+	}
+
+	for _, hole := range holes {
+		best := -1
+		bestArea := math.Inf(1)
+		for i, shell := range shells {
+			if !ringContainsRing(shell, hole) {
+				continue
+			}
+			if area := planar.Area(shell); area < bestArea {
+				best, bestArea = i, area
+			}
+		}
+		if best >= 0 {
+			result[best] = append(result[best], hole)
+		}
+	}
+
+	return result
+}
+
+// ringContainsRing reports whether a shell contains a hole, judged by the first hole
+// vertex that does not lie on the shell's boundary
+func ringContainsRing(shell, hole orb.Ring) bool {
+	if !shell.Bound().Contains(hole.Bound().Min) || !shell.Bound().Contains(hole.Bound().Max) {
+		return false
+	}
+	for _, point := range hole {
+		switch locatePolygon(orb.Polygon{shell}, point) {
+		case locationInside:
+			return true
+		case locationOutside:
+			return false
+		}
+	}
+	return true
+}
+
+// locatePolygon locates a point relative to a polygon without an index
+func locatePolygon(polygon orb.Polygon, point orb.Point) int {
+	if !polygon.Bound().Contains(point) {
+		return locationOutside
+	}
+
+	inside := false
+	for _, ring := range polygon {
+		for i := 0; i+1 < len(ring); i++ {
+			edge := segment{ring[i], ring[i+1]}
+			if onSegment(edge, point) {
+				return locationBoundary
+			}
+			if rayCrosses(edge, point) {
+				inside = !inside
+			}
+		}
+	}
+
+	if inside {
+		return locationInside
+	}
+	return locationOutside
+}
+
+// intersectSegments returns the points where two segments meet. Endpoints are returned
+// exactly so that both boundaries are split at identical coordinates.
+func intersectSegments(s, t segment) []orb.Point {
+	r := orb.Point{s.b[0] - s.a[0], s.b[1] - s.a[1]}
+	q := orb.Point{t.b[0] - t.a[0], t.b[1] - t.a[1]}
+	d := cross(r, q)
+	w := orb.Point{t.a[0] - s.a[0], t.a[1] - s.a[1]}
+
+	if d == 0 {
+		if cross(w, r) != 0 {
+			return nil // parallel
+		}
+		// Collinear: the overlap is bounded by endpoints lying on the other segment
+		var points []orb.Point
+		for _, point := range []orb.Point{t.a, t.b} {
+			if onSegment(s, point) {
+				points = append(points, point)
+			}
+		}
+		for _, point := range []orb.Point{s.a, s.b} {
+			if onSegment(t, point) {
+				points = append(points, point)
+			}
+		}
+		return points
+	}
+
+	u := cross(w, q) / d
+	v := cross(w, r) / d
+	if u < 0 || u > 1 || v < 0 || v > 1 {
+		return nil
+	}
+
+	switch {
+	case u == 0:
+		return []orb.Point{s.a}
+	case u == 1:
+		return []orb.Point{s.b}
+	case v == 0:
+		return []orb.Point{t.a}
+	case v == 1:
+		return []orb.Point{t.b}
+	}
+	return []orb.Point{{s.a[0] + u*r[0], s.a[1] + u*r[1]}}
+}
+
+// onSegment reports whether a point lies exactly on a segment
+func onSegment(s segment, point orb.Point) bool {
+	if cross(orb.Point{s.b[0] - s.a[0], s.b[1] - s.a[1]}, orb.Point{point[0] - s.a[0], point[1] - s.a[1]}) != 0 {
+		return false
+	}
+	return s.bound().Contains(point)
+}
+
+// rayCrosses reports whether a ray cast from the point towards +x crosses the segment
+func rayCrosses(s segment, point orb.Point) bool {
+	if (s.a[1] > point[1]) == (s.b[1] > point[1]) {
+		return false
+	}
+	x := s.a[0] + (point[1]-s.a[1])*(s.b[0]-s.a[0])/(s.b[1]-s.a[1])
+	return point[0] < x
+}
+
+// orientPolygon returns a closed copy of a polygon with a counterclockwise exterior
+// and clockwise holes
+func orientPolygon(polygon orb.Polygon) orb.Polygon {
+	result := make(orb.Polygon, 0, len(polygon))
+	for i, ring := range polygon {
+		if len(ring) == 0 {
+			continue
+		}
+		oriented := make(orb.Ring, len(ring), len(ring)+1)
+		copy(oriented, ring)
+		if oriented[0] != oriented[len(oriented)-1] {
+			oriented = append(oriented, oriented[0])
+		}
+
+		area := planar.Area(oriented)
+		if (i == 0 && area < 0) || (i > 0 && area > 0) {
+			oriented.Reverse()
+		}
+		result = append(result, oriented)
+	}
+	return result
+}
+
+// cross returns the z component of the cross product of two vectors
+func cross(a, b orb.Point) float64 {
+	return a[0]*b[1] - a[1]*b[0]
+}
+
+// midpoint returns the midpoint of a segment
+func midpoint(s segment) orb.Point {
+	return orb.Point{(s.a[0] + s.b[0]) / 2, (s.a[1] + s.b[1]) / 2}
+}
+
+// distanceSquared returns the squared distance between two points
+func distanceSquared(a, b orb.Point) float64 {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	return dx*dx + dy*dy
+}
+
+// collapseMultiPoint returns nil, a Point or a MultiPoint depending on the number of points
+func collapseMultiPoint(points orb.MultiPoint) orb.Geometry {
+	switch len(points) {
+	case 0:
+		return nil
+	case 1:
+		return points[0]
+	}
+	return points
+}
+
+// collapseMultiLineString returns nil, a LineString or a MultiLineString depending on the number of lines
+func collapseMultiLineString(lines orb.MultiLineString) orb.Geometry {
+	switch len(lines) {
+	case 0:
+		return nil
+	case 1:
+		return lines[0]
+	}
+	return lines
+}
+
+// collapseMultiPolygon returns nil, a Polygon or a MultiPolygon depending on the number of polygons
+func collapseMultiPolygon(polygons orb.MultiPolygon) orb.Geometry {
+	switch len(polygons) {
+	case 0:
+		return nil
+	case 1:
+		return polygons[0]
+	}
+	return polygons
+}
+
+// newPreparedArea projects an area of interest given in WGS84 to the output
+// coordinate system and prepares it for queries
+func newPreparedArea(area orb.MultiPolygon, coordinateSystem string) *preparedArea {
+	if coordinateSystem == CoordSystemWebMercator {
+		area = applyGeometryTransform(area, wgs84ToWebMercator).(orb.MultiPolygon)
+	}
+	return prepareArea(area)
+}
+
+// filterByArea keeps the features intersecting the area of interest, clipping them to
+// it when requested
+func (c *Converter) filterByArea(features []*geojson.Feature) []*geojson.Feature {
+	result := features[:0]
+	for _, feature := range features {
+		if !c.area.intersects(feature.Geometry) {
+			continue
+		}
+
+		if c.options.ClipToArea {
+			feature.Geometry = c.area.clip(feature.Geometry)
+			if feature.Geometry == nil {
+				continue
+			}
+		}
+
+		result = append(result, feature)
+	}
+	return result
+}
@@ -0,0 +1,124 @@
+// pkg/mvt/spatial_test.go - Unit tests for area of interest filtering and clipping
+package mvt
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/planar"
+)
+
+func square(minX, minY, maxX, maxY float64) orb.Ring {
+	return orb.Ring{{minX, minY}, {maxX, minY}, {maxX, maxY}, {minX, maxY}, {minX, minY}}
+}
+
+func TestPreparedAreaIntersects(t *testing.T) {
+	area := prepareArea(orb.MultiPolygon{{square(0, 0, 10, 10), square(4, 4, 6, 6)}})
+
+	tests := []struct {
+		name string
+		geom orb.Geometry
+		want bool
+	}{
+		{"point inside", orb.Point{1, 1}, true},
+		{"point in hole", orb.Point{5, 5}, false},
+		{"point on boundary", orb.Point{0, 5}, true},
+		{"point outside", orb.Point{20, 20}, false},
+		{"line crossing without inner vertices", orb.LineString{{-5, 2}, {15, 2}}, true},
+		{"line outside", orb.LineString{{-5, -5}, {-1, 20}}, false},
+		{"line inside hole", orb.LineString{{4.5, 4.5}, {5.5, 5.5}}, false},
+		{"polygon overlapping", orb.Polygon{square(8, 8, 12, 12)}, true},
+		{"polygon containing area", orb.Polygon{square(-10, -10, 20, 20)}, true},
+		{"polygon disjoint", orb.Polygon{square(20, 20, 30, 30)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := area.intersects(tt.geom); got != tt.want {
+				t.Errorf("intersects() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreparedAreaClipLine(t *testing.T) {
+	area := prepareArea(orb.MultiPolygon{{square(0, 0, 10, 10), square(4, 4, 6, 6)}})
+
+	result, ok := area.clip(orb.LineString{{-5, 5}, {15, 5}}).(orb.MultiLineString)
+	if !ok || len(result) != 2 {
+		t.Fatalf("Expected line split around the hole into 2 parts, got %v", result)
+	}
+
+	want := orb.MultiLineString{{{0, 5}, {4, 5}}, {{6, 5}, {10, 5}}}
+	for i := range want {
+		if !result[i].Equal(want[i]) {
+			t.Errorf("Part %d = %v, want %v", i, result[i], want[i])
+		}
+	}
+}
+
+func TestPreparedAreaClipPolygon(t *testing.T) {
+	tests := []struct {
+		name     string
+		area     orb.MultiPolygon
+		subject  orb.Polygon
+		wantArea float64
+	}{
+		{"overlapping squares", orb.MultiPolygon{{square(0, 0, 10, 10)}}, orb.Polygon{square(5, 5, 15, 15)}, 25},
+		{"subject inside", orb.MultiPolygon{{square(0, 0, 10, 10)}}, orb.Polygon{square(2, 2, 4, 4)}, 4},
+		{"area inside subject", orb.MultiPolygon{{square(2, 2, 4, 4)}}, orb.Polygon{square(0, 0, 10, 10)}, 4},
+		{"shared edge", orb.MultiPolygon{{square(0, 0, 10, 10)}}, orb.Polygon{square(0, 0, 5, 10)}, 50},
+		{"area with hole", orb.MultiPolygon{{square(0, 0, 10, 10), square(4, 4, 6, 6)}}, orb.Polygon{square(2, 2, 8, 8)}, 32},
+		{"clockwise subject", orb.MultiPolygon{{square(0, 0, 10, 10)}}, orb.Polygon{{{5, 5}, {5, 15}, {15, 15}, {15, 5}, {5, 5}}}, 25},
+		{"disjoint", orb.MultiPolygon{{square(0, 0, 10, 10)}}, orb.Polygon{square(20, 20, 30, 30)}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := prepareArea(tt.area).clip(tt.subject)
+
+			var got float64
+			if result != nil {
+				got = planar.Area(result)
+				bound := result.Bound()
+				if !tt.area.Bound().Contains(bound.Min) || !tt.area.Bound().Contains(bound.Max) {
+					t.Errorf("Clipped geometry %v extends beyond the area", bound)
+				}
+			}
+			if math.Abs(got-tt.wantArea) > 1e-9 {
+				t.Errorf("Clipped area = %f, want %f", got, tt.wantArea)
+			}
+		})
+	}
+}
+
+func TestLoadAreaOfInterest(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "area.geojson")
+	content := `{"type":"FeatureCollection","features":[
+		{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}},
+		{"type":"Feature","properties":{},"geometry":{"type":"MultiPolygon","coordinates":[[[[2,2],[3,2],[3,3],[2,3],[2,2]]]]}}
+	]}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	area, err := LoadAreaOfInterest(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(area) != 2 {
+		t.Errorf("Expected 2 polygons, got %d", len(area))
+	}
+
+	pointPath := filepath.Join(dir, "point.geojson")
+	if err := os.WriteFile(pointPath, []byte(`{"type":"Point","coordinates":[0,0]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadAreaOfInterest(pointPath); err == nil {
+		t.Error("Expected error for non-polygon area of interest")
+	}
+}
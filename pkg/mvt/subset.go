@@ -0,0 +1,116 @@
+// pkg/mvt/subset.go - Filtering and re-encoding of MVT tiles
+package mvt
+
+import (
+	"fmt"
+
+	"github.com/paulmach/orb/encoding/mvt"
+	"github.com/paulmach/orb/geojson"
+)
+
+// SubsetResult holds a tile re-encoded by Subset and what was kept
+type SubsetResult struct {
+	Data            []byte `json:"-"`
+	FeatureCount    int    `json:"feature_count"`
+	FeaturesDropped int    `json:"features_dropped"`
+	LayersDropped   int    `json:"layers_dropped"`
+	Schema          Schema `json:"schema,omitempty"`
+}
+
+// Subset applies the converter's layer, property and feature filters to a tile and
+// re-encodes the result as MVT. Geometry stays in tile coordinates and layers keep
+// their extent and version; feature IDs and attribute types are preserved, except
+// that int values are re-encoded as sint. Layers left without features are removed,
+// so a tile whose FeatureCount is zero has no layers.
+func (c *Converter) Subset(data []byte, z int) (*SubsetResult, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty tile data")
+	}
+	if DetectTileFormat(data, "") == TileFormatMLT {
+		return nil, fmt.Errorf("subsetting MLT tiles is not supported")
+	}
+
+	layers, err := mvt.Unmarshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal MVT data: %w", err)
+	}
+
+	typed, err := decodeTypedAttributes(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode MVT attributes: %w", err)
+	}
+
+	result := &SubsetResult{Schema: make(Schema)}
+	kept := make(mvt.Layers, 0, len(layers))
+
+	for i, layer := range layers {
+		if !c.includeLayer(layer.Name) {
+			result.LayersDropped++
+			result.FeaturesDropped += len(layer.Features)
+			continue
+		}
+
+		filter := c.layerFilter(layer.Name)
+		layerSchema := &LayerSchema{Attributes: make(map[string]*AttributeSchema)}
+		features := make([]*geojson.Feature, 0, len(layer.Features))
+
+		for j, feature := range layer.Features {
+			if feature.Geometry == nil {
+				result.FeaturesDropped++
+				continue
+			}
+
+			decoded := &DecodedFeature{
+				ID:       feature.ID,
+				Tags:     feature.Properties,
+				Type:     feature.Geometry.GeoJSONType(),
+				Geometry: feature.Geometry,
+			}
+			if i < len(typed) && j < len(typed[i]) {
+				decoded.ID = typed[i][j].id
+				decoded.Tags = typed[i][j].tags
+				decoded.TagTypes = typed[i][j].types
+			}
+
+			if filter != nil && !filter.Matches(decoded, z) {
+				result.FeaturesDropped++
+				continue
+			}
+
+			tags, types := decoded.Tags, decoded.TagTypes
+			if len(c.options.PropertyFilter) > 0 {
+				tags = make(map[string]interface{}, len(c.options.PropertyFilter))
+				types = make(map[string]string, len(c.options.PropertyFilter))
+				for key, value := range decoded.Tags {
+					if c.contains(c.options.PropertyFilter, key) {
+						tags[key] = value
+						types[key] = decoded.TagTypes[key]
+					}
+				}
+			}
+
+			feature.ID = decoded.ID
+			feature.Properties = geojson.Properties(tags)
+			features = append(features, feature)
+			layerSchema.addFeature(types)
+		}
+
+		if len(features) == 0 {
+			result.LayersDropped++
+			continue
+		}
+
+		layer.Features = features
+		kept = append(kept, layer)
+		layerSchema.finalize()
+		result.Schema[layer.Name] = layerSchema
+		result.FeatureCount += len(features)
+	}
+
+	result.Data, err = mvt.Marshal(kept)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode MVT data: %w", err)
+	}
+
+	return result, nil
+}
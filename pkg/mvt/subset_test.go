@@ -0,0 +1,73 @@
+// pkg/mvt/subset_test.go - Unit tests for tile subsetting
+package mvt
+
+import (
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/mvt"
+	"github.com/paulmach/orb/geojson"
+)
+
+func TestSubset(t *testing.T) {
+	motorway := geojson.NewFeature(orb.LineString{{0, 0}, {100, 100}})
+	motorway.ID = uint64(11)
+	motorway.Properties = geojson.Properties{"class": "motorway", "ratio": float32(0.5), "name": "A1"}
+
+	path := geojson.NewFeature(orb.LineString{{10, 10}, {20, 20}})
+	path.ID = uint64(12)
+	path.Properties = geojson.Properties{"class": "path"}
+
+	poi := geojson.NewFeature(orb.Point{5, 5})
+	poi.Properties = geojson.Properties{"name": "cafe"}
+
+	data, err := mvt.Marshal(mvt.Layers{
+		{Name: "roads", Version: 2, Extent: 512, Features: []*geojson.Feature{motorway, path}},
+		{Name: "pois", Version: 2, Extent: 4096, Features: []*geojson.Feature{poi}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode tile: %v", err)
+	}
+
+	converter, err := NewConverterWithOptions(&ConversionOptions{
+		CoordinateSystem: CoordSystemWebMercator,
+		ExcludeLayers:    []string{"pois"},
+		PropertyFilter:   []string{"class", "ratio"},
+		Filters:          map[string]interface{}{"roads": `["!=", "class", "path"]`},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	result, err := converter.Subset(data, 14)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.FeatureCount != 1 || result.FeaturesDropped != 2 || result.LayersDropped != 1 {
+		t.Errorf("Unexpected counts: kept %d, dropped %d features and %d layers",
+			result.FeatureCount, result.FeaturesDropped, result.LayersDropped)
+	}
+
+	layers, err := mvt.Unmarshal(result.Data)
+	if err != nil {
+		t.Fatalf("Failed to decode subset tile: %v", err)
+	}
+	if len(layers) != 1 || layers[0].Name != "roads" || layers[0].Extent != 512 {
+		t.Fatalf("Expected only the roads layer with extent 512, got %v", layers)
+	}
+
+	tile, err := NewDecoder().Decode(result.Data, 14, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	feature := tile.Layers["roads"].Features[0]
+	if feature.ID != uint64(11) {
+		t.Errorf("Expected feature ID 11, got %v", feature.ID)
+	}
+	if len(feature.Tags) != 2 || feature.TagTypes["ratio"] != ValueTypeFloat {
+		t.Errorf("Expected class and float ratio, got %v %v", feature.Tags, feature.TagTypes)
+	}
+	if result.Schema["roads"] == nil || result.Schema["roads"].FeatureCount != 1 {
+		t.Errorf("Expected schema for the kept roads feature, got %v", result.Schema)
+	}
+}
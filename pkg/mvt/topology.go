@@ -0,0 +1,252 @@
+// pkg/mvt/topology.go - Shared-edge aware polygon simplification
+package mvt
+
+import (
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+// vertexNeighbors records the neighbours a vertex was first seen with
+type vertexNeighbors struct {
+	prev, next orb.Point
+}
+
+// simplifyPreservingTopology simplifies all polygon rings of the given features so
+// that boundaries shared between rings are simplified identically and stay coincident.
+// Features whose polygons collapse entirely get a nil geometry.
+func simplifyPreservingTopology(features []*geojson.Feature, simplifier orb.Simplifier) {
+	var rings []orb.Ring
+	for _, feature := range features {
+		switch g := feature.Geometry.(type) {
+		case orb.Polygon:
+			rings = append(rings, g...)
+		case orb.MultiPolygon:
+			for _, polygon := range g {
+				rings = append(rings, polygon...)
+			}
+		}
+	}
+
+	if len(rings) == 0 {
+		return
+	}
+
+	junctions := findJunctions(rings)
+
+	for _, feature := range features {
+		switch g := feature.Geometry.(type) {
+		case orb.Polygon:
+			if polygon := simplifyPolygonTopology(g, junctions, simplifier); polygon != nil {
+				feature.Geometry = polygon
+			} else {
+				feature.Geometry = nil
+			}
+		case orb.MultiPolygon:
+			result := make(orb.MultiPolygon, 0, len(g))
+			for _, polygon := range g {
+				if simplified := simplifyPolygonTopology(polygon, junctions, simplifier); simplified != nil {
+					result = append(result, simplified)
+				}
+			}
+			if len(result) > 0 {
+				feature.Geometry = result
+			} else {
+				feature.Geometry = nil
+			}
+		}
+	}
+}
+
+// findJunctions returns the vertices at which shared ring boundaries begin or end.
+// A vertex is a junction when it occurs more than once with different neighbours.
+func findJunctions(rings []orb.Ring) map[orb.Point]bool {
+	seen := make(map[orb.Point]vertexNeighbors)
+	junctions := make(map[orb.Point]bool)
+
+	for _, ring := range rings {
+		n := openLength(ring)
+		if n < 3 {
+			continue
+		}
+
+		for i := 0; i < n; i++ {
+			point := ring[i]
+			prev := ring[(i+n-1)%n]
+			next := ring[(i+1)%n]
+
+			neighbors, exists := seen[point]
+			if !exists {
+				seen[point] = vertexNeighbors{prev: prev, next: next}
+				continue
+			}
+
+			sameDirection := neighbors.prev == prev && neighbors.next == next
+			oppositeDirection := neighbors.prev == next && neighbors.next == prev
+			if !sameDirection && !oppositeDirection {
+				junctions[point] = true
+			}
+		}
+	}
+
+	return junctions
+}
+
+// simplifyPolygonTopology simplifies each ring of a polygon along its junction-delimited arcs.
+// It returns nil when the exterior ring collapses; collapsed holes are dropped.
+func simplifyPolygonTopology(polygon orb.Polygon, junctions map[orb.Point]bool, simplifier orb.Simplifier) orb.Polygon {
+	result := make(orb.Polygon, 0, len(polygon))
+
+	for i, ring := range polygon {
+		simplified := simplifyRingTopology(ring, junctions, simplifier)
+		if len(simplified) < 4 {
+			if i == 0 {
+				return nil
+			}
+			continue
+		}
+		result = append(result, simplified)
+	}
+
+	return result
+}
+
+// simplifyRingTopology splits a ring at junctions, simplifies each arc and reassembles the ring
+func simplifyRingTopology(ring orb.Ring, junctions map[orb.Point]bool, simplifier orb.Simplifier) orb.Ring {
+	arcs := splitRingArcs(ring, junctions)
+	if arcs == nil {
+		return ring
+	}
+
+	// A ring without junctions is a single closed arc, shared (if at all) in its entirety
+	if len(arcs) == 1 && !junctions[arcs[0][0]] {
+		return simplifyClosedArc(arcs[0], simplifier)
+	}
+
+	result := orb.Ring{arcs[0][0]}
+	for _, arc := range arcs {
+		simplified := simplifyArc(arc, simplifier)
+		result = append(result, simplified[1:]...)
+	}
+
+	return result
+}
+
+// splitRingArcs splits a closed ring into arcs delimited by junction vertices.
+// Rings without junctions are returned as a single closed arc rotated to a canonical start.
+// It returns nil for rings with fewer than three distinct vertices.
+func splitRingArcs(ring orb.Ring, junctions map[orb.Point]bool) []orb.LineString {
+	n := openLength(ring)
+	if n < 3 {
+		return nil
+	}
+
+	start := -1
+	for i := 0; i < n; i++ {
+		if junctions[ring[i]] {
+			start = i
+			break
+		}
+	}
+
+	if start < 0 {
+		// Start at the lowest vertex so that every copy of a shared ring agrees
+		start = 0
+		for i := 1; i < n; i++ {
+			if pointLess(ring[i], ring[start]) {
+				start = i
+			}
+		}
+
+		arc := make(orb.LineString, 0, n+1)
+		for i := 0; i <= n; i++ {
+			arc = append(arc, ring[(start+i)%n])
+		}
+		return []orb.LineString{arc}
+	}
+
+	points := make(orb.LineString, 0, n+1)
+	for i := 0; i <= n; i++ {
+		points = append(points, ring[(start+i)%n])
+	}
+
+	var arcs []orb.LineString
+	arcStart := 0
+	for i := 1; i < len(points); i++ {
+		if i == len(points)-1 || junctions[points[i]] {
+			arc := make(orb.LineString, i-arcStart+1)
+			copy(arc, points[arcStart:i+1])
+			arcs = append(arcs, arc)
+			arcStart = i
+		}
+	}
+
+	return arcs
+}
+
+// simplifyArc simplifies an open arc in a canonical direction so that both
+// rings sharing the arc produce identical results
+func simplifyArc(arc orb.LineString, simplifier orb.Simplifier) orb.LineString {
+	if len(arc) <= 2 {
+		return arc
+	}
+
+	reversed := arcReversed(arc)
+	work := make(orb.LineString, len(arc))
+	copy(work, arc)
+	if reversed {
+		work.Reverse()
+	}
+
+	simplified := simplifier.LineString(work)
+	if reversed {
+		simplified.Reverse()
+	}
+
+	return simplified
+}
+
+// simplifyClosedArc simplifies a junction-free ring in a canonical direction
+func simplifyClosedArc(arc orb.LineString, simplifier orb.Simplifier) orb.Ring {
+	work := make(orb.Ring, len(arc))
+	copy(work, arc)
+
+	// arc starts at its lowest vertex; walk towards the lower of its two neighbours
+	reversed := pointLess(work[len(work)-2], work[1])
+	if reversed {
+		work.Reverse()
+	}
+
+	simplified := simplifier.Ring(work)
+	if reversed {
+		simplified.Reverse()
+	}
+
+	return simplified
+}
+
+// arcReversed reports whether an arc runs against its canonical direction
+func arcReversed(arc orb.LineString) bool {
+	first, last := arc[0], arc[len(arc)-1]
+	if first != last {
+		return pointLess(last, first)
+	}
+	// Arcs that loop back to the same junction are ordered by their inner vertices
+	return pointLess(arc[len(arc)-2], arc[1])
+}
+
+// pointLess orders points lexicographically by x, then y
+func pointLess(a, b orb.Point) bool {
+	if a[0] != b[0] {
+		return a[0] < b[0]
+	}
+	return a[1] < b[1]
+}
+
+// openLength returns the number of distinct vertices of a ring, ignoring the closing point
+func openLength(ring orb.Ring) int {
+	n := len(ring)
+	if n > 1 && ring[0] == ring[n-1] {
+		return n - 1
+	}
+	return n
+}
@@ -0,0 +1,222 @@
+// pkg/mvt/values.go - Typed MVT attribute values
+package mvt
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/paulmach/protoscan"
+)
+
+// Attribute value type constants, matching the MVT Value message fields
+const (
+	ValueTypeString = "string"
+	ValueTypeFloat  = "float"
+	ValueTypeDouble = "double"
+	ValueTypeInt    = "int"
+	ValueTypeUint   = "uint"
+	ValueTypeSint   = "sint"
+	ValueTypeBool   = "bool"
+)
+
+// maxSafeInteger is the largest integer a JSON number (IEEE 754 double) represents exactly
+const maxSafeInteger = 1<<53 - 1
+
+// typedValue is an attribute value decoded with its original MVT type.
+// Values are string, float32, float64, int64, uint64 or bool.
+type typedValue struct {
+	value     interface{}
+	valueType string
+}
+
+// typedFeature holds the typed ID and attributes of a feature
+type typedFeature struct {
+	id    interface{}
+	tags  map[string]interface{}
+	types map[string]string
+}
+
+// decodeTypedAttributes scans MVT data for feature IDs and attributes, keeping their
+// original types. The result is indexed by layer and feature in encoding order.
+func decodeTypedAttributes(data []byte) ([][]*typedFeature, error) {
+	var layers [][]*typedFeature
+	var layerMsg *protoscan.Message
+	var err error
+
+	msg := protoscan.New(data)
+	for msg.Next() {
+		if msg.FieldNumber() != 3 {
+			msg.Skip()
+			continue
+		}
+
+		layerMsg, err = msg.Message(layerMsg)
+		if err != nil {
+			return nil, err
+		}
+
+		features, err := decodeTypedLayer(layerMsg)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, features)
+	}
+
+	if msg.Err() != nil {
+		return nil, msg.Err()
+	}
+
+	return layers, nil
+}
+
+// decodeTypedLayer decodes the typed attributes of every feature in a layer message
+func decodeTypedLayer(msg *protoscan.Message) ([]*typedFeature, error) {
+	var keys []string
+	var values []typedValue
+	var features [][]byte
+	var valueMsg *protoscan.Message
+	var err error
+
+	for msg.Next() {
+		switch msg.FieldNumber() {
+		case 2: // feature
+			data, err := msg.MessageData()
+			if err != nil {
+				return nil, err
+			}
+			features = append(features, data)
+		case 3: // keys
+			key, err := msg.String()
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+		case 4: // values
+			valueMsg, err = msg.Message(valueMsg)
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeTypedValue(valueMsg)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		default:
+			msg.Skip()
+		}
+	}
+
+	if msg.Err() != nil {
+		return nil, msg.Err()
+	}
+
+	result := make([]*typedFeature, len(features))
+	var tags *protoscan.Iterator
+	for i, data := range features {
+		feature := &typedFeature{
+			tags:  make(map[string]interface{}),
+			types: make(map[string]string),
+		}
+
+		msg.Reset(data)
+		for msg.Next() {
+			switch msg.FieldNumber() {
+			case 1: // id
+				id, err := msg.Uint64()
+				if err != nil {
+					return nil, err
+				}
+				feature.id = id
+			case 2: // tags, packed key/value index pairs
+				tags, err = msg.Iterator(tags)
+				if err != nil {
+					return nil, err
+				}
+				for tags.HasNext() {
+					k, err := tags.Uint32()
+					if err != nil {
+						return nil, err
+					}
+					v, err := tags.Uint32()
+					if err != nil {
+						return nil, err
+					}
+					if int(k) >= len(keys) || int(v) >= len(values) {
+						continue
+					}
+					feature.tags[keys[k]] = values[v].value
+					feature.types[keys[k]] = values[v].valueType
+				}
+			default:
+				msg.Skip()
+			}
+		}
+
+		if msg.Err() != nil {
+			return nil, msg.Err()
+		}
+
+		result[i] = feature
+	}
+
+	return result, nil
+}
+
+// decodeTypedValue decodes an MVT Value message without widening numeric types
+func decodeTypedValue(msg *protoscan.Message) (typedValue, error) {
+	for msg.Next() {
+		var value interface{}
+		var valueType string
+		var err error
+
+		switch msg.FieldNumber() {
+		case 1:
+			value, err = msg.String()
+			valueType = ValueTypeString
+		case 2:
+			value, err = msg.Float()
+			valueType = ValueTypeFloat
+		case 3:
+			value, err = msg.Double()
+			valueType = ValueTypeDouble
+		case 4:
+			value, err = msg.Int64()
+			valueType = ValueTypeInt
+		case 5:
+			value, err = msg.Uint64()
+			valueType = ValueTypeUint
+		case 6:
+			value, err = msg.Sint64()
+			valueType = ValueTypeSint
+		case 7:
+			value, err = msg.Bool()
+			valueType = ValueTypeBool
+		default:
+			msg.Skip()
+			continue
+		}
+
+		if err != nil {
+			return typedValue{}, fmt.Errorf("failed to decode %s value: %w", valueType, err)
+		}
+		return typedValue{value: value, valueType: valueType}, nil
+	}
+
+	return typedValue{}, msg.Err()
+}
+
+// largeIntegerAsString returns integers outside the exactly representable JSON number
+// range as decimal strings; all other values are returned unchanged
+func largeIntegerAsString(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int64:
+		if v > maxSafeInteger || v < -maxSafeInteger {
+			return strconv.FormatInt(v, 10)
+		}
+	case uint64:
+		if v > maxSafeInteger {
+			return strconv.FormatUint(v, 10)
+		}
+	}
+	return value
+}
@@ -0,0 +1,141 @@
+// pkg/mvt/values_test.go - Unit tests for typed attribute values and layer schema
+package mvt
+
+import (
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/mvt"
+	"github.com/paulmach/orb/geojson"
+)
+
+const largeID = uint64(1<<60 + 1)
+
+// encodeTypedTile builds a single-layer tile whose attributes cover several MVT value types
+func encodeTypedTile(t *testing.T) []byte {
+	t.Helper()
+
+	first := geojson.NewFeature(orb.Point{10, 10})
+	first.ID = largeID
+	first.Properties = geojson.Properties{
+		"name":  "first",
+		"big":   largeID,
+		"ratio": float32(0.5),
+		"delta": int64(-3),
+		"open":  true,
+	}
+
+	second := geojson.NewFeature(orb.Point{20, 20})
+	second.ID = uint64(7)
+	second.Properties = geojson.Properties{
+		"name":  "second",
+		"big":   uint64(42),
+		"ratio": 1.25,
+	}
+
+	layer := &mvt.Layer{
+		Name:     "places",
+		Version:  2,
+		Extent:   4096,
+		Features: []*geojson.Feature{first, second},
+	}
+
+	data, err := mvt.Marshal(mvt.Layers{layer})
+	if err != nil {
+		t.Fatalf("Failed to encode tile: %v", err)
+	}
+	return data
+}
+
+func TestDecodeTypedAttributes(t *testing.T) {
+	tile, err := NewDecoder().Decode(encodeTypedTile(t), 0, 0, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	feature := tile.Layers["places"].Features[0]
+	if feature.ID != largeID {
+		t.Errorf("Expected exact uint64 ID %d, got %v (%T)", largeID, feature.ID, feature.ID)
+	}
+
+	tests := []struct {
+		key      string
+		want     interface{}
+		wantType string
+	}{
+		{"name", "first", ValueTypeString},
+		{"big", largeID, ValueTypeUint},
+		{"ratio", float32(0.5), ValueTypeFloat},
+		{"delta", int64(-3), ValueTypeSint},
+		{"open", true, ValueTypeBool},
+	}
+
+	for _, tt := range tests {
+		if got := feature.Tags[tt.key]; got != tt.want {
+			t.Errorf("Tag %s = %v (%T), want %v (%T)", tt.key, got, got, tt.want, tt.want)
+		}
+		if got := feature.TagTypes[tt.key]; got != tt.wantType {
+			t.Errorf("Tag %s type = %s, want %s", tt.key, got, tt.wantType)
+		}
+	}
+}
+
+func TestLargeIntAsString(t *testing.T) {
+	converter, _ := NewConverterWithOptions(&ConversionOptions{
+		CoordinateSystem: CoordSystemWebMercator,
+		LargeIntAsString: true,
+	})
+
+	result, _, err := converter.Convert(encodeTypedTile(t), 0, 0, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for _, feature := range result["features"].([]*geojson.Feature) {
+		switch feature.Properties["name"] {
+		case "first":
+			if feature.ID != "1152921504606846977" || feature.Properties["big"] != "1152921504606846977" {
+				t.Errorf("Expected large integers as strings, got ID %v, big %v", feature.ID, feature.Properties["big"])
+			}
+		case "second":
+			if feature.ID != uint64(7) || feature.Properties["big"] != uint64(42) {
+				t.Errorf("Expected small integers unchanged, got ID %v, big %v", feature.ID, feature.Properties["big"])
+			}
+		}
+	}
+}
+
+func TestBuildSchema(t *testing.T) {
+	converter := NewConverter()
+
+	_, metadata, err := converter.Convert(encodeTypedTile(t), 0, 0, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	layer := metadata.Schema["places"]
+	if layer == nil {
+		t.Fatal("Expected schema for layer places")
+	}
+	if layer.FeatureCount != 2 {
+		t.Errorf("Expected feature count 2, got %d", layer.FeatureCount)
+	}
+
+	ratio := layer.Attributes["ratio"]
+	if len(ratio.Types) != 2 || ratio.Types[0] != ValueTypeDouble || ratio.Types[1] != ValueTypeFloat {
+		t.Errorf("Expected ratio types [double float], got %v", ratio.Types)
+	}
+	if ratio.Nullable {
+		t.Error("Expected ratio to be non-nullable")
+	}
+	if open := layer.Attributes["open"]; !open.Nullable {
+		t.Error("Expected open to be nullable")
+	}
+
+	merged := make(Schema)
+	merged.Merge(metadata.Schema)
+	merged.Merge(Schema{"places": {FeatureCount: 1, Attributes: map[string]*AttributeSchema{}}})
+	if !merged["places"].Attributes["ratio"].Nullable {
+		t.Error("Expected ratio to become nullable after merging a tile without it")
+	}
+}
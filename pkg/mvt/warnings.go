@@ -0,0 +1,46 @@
+// pkg/mvt/warnings.go - Structured conversion warnings
+package mvt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Warning codes
+const (
+	WarningNilGeometry     = "nil_geometry"
+	WarningConversionError = "conversion_error"
+)
+
+// Warning describes a feature that was skipped or could not be converted. Feature is
+// the feature's index within its layer.
+type Warning struct {
+	Tile    string `json:"tile"`
+	Layer   string `json:"layer"`
+	Feature int    `json:"feature"`
+	Code    string `json:"code"`
+	Reason  string `json:"reason"`
+}
+
+// String returns a human readable form of the warning
+func (w Warning) String() string {
+	return fmt.Sprintf("tile %s, layer %s, feature %d: %s", w.Tile, w.Layer, w.Feature, w.Reason)
+}
+
+// StrictError is returned by Convert in strict mode when a tile produced warnings
+type StrictError struct {
+	Warnings []Warning
+}
+
+// Error implements the error interface
+func (e *StrictError) Error() string {
+	if len(e.Warnings) == 1 {
+		return fmt.Sprintf("strict mode: %s", e.Warnings[0])
+	}
+
+	reasons := make([]string, len(e.Warnings))
+	for i, warning := range e.Warnings {
+		reasons[i] = warning.String()
+	}
+	return fmt.Sprintf("strict mode: %d warnings: %s", len(e.Warnings), strings.Join(reasons, "; "))
+}
@@ -0,0 +1,365 @@
+// pkg/pmtiles/pmtiles.go - PMTiles v3 archive format
+package pmtiles
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// HeaderLength is the size of the fixed PMTiles v3 header in bytes
+const HeaderLength = 127
+
+// maxRootLength caps the header plus root directory, which clients fetch in one request
+const maxRootLength = 16384
+
+// Compression identifies the compression of tiles or of the internal directories and metadata
+type Compression uint8
+
+// Compression constants
+const (
+	CompressionUnknown Compression = 0
+	CompressionNone    Compression = 1
+	CompressionGzip    Compression = 2
+	CompressionBrotli  Compression = 3
+	CompressionZstd    Compression = 4
+)
+
+// TileType identifies the format of the tiles in an archive
+type TileType uint8
+
+// Tile type constants
+const (
+	TileTypeUnknown TileType = 0
+	TileTypeMVT     TileType = 1
+	TileTypePNG     TileType = 2
+	TileTypeJPEG    TileType = 3
+	TileTypeWebP    TileType = 4
+	TileTypeAVIF    TileType = 5
+)
+
+// Header is the fixed-size header at the start of a PMTiles v3 archive. Offsets are
+// absolute; coordinates are WGS84 degrees.
+type Header struct {
+	RootOffset          uint64
+	RootLength          uint64
+	MetadataOffset      uint64
+	MetadataLength      uint64
+	LeafDirectoryOffset uint64
+	LeafDirectoryLength uint64
+	TileDataOffset      uint64
+	TileDataLength      uint64
+	AddressedTilesCount uint64
+	TileEntriesCount    uint64
+	TileContentsCount   uint64
+	Clustered           bool
+	InternalCompression Compression
+	TileCompression     Compression
+	TileType            TileType
+	MinZoom             uint8
+	MaxZoom             uint8
+	MinLon              float64
+	MinLat              float64
+	MaxLon              float64
+	MaxLat              float64
+	CenterZoom          uint8
+	CenterLon           float64
+	CenterLat           float64
+}
+
+// Entry is a directory entry. A RunLength of zero points to a leaf directory; otherwise
+// the entry covers RunLength consecutive tile IDs sharing the same tile data.
+type Entry struct {
+	TileID    uint64
+	Offset    uint64
+	Length    uint32
+	RunLength uint32
+}
+
+// ZxyToID returns the tile ID of z/x/y: the number of tiles at lower zooms plus the
+// tile's position along the zoom level's Hilbert curve
+func ZxyToID(z uint8, x, y uint32) uint64 {
+	id := (uint64(1)<<(2*uint(z)) - 1) / 3
+	n := uint32(1) << z
+	for s := n / 2; s > 0; s /= 2 {
+		var rx, ry uint32
+		if x&s > 0 {
+			rx = 1
+		}
+		if y&s > 0 {
+			ry = 1
+		}
+		id += uint64(s) * uint64(s) * uint64((3*rx)^ry)
+		x, y = rotate(n, x, y, rx, ry)
+	}
+	return id
+}
+
+// IDToZxy is the inverse of ZxyToID
+func IDToZxy(id uint64) (z uint8, x, y uint32) {
+	var base uint64
+	for z = 0; ; z++ {
+		count := uint64(1) << (2 * uint(z))
+		if id < base+count {
+			break
+		}
+		base += count
+	}
+
+	n := uint32(1) << z
+	d := id - base
+	for s := uint32(1); s < n; s *= 2 {
+		rx := uint32(1 & (d / 2))
+		ry := uint32(1 & (d ^ uint64(rx)))
+		x, y = rotate(s, x, y, rx, ry)
+		x += s * rx
+		y += s * ry
+		d /= 4
+	}
+	return z, x, y
+}
+
+// rotate rotates and flips a Hilbert curve quadrant
+func rotate(n, x, y, rx, ry uint32) (uint32, uint32) {
+	if ry == 0 {
+		if rx == 1 {
+			x = n - 1 - x
+			y = n - 1 - y
+		}
+		return y, x
+	}
+	return x, y
+}
+
+// serializeHeader encodes the header in its 127 byte little-endian layout
+func serializeHeader(h *Header) []byte {
+	b := make([]byte, HeaderLength)
+	copy(b[0:7], "PMTiles")
+	b[7] = 3
+	binary.LittleEndian.PutUint64(b[8:], h.RootOffset)
+	binary.LittleEndian.PutUint64(b[16:], h.RootLength)
+	binary.LittleEndian.PutUint64(b[24:], h.MetadataOffset)
+	binary.LittleEndian.PutUint64(b[32:], h.MetadataLength)
+	binary.LittleEndian.PutUint64(b[40:], h.LeafDirectoryOffset)
+	binary.LittleEndian.PutUint64(b[48:], h.LeafDirectoryLength)
+	binary.LittleEndian.PutUint64(b[56:], h.TileDataOffset)
+	binary.LittleEndian.PutUint64(b[64:], h.TileDataLength)
+	binary.LittleEndian.PutUint64(b[72:], h.AddressedTilesCount)
+	binary.LittleEndian.PutUint64(b[80:], h.TileEntriesCount)
+	binary.LittleEndian.PutUint64(b[88:], h.TileContentsCount)
+	if h.Clustered {
+		b[96] = 1
+	}
+	b[97] = byte(h.InternalCompression)
+	b[98] = byte(h.TileCompression)
+	b[99] = byte(h.TileType)
+	b[100] = h.MinZoom
+	b[101] = h.MaxZoom
+	binary.LittleEndian.PutUint32(b[102:], uint32(toE7(h.MinLon)))
+	binary.LittleEndian.PutUint32(b[106:], uint32(toE7(h.MinLat)))
+	binary.LittleEndian.PutUint32(b[110:], uint32(toE7(h.MaxLon)))
+	binary.LittleEndian.PutUint32(b[114:], uint32(toE7(h.MaxLat)))
+	b[118] = h.CenterZoom
+	binary.LittleEndian.PutUint32(b[119:], uint32(toE7(h.CenterLon)))
+	binary.LittleEndian.PutUint32(b[123:], uint32(toE7(h.CenterLat)))
+	return b
+}
+
+// DeserializeHeader decodes the header at the start of an archive
+func DeserializeHeader(b []byte) (*Header, error) {
+	if len(b) < HeaderLength {
+		return nil, fmt.Errorf("header too short: %d bytes", len(b))
+	}
+	if string(b[0:7]) != "PMTiles" {
+		return nil, fmt.Errorf("not a PMTiles archive")
+	}
+	if b[7] != 3 {
+		return nil, fmt.Errorf("unsupported PMTiles version: %d", b[7])
+	}
+
+	return &Header{
+		RootOffset:          binary.LittleEndian.Uint64(b[8:]),
+		RootLength:          binary.LittleEndian.Uint64(b[16:]),
+		MetadataOffset:      binary.LittleEndian.Uint64(b[24:]),
+		MetadataLength:      binary.LittleEndian.Uint64(b[32:]),
+		LeafDirectoryOffset: binary.LittleEndian.Uint64(b[40:]),
+		LeafDirectoryLength: binary.LittleEndian.Uint64(b[48:]),
+		TileDataOffset:      binary.LittleEndian.Uint64(b[56:]),
+		TileDataLength:      binary.LittleEndian.Uint64(b[64:]),
+		AddressedTilesCount: binary.LittleEndian.Uint64(b[72:]),
+		TileEntriesCount:    binary.LittleEndian.Uint64(b[80:]),
+		TileContentsCount:   binary.LittleEndian.Uint64(b[88:]),
+		Clustered:           b[96] == 1,
+		InternalCompression: Compression(b[97]),
+		TileCompression:     Compression(b[98]),
+		TileType:            TileType(b[99]),
+		MinZoom:             b[100],
+		MaxZoom:             b[101],
+		MinLon:              fromE7(binary.LittleEndian.Uint32(b[102:])),
+		MinLat:              fromE7(binary.LittleEndian.Uint32(b[106:])),
+		MaxLon:              fromE7(binary.LittleEndian.Uint32(b[110:])),
+		MaxLat:              fromE7(binary.LittleEndian.Uint32(b[114:])),
+		CenterZoom:          b[118],
+		CenterLon:           fromE7(binary.LittleEndian.Uint32(b[119:])),
+		CenterLat:           fromE7(binary.LittleEndian.Uint32(b[123:])),
+	}, nil
+}
+
+// toE7 converts degrees to the header's fixed-point representation
+func toE7(degrees float64) int32 {
+	return int32(degrees * 1e7)
+}
+
+// fromE7 converts the header's fixed-point representation to degrees
+func fromE7(value uint32) float64 {
+	return float64(int32(value)) / 1e7
+}
+
+// serializeEntries encodes a directory: the entry count followed by columns of
+// delta-encoded tile IDs, run lengths, lengths and offsets, all as varints. An offset
+// directly following the previous entry's data is stored as zero.
+func serializeEntries(entries []Entry) []byte {
+	var b []byte
+	b = binary.AppendUvarint(b, uint64(len(entries)))
+
+	var lastID uint64
+	for _, entry := range entries {
+		b = binary.AppendUvarint(b, entry.TileID-lastID)
+		lastID = entry.TileID
+	}
+	for _, entry := range entries {
+		b = binary.AppendUvarint(b, uint64(entry.RunLength))
+	}
+	for _, entry := range entries {
+		b = binary.AppendUvarint(b, uint64(entry.Length))
+	}
+	for i, entry := range entries {
+		if i > 0 && entry.Offset == entries[i-1].Offset+uint64(entries[i-1].Length) {
+			b = binary.AppendUvarint(b, 0)
+		} else {
+			b = binary.AppendUvarint(b, entry.Offset+1)
+		}
+	}
+	return b
+}
+
+// DeserializeEntries decodes an uncompressed directory
+func DeserializeEntries(b []byte) ([]Entry, error) {
+	reader := bytes.NewReader(b)
+	count, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+	if count > uint64(len(b)) {
+		return nil, fmt.Errorf("invalid directory entry count: %d", count)
+	}
+
+	entries := make([]Entry, count)
+	columns := []func(i int, value uint64){
+		func(i int, value uint64) {
+			entries[i].TileID = value
+			if i > 0 {
+				entries[i].TileID += entries[i-1].TileID
+			}
+		},
+		func(i int, value uint64) { entries[i].RunLength = uint32(value) },
+		func(i int, value uint64) { entries[i].Length = uint32(value) },
+		func(i int, value uint64) {
+			if value == 0 && i > 0 {
+				entries[i].Offset = entries[i-1].Offset + uint64(entries[i-1].Length)
+			} else {
+				entries[i].Offset = value - 1
+			}
+		},
+	}
+
+	for _, column := range columns {
+		for i := range entries {
+			value, err := binary.ReadUvarint(reader)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read directory: %w", err)
+			}
+			column(i, value)
+		}
+	}
+	return entries, nil
+}
+
+// compress applies an internal compression to directory or metadata bytes
+func compress(data []byte, compression Compression) ([]byte, error) {
+	switch compression {
+	case CompressionNone:
+		return data, nil
+	case CompressionGzip:
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		if _, err := writer.Write(data); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, fmt.Errorf("unsupported compression: %d", compression)
+}
+
+// Decompress reverses an internal compression
+func Decompress(data []byte, compression Compression) ([]byte, error) {
+	switch compression {
+	case CompressionNone:
+		return data, nil
+	case CompressionGzip:
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return io.ReadAll(reader)
+	}
+	return nil, fmt.Errorf("unsupported compression: %d", compression)
+}
+
+// buildDirectories compresses the entries into a root directory of at most maxLength
+// bytes, moving them into leaf directories when they do not fit
+func buildDirectories(entries []Entry, compression Compression, maxLength int) (root, leaves []byte, err error) {
+	root, err = compress(serializeEntries(entries), compression)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(root) <= maxLength {
+		return root, nil, nil
+	}
+
+	for leafSize := 4096; ; leafSize *= 2 {
+		var rootEntries []Entry
+		leaves = leaves[:0]
+		for start := 0; start < len(entries); start += leafSize {
+			end := start + leafSize
+			if end > len(entries) {
+				end = len(entries)
+			}
+
+			leaf, err := compress(serializeEntries(entries[start:end]), compression)
+			if err != nil {
+				return nil, nil, err
+			}
+			rootEntries = append(rootEntries, Entry{
+				TileID: entries[start].TileID,
+				Offset: uint64(len(leaves)),
+				Length: uint32(len(leaf)),
+			})
+			leaves = append(leaves, leaf...)
+		}
+
+		root, err = compress(serializeEntries(rootEntries), compression)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(root) <= maxLength {
+			return root, leaves, nil
+		}
+	}
+}
@@ -0,0 +1,219 @@
+// pkg/pmtiles/pmtiles_test.go - Unit tests for the PMTiles archive format
+package pmtiles
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestZxyToID(t *testing.T) {
+	tests := []struct {
+		z    uint8
+		x, y uint32
+		want uint64
+	}{
+		{0, 0, 0, 0},
+		{1, 0, 0, 1},
+		{1, 0, 1, 2},
+		{1, 1, 1, 3},
+		{1, 1, 0, 4},
+		{2, 0, 0, 5},
+		{12, 3423, 1763, 19078479},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d/%d/%d", tt.z, tt.x, tt.y), func(t *testing.T) {
+			if got := ZxyToID(tt.z, tt.x, tt.y); got != tt.want {
+				t.Errorf("ZxyToID() = %d, want %d", got, tt.want)
+			}
+			z, x, y := IDToZxy(tt.want)
+			if z != tt.z || x != tt.x || y != tt.y {
+				t.Errorf("IDToZxy() = %d/%d/%d, want %d/%d/%d", z, x, y, tt.z, tt.x, tt.y)
+			}
+		})
+	}
+}
+
+func TestSerializeEntries(t *testing.T) {
+	entries := []Entry{
+		{TileID: 0, Offset: 0, Length: 10, RunLength: 1},
+		{TileID: 1, Offset: 10, Length: 20, RunLength: 3},
+		{TileID: 9, Offset: 0, Length: 10, RunLength: 1},
+	}
+
+	got, err := DeserializeEntries(serializeEntries(entries))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(got) != len(entries) {
+		t.Fatalf("Expected %d entries, got %d", len(entries), len(got))
+	}
+	for i := range entries {
+		if got[i] != entries[i] {
+			t.Errorf("Entry %d = %+v, want %+v", i, got[i], entries[i])
+		}
+	}
+}
+
+// readTile looks up a tile in an archive, following leaf directories
+func readTile(t *testing.T, archive []byte, header *Header, z uint8, x, y uint32) []byte {
+	t.Helper()
+
+	id := ZxyToID(z, x, y)
+	offset, length := header.RootOffset, header.RootLength
+	for depth := 0; depth < 4; depth++ {
+		raw, err := Decompress(archive[offset:offset+length], header.InternalCompression)
+		if err != nil {
+			t.Fatalf("Failed to decompress directory: %v", err)
+		}
+		entries, err := DeserializeEntries(raw)
+		if err != nil {
+			t.Fatalf("Failed to read directory: %v", err)
+		}
+
+		var found *Entry
+		for i := range entries {
+			if entries[i].TileID <= id {
+				found = &entries[i]
+			}
+		}
+		if found == nil {
+			return nil
+		}
+		if found.RunLength == 0 {
+			offset = header.LeafDirectoryOffset + found.Offset
+			length = uint64(found.Length)
+			continue
+		}
+		if id >= found.TileID+uint64(found.RunLength) {
+			return nil
+		}
+		start := header.TileDataOffset + found.Offset
+		return archive[start : start+uint64(found.Length)]
+	}
+	t.Fatal("Directory nesting too deep")
+	return nil
+}
+
+func TestWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tiles.pmtiles")
+	writer, err := NewWriter(path, TileTypeMVT, CompressionNone)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	writer.SetMetadata("name", "test")
+
+	// Written out of order; the two ocean tiles share data
+	tiles := map[[3]int][]byte{
+		{1, 1, 1}: []byte("ocean"),
+		{0, 0, 0}: []byte("world"),
+		{1, 0, 1}: []byte("ocean"),
+		{1, 0, 0}: []byte("land"),
+	}
+	for coords, data := range tiles {
+		if err := writer.WriteTile(coords[0], coords[1], coords[2], data); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if err := writer.WriteTile(1, 2, 0, []byte("x")); err == nil {
+		t.Error("Expected error for tile outside its zoom level")
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	archive, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, err := DeserializeHeader(archive)
+	if err != nil {
+		t.Fatalf("Expected valid header, got %v", err)
+	}
+
+	if header.AddressedTilesCount != 4 || header.TileEntriesCount != 3 || header.TileContentsCount != 3 {
+		t.Errorf("Unexpected counts: addressed %d, entries %d, contents %d",
+			header.AddressedTilesCount, header.TileEntriesCount, header.TileContentsCount)
+	}
+	if header.MinZoom != 0 || header.MaxZoom != 1 || !header.Clustered || header.TileType != TileTypeMVT {
+		t.Errorf("Unexpected header: %+v", header)
+	}
+	if header.MinLon != -180 || header.MaxLon != 180 {
+		t.Errorf("Expected world longitude bounds, got %f..%f", header.MinLon, header.MaxLon)
+	}
+
+	for coords, want := range tiles {
+		got := readTile(t, archive, header, uint8(coords[0]), uint32(coords[1]), uint32(coords[2]))
+		if !bytes.Equal(got, want) {
+			t.Errorf("Tile %v = %q, want %q", coords, got, want)
+		}
+	}
+	if got := readTile(t, archive, header, 1, 1, 0); got != nil {
+		t.Errorf("Expected missing tile, got %q", got)
+	}
+
+	metadata, err := Decompress(archive[header.MetadataOffset:header.MetadataOffset+header.MetadataLength], header.InternalCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(metadata, &decoded); err != nil || decoded["name"] != "test" {
+		t.Errorf("Expected metadata name, got %s (%v)", metadata, err)
+	}
+}
+
+func TestBuildDirectoriesLeaves(t *testing.T) {
+	entries := make([]Entry, 10000)
+	for i := range entries {
+		entries[i] = Entry{TileID: uint64(i * 3), Offset: uint64(i * 7), Length: uint32(i%5 + 1), RunLength: 1}
+	}
+
+	root, leaves, err := buildDirectories(entries, CompressionGzip, 512)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(root) > 512 || len(leaves) == 0 {
+		t.Fatalf("Expected a root of at most 512 bytes with leaves, got root %d, leaves %d", len(root), len(leaves))
+	}
+
+	raw, err := Decompress(root, CompressionGzip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootEntries, err := DeserializeEntries(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var all []Entry
+	for _, leafEntry := range rootEntries {
+		if leafEntry.RunLength != 0 {
+			t.Fatalf("Expected root entries to point to leaves, got %+v", leafEntry)
+		}
+		raw, err := Decompress(leaves[leafEntry.Offset:leafEntry.Offset+uint64(leafEntry.Length)], CompressionGzip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		leafEntries, err := DeserializeEntries(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if leafEntries[0].TileID != leafEntry.TileID {
+			t.Errorf("Leaf starts at tile %d, root entry says %d", leafEntries[0].TileID, leafEntry.TileID)
+		}
+		all = append(all, leafEntries...)
+	}
+
+	if len(all) != len(entries) {
+		t.Fatalf("Expected %d entries across leaves, got %d", len(entries), len(all))
+	}
+	for i := range entries {
+		if all[i] != entries[i] {
+			t.Fatalf("Entry %d = %+v, want %+v", i, all[i], entries[i])
+		}
+	}
+}
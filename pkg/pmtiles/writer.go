@@ -0,0 +1,245 @@
+// pkg/pmtiles/writer.go - PMTiles v3 archive writer
+package pmtiles
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Writer builds a PMTiles archive from tiles written in any order. Tile data is spooled
+// to a temporary file and the archive is assembled, clustered by tile ID, on Close.
+// Identical tiles are stored once. Writer is safe for concurrent use.
+type Writer struct {
+	path            string
+	tileType        TileType
+	tileCompression Compression
+	metadata        map[string]interface{}
+	spool           *os.File
+	spoolLength     uint64
+	tiles           map[uint64]*spooledTile
+	contents        map[[sha256.Size]byte]*spooledTile
+	minZoom         uint8
+	maxZoom         uint8
+	mutex           sync.Mutex
+}
+
+// spooledTile locates a tile's data in the spool file
+type spooledTile struct {
+	offset uint64
+	length uint32
+}
+
+// NewWriter creates a writer for the archive at path. tileCompression describes how the
+// tiles passed to WriteTile are already compressed; the writer stores them as given.
+func NewWriter(path string, tileType TileType, tileCompression Compression) (*Writer, error) {
+	spool, err := os.CreateTemp("", "pmtiles-*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spool file: %w", err)
+	}
+
+	return &Writer{
+		path:            path,
+		tileType:        tileType,
+		tileCompression: tileCompression,
+		metadata:        make(map[string]interface{}),
+		spool:           spool,
+		tiles:           make(map[uint64]*spooledTile),
+		contents:        make(map[[sha256.Size]byte]*spooledTile),
+		minZoom:         math.MaxUint8,
+	}, nil
+}
+
+// SetMetadata sets a key of the archive's JSON metadata, such as "name" or "vector_layers"
+func (w *Writer) SetMetadata(key string, value interface{}) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.metadata[key] = value
+}
+
+// WriteTile adds a tile to the archive, replacing any earlier tile at z/x/y
+func (w *Writer) WriteTile(z, x, y int, data []byte) error {
+	if z < 0 || z > 31 || x < 0 || y < 0 || x >= 1<<uint(z) || y >= 1<<uint(z) {
+		return fmt.Errorf("invalid tile coordinates %d/%d/%d", z, x, y)
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("empty tile %d/%d/%d", z, x, y)
+	}
+
+	hash := sha256.Sum256(data)
+	id := ZxyToID(uint8(z), uint32(x), uint32(y))
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.spool == nil {
+		return fmt.Errorf("writer is closed")
+	}
+
+	tile, exists := w.contents[hash]
+	if !exists {
+		if _, err := w.spool.Write(data); err != nil {
+			return fmt.Errorf("failed to spool tile %d/%d/%d: %w", z, x, y, err)
+		}
+		tile = &spooledTile{offset: w.spoolLength, length: uint32(len(data))}
+		w.contents[hash] = tile
+		w.spoolLength += uint64(len(data))
+	}
+	w.tiles[id] = tile
+
+	if uint8(z) < w.minZoom {
+		w.minZoom = uint8(z)
+	}
+	if uint8(z) > w.maxZoom {
+		w.maxZoom = uint8(z)
+	}
+	return nil
+}
+
+// TileCount returns the number of tiles written so far
+func (w *Writer) TileCount() int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return len(w.tiles)
+}
+
+// Close assembles the archive and removes the spool file. An archive without tiles is
+// not written.
+func (w *Writer) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.spool == nil {
+		return nil
+	}
+	defer func() {
+		w.spool.Close()
+		os.Remove(w.spool.Name())
+		w.spool = nil
+	}()
+
+	if len(w.tiles) == 0 {
+		return fmt.Errorf("no tiles to write")
+	}
+
+	ids := make([]uint64, 0, len(w.tiles))
+	for id := range w.tiles {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	// Lay out tile data in tile ID order, storing each distinct tile once and merging
+	// runs of consecutive IDs that share data
+	var entries []Entry
+	var order []*spooledTile
+	placed := make(map[*spooledTile]uint64)
+	var tileDataLength uint64
+	for _, id := range ids {
+		tile := w.tiles[id]
+		if last := len(entries) - 1; last >= 0 && entries[last].TileID+uint64(entries[last].RunLength) == id &&
+			w.tiles[entries[last].TileID] == tile {
+			entries[last].RunLength++
+			continue
+		}
+
+		offset, exists := placed[tile]
+		if !exists {
+			offset = tileDataLength
+			placed[tile] = offset
+			order = append(order, tile)
+			tileDataLength += uint64(tile.length)
+		}
+		entries = append(entries, Entry{TileID: id, Offset: offset, Length: tile.length, RunLength: 1})
+	}
+
+	root, leaves, err := buildDirectories(entries, CompressionGzip, maxRootLength-HeaderLength)
+	if err != nil {
+		return fmt.Errorf("failed to build directories: %w", err)
+	}
+
+	metadataJSON, err := json.Marshal(w.metadata)
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata: %w", err)
+	}
+	metadata, err := compress(metadataJSON, CompressionGzip)
+	if err != nil {
+		return fmt.Errorf("failed to compress metadata: %w", err)
+	}
+
+	header := &Header{
+		RootOffset:          HeaderLength,
+		RootLength:          uint64(len(root)),
+		MetadataOffset:      HeaderLength + uint64(len(root)),
+		MetadataLength:      uint64(len(metadata)),
+		LeafDirectoryLength: uint64(len(leaves)),
+		TileDataLength:      tileDataLength,
+		AddressedTilesCount: uint64(len(ids)),
+		TileEntriesCount:    uint64(len(entries)),
+		TileContentsCount:   uint64(len(order)),
+		Clustered:           true,
+		InternalCompression: CompressionGzip,
+		TileCompression:     w.tileCompression,
+		TileType:            w.tileType,
+		MinZoom:             w.minZoom,
+		MaxZoom:             w.maxZoom,
+		CenterZoom:          w.minZoom,
+	}
+	header.LeafDirectoryOffset = header.MetadataOffset + header.MetadataLength
+	header.TileDataOffset = header.LeafDirectoryOffset + header.LeafDirectoryLength
+	header.MinLon, header.MinLat, header.MaxLon, header.MaxLat = w.bounds(ids)
+	header.CenterLon = (header.MinLon + header.MaxLon) / 2
+	header.CenterLat = (header.MinLat + header.MaxLat) / 2
+
+	file, err := os.Create(w.path)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer file.Close()
+
+	for _, section := range [][]byte{serializeHeader(header), root, metadata, leaves} {
+		if _, err := file.Write(section); err != nil {
+			return fmt.Errorf("failed to write archive: %w", err)
+		}
+	}
+
+	for _, tile := range order {
+		reader := io.NewSectionReader(w.spool, int64(tile.offset), int64(tile.length))
+		if _, err := io.Copy(file, reader); err != nil {
+			return fmt.Errorf("failed to write tile data: %w", err)
+		}
+	}
+
+	return file.Close()
+}
+
+// bounds returns the WGS84 bounds covered by the tiles at the maximum zoom
+func (w *Writer) bounds(ids []uint64) (minLon, minLat, maxLon, maxLat float64) {
+	minLon, minLat = 180, 90
+	maxLon, maxLat = -180, -90
+	for _, id := range ids {
+		z, x, y := IDToZxy(id)
+		if z != w.maxZoom {
+			continue
+		}
+
+		n := math.Exp2(float64(z))
+		west := float64(x)/n*360 - 180
+		east := float64(x+1)/n*360 - 180
+		north := tileLatitude(float64(y), n)
+		south := tileLatitude(float64(y+1), n)
+
+		minLon, maxLon = math.Min(minLon, west), math.Max(maxLon, east)
+		minLat, maxLat = math.Min(minLat, south), math.Max(maxLat, north)
+	}
+	return minLon, minLat, maxLon, maxLat
+}
+
+// tileLatitude returns the latitude of a tile row edge at a zoom with n tiles per axis
+func tileLatitude(y, n float64) float64 {
+	return math.Atan(math.Sinh(math.Pi*(1-2*y/n))) * 180 / math.Pi
+}
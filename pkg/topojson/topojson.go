@@ -0,0 +1,240 @@
+// pkg/topojson/topojson.go - TopoJSON topology types and geometry conversion
+package topojson
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/paulmach/orb"
+)
+
+// DefaultObject is the object holding features without a layer
+const DefaultObject = "features"
+
+// Topology is a TopoJSON topology. Lines and rings of every object refer to the shared
+// arcs by index, with a negative index ~i for arc i traversed in reverse.
+type Topology struct {
+	Type      string               `json:"type"`
+	BBox      []float64            `json:"bbox,omitempty"`
+	Transform *Transform           `json:"transform,omitempty"`
+	Objects   map[string]*Geometry `json:"objects"`
+	Arcs      [][]orb.Point        `json:"arcs"`
+}
+
+// Transform maps quantized positions back to coordinates. Positions are absolute for
+// points and delta-encoded along arcs.
+type Transform struct {
+	Scale     [2]float64 `json:"scale"`
+	Translate [2]float64 `json:"translate"`
+}
+
+// Geometry is a TopoJSON geometry object. Points carry their coordinates, lines and
+// polygons their arc references, and collections their geometries. An empty type is a
+// feature without a geometry.
+type Geometry struct {
+	Type        string
+	ID          interface{}
+	Properties  map[string]interface{}
+	Coordinates interface{} // orb.Point or []orb.Point
+	Arcs        interface{} // []int, [][]int or [][][]int
+	Geometries  []*Geometry
+}
+
+// MarshalJSON writes the members used by the geometry's type, with a null type for a
+// feature without a geometry
+func (g *Geometry) MarshalJSON() ([]byte, error) {
+	out := struct {
+		Type        interface{}            `json:"type"`
+		ID          interface{}            `json:"id,omitempty"`
+		Properties  map[string]interface{} `json:"properties,omitempty"`
+		Coordinates interface{}            `json:"coordinates,omitempty"`
+		Arcs        interface{}            `json:"arcs,omitempty"`
+		Geometries  interface{}            `json:"geometries,omitempty"`
+	}{
+		ID:         g.ID,
+		Properties: g.Properties,
+	}
+
+	switch g.Type {
+	case "":
+	case "Point", "MultiPoint":
+		out.Type, out.Coordinates = g.Type, g.Coordinates
+	case "GeometryCollection":
+		geometries := g.Geometries
+		if geometries == nil {
+			geometries = []*Geometry{}
+		}
+		out.Type, out.Geometries = g.Type, geometries
+	default:
+		out.Type, out.Arcs = g.Type, g.Arcs
+	}
+	return json.Marshal(out)
+}
+
+// quantizer maps coordinates onto the integer grid of a transform
+type quantizer struct {
+	kx, ky float64
+	x0, y0 float64
+}
+
+// newQuantizer creates a quantizer with n grid positions per axis over bound
+func newQuantizer(bound orb.Bound, n int) (*quantizer, *Transform) {
+	q := &quantizer{kx: 1, ky: 1, x0: bound.Min[0], y0: bound.Min[1]}
+	if dx := bound.Max[0] - bound.Min[0]; dx > 0 {
+		q.kx = float64(n-1) / dx
+	}
+	if dy := bound.Max[1] - bound.Min[1]; dy > 0 {
+		q.ky = float64(n-1) / dy
+	}
+	return q, &Transform{
+		Scale:     [2]float64{1 / q.kx, 1 / q.ky},
+		Translate: [2]float64{q.x0, q.y0},
+	}
+}
+
+// point returns the grid position of a point. Adding zero turns a rounded -0 into 0.
+func (q *quantizer) point(p orb.Point) orb.Point {
+	if q == nil {
+		return p
+	}
+	return orb.Point{
+		math.Round((p[0]-q.x0)*q.kx) + 0,
+		math.Round((p[1]-q.y0)*q.ky) + 0,
+	}
+}
+
+// bounds returns the bounds of the coordinates of the geometries, and false when they
+// have none
+func bounds(geometries []orb.Geometry) (orb.Bound, bool) {
+	var bound orb.Bound
+	found := false
+	extend := func(points ...orb.Point) {
+		for _, p := range points {
+			if !found {
+				bound, found = orb.Bound{Min: p, Max: p}, true
+				continue
+			}
+			bound = bound.Extend(p)
+		}
+	}
+
+	var add func(orb.Geometry)
+	add = func(geometry orb.Geometry) {
+		switch g := geometry.(type) {
+		case orb.Point:
+			extend(g)
+		case orb.MultiPoint:
+			extend(g...)
+		case orb.LineString:
+			extend(g...)
+		case orb.Ring:
+			extend(g...)
+		case orb.MultiLineString:
+			for _, line := range g {
+				extend(line...)
+			}
+		case orb.Polygon:
+			for _, ring := range g {
+				extend(ring...)
+			}
+		case orb.MultiPolygon:
+			for _, polygon := range g {
+				add(polygon)
+			}
+		case orb.Collection:
+			for _, child := range g {
+				add(child)
+			}
+		case orb.Bound:
+			extend(g.Min, g.Max)
+		}
+	}
+
+	for _, geometry := range geometries {
+		add(geometry)
+	}
+	return bound, found
+}
+
+// builder converts geometries to TopoJSON in two passes over the same features: the
+// first collects their lines and rings, and the second, once the lines and rings are
+// cut into shared arcs, reads back the arc references of each in the same order
+type builder struct {
+	quantizer *quantizer
+	paths     []*path
+	resolve   bool
+	next      int
+}
+
+// arcs records a line or ring in the first pass and returns its arc references in
+// the second
+func (b *builder) arcs(points []orb.Point, ring bool) []int {
+	if len(points) == 0 {
+		return []int{}
+	}
+	if !b.resolve {
+		b.paths = append(b.paths, newPath(points, ring, b.quantizer))
+		return nil
+	}
+	p := b.paths[b.next]
+	b.next++
+	return p.arcs
+}
+
+// rings returns the arc references of the rings of a polygon
+func (b *builder) rings(polygon orb.Polygon) [][]int {
+	rings := make([][]int, 0, len(polygon))
+	for _, ring := range polygon {
+		rings = append(rings, b.arcs(ring, true))
+	}
+	return rings
+}
+
+// geometry converts a geometry to TopoJSON
+func (b *builder) geometry(geometry orb.Geometry) (*Geometry, error) {
+	switch g := geometry.(type) {
+	case nil:
+		return &Geometry{}, nil
+	case orb.Point:
+		return &Geometry{Type: "Point", Coordinates: b.quantizer.point(g)}, nil
+	case orb.MultiPoint:
+		points := make([]orb.Point, len(g))
+		for i, p := range g {
+			points[i] = b.quantizer.point(p)
+		}
+		return &Geometry{Type: "MultiPoint", Coordinates: points}, nil
+	case orb.LineString:
+		return &Geometry{Type: "LineString", Arcs: b.arcs(g, false)}, nil
+	case orb.MultiLineString:
+		lines := make([][]int, 0, len(g))
+		for _, line := range g {
+			lines = append(lines, b.arcs(line, false))
+		}
+		return &Geometry{Type: "MultiLineString", Arcs: lines}, nil
+	case orb.Ring:
+		return b.geometry(orb.Polygon{g})
+	case orb.Bound:
+		return b.geometry(g.ToPolygon())
+	case orb.Polygon:
+		return &Geometry{Type: "Polygon", Arcs: b.rings(g)}, nil
+	case orb.MultiPolygon:
+		polygons := make([][][]int, 0, len(g))
+		for _, polygon := range g {
+			polygons = append(polygons, b.rings(polygon))
+		}
+		return &Geometry{Type: "MultiPolygon", Arcs: polygons}, nil
+	case orb.Collection:
+		geometries := make([]*Geometry, 0, len(g))
+		for _, child := range g {
+			converted, err := b.geometry(child)
+			if err != nil {
+				return nil, err
+			}
+			geometries = append(geometries, converted)
+		}
+		return &Geometry{Type: "GeometryCollection", Geometries: geometries}, nil
+	default:
+		return nil, fmt.Errorf("unsupported geometry type %T", geometry)
+	}
+}
@@ -0,0 +1,362 @@
+// pkg/topojson/topojson_test.go - Unit tests for the TopoJSON writer
+package topojson
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+func TestNewPath(t *testing.T) {
+	q, _ := newQuantizer(orb.Bound{Min: orb.Point{0, 0}, Max: orb.Point{10, 10}}, 11)
+
+	tests := []struct {
+		name      string
+		points    []orb.Point
+		ring      bool
+		quantizer *quantizer
+		expected  []orb.Point
+	}{
+		{"line", []orb.Point{{0, 0}, {1, 1}, {1, 1}, {2, 0}}, false, nil, []orb.Point{{0, 0}, {1, 1}, {2, 0}}},
+		{"collapsed line", []orb.Point{{1, 1}, {1, 1}}, false, nil, []orb.Point{{1, 1}, {1, 1}}},
+		{"open ring", []orb.Point{{0, 0}, {1, 0}, {1, 1}}, true, nil, []orb.Point{{0, 0}, {1, 0}, {1, 1}, {0, 0}}},
+		{"collapsed ring", []orb.Point{{0, 0}, {0, 0}, {0, 0}}, true, nil, []orb.Point{{0, 0}, {0, 0}, {0, 0}, {0, 0}}},
+		{"quantized", []orb.Point{{0.2, 0.1}, {0.4, -0.1}, {2.6, 3}}, false, q, []orb.Point{{0, 0}, {3, 3}}},
+	}
+
+	for _, test := range tests {
+		p := newPath(test.points, test.ring, test.quantizer)
+		if !reflect.DeepEqual(p.points, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, p.points)
+		}
+	}
+}
+
+func TestQuantizer(t *testing.T) {
+	q, transform := newQuantizer(orb.Bound{Min: orb.Point{-10, 20}, Max: orb.Point{10, 30}}, 101)
+
+	if expected := (Transform{Scale: [2]float64{0.2, 0.1}, Translate: [2]float64{-10, 20}}); *transform != expected {
+		t.Errorf("Expected transform %v, got %v", expected, *transform)
+	}
+
+	tests := []struct {
+		point    orb.Point
+		expected orb.Point
+	}{
+		{orb.Point{-10, 20}, orb.Point{0, 0}},
+		{orb.Point{10, 30}, orb.Point{100, 100}},
+		{orb.Point{0, 25}, orb.Point{50, 50}},
+		{orb.Point{-10.05, 19.99}, orb.Point{0, 0}},
+	}
+
+	for _, test := range tests {
+		got := q.point(test.point)
+		if got != test.expected {
+			t.Errorf("Expected %v for %v, got %v", test.expected, test.point, got)
+		}
+		if got := mustMarshal(t, got); got != "["+mustMarshal(t, test.expected[0])+","+mustMarshal(t, test.expected[1])+"]" {
+			t.Errorf("Expected no negative zero for %v, got %s", test.point, got)
+		}
+	}
+}
+
+func TestBuildArcs(t *testing.T) {
+	tests := []struct {
+		name     string
+		paths    []*path
+		arcs     int
+		expected [][]int
+	}{
+		{
+			name: "adjacent squares",
+			paths: []*path{
+				{points: []orb.Point{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}, ring: true},
+				{points: []orb.Point{{1, 0}, {2, 0}, {2, 1}, {1, 1}, {1, 0}}, ring: true},
+			},
+			arcs:     3,
+			expected: [][]int{{0, 1}, {2, ^0}},
+		},
+		{
+			name: "equal rings",
+			paths: []*path{
+				{points: []orb.Point{{1, 1}, {2, 1}, {2, 2}, {1, 1}}, ring: true},
+				{points: []orb.Point{{2, 2}, {2, 1}, {1, 1}, {2, 2}}, ring: true},
+			},
+			arcs:     1,
+			expected: [][]int{{0}, {^0}},
+		},
+		{
+			name: "line along ring",
+			paths: []*path{
+				{points: []orb.Point{{0, 0}, {2, 0}, {2, 2}, {0, 2}, {0, 0}}, ring: true},
+				{points: []orb.Point{{2, 2}, {2, 0}}, ring: false},
+			},
+			arcs:     2,
+			expected: [][]int{{0, 1}, {^0}},
+		},
+		{
+			name: "crossing lines",
+			paths: []*path{
+				{points: []orb.Point{{0, 1}, {1, 1}, {2, 1}}},
+				{points: []orb.Point{{1, 0}, {1, 1}, {1, 2}}},
+			},
+			arcs:     4,
+			expected: [][]int{{0, 1}, {2, 3}},
+		},
+		{
+			name: "repeated line",
+			paths: []*path{
+				{points: []orb.Point{{0, 0}, {1, 1}, {2, 0}}},
+				{points: []orb.Point{{2, 0}, {1, 1}, {0, 0}}},
+			},
+			arcs:     1,
+			expected: [][]int{{0}, {^0}},
+		},
+	}
+
+	for _, test := range tests {
+		arcs := buildArcs(test.paths)
+		if len(arcs) != test.arcs {
+			t.Errorf("%s: expected %d arcs, got %d: %v", test.name, test.arcs, len(arcs), arcs)
+		}
+		for i, p := range test.paths {
+			if !reflect.DeepEqual(p.arcs, test.expected[i]) {
+				t.Errorf("%s: expected path %d arcs %v, got %v", test.name, i, test.expected[i], p.arcs)
+			}
+			// The arcs must trace the original path, apart from the start of a ring
+			if got := tracePath(arcs, p.arcs); !sameRing(got, p.points, p.ring) {
+				t.Errorf("%s: expected path %d to trace %v, got %v", test.name, i, p.points, got)
+			}
+		}
+	}
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	writer, err := NewWriter(Options{Quantization: 3, LayerProperty: "_layer"})
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+
+	left := orb.Polygon{{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}}
+	right := orb.Polygon{{{1, 0}, {2, 0}, {2, 1}, {1, 1}, {1, 0}}}
+	road := orb.LineString{{0, 2}, {1, 1}, {2, 2}}
+
+	features := []*geojson.Feature{
+		newFeature(left, map[string]interface{}{"_layer": "admin", "name": "left"}, 1),
+		newFeature(road, map[string]interface{}{"_layer": "roads"}, nil),
+		newFeature(right, map[string]interface{}{"_layer": "admin", "name": "right"}, 2),
+		newFeature(orb.Point{2, 2}, map[string]interface{}{"kind": "peak"}, nil),
+		newFeature(nil, map[string]interface{}{"_layer": "roads", "name": "unknown"}, nil),
+	}
+	for _, feature := range features {
+		if err := writer.Write(feature); err != nil {
+			t.Fatalf("Failed to write feature: %v", err)
+		}
+	}
+
+	if writer.FeatureCount() != 5 {
+		t.Errorf("Expected 5 features, got %d", writer.FeatureCount())
+	}
+	if expected := []string{"admin", "roads", DefaultObject}; !reflect.DeepEqual(writer.Objects(), expected) {
+		t.Errorf("Expected objects %v, got %v", expected, writer.Objects())
+	}
+
+	var buf bytes.Buffer
+	if _, err := writer.WriteTo(&buf); err != nil {
+		t.Fatalf("Failed to write topology: %v", err)
+	}
+
+	var topology struct {
+		Type      string        `json:"type"`
+		BBox      []float64     `json:"bbox"`
+		Transform *Transform    `json:"transform"`
+		Arcs      [][]orb.Point `json:"arcs"`
+		Objects   map[string]struct {
+			Type       string `json:"type"`
+			Geometries []struct {
+				Type        *string                `json:"type"`
+				ID          interface{}            `json:"id"`
+				Properties  map[string]interface{} `json:"properties"`
+				Coordinates json.RawMessage        `json:"coordinates"`
+				Arcs        json.RawMessage        `json:"arcs"`
+			} `json:"geometries"`
+		} `json:"objects"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &topology); err != nil {
+		t.Fatalf("Failed to parse topology: %v", err)
+	}
+
+	if topology.Type != "Topology" {
+		t.Errorf("Expected type Topology, got %s", topology.Type)
+	}
+	if expected := []float64{0, 0, 2, 2}; !reflect.DeepEqual(topology.BBox, expected) {
+		t.Errorf("Expected bbox %v, got %v", expected, topology.BBox)
+	}
+	if topology.Transform == nil || topology.Transform.Scale != [2]float64{1, 1} {
+		t.Fatalf("Expected a unit transform, got %v", topology.Transform)
+	}
+
+	// Undo the delta encoding; the unit transform leaves positions as coordinates
+	arcs := make([][]orb.Point, len(topology.Arcs))
+	for i, arc := range topology.Arcs {
+		x, y := 0.0, 0.0
+		for _, p := range arc {
+			x, y = x+p[0], y+p[1]
+			arcs[i] = append(arcs[i], orb.Point{x, y})
+		}
+	}
+	// The polygons share one arc, and the road is cut where it touches their corner
+	if len(arcs) != 5 {
+		t.Errorf("Expected 5 arcs, got %d: %v", len(arcs), arcs)
+	}
+
+	admin := topology.Objects["admin"]
+	if admin.Type != "GeometryCollection" || len(admin.Geometries) != 2 {
+		t.Fatalf("Expected a collection of 2 admin geometries, got %+v", admin)
+	}
+	for i, polygon := range []orb.Polygon{left, right} {
+		g := admin.Geometries[i]
+		if g.Type == nil || *g.Type != "Polygon" {
+			t.Errorf("Expected admin geometry %d to be a Polygon, got %v", i, g.Type)
+			continue
+		}
+		var rings [][]int
+		if err := json.Unmarshal(g.Arcs, &rings); err != nil || len(rings) != 1 {
+			t.Fatalf("Expected one ring for admin geometry %d, got %s", i, g.Arcs)
+		}
+		if got := tracePath(arcs, rings[0]); !sameRing(got, polygon[0], true) {
+			t.Errorf("Expected admin geometry %d ring %v, got %v", i, polygon[0], got)
+		}
+		if _, ok := g.Properties["_layer"]; ok {
+			t.Errorf("Expected the layer property to be removed, got %v", g.Properties)
+		}
+	}
+	if admin.Geometries[0].Properties["name"] != "left" || admin.Geometries[1].ID != float64(2) {
+		t.Errorf("Expected properties and IDs to be kept, got %+v", admin.Geometries)
+	}
+
+	roads := topology.Objects["roads"]
+	if len(roads.Geometries) != 2 {
+		t.Fatalf("Expected 2 road geometries, got %d", len(roads.Geometries))
+	}
+	var lineArcs []int
+	if err := json.Unmarshal(roads.Geometries[0].Arcs, &lineArcs); err != nil {
+		t.Fatalf("Failed to parse line arcs: %v", err)
+	}
+	if got := tracePath(arcs, lineArcs); !reflect.DeepEqual(got, []orb.Point(road)) {
+		t.Errorf("Expected line %v, got %v", road, got)
+	}
+	if roads.Geometries[0].Properties != nil {
+		t.Errorf("Expected no properties, got %v", roads.Geometries[0].Properties)
+	}
+	if null := roads.Geometries[1]; null.Type != nil || null.Properties["name"] != "unknown" {
+		t.Errorf("Expected a null geometry with properties, got %+v", null)
+	}
+
+	point := topology.Objects[DefaultObject].Geometries[0]
+	if string(point.Coordinates) != "[2,2]" || point.Properties["kind"] != "peak" {
+		t.Errorf("Expected quantized point [2,2] with its properties, got %s %v", point.Coordinates, point.Properties)
+	}
+}
+
+func TestWriterFullPrecision(t *testing.T) {
+	writer, err := NewWriter(Options{})
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	if err := writer.Write(newFeature(orb.LineString{{0.5, 0.25}, {1.125, 2}}, nil, nil)); err != nil {
+		t.Fatalf("Failed to write feature: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := writer.WriteTo(&buf); err != nil {
+		t.Fatalf("Failed to write topology: %v", err)
+	}
+
+	expected := `{"type":"Topology","bbox":[0.5,0.25,1.125,2],"objects":{"features":{"type":"GeometryCollection","geometries":[{"type":"LineString","arcs":[0]}]}},"arcs":[[[0.5,0.25],[1.125,2]]]}`
+	if buf.String() != expected {
+		t.Errorf("Expected %s, got %s", expected, buf.String())
+	}
+}
+
+func TestWriterEmpty(t *testing.T) {
+	writer, err := NewWriter(Options{Quantization: 10000})
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := writer.WriteTo(&buf); err != nil {
+		t.Fatalf("Failed to write topology: %v", err)
+	}
+
+	if expected := `{"type":"Topology","objects":{},"arcs":[]}`; buf.String() != expected {
+		t.Errorf("Expected %s, got %s", expected, buf.String())
+	}
+}
+
+func TestNewWriterInvalidOptions(t *testing.T) {
+	for _, quantization := range []int{-1, 1} {
+		if _, err := NewWriter(Options{Quantization: quantization}); err == nil {
+			t.Errorf("Expected an error for quantization %d", quantization)
+		}
+	}
+}
+
+func newFeature(geometry orb.Geometry, properties map[string]interface{}, id interface{}) *geojson.Feature {
+	feature := &geojson.Feature{Type: "Feature", Geometry: geometry, Properties: properties, ID: id}
+	if feature.Properties == nil {
+		feature.Properties = geojson.Properties{}
+	}
+	return feature
+}
+
+func mustMarshal(t *testing.T, value interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("Failed to marshal %v: %v", value, err)
+	}
+	return string(data)
+}
+
+// tracePath joins the arcs of a line or ring, reversing negative references and
+// dropping the shared point between consecutive arcs
+func tracePath(arcs [][]orb.Point, refs []int) []orb.Point {
+	var points []orb.Point
+	for _, ref := range refs {
+		var arc []orb.Point
+		if ref >= 0 {
+			arc = arcs[ref]
+		} else {
+			arc = make([]orb.Point, len(arcs[^ref]))
+			for i, p := range arcs[^ref] {
+				arc[len(arc)-1-i] = p
+			}
+		}
+		if len(points) > 0 {
+			arc = arc[1:]
+		}
+		points = append(points, arc...)
+	}
+	return points
+}
+
+// sameRing reports whether two closed rings have the same points in the same order,
+// starting anywhere; lines must match exactly
+func sameRing(a, b []orb.Point, ring bool) bool {
+	if !ring || len(a) != len(b) {
+		return reflect.DeepEqual(a, b)
+	}
+	n := len(a) - 1
+	for start := 0; start < n; start++ {
+		if equalPoints(rotate(a, start), b) {
+			return true
+		}
+	}
+	return false
+}
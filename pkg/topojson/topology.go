@@ -0,0 +1,229 @@
+// pkg/topojson/topology.go - Junction detection and shared arc extraction
+package topojson
+
+import (
+	"github.com/paulmach/orb"
+)
+
+// path is a line or ring of a geometry, with its arc references once arcs are built
+type path struct {
+	points []orb.Point
+	ring   bool
+	arcs   []int
+}
+
+// newPath quantizes the points of a line or ring and removes repeated points. Rings
+// are closed, and lines padded to two points and rings to four so collapsed paths
+// still have an arc.
+func newPath(points []orb.Point, ring bool, q *quantizer) *path {
+	cleaned := make([]orb.Point, 0, len(points)+1)
+	for _, p := range points {
+		p = q.point(p)
+		if len(cleaned) == 0 || p != cleaned[len(cleaned)-1] {
+			cleaned = append(cleaned, p)
+		}
+	}
+
+	minimum := 2
+	if ring {
+		if len(cleaned) > 1 && cleaned[0] != cleaned[len(cleaned)-1] {
+			cleaned = append(cleaned, cleaned[0])
+		}
+		minimum = 4
+	}
+	for len(cleaned) < minimum {
+		cleaned = append(cleaned, cleaned[0])
+	}
+	return &path{points: cleaned, ring: ring}
+}
+
+// neighbors records the neighbours a point was first seen with
+type neighbors struct {
+	prev, next orb.Point
+}
+
+// findJunctions returns the points where paths meet or part: the ends of lines, and
+// points that are visited again with different neighbours
+func findJunctions(paths []*path) map[orb.Point]bool {
+	junctions := make(map[orb.Point]bool)
+	seen := make(map[orb.Point]neighbors)
+
+	visit := func(point, prev, next orb.Point) {
+		if junctions[point] {
+			return
+		}
+		first, ok := seen[point]
+		if !ok {
+			seen[point] = neighbors{prev: prev, next: next}
+			return
+		}
+		if (first.prev != prev || first.next != next) && (first.prev != next || first.next != prev) {
+			junctions[point] = true
+		}
+	}
+
+	for _, p := range paths {
+		points := p.points
+		if !p.ring {
+			junctions[points[0]] = true
+			junctions[points[len(points)-1]] = true
+			for i := 1; i < len(points)-1; i++ {
+				visit(points[i], points[i-1], points[i+1])
+			}
+			continue
+		}
+		n := len(points) - 1
+		for i := 0; i < n; i++ {
+			visit(points[i], points[(i+n-1)%n], points[i+1])
+		}
+	}
+
+	return junctions
+}
+
+// cut splits a path into arcs at its junctions. A ring with junctions is first rotated
+// to start at one; a ring without junctions is a single closed arc, rotated to start at
+// its lowest point so equal rings compare equal.
+func cut(p *path, junctions map[orb.Point]bool) (segments [][]orb.Point, closed bool) {
+	points := p.points
+	if p.ring {
+		n := len(points) - 1
+		start := -1
+		for i := 0; i < n; i++ {
+			if junctions[points[i]] {
+				start = i
+				break
+			}
+		}
+		if start < 0 {
+			return [][]orb.Point{rotate(points, lowest(points[:n]))}, true
+		}
+		points = rotate(points, start)
+	}
+
+	first := 0
+	for i := 1; i < len(points)-1; i++ {
+		if junctions[points[i]] {
+			segments = append(segments, points[first:i+1])
+			first = i
+		}
+	}
+	return append(segments, points[first:]), false
+}
+
+// rotate returns a closed ring starting at index start
+func rotate(ring []orb.Point, start int) []orb.Point {
+	n := len(ring) - 1
+	rotated := make([]orb.Point, 0, n+1)
+	rotated = append(rotated, ring[start:n]...)
+	rotated = append(rotated, ring[:start]...)
+	return append(rotated, ring[start])
+}
+
+// lowest returns the index of the lowest point by x, then y
+func lowest(points []orb.Point) int {
+	index := 0
+	for i, p := range points {
+		if p[0] < points[index][0] || p[0] == points[index][0] && p[1] < points[index][1] {
+			index = i
+		}
+	}
+	return index
+}
+
+// arcSet deduplicates arcs, so each is stored once and shared in either direction
+type arcSet struct {
+	arcs  [][]orb.Point
+	lines map[[2]orb.Point][]int // arcs between junctions by first and last point
+	rings map[orb.Point][]int    // closed arcs without junctions by first point
+}
+
+func newArcSet() *arcSet {
+	return &arcSet{
+		lines: make(map[[2]orb.Point][]int),
+		rings: make(map[orb.Point][]int),
+	}
+}
+
+// add returns the reference of a segment, adding it when no arc matches it either way
+func (s *arcSet) add(segment []orb.Point, closed bool) int {
+	first, last := segment[0], segment[len(segment)-1]
+
+	if closed {
+		for _, i := range s.rings[first] {
+			if equalPoints(s.arcs[i], segment) {
+				return i
+			}
+			if equalReversed(s.arcs[i], segment) {
+				return ^i
+			}
+		}
+		s.rings[first] = append(s.rings[first], len(s.arcs))
+	} else {
+		for _, i := range s.lines[[2]orb.Point{first, last}] {
+			if equalPoints(s.arcs[i], segment) {
+				return i
+			}
+		}
+		for _, i := range s.lines[[2]orb.Point{last, first}] {
+			if equalReversed(s.arcs[i], segment) {
+				return ^i
+			}
+		}
+		key := [2]orb.Point{first, last}
+		s.lines[key] = append(s.lines[key], len(s.arcs))
+	}
+
+	s.arcs = append(s.arcs, segment)
+	return len(s.arcs) - 1
+}
+
+func equalPoints(a, b []orb.Point) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func equalReversed(a, b []orb.Point) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[len(b)-1-i] {
+			return false
+		}
+	}
+	return true
+}
+
+// buildArcs cuts all paths at their junctions and sets the arc references of each,
+// returning the shared arcs
+func buildArcs(paths []*path) [][]orb.Point {
+	junctions := findJunctions(paths)
+	set := newArcSet()
+	for _, p := range paths {
+		segments, closed := cut(p, junctions)
+		p.arcs = make([]int, len(segments))
+		for i, segment := range segments {
+			p.arcs[i] = set.add(segment, closed)
+		}
+	}
+	return set.arcs
+}
+
+// deltaEncode returns an arc with each position after the first relative to the one
+// before it
+func deltaEncode(arc []orb.Point) []orb.Point {
+	encoded := make([]orb.Point, len(arc))
+	encoded[0] = arc[0]
+	for i := 1; i < len(arc); i++ {
+		encoded[i] = orb.Point{arc[i][0] - arc[i-1][0], arc[i][1] - arc[i-1][1]}
+	}
+	return encoded
+}
@@ -0,0 +1,195 @@
+// pkg/topojson/writer.go - TopoJSON topology writer
+package topojson
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+// Options configures a topology
+type Options struct {
+	// Quantization is the number of grid positions per axis coordinates are snapped to
+	// before arcs are built; 0 keeps full precision and writes no transform
+	Quantization int
+
+	// LayerProperty names the property holding each feature's layer. Features are
+	// added to one object per layer and the property is not stored; features without
+	// it, or all features when it is empty, go to DefaultObject.
+	LayerProperty string
+
+	// Pretty indents the written JSON
+	Pretty bool
+}
+
+// Writer builds a TopoJSON topology from features written in any order. Arcs are shared
+// across all features, which are kept in memory until WriteTo builds the topology.
+// Writer is safe for concurrent use.
+type Writer struct {
+	options Options
+	objects []*object
+	byName  map[string]*object
+	count   int
+	mutex   sync.Mutex
+}
+
+// object is a named collection of the topology
+type object struct {
+	name     string
+	features []*geojson.Feature
+}
+
+// NewWriter creates a writer
+func NewWriter(options Options) (*Writer, error) {
+	if options.Quantization < 0 || options.Quantization == 1 {
+		return nil, fmt.Errorf("invalid quantization %d", options.Quantization)
+	}
+	return &Writer{
+		options: options,
+		byName:  make(map[string]*object),
+	}, nil
+}
+
+// Write adds a feature to the object of its layer
+func (w *Writer) Write(feature *geojson.Feature) error {
+	if feature == nil {
+		return nil
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	name := ""
+	if w.options.LayerProperty != "" {
+		name, _ = feature.Properties[w.options.LayerProperty].(string)
+	}
+	if name == "" {
+		name = DefaultObject
+	}
+
+	o, ok := w.byName[name]
+	if !ok {
+		o = &object{name: name}
+		w.byName[name] = o
+		w.objects = append(w.objects, o)
+	}
+	o.features = append(o.features, feature)
+	w.count++
+	return nil
+}
+
+// FeatureCount returns the number of features written
+func (w *Writer) FeatureCount() int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.count
+}
+
+// Objects returns the object names in the order they were first written
+func (w *Writer) Objects() []string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	names := make([]string, len(w.objects))
+	for i, o := range w.objects {
+		names[i] = o.name
+	}
+	return names
+}
+
+// Topology builds the topology of the features written so far
+func (w *Writer) Topology() (*Topology, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	var geometries []orb.Geometry
+	for _, o := range w.objects {
+		for _, feature := range o.features {
+			geometries = append(geometries, feature.Geometry)
+		}
+	}
+
+	topology := &Topology{
+		Type:    "Topology",
+		Objects: make(map[string]*Geometry, len(w.objects)),
+		Arcs:    [][]orb.Point{},
+	}
+
+	b := &builder{}
+	if bound, ok := bounds(geometries); ok {
+		topology.BBox = []float64{bound.Min[0], bound.Min[1], bound.Max[0], bound.Max[1]}
+		if w.options.Quantization > 0 {
+			b.quantizer, topology.Transform = newQuantizer(bound, w.options.Quantization)
+		}
+	}
+
+	// Collect every line and ring, then cut them into arcs shared across all objects
+	for _, geometry := range geometries {
+		if _, err := b.geometry(geometry); err != nil {
+			return nil, err
+		}
+	}
+	for _, arc := range buildArcs(b.paths) {
+		if topology.Transform != nil {
+			arc = deltaEncode(arc)
+		}
+		topology.Arcs = append(topology.Arcs, arc)
+	}
+
+	b.resolve = true
+	for _, o := range w.objects {
+		collection := &Geometry{Type: "GeometryCollection", Geometries: make([]*Geometry, 0, len(o.features))}
+		for _, feature := range o.features {
+			g, err := b.geometry(feature.Geometry)
+			if err != nil {
+				return nil, err
+			}
+			g.ID = feature.ID
+			g.Properties = w.properties(feature)
+			collection.Geometries = append(collection.Geometries, g)
+		}
+		topology.Objects[o.name] = collection
+	}
+
+	return topology, nil
+}
+
+// properties returns a feature's properties without its layer property, or nil when
+// none remain
+func (w *Writer) properties(feature *geojson.Feature) map[string]interface{} {
+	properties := make(map[string]interface{}, len(feature.Properties))
+	for key, value := range feature.Properties {
+		if key != w.options.LayerProperty || w.options.LayerProperty == "" {
+			properties[key] = value
+		}
+	}
+	if len(properties) == 0 {
+		return nil
+	}
+	return properties
+}
+
+// WriteTo builds the topology and writes it as JSON
+func (w *Writer) WriteTo(out io.Writer) (int64, error) {
+	topology, err := w.Topology()
+	if err != nil {
+		return 0, err
+	}
+
+	var data []byte
+	if w.options.Pretty {
+		data, err = json.MarshalIndent(topology, "", "  ")
+	} else {
+		data, err = json.Marshal(topology)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal topology: %w", err)
+	}
+
+	n, err := out.Write(data)
+	return int64(n), err
+}
@@ -0,0 +1,180 @@
+// pkg/wellknown/wellknown.go - WKT and WKB feature rows for database loading
+package wellknown
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/ewkb"
+	"github.com/paulmach/orb/encoding/wkt"
+	"github.com/paulmach/orb/geojson"
+)
+
+// Encoding selects how a Writer encodes features
+type Encoding string
+
+const (
+	EncodingWKT    Encoding = "wkt"    // Text rows with a WKT geometry column
+	EncodingHex    Encoding = "hex"    // Text rows with a hex-encoded WKB geometry column
+	EncodingBinary Encoding = "binary" // Raw WKB records, one after another
+)
+
+// copyNull is the NULL marker of PostgreSQL's COPY text format
+const copyNull = `\N`
+
+// MarshalText encodes a geometry as WKT, or as EWKT prefixed with SRID=<srid>; when
+// srid is not 0. A nil geometry encodes as "".
+func MarshalText(geometry orb.Geometry, srid int) string {
+	if geometry == nil {
+		return ""
+	}
+	text := wkt.MarshalString(geometry)
+	if srid != 0 {
+		text = fmt.Sprintf("SRID=%d;%s", srid, text)
+	}
+	return text
+}
+
+// MarshalBinary encodes a geometry as little-endian WKB, or as EWKB carrying srid
+// when it is not 0. A nil geometry encodes as an empty GEOMETRYCOLLECTION.
+func MarshalBinary(geometry orb.Geometry, srid int) ([]byte, error) {
+	if geometry == nil {
+		geometry = orb.Collection{}
+	}
+	data, err := ewkb.Marshal(geometry, srid, binary.LittleEndian)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s geometry: %w", geometry.GeoJSONType(), err)
+	}
+	return data, nil
+}
+
+// Options configures a Writer
+type Options struct {
+	Encoding Encoding
+
+	// SRID is written with each geometry, as EWKT or EWKB; 0 writes plain WKT or WKB
+	SRID int
+
+	// Copy escapes text rows for PostgreSQL's COPY text format: backslashes are
+	// doubled and missing geometries are written as \N
+	Copy bool
+}
+
+// Writer writes features as database load rows. Text encodings write one line per
+// feature with a geometry column and a JSON properties column separated by a tab;
+// the binary encoding writes each geometry as a WKB record without properties. Writer
+// is safe for concurrent use.
+type Writer struct {
+	options Options
+	out     *bufio.Writer
+	count   int
+	mutex   sync.Mutex
+}
+
+// NewWriter creates a writer. Rows are buffered until Flush.
+func NewWriter(out io.Writer, options Options) (*Writer, error) {
+	switch options.Encoding {
+	case EncodingWKT, EncodingHex, EncodingBinary:
+	default:
+		return nil, fmt.Errorf("invalid encoding %q, must be %s, %s or %s", options.Encoding, EncodingWKT, EncodingHex, EncodingBinary)
+	}
+	if options.SRID < 0 {
+		return nil, fmt.Errorf("invalid srid %d", options.SRID)
+	}
+
+	return &Writer{
+		options: options,
+		out:     bufio.NewWriter(out),
+	}, nil
+}
+
+// Write writes the row or record of a feature
+func (w *Writer) Write(feature *geojson.Feature) error {
+	if feature == nil {
+		return nil
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.options.Encoding == EncodingBinary {
+		data, err := MarshalBinary(feature.Geometry, w.options.SRID)
+		if err != nil {
+			return err
+		}
+		if _, err := w.out.Write(data); err != nil {
+			return fmt.Errorf("failed to write record: %w", err)
+		}
+		w.count++
+		return nil
+	}
+
+	row, err := w.row(feature)
+	if err != nil {
+		return err
+	}
+	if _, err := w.out.WriteString(row); err != nil {
+		return fmt.Errorf("failed to write row: %w", err)
+	}
+	w.count++
+	return nil
+}
+
+// row returns the text row of a feature, ending with a newline
+func (w *Writer) row(feature *geojson.Feature) (string, error) {
+	geometry := ""
+	switch {
+	case feature.Geometry == nil && w.options.Copy:
+		geometry = copyNull
+	case feature.Geometry == nil:
+	case w.options.Encoding == EncodingHex:
+		data, err := MarshalBinary(feature.Geometry, w.options.SRID)
+		if err != nil {
+			return "", err
+		}
+		geometry = strings.ToUpper(hex.EncodeToString(data))
+	default:
+		geometry = MarshalText(feature.Geometry, w.options.SRID)
+	}
+
+	properties := []byte("{}")
+	if len(feature.Properties) > 0 {
+		data, err := json.Marshal(feature.Properties)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode properties: %w", err)
+		}
+		properties = data
+	}
+
+	// JSON escapes control characters, so backslashes are all that COPY needs escaped
+	text := string(properties)
+	if w.options.Copy {
+		text = strings.ReplaceAll(text, `\`, `\\`)
+	}
+	return geometry + "\t" + text + "\n", nil
+}
+
+// FeatureCount returns the number of features written
+func (w *Writer) FeatureCount() int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.count
+}
+
+// Flush writes any buffered rows to the underlying writer
+func (w *Writer) Flush() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if err := w.out.Flush(); err != nil {
+		return fmt.Errorf("failed to flush rows: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+// pkg/wellknown/wellknown_test.go - Unit tests for WKT and WKB rows
+package wellknown
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/ewkb"
+	"github.com/paulmach/orb/geojson"
+)
+
+func TestMarshalText(t *testing.T) {
+	tests := []struct {
+		name     string
+		geometry orb.Geometry
+		srid     int
+		expected string
+	}{
+		{"point", orb.Point{1.5, -2}, 0, "POINT(1.5 -2)"},
+		{"line with srid", orb.LineString{{0, 0}, {1, 1}}, 4326, "SRID=4326;LINESTRING(0 0,1 1)"},
+		{"polygon", orb.Polygon{{{0, 0}, {1, 0}, {1, 1}, {0, 0}}}, 3857, "SRID=3857;POLYGON((0 0,1 0,1 1,0 0))"},
+		{"nil", nil, 4326, ""},
+	}
+
+	for _, test := range tests {
+		if got := MarshalText(test.geometry, test.srid); got != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestMarshalBinary(t *testing.T) {
+	tests := []struct {
+		name     string
+		geometry orb.Geometry
+		srid     int
+		expected orb.Geometry
+		header   string
+	}{
+		{"point", orb.Point{1, 2}, 0, orb.Point{1, 2}, "0101000000"},
+		{"point with srid", orb.Point{1, 2}, 4326, orb.Point{1, 2}, "0101000020e6100000"},
+		{"multipolygon with srid", orb.MultiPolygon{{{{0, 0}, {1, 0}, {1, 1}, {0, 0}}}}, 3857, orb.MultiPolygon{{{{0, 0}, {1, 0}, {1, 1}, {0, 0}}}}, "0106000020110f0000"},
+		{"nil", nil, 0, orb.Collection{}, "0107000000"},
+	}
+
+	for _, test := range tests {
+		data, err := MarshalBinary(test.geometry, test.srid)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.name, err)
+		}
+		if header := hex.EncodeToString(data); !strings.HasPrefix(header, test.header) {
+			t.Errorf("%s: expected header %s, got %s", test.name, test.header, header)
+		}
+
+		geometry, srid, err := ewkb.Unmarshal(data)
+		if err != nil {
+			t.Fatalf("%s: failed to decode: %v", test.name, err)
+		}
+		if srid != test.srid {
+			t.Errorf("%s: expected srid %d, got %d", test.name, test.srid, srid)
+		}
+		if !reflect.DeepEqual(geometry, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, geometry)
+		}
+	}
+}
+
+func testFeatures() []*geojson.Feature {
+	point := geojson.NewFeature(orb.Point{1, 2})
+	point.Properties["layer"] = "poi"
+	point.Properties["name"] = `a "quoted" \ name`
+
+	empty := geojson.NewFeature(nil)
+
+	line := geojson.NewFeature(orb.LineString{{0, 0}, {1, 1}})
+	line.Properties = nil
+	return []*geojson.Feature{point, empty, line}
+}
+
+func TestWriterText(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  Options
+		expected []string
+	}{
+		{
+			"wkt",
+			Options{Encoding: EncodingWKT},
+			[]string{
+				`POINT(1 2)` + "\t" + `{"layer":"poi","name":"a \"quoted\" \\ name"}`,
+				"\t{}",
+				"LINESTRING(0 0,1 1)\t{}",
+			},
+		},
+		{
+			"ewkt for copy",
+			Options{Encoding: EncodingWKT, SRID: 4326, Copy: true},
+			[]string{
+				`SRID=4326;POINT(1 2)` + "\t" + `{"layer":"poi","name":"a \\"quoted\\" \\\\ name"}`,
+				`\N` + "\t{}",
+				"SRID=4326;LINESTRING(0 0,1 1)\t{}",
+			},
+		},
+		{
+			"hex ewkb for copy",
+			Options{Encoding: EncodingHex, SRID: 3857, Copy: true},
+			[]string{
+				"0101000020110F0000000000000000F03F0000000000000040\t" + `{"layer":"poi","name":"a \\"quoted\\" \\\\ name"}`,
+				`\N` + "\t{}",
+				"0102000020110F00000200000000000000000000000000000000000000000000000000F03F000000000000F03F\t{}",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		writer, err := NewWriter(&buf, test.options)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.name, err)
+		}
+		for _, feature := range testFeatures() {
+			if err := writer.Write(feature); err != nil {
+				t.Fatalf("%s: failed to write feature: %v", test.name, err)
+			}
+		}
+		if err := writer.Flush(); err != nil {
+			t.Fatalf("%s: failed to flush: %v", test.name, err)
+		}
+
+		if writer.FeatureCount() != 3 {
+			t.Errorf("%s: expected 3 features, got %d", test.name, writer.FeatureCount())
+		}
+		expected := strings.Join(test.expected, "\n") + "\n"
+		if buf.String() != expected {
+			t.Errorf("%s: expected %q, got %q", test.name, expected, buf.String())
+		}
+	}
+}
+
+func TestWriterBinary(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewWriter(&buf, Options{Encoding: EncodingBinary, SRID: 4326, Copy: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, feature := range testFeatures() {
+		if err := writer.Write(feature); err != nil {
+			t.Fatalf("Failed to write feature: %v", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+
+	expected := []orb.Geometry{orb.Point{1, 2}, orb.Collection{}, orb.LineString{{0, 0}, {1, 1}}}
+	decoder := ewkb.NewDecoder(&buf)
+	for i, want := range expected {
+		geometry, srid, err := decoder.Decode()
+		if err != nil {
+			t.Fatalf("Failed to decode record %d: %v", i, err)
+		}
+		if srid != 4326 {
+			t.Errorf("Expected record %d srid 4326, got %d", i, srid)
+		}
+		if !reflect.DeepEqual(geometry, want) {
+			t.Errorf("Expected record %d to be %v, got %v", i, want, geometry)
+		}
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected no bytes after the records, got %d", buf.Len())
+	}
+}
+
+func TestNewWriterInvalidOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+	}{
+		{"missing encoding", Options{}},
+		{"unknown encoding", Options{Encoding: "geojson"}},
+		{"negative srid", Options{Encoding: EncodingWKT, SRID: -1}},
+	}
+
+	for _, test := range tests {
+		if _, err := NewWriter(&bytes.Buffer{}, test.options); err == nil {
+			t.Errorf("%s: expected an error, got nil", test.name)
+		}
+	}
+}